@@ -22,6 +22,10 @@ type ApiGroup struct {
 	AutoCodeHistoryApi
 	AutoCodeTemplateApi
 	SysParamsApi
+	AuthorityClaimMapApi
+	ApiKeyApi
+	LoginAttemptApi
+	SignUpApi
 }
 
 var (
@@ -44,4 +48,23 @@ var (
 	autoCodePackageService  = service.ServiceGroupApp.SystemServiceGroup.AutoCodePackage
 	autoCodeHistoryService  = service.ServiceGroupApp.SystemServiceGroup.AutoCodeHistory
 	autoCodeTemplateService = service.ServiceGroupApp.SystemServiceGroup.AutoCodeTemplate
+	refreshTokenService     = service.ServiceGroupApp.SystemServiceGroup.RefreshTokenService
+	mfaService              = service.ServiceGroupApp.SystemServiceGroup.MfaService
+	sessionService          = service.ServiceGroupApp.SystemServiceGroup.SessionService
+	jwtKeyService           = service.ServiceGroupApp.SystemServiceGroup.JwtKeyService
+	oidcService             = service.ServiceGroupApp.SystemServiceGroup.OidcService
+	userIdentityService     = service.ServiceGroupApp.SystemServiceGroup.UserIdentityService
+	claimMapService         = service.ServiceGroupApp.SystemServiceGroup.AuthorityClaimMapService
+	authenticatorService    = service.ServiceGroupApp.SystemServiceGroup.AuthenticatorService
+	apiKeyService           = service.ServiceGroupApp.SystemServiceGroup.ApiKeyService
+	passwordPolicyService   = service.ServiceGroupApp.SystemServiceGroup.PasswordPolicyService
+	loginLockoutService     = service.ServiceGroupApp.SystemServiceGroup.LoginLockoutService
+	securityStampService    = service.ServiceGroupApp.SystemServiceGroup.SecurityStampService
+	impersonateService      = service.ServiceGroupApp.SystemServiceGroup.ImpersonateService
+	passwordResetService    = service.ServiceGroupApp.SystemServiceGroup.PasswordResetService
+	signUpService           = service.ServiceGroupApp.SystemServiceGroup.SignUpService
+	passkeyService          = service.ServiceGroupApp.SystemServiceGroup.PasskeyService
+	dataScopeService        = service.ServiceGroupApp.SystemServiceGroup.DataScopeService
+	fieldRuleService        = service.ServiceGroupApp.SystemServiceGroup.FieldRuleService
+	authorityVersionService = service.ServiceGroupApp.SystemServiceGroup.AuthorityVersionService
 )
@@ -127,7 +127,7 @@ func (s *SystemApiApi) EnterSyncApi(c *gin.Context) {
 		response.FailWithMessage(err.Error(), c)
 		return
 	}
-	err = apiService.EnterSyncApi(syncApi)
+	err = apiService.EnterSyncApi(utils.GetUserID(c), utils.GetUserName(c), syncApi)
 	if err != nil {
 		global.GVA_LOG.Error("忽略失败!", zap.Error(err))
 		response.FailWithMessage("忽略失败", c)
@@ -157,7 +157,7 @@ func (s *SystemApiApi) DeleteApi(c *gin.Context) {
 		response.FailWithMessage(err.Error(), c)
 		return
 	}
-	err = apiService.DeleteApi(api)
+	err = apiService.DeleteApi(utils.GetUserID(c), utils.GetUserName(c), api)
 	if err != nil {
 		global.GVA_LOG.Error("删除失败!", zap.Error(err))
 		response.FailWithMessage("删除失败", c)
@@ -252,7 +252,7 @@ func (s *SystemApiApi) UpdateApi(c *gin.Context) {
 		response.FailWithMessage(err.Error(), c)
 		return
 	}
-	err = apiService.UpdateApi(api)
+	err = apiService.UpdateApi(utils.GetUserID(c), utils.GetUserName(c), api)
 	if err != nil {
 		global.GVA_LOG.Error("修改失败!", zap.Error(err))
 		response.FailWithMessage("修改失败", c)
@@ -296,7 +296,7 @@ func (s *SystemApiApi) DeleteApisByIds(c *gin.Context) {
 		response.FailWithMessage(err.Error(), c)
 		return
 	}
-	err = apiService.DeleteApisByIds(ids)
+	err = apiService.DeleteApisByIds(utils.GetUserID(c), utils.GetUserName(c), ids)
 	if err != nil {
 		global.GVA_LOG.Error("删除失败!", zap.Error(err))
 		response.FailWithMessage("删除失败", c)
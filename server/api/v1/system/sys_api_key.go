@@ -0,0 +1,96 @@
+package system
+
+import (
+	"github.com/flipped-aurora/gin-vue-admin/server/global"
+	"github.com/flipped-aurora/gin-vue-admin/server/model/common/request"
+	"github.com/flipped-aurora/gin-vue-admin/server/model/common/response"
+	systemReq "github.com/flipped-aurora/gin-vue-admin/server/model/system/request"
+	systemRes "github.com/flipped-aurora/gin-vue-admin/server/model/system/response"
+	"github.com/flipped-aurora/gin-vue-admin/server/utils"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+type ApiKeyApi struct{}
+
+// CreateApiKey
+// @Tags      ApiKey
+// @Summary   创建API密钥
+// @Security  ApiKeyAuth
+// @accept    application/json
+// @Produce   application/json
+// @Param     data  body      systemReq.CreateApiKey                                       true  "名称, 过期时间, 接口范围, IP白名单"
+// @Success   200   {object}  response.Response{data=systemRes.ApiKeyResponse,msg=string}  "返回明文密钥 只显示一次"
+// @Router    /apiKey/createApiKey [post]
+func (a *ApiKeyApi) CreateApiKey(c *gin.Context) {
+	// 不允许用API密钥创建新的密钥 避免绕过原密钥的过期时间和接口范围
+	if _, ok := c.Get("apiKey"); ok {
+		response.FailWithMessage("API密钥不能用于创建新的密钥", c)
+		return
+	}
+	var req systemReq.CreateApiKey
+	err := c.ShouldBindJSON(&req)
+	if err != nil {
+		response.FailWithMessage(err.Error(), c)
+		return
+	}
+	err = utils.Verify(req, utils.ApiKeyVerify)
+	if err != nil {
+		response.FailWithMessage(err.Error(), c)
+		return
+	}
+	key, apiKey, err := apiKeyService.CreateApiKey(utils.GetUserID(c), utils.GetUserAuthorityId(c), req)
+	if err != nil {
+		global.GVA_LOG.Error("创建失败!", zap.Error(err))
+		response.FailWithMessage("创建失败:"+err.Error(), c)
+		return
+	}
+	response.OkWithDetailed(systemRes.ApiKeyResponse{Key: key, ApiKey: apiKey}, "创建成功, 请妥善保存密钥", c)
+}
+
+// DeleteApiKey
+// @Tags      ApiKey
+// @Summary   吊销API密钥
+// @Security  ApiKeyAuth
+// @accept    application/json
+// @Produce   application/json
+// @Param     data  body      request.GetById                true  "密钥ID"
+// @Success   200   {object}  response.Response{msg=string}  "吊销API密钥"
+// @Router    /apiKey/deleteApiKey [delete]
+func (a *ApiKeyApi) DeleteApiKey(c *gin.Context) {
+	var reqId request.GetById
+	err := c.ShouldBindJSON(&reqId)
+	if err != nil {
+		response.FailWithMessage(err.Error(), c)
+		return
+	}
+	err = utils.Verify(reqId, utils.IdVerify)
+	if err != nil {
+		response.FailWithMessage(err.Error(), c)
+		return
+	}
+	err = apiKeyService.DeleteApiKey(utils.GetUserID(c), uint(reqId.ID))
+	if err != nil {
+		global.GVA_LOG.Error("吊销失败!", zap.Error(err))
+		response.FailWithMessage("吊销失败:"+err.Error(), c)
+		return
+	}
+	response.OkWithMessage("吊销成功", c)
+}
+
+// GetApiKeyList
+// @Tags      ApiKey
+// @Summary   获取自身的API密钥列表
+// @Security  ApiKeyAuth
+// @Produce   application/json
+// @Success   200  {object}  response.Response{data=[]system.SysApiKey,msg=string}  "API密钥列表"
+// @Router    /apiKey/getApiKeyList [get]
+func (a *ApiKeyApi) GetApiKeyList(c *gin.Context) {
+	list, err := apiKeyService.GetApiKeyList(utils.GetUserID(c))
+	if err != nil {
+		global.GVA_LOG.Error("获取失败!", zap.Error(err))
+		response.FailWithMessage("获取失败", c)
+		return
+	}
+	response.OkWithDetailed(list, "获取成功", c)
+}
@@ -40,17 +40,11 @@ func (a *AuthorityApi) CreateAuthority(c *gin.Context) {
 		authority.ParentId = utils.Pointer(utils.GetUserAuthorityId(c))
 	}
 
-	if authBack, err = authorityService.CreateAuthority(authority); err != nil {
+	if authBack, err = authorityService.CreateAuthority(utils.GetUserID(c), utils.GetUserName(c), authority); err != nil {
 		global.GVA_LOG.Error("创建失败!", zap.Error(err))
 		response.FailWithMessage("创建失败"+err.Error(), c)
 		return
 	}
-	err = casbinService.FreshCasbin()
-	if err != nil {
-		global.GVA_LOG.Error("创建成功，权限刷新失败。", zap.Error(err))
-		response.FailWithMessage("创建成功，权限刷新失败。"+err.Error(), c)
-		return
-	}
 	response.OkWithDetailed(systemRes.SysAuthorityResponse{Authority: authBack}, "创建成功", c)
 }
 
@@ -81,7 +75,7 @@ func (a *AuthorityApi) CopyAuthority(c *gin.Context) {
 		return
 	}
 	adminAuthorityID := utils.GetUserAuthorityId(c)
-	authBack, err := authorityService.CopyAuthority(adminAuthorityID, copyInfo)
+	authBack, err := authorityService.CopyAuthority(adminAuthorityID, utils.GetUserID(c), utils.GetUserName(c), copyInfo)
 	if err != nil {
 		global.GVA_LOG.Error("拷贝失败!", zap.Error(err))
 		response.FailWithMessage("拷贝失败"+err.Error(), c)
@@ -141,7 +135,7 @@ func (a *AuthorityApi) UpdateAuthority(c *gin.Context) {
 		response.FailWithMessage(err.Error(), c)
 		return
 	}
-	authority, err := authorityService.UpdateAuthority(auth)
+	authority, err := authorityService.UpdateAuthority(utils.GetUserID(c), utils.GetUserName(c), auth)
 	if err != nil {
 		global.GVA_LOG.Error("更新失败!", zap.Error(err))
 		response.FailWithMessage("更新失败"+err.Error(), c)
@@ -192,7 +186,7 @@ func (a *AuthorityApi) SetDataAuthority(c *gin.Context) {
 		return
 	}
 	adminAuthorityID := utils.GetUserAuthorityId(c)
-	err = authorityService.SetDataAuthority(adminAuthorityID, auth)
+	err = authorityService.SetDataAuthority(adminAuthorityID, utils.GetUserID(c), utils.GetUserName(c), auth)
 	if err != nil {
 		global.GVA_LOG.Error("设置失败!", zap.Error(err))
 		response.FailWithMessage("设置失败"+err.Error(), c)
@@ -0,0 +1,78 @@
+package system
+
+import (
+	"github.com/flipped-aurora/gin-vue-admin/server/global"
+	"github.com/flipped-aurora/gin-vue-admin/server/model/common/response"
+	systemReq "github.com/flipped-aurora/gin-vue-admin/server/model/system/request"
+	"github.com/flipped-aurora/gin-vue-admin/server/utils"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// ExportAuthorityBundle
+// @Tags      Authority
+// @Summary   导出角色及其接口 菜单 按钮和资源权限
+// @Security  ApiKeyAuth
+// @accept    application/json
+// @Produce   application/json
+// @Param     data  query     systemReq.ExportAuthorityBundle                                     true  "角色ID, 格式json|yaml"
+// @Success   200   {object}  response.Response{data=systemRes.AuthorityBundleExport,msg=string}  "返回文件名和内容"
+// @Router    /authority/exportAuthorityBundle [get]
+func (a *AuthorityApi) ExportAuthorityBundle(c *gin.Context) {
+	var req systemReq.ExportAuthorityBundle
+	err := c.ShouldBindQuery(&req)
+	if err != nil {
+		response.FailWithMessage(err.Error(), c)
+		return
+	}
+	err = utils.Verify(req, utils.AuthorityIdVerify)
+	if err != nil {
+		response.FailWithMessage(err.Error(), c)
+		return
+	}
+	res, err := authorityService.ExportAuthorityBundle(utils.GetUserAuthorityId(c), req)
+	if err != nil {
+		global.GVA_LOG.Error("导出失败!", zap.Error(err))
+		response.FailWithMessage("导出失败: "+err.Error(), c)
+		return
+	}
+	response.OkWithDetailed(res, "导出成功", c)
+}
+
+// ImportAuthorityBundle
+// @Tags      Authority
+// @Summary   导入角色 可只预览差异
+// @Security  ApiKeyAuth
+// @accept    application/json
+// @Produce   application/json
+// @Param     data  body      systemReq.ImportAuthorityBundle                                   true  "导出的内容, 导入方式merge|replace, 是否只预览"
+// @Success   200   {object}  response.Response{data=systemRes.AuthorityBundleDiff,msg=string}  "返回导入前后的差异"
+// @Router    /authority/importAuthorityBundle [post]
+func (a *AuthorityApi) ImportAuthorityBundle(c *gin.Context) {
+	var req systemReq.ImportAuthorityBundle
+	err := c.ShouldBindJSON(&req)
+	if err != nil {
+		response.FailWithMessage(err.Error(), c)
+		return
+	}
+	err = utils.Verify(req, utils.AuthorityBundleVerify)
+	if err != nil {
+		response.FailWithMessage(err.Error(), c)
+		return
+	}
+	diff, err := authorityService.ImportAuthorityBundle(utils.GetUserAuthorityId(c), utils.GetUserID(c), utils.GetUserName(c), req)
+	if err != nil {
+		global.GVA_LOG.Error("导入失败!", zap.Error(err))
+		if authorityService.IsBundleConflict(err) {
+			response.FailWithDetailed(diff, err.Error(), c)
+			return
+		}
+		response.FailWithMessage("导入失败: "+err.Error(), c)
+		return
+	}
+	if req.DryRun {
+		response.OkWithDetailed(diff, "预览成功", c)
+		return
+	}
+	response.OkWithDetailed(diff, "导入成功", c)
+}
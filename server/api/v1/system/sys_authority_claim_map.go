@@ -0,0 +1,98 @@
+package system
+
+import (
+	"github.com/flipped-aurora/gin-vue-admin/server/global"
+	"github.com/flipped-aurora/gin-vue-admin/server/model/common/request"
+	"github.com/flipped-aurora/gin-vue-admin/server/model/common/response"
+	"github.com/flipped-aurora/gin-vue-admin/server/model/system"
+	systemReq "github.com/flipped-aurora/gin-vue-admin/server/model/system/request"
+	"github.com/flipped-aurora/gin-vue-admin/server/utils"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+type AuthorityClaimMapApi struct{}
+
+// CreateClaimMap
+// @Tags      AuthorityClaimMap
+// @Summary   新增外部组到角色的映射
+// @Security  ApiKeyAuth
+// @accept    application/json
+// @Produce   application/json
+// @Param     data  body      system.SysAuthorityClaimMap    true  "身份源, claim值, 角色ID"
+// @Success   200   {object}  response.Response{msg=string}  "新增映射"
+// @Router    /authorityClaimMap/createClaimMap [post]
+func (a *AuthorityClaimMapApi) CreateClaimMap(c *gin.Context) {
+	var m system.SysAuthorityClaimMap
+	err := c.ShouldBindJSON(&m)
+	if err != nil {
+		response.FailWithMessage(err.Error(), c)
+		return
+	}
+	err = utils.Verify(m, utils.ClaimMapVerify)
+	if err != nil {
+		response.FailWithMessage(err.Error(), c)
+		return
+	}
+	err = claimMapService.CreateClaimMap(m)
+	if err != nil {
+		global.GVA_LOG.Error("创建失败!", zap.Error(err))
+		response.FailWithMessage("创建失败:"+err.Error(), c)
+		return
+	}
+	response.OkWithMessage("创建成功", c)
+}
+
+// DeleteClaimMap
+// @Tags      AuthorityClaimMap
+// @Summary   删除外部组到角色的映射
+// @Security  ApiKeyAuth
+// @accept    application/json
+// @Produce   application/json
+// @Param     data  body      request.GetById                true  "映射ID"
+// @Success   200   {object}  response.Response{msg=string}  "删除映射"
+// @Router    /authorityClaimMap/deleteClaimMap [delete]
+func (a *AuthorityClaimMapApi) DeleteClaimMap(c *gin.Context) {
+	var reqId request.GetById
+	err := c.ShouldBindJSON(&reqId)
+	if err != nil {
+		response.FailWithMessage(err.Error(), c)
+		return
+	}
+	err = utils.Verify(reqId, utils.IdVerify)
+	if err != nil {
+		response.FailWithMessage(err.Error(), c)
+		return
+	}
+	err = claimMapService.DeleteClaimMap(uint(reqId.ID))
+	if err != nil {
+		global.GVA_LOG.Error("删除失败!", zap.Error(err))
+		response.FailWithMessage("删除失败", c)
+		return
+	}
+	response.OkWithMessage("删除成功", c)
+}
+
+// GetClaimMapList
+// @Tags      AuthorityClaimMap
+// @Summary   获取外部组到角色的映射列表
+// @Security  ApiKeyAuth
+// @Produce   application/json
+// @Param     data  query     systemReq.ClaimMapSearch                                          true  "角色ID"
+// @Success   200   {object}  response.Response{data=[]system.SysAuthorityClaimMap,msg=string}  "映射列表"
+// @Router    /authorityClaimMap/getClaimMapList [get]
+func (a *AuthorityClaimMapApi) GetClaimMapList(c *gin.Context) {
+	var req systemReq.ClaimMapSearch
+	err := c.ShouldBindQuery(&req)
+	if err != nil {
+		response.FailWithMessage(err.Error(), c)
+		return
+	}
+	list, err := claimMapService.GetClaimMapList(req.AuthorityId)
+	if err != nil {
+		global.GVA_LOG.Error("获取失败!", zap.Error(err))
+		response.FailWithMessage("获取失败", c)
+		return
+	}
+	response.OkWithDetailed(list, "获取成功", c)
+}
@@ -0,0 +1,113 @@
+package system
+
+import (
+	"github.com/flipped-aurora/gin-vue-admin/server/global"
+	"github.com/flipped-aurora/gin-vue-admin/server/model/common/response"
+	systemReq "github.com/flipped-aurora/gin-vue-admin/server/model/system/request"
+	"github.com/flipped-aurora/gin-vue-admin/server/utils"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// GetAuthorityVersionList
+// @Tags      Authority
+// @Summary   分页获取角色权限的历史版本
+// @Security  ApiKeyAuth
+// @accept    application/json
+// @Produce   application/json
+// @Param     data  query     systemReq.AuthorityVersionSearch                        true  "角色ID, 页码, 每页大小"
+// @Success   200   {object}  response.Response{data=response.PageResult,msg=string}  "分页获取角色权限的历史版本"
+// @Router    /authority/getAuthorityVersionList [get]
+func (a *AuthorityApi) GetAuthorityVersionList(c *gin.Context) {
+	var req systemReq.AuthorityVersionSearch
+	err := c.ShouldBindQuery(&req)
+	if err != nil {
+		response.FailWithMessage(err.Error(), c)
+		return
+	}
+	err = utils.Verify(req, utils.AuthorityIdVerify)
+	if err != nil {
+		response.FailWithMessage(err.Error(), c)
+		return
+	}
+	list, total, err := authorityVersionService.GetAuthorityVersionList(utils.GetUserAuthorityId(c), req)
+	if err != nil {
+		global.GVA_LOG.Error("获取失败!", zap.Error(err))
+		response.FailWithMessage("获取失败", c)
+		return
+	}
+	response.OkWithDetailed(response.PageResult{
+		List:     list,
+		Total:    total,
+		Page:     req.Page,
+		PageSize: req.PageSize,
+	}, "获取成功", c)
+}
+
+// GetAuthorityVersion
+// @Tags      Authority
+// @Summary   获取角色权限历史版本的快照及与上一版本的差异
+// @Security  ApiKeyAuth
+// @accept    application/json
+// @Produce   application/json
+// @Param     data  query     systemReq.AuthorityVersionReq                                          true  "角色ID, 版本号"
+// @Success   200   {object}  response.Response{data=systemRes.AuthorityVersionDetail,msg=string}  "获取角色权限历史版本的快照及与上一版本的差异"
+// @Router    /authority/getAuthorityVersion [get]
+func (a *AuthorityApi) GetAuthorityVersion(c *gin.Context) {
+	var req systemReq.AuthorityVersionReq
+	err := c.ShouldBindQuery(&req)
+	if err != nil {
+		response.FailWithMessage(err.Error(), c)
+		return
+	}
+	err = utils.Verify(req, utils.AuthorityVersionVerify)
+	if err != nil {
+		response.FailWithMessage(err.Error(), c)
+		return
+	}
+	res, err := authorityVersionService.GetAuthorityVersion(utils.GetUserAuthorityId(c), req)
+	if err != nil {
+		global.GVA_LOG.Error("获取失败!", zap.Error(err))
+		response.FailWithMessage("获取失败: "+err.Error(), c)
+		return
+	}
+	response.OkWithDetailed(res, "获取成功", c)
+}
+
+// RollbackAuthorityVersion
+// @Tags      Authority
+// @Summary   将角色权限恢复到历史版本
+// @Security  ApiKeyAuth
+// @accept    application/json
+// @Produce   application/json
+// @Param     data  body      systemReq.RollbackAuthorityVersion                                  true  "角色ID, 版本号, 是否只预览"
+// @Success   200   {object}  response.Response{data=systemRes.AuthorityBundleDiff,msg=string}  "返回恢复前后的差异"
+// @Router    /authority/rollbackAuthorityVersion [post]
+func (a *AuthorityApi) RollbackAuthorityVersion(c *gin.Context) {
+	var req systemReq.RollbackAuthorityVersion
+	err := c.ShouldBindJSON(&req)
+	if err != nil {
+		response.FailWithMessage(err.Error(), c)
+		return
+	}
+	err = utils.Verify(req, utils.AuthorityVersionVerify)
+	if err != nil {
+		response.FailWithMessage(err.Error(), c)
+		return
+	}
+	diff, err := authorityVersionService.RollbackAuthorityVersion(utils.GetUserAuthorityId(c), utils.GetUserID(c), utils.GetUserName(c), req)
+	if err != nil {
+		global.GVA_LOG.Error("恢复失败!", zap.Error(err))
+		if authorityService.IsBundleConflict(err) {
+			response.FailWithDetailed(diff, "历史版本引用的数据已不存在, 未恢复", c)
+			return
+		}
+		response.FailWithMessage("恢复失败: "+err.Error(), c)
+		return
+	}
+	if req.DryRun {
+		response.OkWithDetailed(diff, "预览成功", c)
+		return
+	}
+	response.OkWithDetailed(diff, "恢复成功", c)
+}
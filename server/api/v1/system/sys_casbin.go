@@ -34,7 +34,7 @@ func (cas *CasbinApi) UpdateCasbin(c *gin.Context) {
 		return
 	}
 	adminAuthorityID := utils.GetUserAuthorityId(c)
-	err = casbinService.UpdateCasbin(adminAuthorityID, cmr.AuthorityId, cmr.CasbinInfos)
+	err = casbinService.UpdateCasbin(adminAuthorityID, utils.GetUserID(c), utils.GetUserName(c), cmr.AuthorityId, cmr.CasbinInfos)
 	if err != nil {
 		global.GVA_LOG.Error("更新失败!", zap.Error(err))
 		response.FailWithMessage("更新失败", c)
@@ -67,3 +67,58 @@ func (cas *CasbinApi) GetPolicyPathByAuthorityId(c *gin.Context) {
 	paths := casbinService.GetPolicyPathByAuthorityId(casbin.AuthorityId)
 	response.OkWithDetailed(systemRes.PolicyPathResponse{Paths: paths}, "获取成功", c)
 }
+
+// ExplainCasbin
+// @Tags      Casbin
+// @Summary   模拟用户或角色访问接口 说明是否通过及原因
+// @Security  ApiKeyAuth
+// @accept    application/json
+// @Produce   application/json
+// @Param     data  body      request.CasbinExplain                                               true  "用户ID或角色ID, 方法, 路径, IP"
+// @Success   200   {object}  response.Response{data=systemRes.CasbinExplainResponse,msg=string}  "返回校验结果, 命中的策略, 可见的菜单按钮"
+// @Router    /casbin/explain [post]
+func (cas *CasbinApi) ExplainCasbin(c *gin.Context) {
+	var req request.CasbinExplain
+	err := c.ShouldBindJSON(&req)
+	if err != nil {
+		response.FailWithMessage(err.Error(), c)
+		return
+	}
+	err = utils.Verify(req, utils.CasbinExplainVerify)
+	if err != nil {
+		response.FailWithMessage(err.Error(), c)
+		return
+	}
+	res, err := casbinService.Explain(utils.GetUserAuthorityId(c), req)
+	if err != nil {
+		global.GVA_LOG.Error("模拟失败!", zap.Error(err))
+		response.FailWithMessage("模拟失败: "+err.Error(), c)
+		return
+	}
+	response.OkWithDetailed(res, "获取成功", c)
+}
+
+// SimulateCasbin
+// @Tags      Casbin
+// @Summary   模拟用户或角色访问全部路由
+// @Security  ApiKeyAuth
+// @accept    application/json
+// @Produce   application/json
+// @Param     data  body      request.CasbinSimulate                                               true  "用户ID或角色ID, IP"
+// @Success   200   {object}  response.Response{data=systemRes.CasbinSimulateResponse,msg=string}  "返回每个路由的校验结果"
+// @Router    /casbin/simulate [post]
+func (cas *CasbinApi) SimulateCasbin(c *gin.Context) {
+	var req request.CasbinSimulate
+	err := c.ShouldBindJSON(&req)
+	if err != nil {
+		response.FailWithMessage(err.Error(), c)
+		return
+	}
+	res, err := casbinService.Simulate(utils.GetUserAuthorityId(c), req)
+	if err != nil {
+		global.GVA_LOG.Error("模拟失败!", zap.Error(err))
+		response.FailWithMessage("模拟失败: "+err.Error(), c)
+		return
+	}
+	response.OkWithDetailed(res, "获取成功", c)
+}
@@ -197,8 +197,12 @@ func (sysExportTemplateApi *SysExportTemplateApi) ExportExcel(c *gin.Context) {
 		response.FailWithMessage("模板ID不能为空", c)
 		return
 	}
-	if file, name, err := sysExportTemplateService.ExportExcel(templateID, queryParams); err != nil {
+	if file, name, err := sysExportTemplateService.ExportExcel(utils.DataScopeContext(c), templateID, queryParams); err != nil {
 		global.GVA_LOG.Error("获取失败!", zap.Error(err))
+		if sysExportTemplateService.IsFieldRuleDenied(err) {
+			response.FailWithMessage(err.Error(), c)
+			return
+		}
 		response.FailWithMessage("获取失败", c)
 	} else {
 		c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%s", name+utils.RandomString(6)+".xlsx")) // 对下载的文件重命名
@@ -0,0 +1,71 @@
+package system
+
+import (
+	"github.com/flipped-aurora/gin-vue-admin/server/global"
+	"github.com/flipped-aurora/gin-vue-admin/server/model/common/request"
+	"github.com/flipped-aurora/gin-vue-admin/server/model/common/response"
+	systemReq "github.com/flipped-aurora/gin-vue-admin/server/model/system/request"
+	"github.com/flipped-aurora/gin-vue-admin/server/utils"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// GetFieldRules
+// @Tags      Authority
+// @Summary   获取角色的字段权限
+// @Security  ApiKeyAuth
+// @accept    application/json
+// @Produce   application/json
+// @Param     data  query     request.GetAuthorityId                                           true  "角色ID"
+// @Success   200   {object}  response.Response{data=[]systemReq.FieldRuleInfo,msg=string}  "获取角色的字段权限"
+// @Router    /authority/getFieldRules [get]
+func (a *AuthorityApi) GetFieldRules(c *gin.Context) {
+	var req request.GetAuthorityId
+	err := c.ShouldBindQuery(&req)
+	if err != nil {
+		response.FailWithMessage(err.Error(), c)
+		return
+	}
+	err = utils.Verify(req, utils.AuthorityIdVerify)
+	if err != nil {
+		response.FailWithMessage(err.Error(), c)
+		return
+	}
+	list, err := fieldRuleService.GetFieldRules(req.AuthorityId)
+	if err != nil {
+		global.GVA_LOG.Error("获取失败!", zap.Error(err))
+		response.FailWithMessage("获取失败", c)
+		return
+	}
+	response.OkWithDetailed(list, "获取成功", c)
+}
+
+// SetFieldRules
+// @Tags      Authority
+// @Summary   设置角色的字段权限
+// @Security  ApiKeyAuth
+// @accept    application/json
+// @Produce   application/json
+// @Param     data  body      systemReq.SetFieldRules        true  "角色ID, 各字段的权限"
+// @Success   200   {object}  response.Response{msg=string}  "设置角色的字段权限"
+// @Router    /authority/setFieldRules [post]
+func (a *AuthorityApi) SetFieldRules(c *gin.Context) {
+	var req systemReq.SetFieldRules
+	err := c.ShouldBindJSON(&req)
+	if err != nil {
+		response.FailWithMessage(err.Error(), c)
+		return
+	}
+	err = utils.Verify(req, utils.AuthorityIdVerify)
+	if err != nil {
+		response.FailWithMessage(err.Error(), c)
+		return
+	}
+	err = fieldRuleService.SetFieldRules(utils.GetUserAuthorityId(c), utils.GetUserID(c), utils.GetUserName(c), req)
+	if err != nil {
+		global.GVA_LOG.Error("设置失败!", zap.Error(err))
+		response.FailWithMessage("设置失败: "+err.Error(), c)
+		return
+	}
+	response.OkWithMessage("设置成功", c)
+}
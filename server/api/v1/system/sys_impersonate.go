@@ -0,0 +1,87 @@
+package system
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/flipped-aurora/gin-vue-admin/server/global"
+	"github.com/flipped-aurora/gin-vue-admin/server/model/common/request"
+	"github.com/flipped-aurora/gin-vue-admin/server/model/common/response"
+	"github.com/flipped-aurora/gin-vue-admin/server/model/system"
+	systemRes "github.com/flipped-aurora/gin-vue-admin/server/model/system/response"
+	"github.com/flipped-aurora/gin-vue-admin/server/utils"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// Impersonate
+// @Tags      SysUser
+// @Summary   以指定用户身份登录 用于排查该用户看到的界面和数据
+// @Security  ApiKeyAuth
+// @accept    application/json
+// @Produce   application/json
+// @Param     data  body      request.GetById                                             true  "用户ID"
+// @Success   200   {object}  response.Response{data=systemRes.LoginResponse,msg=string}  "返回被代登录的用户信息和代登录令牌"
+// @Router    /user/impersonate [post]
+func (b *BaseApi) Impersonate(c *gin.Context) {
+	var reqId request.GetById
+	err := c.ShouldBindJSON(&reqId)
+	if err != nil {
+		response.FailWithMessage(err.Error(), c)
+		return
+	}
+	err = utils.Verify(reqId, utils.IdVerify)
+	if err != nil {
+		response.FailWithMessage(err.Error(), c)
+		return
+	}
+	// API密钥只用于机器调用 不能换取用户令牌
+	if _, ok := c.Get("apiKey"); ok {
+		response.FailWithMessage("API密钥不能代登录", c)
+		return
+	}
+	claims := utils.GetUserInfo(c)
+	user, err := impersonateService.Impersonate(claims, uint(reqId.ID))
+	if err != nil {
+		global.GVA_LOG.Error("代登录失败!", zap.Error(err))
+		response.FailWithMessage("代登录失败:"+err.Error(), c)
+		return
+	}
+	stamp, err := securityStampService.Stamp(user.ID)
+	if err != nil {
+		global.GVA_LOG.Error("获取安全戳失败!", zap.Error(err))
+		response.FailWithMessage("代登录失败:"+err.Error(), c)
+		return
+	}
+	d, err := utils.ParseDuration(global.GVA_CONFIG.JWT.ImpersonateExpires)
+	if err != nil || d <= 0 {
+		d = 30 * time.Minute
+	}
+	token, newClaims, err := utils.ImpersonateToken(&user, claims, stamp, d)
+	if err != nil {
+		global.GVA_LOG.Error("获取token失败!", zap.Error(err))
+		response.FailWithMessage("获取token失败", c)
+		return
+	}
+	// 响应含令牌 不经过操作记录中间件 这里单独记录一条不含令牌的操作日志
+	err = operationRecordService.CreateSysOperationRecord(system.SysOperationRecord{
+		Ip:             c.ClientIP(),
+		Method:         c.Request.Method,
+		Path:           c.Request.URL.Path,
+		Agent:          c.Request.UserAgent(),
+		Status:         http.StatusOK,
+		Body:           fmt.Sprintf(`{"id":%d}`, user.ID),
+		Resp:           "代登录成功",
+		UserID:         int(user.ID),
+		ImpersonatorID: int(claims.BaseClaims.ID),
+	})
+	if err != nil {
+		global.GVA_LOG.Error("记录代登录失败!", zap.Error(err))
+	}
+	response.OkWithDetailed(systemRes.LoginResponse{
+		User:      user,
+		Token:     token,
+		ExpiresAt: newClaims.RegisteredClaims.ExpiresAt.Unix() * 1000,
+	}, "代登录成功", c)
+}
@@ -21,12 +21,23 @@ type JwtApi struct{}
 // @Router    /jwt/jsonInBlacklist [post]
 func (j *JwtApi) JsonInBlacklist(c *gin.Context) {
 	token := utils.GetToken(c)
-	jwt := system.JwtBlacklist{Jwt: token}
-	err := jwtService.JsonInBlacklist(jwt)
-	if err != nil {
-		global.GVA_LOG.Error("jwt作废失败!", zap.Error(err))
-		response.FailWithMessage("jwt作废失败", c)
-		return
+	claims := utils.GetUserInfo(c)
+	// API密钥没有过期时间 不需要拉黑
+	if token != "" && claims != nil && claims.ExpiresAt != nil {
+		jwt := system.JwtBlacklist{Jti: jwtService.TokenKey(token, claims), ExpiresAt: claims.ExpiresAt.Time}
+		if err := jwtService.JsonInBlacklist(jwt); err != nil {
+			global.GVA_LOG.Error("jwt作废失败!", zap.Error(err))
+			response.FailWithMessage("jwt作废失败", c)
+			return
+		}
+	}
+	// 登出时同时作废本次登录的刷新令牌 退出代登录时操作人本身的登录不受影响
+	if claims != nil && claims.Actor == nil {
+		if err := refreshTokenService.RevokeFamily(claims.FamilyId); err != nil {
+			global.GVA_LOG.Error("刷新令牌作废失败!", zap.Error(err))
+			response.FailWithMessage("jwt作废失败", c)
+			return
+		}
 	}
 	utils.ClearToken(c)
 	response.OkWithMessage("jwt作废成功", c)
@@ -0,0 +1,18 @@
+package system
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Jwks
+// @Tags     Jwt
+// @Summary  获取jwt验签公钥(JWKS) 供其他服务校验令牌
+// @Produce  application/json
+// @Success  200  {object}  utils.JSONWebKeySet  "JSON Web Key Set"
+// @Router   /.well-known/jwks.json [get]
+func (j *JwtApi) Jwks(c *gin.Context) {
+	c.Header("Cache-Control", "public, max-age=300")
+	c.JSON(http.StatusOK, jwtKeyService.GetJwks())
+}
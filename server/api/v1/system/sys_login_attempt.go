@@ -0,0 +1,77 @@
+package system
+
+import (
+	"github.com/flipped-aurora/gin-vue-admin/server/global"
+	"github.com/flipped-aurora/gin-vue-admin/server/model/common/response"
+	systemReq "github.com/flipped-aurora/gin-vue-admin/server/model/system/request"
+	"github.com/flipped-aurora/gin-vue-admin/server/utils"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+type LoginAttemptApi struct{}
+
+// GetLoginAttemptList
+// @Tags      LoginAttempt
+// @Summary   分页获取登录失败记录
+// @Security  ApiKeyAuth
+// @accept    application/json
+// @Produce   application/json
+// @Param     data  query     systemReq.SysLoginAttemptSearch                         true  "页码, 每页大小, 用户名, IP"
+// @Success   200   {object}  response.Response{data=response.PageResult,msg=string}  "分页获取登录失败记录,返回包括列表,总数,页码,每页数量"
+// @Router    /loginAttempt/getLoginAttemptList [get]
+func (a *LoginAttemptApi) GetLoginAttemptList(c *gin.Context) {
+	var pageInfo systemReq.SysLoginAttemptSearch
+	err := c.ShouldBindQuery(&pageInfo)
+	if err != nil {
+		response.FailWithMessage(err.Error(), c)
+		return
+	}
+	err = utils.Verify(pageInfo, utils.PageInfoVerify)
+	if err != nil {
+		response.FailWithMessage(err.Error(), c)
+		return
+	}
+	list, total, err := loginLockoutService.GetLoginAttemptList(pageInfo)
+	if err != nil {
+		global.GVA_LOG.Error("获取失败!", zap.Error(err))
+		response.FailWithMessage("获取失败", c)
+		return
+	}
+	response.OkWithDetailed(response.PageResult{
+		List:     list,
+		Total:    total,
+		Page:     pageInfo.Page,
+		PageSize: pageInfo.PageSize,
+	}, "获取成功", c)
+}
+
+// UnlockUser
+// @Tags      LoginAttempt
+// @Summary   解除账号锁定
+// @Security  ApiKeyAuth
+// @accept    application/json
+// @Produce   application/json
+// @Param     data  body      systemReq.UnlockUser           true  "用户名"
+// @Success   200   {object}  response.Response{msg=string}  "解除账号锁定"
+// @Router    /loginAttempt/unlockUser [post]
+func (a *LoginAttemptApi) UnlockUser(c *gin.Context) {
+	var req systemReq.UnlockUser
+	err := c.ShouldBindJSON(&req)
+	if err != nil {
+		response.FailWithMessage(err.Error(), c)
+		return
+	}
+	err = utils.Verify(req, utils.UnlockUserVerify)
+	if err != nil {
+		response.FailWithMessage(err.Error(), c)
+		return
+	}
+	err = loginLockoutService.Unlock(req.Username)
+	if err != nil {
+		global.GVA_LOG.Error("解锁失败!", zap.Error(err))
+		response.FailWithMessage("解锁失败", c)
+		return
+	}
+	response.OkWithMessage("解锁成功", c)
+}
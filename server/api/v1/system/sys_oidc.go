@@ -0,0 +1,94 @@
+package system
+
+import (
+	"github.com/flipped-aurora/gin-vue-admin/server/global"
+	"github.com/flipped-aurora/gin-vue-admin/server/model/common/response"
+	systemReq "github.com/flipped-aurora/gin-vue-admin/server/model/system/request"
+	systemRes "github.com/flipped-aurora/gin-vue-admin/server/model/system/response"
+	"github.com/flipped-aurora/gin-vue-admin/server/utils"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// GetOidcProviders
+// @Tags     Base
+// @Summary  获取可用的单点登录身份源
+// @Produce   application/json
+// @Success  200  {object}  response.Response{data=[]systemRes.OidcProvider,msg=string}  "身份源列表"
+// @Router   /base/oidcProviders [get]
+func (b *BaseApi) GetOidcProviders(c *gin.Context) {
+	response.OkWithDetailed(oidcService.GetProviders(), "获取成功", c)
+}
+
+// GetOidcAuthUrl
+// @Tags     Base
+// @Summary  获取单点登录授权地址
+// @Produce   application/json
+// @Param    data  query     systemReq.OidcAuthUrl                                             true  "身份源标识"
+// @Success  200   {object}  response.Response{data=systemRes.OidcAuthUrlResponse,msg=string}  "授权地址"
+// @Router   /base/oidcAuthUrl [get]
+func (b *BaseApi) GetOidcAuthUrl(c *gin.Context) {
+	var req systemReq.OidcAuthUrl
+	err := c.ShouldBindQuery(&req)
+	if err != nil {
+		response.FailWithMessage(err.Error(), c)
+		return
+	}
+	err = utils.Verify(req, utils.OidcAuthUrlVerify)
+	if err != nil {
+		response.FailWithMessage(err.Error(), c)
+		return
+	}
+	url, err := oidcService.AuthUrl(c.Request.Context(), req.Provider)
+	if err != nil {
+		global.GVA_LOG.Error("获取授权地址失败!", zap.Error(err))
+		response.FailWithMessage(err.Error(), c)
+		return
+	}
+	response.OkWithDetailed(systemRes.OidcAuthUrlResponse{AuthUrl: url}, "获取成功", c)
+}
+
+// OidcLogin
+// @Tags     Base
+// @Summary  单点登录回调 使用授权码登录
+// @Produce   application/json
+// @Param    data  body      systemReq.OidcLogin                                         true  "state, 授权码"
+// @Success  200   {object}  response.Response{data=systemRes.LoginResponse,msg=string}  "返回包括用户信息,token,过期时间"
+// @Router   /base/oidcLogin [post]
+func (b *BaseApi) OidcLogin(c *gin.Context) {
+	var req systemReq.OidcLogin
+	err := c.ShouldBindJSON(&req)
+	if err != nil {
+		response.FailWithMessage(err.Error(), c)
+		return
+	}
+	err = utils.Verify(req, utils.OidcLoginVerify)
+	if err != nil {
+		response.FailWithMessage(err.Error(), c)
+		return
+	}
+	ext, cfg, err := oidcService.Exchange(c.Request.Context(), req.State, req.Code)
+	if err != nil {
+		global.GVA_LOG.Error("单点登录失败!", zap.Error(err))
+		response.FailWithMessage(err.Error(), c)
+		return
+	}
+	user, err := userIdentityService.LoginExternalUser(ext, systemReq.ProvisionOptions{
+		AutoCreate:         cfg.AutoCreate,
+		DefaultAuthorityId: cfg.DefaultAuthorityId,
+	})
+	if err != nil {
+		global.GVA_LOG.Error("单点登录失败!", zap.String("provider", ext.Provider), zap.String("subject", ext.Subject), zap.Error(err))
+		response.FailWithMessage(err.Error(), c)
+		return
+	}
+	if user.Enable != 1 {
+		response.FailWithMessage("用户被禁止登录", c)
+		return
+	}
+	if mfaService.IsMfaEnabled(user.ID) || mfaService.IsMfaRequired(user.ID) || passkeyService.HasPasskey(user.ID) {
+		b.mfaChallengeNext(c, *user)
+		return
+	}
+	b.TokenNext(c, *user)
+}
@@ -0,0 +1,100 @@
+package system
+
+import (
+	"github.com/flipped-aurora/gin-vue-admin/server/global"
+	"github.com/flipped-aurora/gin-vue-admin/server/model/common/response"
+	"github.com/flipped-aurora/gin-vue-admin/server/model/system"
+	systemReq "github.com/flipped-aurora/gin-vue-admin/server/model/system/request"
+	systemRes "github.com/flipped-aurora/gin-vue-admin/server/model/system/response"
+	"github.com/flipped-aurora/gin-vue-admin/server/utils"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// loginNext 密码和两步验证均已通过 密码过期或被管理员重置时要求先修改密码 否则签发令牌
+func (b *BaseApi) loginNext(c *gin.Context, user system.SysUser, recoveryCodes []string) {
+	// 全部认证因素都已通过 清零登录失败次数
+	loginLockoutService.Succeed(user.Username)
+	if need, reason := passwordPolicyService.NeedChangePassword(user); need {
+		challengeId, err := passwordPolicyService.CreateChallenge(user.ID)
+		if err != nil {
+			global.GVA_LOG.Error("创建修改密码挑战失败!", zap.Error(err))
+			response.FailWithMessage("创建修改密码挑战失败", c)
+			return
+		}
+		response.OkWithDetailed(systemRes.PasswordChangeChallengeResponse{
+			NeedChangePassword: true,
+			ChallengeId:        challengeId,
+			Reason:             reason,
+			RecoveryCodes:      recoveryCodes,
+		}, reason, c)
+		return
+	}
+	resp, err := b.tokenNext(c, user)
+	if err != nil {
+		response.FailWithMessage(err.Error(), c)
+		return
+	}
+	resp.RecoveryCodes = recoveryCodes
+	response.OkWithDetailed(resp, "登录成功", c)
+}
+
+// ChangeExpiredPassword
+// @Tags     Base
+// @Summary  登录时修改过期或被重置的密码
+// @Produce   application/json
+// @Param    data  body      systemReq.ChangeExpiredPassword                             true  "挑战ID, 新密码"
+// @Success  200   {object}  response.Response{data=systemRes.LoginResponse,msg=string}  "返回包括用户信息,token,过期时间"
+// @Router   /base/changeExpiredPassword [post]
+func (b *BaseApi) ChangeExpiredPassword(c *gin.Context) {
+	var req systemReq.ChangeExpiredPassword
+	err := c.ShouldBindJSON(&req)
+	if err != nil {
+		response.FailWithMessage(err.Error(), c)
+		return
+	}
+	err = utils.Verify(req, utils.ExpiredPasswordVerify)
+	if err != nil {
+		response.FailWithMessage(err.Error(), c)
+		return
+	}
+	userId, err := passwordPolicyService.GetChallenge(req.ChallengeId)
+	if err != nil {
+		response.FailWithMessage(err.Error(), c)
+		return
+	}
+	u, err := userService.FindUserById(int(userId))
+	if err != nil || u.Enable != 1 {
+		passwordPolicyService.FinishChallenge(req.ChallengeId)
+		response.FailWithMessage("用户被禁止登录", c)
+		return
+	}
+	if err = passwordPolicyService.CheckPassword(u.ID, u.Username, req.NewPassword); err != nil {
+		response.FailWithMessage(err.Error(), c)
+		return
+	}
+	// 修改前取出并删除挑战 并发提交同一挑战时只有一个能修改
+	if _, err = passwordPolicyService.TakeChallenge(req.ChallengeId); err != nil {
+		response.FailWithMessage(err.Error(), c)
+		return
+	}
+	if err = passwordPolicyService.SetPassword(global.GVA_DB, u.ID, req.NewPassword, false); err != nil {
+		global.GVA_LOG.Error("修改密码失败!", zap.Error(err))
+		response.FailWithMessage("修改密码失败", c)
+		return
+	}
+	// 旧密码签发的令牌和会话全部失效
+	if err = securityStampService.Bump(u.ID); err != nil {
+		global.GVA_LOG.Error("更换安全戳失败!", zap.Error(err))
+	}
+	if err = sessionService.RevokeUserSessions(u.ID); err != nil {
+		global.GVA_LOG.Error("下线会话失败!", zap.Error(err))
+	}
+	user, err := userService.GetUserInfo(u.UUID)
+	if err != nil {
+		global.GVA_LOG.Error("获取用户信息失败!", zap.Error(err))
+		response.FailWithMessage("获取用户信息失败", c)
+		return
+	}
+	b.TokenNext(c, user)
+}
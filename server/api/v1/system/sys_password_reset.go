@@ -0,0 +1,70 @@
+package system
+
+import (
+	"github.com/flipped-aurora/gin-vue-admin/server/global"
+	"github.com/flipped-aurora/gin-vue-admin/server/model/common/response"
+	systemReq "github.com/flipped-aurora/gin-vue-admin/server/model/system/request"
+	"github.com/flipped-aurora/gin-vue-admin/server/utils"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// ForgotPassword
+// @Tags     Base
+// @Summary  忘记密码 向邮箱发送重置链接
+// @Produce   application/json
+// @Param    data  body      systemReq.ForgotPassword       true  "用户邮箱"
+// @Success  200   {object}  response.Response{msg=string}  "无论邮箱是否注册均返回成功"
+// @Router   /base/forgotPassword [post]
+func (b *BaseApi) ForgotPassword(c *gin.Context) {
+	var req systemReq.ForgotPassword
+	err := c.ShouldBindJSON(&req)
+	if err != nil {
+		response.FailWithMessage(err.Error(), c)
+		return
+	}
+	err = utils.Verify(req, utils.ForgotPasswordVerify)
+	if err != nil {
+		response.FailWithMessage(err.Error(), c)
+		return
+	}
+	err = passwordResetService.RequestReset(req.Email, c.ClientIP())
+	if err != nil {
+		if passwordResetService.IsDisabled(err) {
+			response.FailWithMessage(err.Error(), c)
+			return
+		}
+		global.GVA_LOG.Error("发送重置密码邮件失败!", zap.Error(err))
+		response.FailWithMessage("发送失败", c)
+		return
+	}
+	response.OkWithMessage("如果该邮箱已注册, 重置链接已发送, 请查收邮件", c)
+}
+
+// ResetPasswordByToken
+// @Tags     Base
+// @Summary  通过邮件中的重置链接设置新密码
+// @Produce   application/json
+// @Param    data  body      systemReq.ResetPasswordByToken  true  "重置令牌, 新密码"
+// @Success  200   {object}  response.Response{msg=string}   "重置密码"
+// @Router   /base/resetPasswordByToken [post]
+func (b *BaseApi) ResetPasswordByToken(c *gin.Context) {
+	var req systemReq.ResetPasswordByToken
+	err := c.ShouldBindJSON(&req)
+	if err != nil {
+		response.FailWithMessage(err.Error(), c)
+		return
+	}
+	err = utils.Verify(req, utils.ResetByTokenVerify)
+	if err != nil {
+		response.FailWithMessage(err.Error(), c)
+		return
+	}
+	err = passwordResetService.Reset(req.Token, req.NewPassword)
+	if err != nil {
+		global.GVA_LOG.Error("重置密码失败!", zap.Error(err))
+		response.FailWithMessage("重置密码失败:"+err.Error(), c)
+		return
+	}
+	response.OkWithMessage("密码已重置, 请使用新密码登录", c)
+}
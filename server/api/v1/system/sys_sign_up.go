@@ -0,0 +1,151 @@
+package system
+
+import (
+	"github.com/flipped-aurora/gin-vue-admin/server/global"
+	"github.com/flipped-aurora/gin-vue-admin/server/model/common/response"
+	"github.com/flipped-aurora/gin-vue-admin/server/model/system"
+	systemReq "github.com/flipped-aurora/gin-vue-admin/server/model/system/request"
+	"github.com/flipped-aurora/gin-vue-admin/server/utils"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+type SignUpApi struct{}
+
+var signUpStatusMessages = map[int]string{
+	system.SignUpStatusUnverified: "注册成功, 请查收邮件并点击链接验证邮箱",
+	system.SignUpStatusPending:    "注册成功, 请等待管理员审核",
+	system.SignUpStatusApproved:   "注册成功, 现在可以登录了",
+}
+
+// SignUp
+// @Tags     Base
+// @Summary  自助注册
+// @Produce   application/json
+// @Param    data  body      systemReq.SignUp                                   true  "用户名, 密码, 昵称, 邮箱, 手机号, 验证码"
+// @Success  200   {object}  response.Response{data=map[string]int,msg=string}  "返回申请状态 1待验证邮箱 2待审核 3已通过"
+// @Router   /base/signUp [post]
+func (a *SignUpApi) SignUp(c *gin.Context) {
+	var req systemReq.SignUp
+	err := c.ShouldBindJSON(&req)
+	if err != nil {
+		response.FailWithMessage(err.Error(), c)
+		return
+	}
+	err = utils.Verify(req, utils.SignUpVerify)
+	if err != nil {
+		response.FailWithMessage(err.Error(), c)
+		return
+	}
+	if !store.Verify(req.CaptchaId, req.Captcha, true) {
+		response.FailWithMessage("验证码错误", c)
+		return
+	}
+	status, err := signUpService.SignUp(req, c.ClientIP())
+	if err != nil {
+		global.GVA_LOG.Error("注册失败!", zap.Error(err))
+		response.FailWithMessage("注册失败:"+err.Error(), c)
+		return
+	}
+	response.OkWithDetailed(gin.H{"status": status}, signUpStatusMessages[status], c)
+}
+
+// VerifySignUp
+// @Tags     Base
+// @Summary  通过邮件中的链接验证注册邮箱
+// @Produce   application/json
+// @Param    data  body      systemReq.VerifySignUp                             true  "验证令牌"
+// @Success  200   {object}  response.Response{data=map[string]int,msg=string}  "返回申请状态 2待审核 3已通过"
+// @Router   /base/verifySignUp [post]
+func (a *SignUpApi) VerifySignUp(c *gin.Context) {
+	var req systemReq.VerifySignUp
+	err := c.ShouldBindJSON(&req)
+	if err != nil {
+		response.FailWithMessage(err.Error(), c)
+		return
+	}
+	err = utils.Verify(req, utils.VerifySignUpVerify)
+	if err != nil {
+		response.FailWithMessage(err.Error(), c)
+		return
+	}
+	status, err := signUpService.Verify(req.Token)
+	if err != nil {
+		if signUpService.IsInvalidToken(err) {
+			response.FailWithMessage(err.Error(), c)
+			return
+		}
+		global.GVA_LOG.Error("验证邮箱失败!", zap.Error(err))
+		response.FailWithMessage("验证邮箱失败", c)
+		return
+	}
+	msg := "邮箱验证成功, 请等待管理员审核"
+	if status == system.SignUpStatusApproved {
+		msg = "邮箱验证成功, 现在可以登录了"
+	}
+	response.OkWithDetailed(gin.H{"status": status}, msg, c)
+}
+
+// GetSignUpList
+// @Tags      SignUp
+// @Summary   分页获取注册申请
+// @Security  ApiKeyAuth
+// @accept    application/json
+// @Produce   application/json
+// @Param     data  query     systemReq.SysSignUpSearch                               true  "页码, 每页大小, 用户名, 邮箱, 状态"
+// @Success   200   {object}  response.Response{data=response.PageResult,msg=string}  "分页获取注册申请,返回包括列表,总数,页码,每页数量"
+// @Router    /signUp/getSignUpList [get]
+func (a *SignUpApi) GetSignUpList(c *gin.Context) {
+	var pageInfo systemReq.SysSignUpSearch
+	err := c.ShouldBindQuery(&pageInfo)
+	if err != nil {
+		response.FailWithMessage(err.Error(), c)
+		return
+	}
+	err = utils.Verify(pageInfo, utils.PageInfoVerify)
+	if err != nil {
+		response.FailWithMessage(err.Error(), c)
+		return
+	}
+	list, total, err := signUpService.GetSignUpList(pageInfo)
+	if err != nil {
+		global.GVA_LOG.Error("获取失败!", zap.Error(err))
+		response.FailWithMessage("获取失败", c)
+		return
+	}
+	response.OkWithDetailed(response.PageResult{
+		List:     list,
+		Total:    total,
+		Page:     pageInfo.Page,
+		PageSize: pageInfo.PageSize,
+	}, "获取成功", c)
+}
+
+// ReviewSignUp
+// @Tags      SignUp
+// @Summary   审核注册申请 通过后启用用户 拒绝后删除用户
+// @Security  ApiKeyAuth
+// @accept    application/json
+// @Produce   application/json
+// @Param     data  body      systemReq.ReviewSignUp         true  "申请ID, 是否通过, 审核意见"
+// @Success   200   {object}  response.Response{msg=string}  "审核注册申请"
+// @Router    /signUp/reviewSignUp [post]
+func (a *SignUpApi) ReviewSignUp(c *gin.Context) {
+	var req systemReq.ReviewSignUp
+	err := c.ShouldBindJSON(&req)
+	if err != nil {
+		response.FailWithMessage(err.Error(), c)
+		return
+	}
+	if req.ID == 0 {
+		response.FailWithMessage("申请ID不能为空", c)
+		return
+	}
+	err = signUpService.Review(req, utils.GetUserID(c))
+	if err != nil {
+		global.GVA_LOG.Error("审核失败!", zap.Error(err))
+		response.FailWithMessage("审核失败:"+err.Error(), c)
+		return
+	}
+	response.OkWithMessage("审核成功", c)
+}
@@ -1,6 +1,8 @@
 package system
 
 import (
+	"errors"
+
 	"github.com/flipped-aurora/gin-vue-admin/server/model/common"
 	"strconv"
 	"time"
@@ -14,7 +16,6 @@ import (
 	"github.com/flipped-aurora/gin-vue-admin/server/utils"
 
 	"github.com/gin-gonic/gin"
-	"github.com/redis/go-redis/v9"
 	"go.uber.org/zap"
 )
 
@@ -51,23 +52,41 @@ func (b *BaseApi) Login(c *gin.Context) {
 	var oc bool = openCaptcha == 0 || openCaptcha < interfaceToInt(v)
 
 	if !oc || (l.CaptchaId != "" && l.Captcha != "" && store.Verify(l.CaptchaId, l.Captcha, true)) {
-		u := &system.SysUser{Username: l.Username, Password: l.Password}
-		user, err := userService.Login(u)
+		// 账号被锁定或连续失败后未满等待时间 不再校验密码
+		if err = loginLockoutService.Check(l.Username); err != nil {
+			response.FailWithMessage(err.Error(), c)
+			return
+		}
+		// 按配置的认证链依次尝试本地用户 LDAP 和插件注册的认证器
+		user, err := authenticatorService.Authenticate(c.Request.Context(), l.Username, l.Password)
 		if err != nil {
 			global.GVA_LOG.Error("登陆失败! 用户名不存在或者密码错误!", zap.Error(err))
 			// 验证码次数+1
 			global.BlackCache.Increment(key, 1)
-			response.FailWithMessage("用户名不存在或者密码错误", c)
+			if authenticatorService.IsInvalidCredentials(err) {
+				loginLockoutService.Fail(l.Username, c.ClientIP(), c.Request.UserAgent(), "用户名不存在或者密码错误")
+				response.FailWithMessage("用户名不存在或者密码错误", c)
+				return
+			}
+			response.FailWithMessage(err.Error(), c)
 			return
 		}
 		if user.Enable != 1 {
 			global.GVA_LOG.Error("登陆失败! 用户被禁止登录!")
 			// 验证码次数+1
 			global.BlackCache.Increment(key, 1)
-			response.FailWithMessage("用户被禁止登录", c)
+			msg := signUpService.DisabledReason(user.ID)
+			if msg == "" {
+				msg = "用户被禁止登录"
+			}
+			response.FailWithMessage(msg, c)
+			return
+		}
+		if mfaService.IsMfaEnabled(user.ID) || mfaService.IsMfaRequired(user.ID) || passkeyService.HasPasskey(user.ID) {
+			b.mfaChallengeNext(c, *user)
 			return
 		}
-		b.TokenNext(c, *user)
+		b.loginNext(c, *user, nil)
 		return
 	}
 	// 验证码次数+1
@@ -75,57 +94,105 @@ func (b *BaseApi) Login(c *gin.Context) {
 	response.FailWithMessage("验证码错误", c)
 }
 
-// TokenNext 登录以后签发jwt
+// TokenNext 登录以后签发jwt 同时签发刷新令牌
 func (b *BaseApi) TokenNext(c *gin.Context, user system.SysUser) {
-	token, claims, err := utils.LoginToken(&user)
+	resp, err := b.tokenNext(c, user)
+	if err != nil {
+		response.FailWithMessage(err.Error(), c)
+		return
+	}
+	response.OkWithDetailed(resp, "登录成功", c)
+}
+
+// tokenNext 开启新的登录会话并签发令牌
+func (b *BaseApi) tokenNext(c *gin.Context, user system.SysUser) (systemRes.LoginResponse, error) {
+	// 每次登录开启新的令牌族 即新的会话 超出角色最大会话数时由会话服务下线最早的会话
+	return b.issueTokens(c, user, refreshTokenService.NewFamily())
+}
+
+// issueTokens 签发访问令牌和刷新令牌 familyId 为本次登录的令牌族
+func (b *BaseApi) issueTokens(c *gin.Context, user system.SysUser, familyId string) (systemRes.LoginResponse, error) {
+	// 安全戳以数据库为准 不依赖认证器返回的用户字段
+	stamp, err := securityStampService.Stamp(user.ID)
+	if err != nil {
+		global.GVA_LOG.Error("获取安全戳失败!", zap.Error(err))
+		return systemRes.LoginResponse{}, err
+	}
+	token, claims, err := utils.LoginToken(&user, familyId, stamp)
 	if err != nil {
 		global.GVA_LOG.Error("获取token失败!", zap.Error(err))
-		response.FailWithMessage("获取token失败", c)
+		return systemRes.LoginResponse{}, errors.New("获取token失败")
+	}
+	refreshToken, refreshExpiresAt, err := refreshTokenService.IssueRefreshToken(user.ID, familyId)
+	if err != nil {
+		global.GVA_LOG.Error("获取刷新令牌失败!", zap.Error(err))
+		return systemRes.LoginResponse{}, errors.New("获取刷新令牌失败")
+	}
+	err = sessionService.RecordSession(user, familyId, claims.RegisteredClaims.ID, c.ClientIP(), c.Request.UserAgent(), refreshExpiresAt)
+	if err != nil {
+		global.GVA_LOG.Error("记录会话失败!", zap.Error(err))
+		return systemRes.LoginResponse{}, errors.New("记录会话失败")
+	}
+	utils.SetToken(c, token, int(claims.RegisteredClaims.ExpiresAt.Unix()-time.Now().Unix()))
+	return systemRes.LoginResponse{
+		User:             user,
+		Token:            token,
+		ExpiresAt:        claims.RegisteredClaims.ExpiresAt.Unix() * 1000,
+		RefreshToken:     refreshToken,
+		RefreshExpiresAt: refreshExpiresAt.Unix() * 1000,
+	}, nil
+}
+
+// RefreshToken
+// @Tags     Base
+// @Summary  使用刷新令牌换取新的令牌
+// @Produce   application/json
+// @Param    data  body      systemReq.RefreshToken                                      true  "刷新令牌"
+// @Success  200   {object}  response.Response{data=systemRes.LoginResponse,msg=string}  "返回包括用户信息,新的token,新的刷新令牌"
+// @Router   /base/refresh [post]
+func (b *BaseApi) RefreshToken(c *gin.Context) {
+	var req systemReq.RefreshToken
+	err := c.ShouldBindJSON(&req)
+	if err != nil {
+		response.FailWithMessage(err.Error(), c)
 		return
 	}
-	if !global.GVA_CONFIG.System.UseMultipoint {
-		utils.SetToken(c, token, int(claims.RegisteredClaims.ExpiresAt.Unix()-time.Now().Unix()))
-		response.OkWithDetailed(systemRes.LoginResponse{
-			User:      user,
-			Token:     token,
-			ExpiresAt: claims.RegisteredClaims.ExpiresAt.Unix() * 1000,
-		}, "登录成功", c)
+	err = utils.Verify(req, utils.RefreshTokenVerify)
+	if err != nil {
+		response.FailWithMessage(err.Error(), c)
 		return
 	}
-
-	if jwtStr, err := jwtService.GetRedisJWT(user.Username); err == redis.Nil {
-		if err := jwtService.SetRedisJWT(token, user.Username); err != nil {
-			global.GVA_LOG.Error("设置登录状态失败!", zap.Error(err))
-			response.FailWithMessage("设置登录状态失败", c)
-			return
-		}
-		utils.SetToken(c, token, int(claims.RegisteredClaims.ExpiresAt.Unix()-time.Now().Unix()))
-		response.OkWithDetailed(systemRes.LoginResponse{
-			User:      user,
-			Token:     token,
-			ExpiresAt: claims.RegisteredClaims.ExpiresAt.Unix() * 1000,
-		}, "登录成功", c)
-	} else if err != nil {
-		global.GVA_LOG.Error("设置登录状态失败!", zap.Error(err))
-		response.FailWithMessage("设置登录状态失败", c)
-	} else {
-		var blackJWT system.JwtBlacklist
-		blackJWT.Jwt = jwtStr
-		if err := jwtService.JsonInBlacklist(blackJWT); err != nil {
-			response.FailWithMessage("jwt作废失败", c)
-			return
-		}
-		if err := jwtService.SetRedisJWT(token, user.GetUsername()); err != nil {
-			response.FailWithMessage("设置登录状态失败", c)
-			return
-		}
-		utils.SetToken(c, token, int(claims.RegisteredClaims.ExpiresAt.Unix()-time.Now().Unix()))
-		response.OkWithDetailed(systemRes.LoginResponse{
-			User:      user,
-			Token:     token,
-			ExpiresAt: claims.RegisteredClaims.ExpiresAt.Unix() * 1000,
-		}, "登录成功", c)
+	record, err := refreshTokenService.RotateRefreshToken(req.RefreshToken)
+	if err != nil {
+		global.GVA_LOG.Error("刷新令牌失败!", zap.Error(err))
+		utils.ClearToken(c)
+		response.NoAuth(err.Error(), c)
+		return
+	}
+	u, err := userService.FindUserById(int(record.UserId))
+	if err != nil {
+		global.GVA_LOG.Error("刷新令牌失败! 用户不存在!", zap.Error(err))
+		_ = refreshTokenService.RevokeFamily(record.FamilyId)
+		response.NoAuth("用户不存在", c)
+		return
+	}
+	if u.Enable != 1 {
+		_ = refreshTokenService.RevokeFamily(record.FamilyId)
+		response.NoAuth("用户被禁止登录", c)
+		return
 	}
+	user, err := userService.GetUserInfo(u.UUID)
+	if err != nil {
+		global.GVA_LOG.Error("获取用户信息失败!", zap.Error(err))
+		response.FailWithMessage("获取用户信息失败", c)
+		return
+	}
+	resp, err := b.issueTokens(c, user, record.FamilyId)
+	if err != nil {
+		response.FailWithMessage(err.Error(), c)
+		return
+	}
+	response.OkWithDetailed(resp, "刷新成功", c)
 }
 
 // Register
@@ -157,7 +224,7 @@ func (b *BaseApi) Register(c *gin.Context) {
 	userReturn, err := userService.Register(*user)
 	if err != nil {
 		global.GVA_LOG.Error("注册失败!", zap.Error(err))
-		response.FailWithDetailed(systemRes.SysUserResponse{User: userReturn}, "注册失败", c)
+		response.FailWithDetailed(systemRes.SysUserResponse{User: userReturn}, "注册失败:"+err.Error(), c)
 		return
 	}
 	response.OkWithDetailed(systemRes.SysUserResponse{User: userReturn}, "注册成功", c)
@@ -183,14 +250,18 @@ func (b *BaseApi) ChangePassword(c *gin.Context) {
 		response.FailWithMessage(err.Error(), c)
 		return
 	}
-	uid := utils.GetUserID(c)
-	u := &system.SysUser{GVA_MODEL: global.GVA_MODEL{ID: uid}, Password: req.Password}
+	claims := utils.GetUserInfo(c)
+	u := &system.SysUser{GVA_MODEL: global.GVA_MODEL{ID: claims.BaseClaims.ID}, Password: req.Password}
 	_, err = userService.ChangePassword(u, req.NewPassword)
 	if err != nil {
 		global.GVA_LOG.Error("修改失败!", zap.Error(err))
-		response.FailWithMessage("修改失败，原密码与当前账户不符", c)
+		response.FailWithMessage("修改失败，"+err.Error(), c)
 		return
 	}
+	// 其他设备上的会话全部下线 当前会话通过刷新令牌换取新令牌继续使用
+	if err = sessionService.RevokeOtherSessions(claims.BaseClaims.ID, claims.FamilyId); err != nil {
+		global.GVA_LOG.Error("下线其他会话失败!", zap.Error(err))
+	}
 	response.OkWithMessage("修改成功", c)
 }
 
@@ -256,9 +327,15 @@ func (b *BaseApi) SetUserAuthority(c *gin.Context) {
 		response.FailWithMessage(err.Error(), c)
 		return
 	}
+	stamp, err := securityStampService.Stamp(userID)
+	if err != nil {
+		response.FailWithMessage(err.Error(), c)
+		return
+	}
 	claims := utils.GetUserInfo(c)
 	j := &utils.JWT{SigningKey: []byte(global.GVA_CONFIG.JWT.SigningKey)} // 唯一签名
 	claims.AuthorityId = sua.AuthorityId
+	claims.SecurityStamp = stamp
 	if token, err := j.CreateToken(*claims); err != nil {
 		global.GVA_LOG.Error("修改失败!", zap.Error(err))
 		response.FailWithMessage(err.Error(), c)
@@ -328,6 +405,9 @@ func (b *BaseApi) DeleteUser(c *gin.Context) {
 		response.FailWithMessage("删除失败", c)
 		return
 	}
+	if err = sessionService.RevokeUserSessions(uint(reqId.ID)); err != nil {
+		global.GVA_LOG.Error("下线用户会话失败!", zap.Error(err))
+	}
 	response.OkWithMessage("删除成功", c)
 }
 
@@ -376,6 +456,12 @@ func (b *BaseApi) SetUserInfo(c *gin.Context) {
 		response.FailWithMessage("设置失败", c)
 		return
 	}
+	if user.Enable == 2 {
+		// 用户被禁用 立即下线其全部会话
+		if err = sessionService.RevokeUserSessions(user.ID); err != nil {
+			global.GVA_LOG.Error("下线用户会话失败!", zap.Error(err))
+		}
+	}
 	response.OkWithMessage("设置成功", c)
 }
 
@@ -411,6 +497,12 @@ func (b *BaseApi) SetSelfInfo(c *gin.Context) {
 		response.FailWithMessage("设置失败", c)
 		return
 	}
+	if user.Enable == 2 {
+		// 用户被禁用 立即下线其全部会话
+		if err = sessionService.RevokeUserSessions(user.ID); err != nil {
+			global.GVA_LOG.Error("下线用户会话失败!", zap.Error(err))
+		}
+	}
 	response.OkWithMessage("设置成功", c)
 }
 
@@ -456,7 +548,12 @@ func (b *BaseApi) GetUserInfo(c *gin.Context) {
 		response.FailWithMessage("获取失败", c)
 		return
 	}
-	response.OkWithDetailed(gin.H{"userInfo": ReqUser}, "获取成功", c)
+	// 代登录时返回实际操作人 前端据此显示代登录提示条
+	var impersonator gin.H
+	if claims := utils.GetUserInfo(c); claims != nil && claims.Actor != nil {
+		impersonator = gin.H{"id": claims.Actor.ID, "userName": claims.Actor.Username}
+	}
+	response.OkWithDetailed(gin.H{"userInfo": ReqUser, "impersonator": impersonator}, "获取成功", c)
 }
 
 // ResetPassword
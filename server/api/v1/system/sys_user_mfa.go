@@ -0,0 +1,282 @@
+package system
+
+import (
+	"github.com/flipped-aurora/gin-vue-admin/server/global"
+	"github.com/flipped-aurora/gin-vue-admin/server/model/common/request"
+	"github.com/flipped-aurora/gin-vue-admin/server/model/common/response"
+	"github.com/flipped-aurora/gin-vue-admin/server/model/system"
+	systemReq "github.com/flipped-aurora/gin-vue-admin/server/model/system/request"
+	systemRes "github.com/flipped-aurora/gin-vue-admin/server/model/system/response"
+	"github.com/flipped-aurora/gin-vue-admin/server/utils"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// mfaChallengeNext 密码校验通过但需要两步验证 返回登录挑战而不是jwt
+func (b *BaseApi) mfaChallengeNext(c *gin.Context, user system.SysUser) {
+	challengeId, challenge, err := mfaService.CreateChallenge(user.ID)
+	if err != nil {
+		global.GVA_LOG.Error("创建登录挑战失败!", zap.Error(err))
+		response.FailWithMessage("创建登录挑战失败", c)
+		return
+	}
+	response.OkWithDetailed(systemRes.MfaChallengeResponse{
+		ChallengeId: challengeId,
+		NeedEnroll:  challenge.NeedEnroll,
+		Passkey:     passkeyService.HasPasskey(user.ID),
+	}, "请完成两步验证", c)
+}
+
+// MfaEnroll
+// @Tags     Base
+// @Summary  登录时绑定两步验证(角色要求但尚未绑定)
+// @Produce   application/json
+// @Param    data  body      systemReq.MfaChallenge                                          true  "登录挑战ID"
+// @Success  200   {object}  response.Response{data=systemRes.MfaEnrollResponse,msg=string}  "返回otpauth URI"
+// @Router   /base/mfaEnroll [post]
+func (b *BaseApi) MfaEnroll(c *gin.Context) {
+	var req systemReq.MfaChallenge
+	err := c.ShouldBindJSON(&req)
+	if err != nil {
+		response.FailWithMessage(err.Error(), c)
+		return
+	}
+	err = utils.Verify(req, utils.MfaChallengeVerify)
+	if err != nil {
+		response.FailWithMessage(err.Error(), c)
+		return
+	}
+	challenge, err := mfaService.GetChallenge(req.ChallengeId)
+	if err != nil {
+		response.FailWithMessage(err.Error(), c)
+		return
+	}
+	user, err := userService.FindUserById(int(challenge.UserId))
+	if err != nil {
+		global.GVA_LOG.Error("获取用户失败!", zap.Error(err))
+		response.FailWithMessage("获取用户失败", c)
+		return
+	}
+	secret, uri, err := mfaService.EnrollChallenge(challenge, *user)
+	if err != nil {
+		global.GVA_LOG.Error("绑定失败!", zap.Error(err))
+		response.FailWithMessage(err.Error(), c)
+		return
+	}
+	response.OkWithDetailed(systemRes.MfaEnrollResponse{Secret: secret, OtpAuthUri: uri}, "请使用验证器扫码绑定", c)
+}
+
+// MfaLogin
+// @Tags     Base
+// @Summary  登录第二步 校验两步验证码
+// @Produce   application/json
+// @Param    data  body      systemReq.MfaLogin                                          true  "登录挑战ID, 验证码或恢复码"
+// @Success  200   {object}  response.Response{data=systemRes.LoginResponse,msg=string}  "返回包括用户信息,token,过期时间"
+// @Router   /base/mfaLogin [post]
+func (b *BaseApi) MfaLogin(c *gin.Context) {
+	var req systemReq.MfaLogin
+	err := c.ShouldBindJSON(&req)
+	if err != nil {
+		response.FailWithMessage(err.Error(), c)
+		return
+	}
+	err = utils.Verify(req, utils.MfaLoginVerify)
+	if err != nil {
+		response.FailWithMessage(err.Error(), c)
+		return
+	}
+	challenge, err := mfaService.GetChallenge(req.ChallengeId)
+	if err != nil {
+		response.FailWithMessage(err.Error(), c)
+		return
+	}
+	u, err := userService.FindUserById(int(challenge.UserId))
+	if err != nil || u.Enable != 1 {
+		mfaService.FinishChallenge(req.ChallengeId)
+		response.FailWithMessage("用户被禁止登录", c)
+		return
+	}
+	// 两步验证失败同样计入登录锁定 锁定后不再校验
+	if err = loginLockoutService.Check(u.Username); err != nil {
+		mfaService.FinishChallenge(req.ChallengeId)
+		response.FailWithMessage(err.Error(), c)
+		return
+	}
+	if err = mfaService.AttemptChallenge(req.ChallengeId); err != nil {
+		response.FailWithMessage(err.Error(), c)
+		return
+	}
+	// 角色要求两步验证且尚未绑定时 用户在登录过程中完成绑定
+	recoveryCodes, err := mfaService.VerifyChallenge(challenge, req.Code)
+	if err != nil {
+		global.GVA_LOG.Error("两步验证失败!", zap.String("username", u.Username), zap.Error(err))
+		loginLockoutService.Fail(u.Username, c.ClientIP(), c.Request.UserAgent(), "两步验证失败")
+		response.FailWithMessage(err.Error(), c)
+		return
+	}
+	mfaService.FinishChallenge(req.ChallengeId)
+	user, err := userService.GetUserInfo(u.UUID)
+	if err != nil {
+		global.GVA_LOG.Error("获取用户信息失败!", zap.Error(err))
+		response.FailWithMessage("获取用户信息失败", c)
+		return
+	}
+	b.loginNext(c, user, recoveryCodes)
+}
+
+// GetMfaStatus
+// @Tags      SysUser
+// @Summary   获取自身两步验证状态
+// @Security  ApiKeyAuth
+// @Produce   application/json
+// @Success   200  {object}  response.Response{data=systemRes.MfaStatusResponse,msg=string}  "是否开启,是否被要求开启"
+// @Router    /user/getMfaStatus [get]
+func (b *BaseApi) GetMfaStatus(c *gin.Context) {
+	uid := utils.GetUserID(c)
+	response.OkWithDetailed(systemRes.MfaStatusResponse{
+		Enabled:  mfaService.IsMfaEnabled(uid),
+		Required: mfaService.IsMfaRequired(uid),
+	}, "获取成功", c)
+}
+
+// EnrollMfa
+// @Tags      SysUser
+// @Summary   绑定两步验证 生成新的密钥
+// @Security  ApiKeyAuth
+// @Produce   application/json
+// @Success   200  {object}  response.Response{data=systemRes.MfaEnrollResponse,msg=string}  "返回otpauth URI"
+// @Router    /user/enrollMfa [post]
+func (b *BaseApi) EnrollMfa(c *gin.Context) {
+	user, err := userService.FindUserById(int(utils.GetUserID(c)))
+	if err != nil {
+		global.GVA_LOG.Error("获取用户失败!", zap.Error(err))
+		response.FailWithMessage("获取用户失败", c)
+		return
+	}
+	secret, uri, err := mfaService.EnrollMfa(*user)
+	if err != nil {
+		global.GVA_LOG.Error("绑定失败!", zap.Error(err))
+		response.FailWithMessage(err.Error(), c)
+		return
+	}
+	response.OkWithDetailed(systemRes.MfaEnrollResponse{Secret: secret, OtpAuthUri: uri}, "请使用验证器扫码绑定", c)
+}
+
+// ActivateMfa
+// @Tags      SysUser
+// @Summary   验证并启用两步验证
+// @Security  ApiKeyAuth
+// @Produce   application/json
+// @Param     data  body      systemReq.MfaCode                                                      true  "验证码"
+// @Success   200   {object}  response.Response{data=systemRes.MfaRecoveryCodesResponse,msg=string}  "返回恢复码"
+// @Router    /user/activateMfa [post]
+func (b *BaseApi) ActivateMfa(c *gin.Context) {
+	var req systemReq.MfaCode
+	err := c.ShouldBindJSON(&req)
+	if err != nil {
+		response.FailWithMessage(err.Error(), c)
+		return
+	}
+	err = utils.Verify(req, utils.MfaCodeVerify)
+	if err != nil {
+		response.FailWithMessage(err.Error(), c)
+		return
+	}
+	codes, err := mfaService.ActivateMfa(utils.GetUserID(c), req.Code)
+	if err != nil {
+		global.GVA_LOG.Error("启用失败!", zap.Error(err))
+		response.FailWithMessage(err.Error(), c)
+		return
+	}
+	response.OkWithDetailed(systemRes.MfaRecoveryCodesResponse{RecoveryCodes: codes}, "启用成功", c)
+}
+
+// DisableMfa
+// @Tags      SysUser
+// @Summary   关闭两步验证
+// @Security  ApiKeyAuth
+// @Produce   application/json
+// @Param     data  body      systemReq.MfaCode              true  "验证码或恢复码"
+// @Success   200   {object}  response.Response{msg=string}  "关闭两步验证"
+// @Router    /user/disableMfa [post]
+func (b *BaseApi) DisableMfa(c *gin.Context) {
+	var req systemReq.MfaCode
+	err := c.ShouldBindJSON(&req)
+	if err != nil {
+		response.FailWithMessage(err.Error(), c)
+		return
+	}
+	err = utils.Verify(req, utils.MfaCodeVerify)
+	if err != nil {
+		response.FailWithMessage(err.Error(), c)
+		return
+	}
+	err = mfaService.DisableMfa(utils.GetUserID(c), req.Code)
+	if err != nil {
+		global.GVA_LOG.Error("关闭失败!", zap.Error(err))
+		response.FailWithMessage(err.Error(), c)
+		return
+	}
+	response.OkWithMessage("关闭成功", c)
+}
+
+// RegenerateRecoveryCodes
+// @Tags      SysUser
+// @Summary   重新生成恢复码
+// @Security  ApiKeyAuth
+// @Produce   application/json
+// @Param     data  body      systemReq.MfaCode                                                      true  "验证码"
+// @Success   200   {object}  response.Response{data=systemRes.MfaRecoveryCodesResponse,msg=string}  "返回恢复码"
+// @Router    /user/regenerateRecoveryCodes [post]
+func (b *BaseApi) RegenerateRecoveryCodes(c *gin.Context) {
+	var req systemReq.MfaCode
+	err := c.ShouldBindJSON(&req)
+	if err != nil {
+		response.FailWithMessage(err.Error(), c)
+		return
+	}
+	err = utils.Verify(req, utils.MfaCodeVerify)
+	if err != nil {
+		response.FailWithMessage(err.Error(), c)
+		return
+	}
+	codes, err := mfaService.RegenerateRecoveryCodes(utils.GetUserID(c), req.Code)
+	if err != nil {
+		global.GVA_LOG.Error("生成失败!", zap.Error(err))
+		response.FailWithMessage(err.Error(), c)
+		return
+	}
+	response.OkWithDetailed(systemRes.MfaRecoveryCodesResponse{RecoveryCodes: codes}, "生成成功", c)
+}
+
+// ResetMfa
+// @Tags      SysUser
+// @Summary   管理员重置用户两步验证
+// @Security  ApiKeyAuth
+// @Produce   application/json
+// @Param     data  body      request.GetById                true  "用户ID"
+// @Success   200   {object}  response.Response{msg=string}  "重置两步验证"
+// @Router    /user/resetMfa [post]
+func (b *BaseApi) ResetMfa(c *gin.Context) {
+	var reqId request.GetById
+	err := c.ShouldBindJSON(&reqId)
+	if err != nil {
+		response.FailWithMessage(err.Error(), c)
+		return
+	}
+	err = utils.Verify(reqId, utils.IdVerify)
+	if err != nil {
+		response.FailWithMessage(err.Error(), c)
+		return
+	}
+	err = mfaService.ResetMfa(uint(reqId.ID))
+	if err == nil {
+		err = passkeyService.ResetPasskeys(uint(reqId.ID))
+	}
+	if err != nil {
+		global.GVA_LOG.Error("重置失败!", zap.Error(err))
+		response.FailWithMessage("重置失败", c)
+		return
+	}
+	response.OkWithMessage("重置成功", c)
+}
@@ -0,0 +1,283 @@
+package system
+
+import (
+	"github.com/flipped-aurora/gin-vue-admin/server/global"
+	"github.com/flipped-aurora/gin-vue-admin/server/model/common/request"
+	"github.com/flipped-aurora/gin-vue-admin/server/model/common/response"
+	systemReq "github.com/flipped-aurora/gin-vue-admin/server/model/system/request"
+	systemRes "github.com/flipped-aurora/gin-vue-admin/server/model/system/response"
+	"github.com/flipped-aurora/gin-vue-admin/server/utils"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// PasskeyLoginBegin
+// @Tags     Base
+// @Summary  开始通行密钥免密码登录
+// @Produce   application/json
+// @Success  200  {object}  response.Response{data=systemRes.PasskeyLoginOptionsResponse,msg=string}  "返回会话ID和navigator.credentials.get参数"
+// @Router   /base/passkeyLoginBegin [post]
+func (b *BaseApi) PasskeyLoginBegin(c *gin.Context) {
+	sessionId, options, err := passkeyService.BeginPasswordless(c.GetHeader("Origin"))
+	if err != nil {
+		global.GVA_LOG.Error("开始通行密钥登录失败!", zap.Error(err))
+		response.FailWithMessage(err.Error(), c)
+		return
+	}
+	response.OkWithDetailed(systemRes.PasskeyLoginOptionsResponse{SessionId: sessionId, Options: options}, "获取成功", c)
+}
+
+// PasskeyLogin
+// @Tags     Base
+// @Summary  使用通行密钥免密码登录
+// @Produce   application/json
+// @Param    data  body      systemReq.PasskeyLogin                                      true  "会话ID, 浏览器返回的凭证"
+// @Success  200   {object}  response.Response{data=systemRes.LoginResponse,msg=string}  "返回包括用户信息,token,过期时间"
+// @Router   /base/passkeyLogin [post]
+func (b *BaseApi) PasskeyLogin(c *gin.Context) {
+	var req systemReq.PasskeyLogin
+	err := c.ShouldBindJSON(&req)
+	if err != nil {
+		response.FailWithMessage(err.Error(), c)
+		return
+	}
+	err = utils.Verify(req, utils.PasskeyLoginVerify)
+	if err != nil {
+		response.FailWithMessage(err.Error(), c)
+		return
+	}
+	userId, err := passkeyService.FinishPasswordless(req.SessionId, req.Credential)
+	if err != nil {
+		global.GVA_LOG.Error("通行密钥登录失败!", zap.Error(err))
+		response.FailWithMessage(err.Error(), c)
+		return
+	}
+	u, err := userService.FindUserById(int(userId))
+	if err != nil || u.Enable != 1 {
+		response.FailWithMessage("用户被禁止登录", c)
+		return
+	}
+	user, err := userService.GetUserInfo(u.UUID)
+	if err != nil {
+		global.GVA_LOG.Error("获取用户信息失败!", zap.Error(err))
+		response.FailWithMessage("获取用户信息失败", c)
+		return
+	}
+	// 通行密钥本身同时验证了持有设备和PIN或生物识别 不再要求两步验证
+	b.loginNext(c, user, nil)
+}
+
+// MfaPasskeyBegin
+// @Tags     Base
+// @Summary  登录第二步 开始使用通行密钥验证
+// @Produce   application/json
+// @Param    data  body      systemReq.MfaChallenge                                          true  "登录挑战ID"
+// @Success  200   {object}  response.Response{data=systemRes.PasskeyRequestOptions,msg=string}  "返回navigator.credentials.get参数"
+// @Router   /base/mfaPasskeyBegin [post]
+func (b *BaseApi) MfaPasskeyBegin(c *gin.Context) {
+	var req systemReq.MfaChallenge
+	err := c.ShouldBindJSON(&req)
+	if err != nil {
+		response.FailWithMessage(err.Error(), c)
+		return
+	}
+	err = utils.Verify(req, utils.MfaChallengeVerify)
+	if err != nil {
+		response.FailWithMessage(err.Error(), c)
+		return
+	}
+	challenge, err := mfaService.GetChallenge(req.ChallengeId)
+	if err != nil {
+		response.FailWithMessage(err.Error(), c)
+		return
+	}
+	options, err := passkeyService.BeginMfa(req.ChallengeId, challenge.UserId, c.GetHeader("Origin"))
+	if err != nil {
+		global.GVA_LOG.Error("开始通行密钥验证失败!", zap.Error(err))
+		response.FailWithMessage(err.Error(), c)
+		return
+	}
+	response.OkWithDetailed(options, "获取成功", c)
+}
+
+// MfaPasskeyLogin
+// @Tags     Base
+// @Summary  登录第二步 使用通行密钥代替验证码
+// @Produce   application/json
+// @Param    data  body      systemReq.MfaPasskeyLogin                                   true  "登录挑战ID, 浏览器返回的凭证"
+// @Success  200   {object}  response.Response{data=systemRes.LoginResponse,msg=string}  "返回包括用户信息,token,过期时间"
+// @Router   /base/mfaPasskeyLogin [post]
+func (b *BaseApi) MfaPasskeyLogin(c *gin.Context) {
+	var req systemReq.MfaPasskeyLogin
+	err := c.ShouldBindJSON(&req)
+	if err != nil {
+		response.FailWithMessage(err.Error(), c)
+		return
+	}
+	err = utils.Verify(req, utils.MfaChallengeVerify)
+	if err != nil {
+		response.FailWithMessage(err.Error(), c)
+		return
+	}
+	challenge, err := mfaService.GetChallenge(req.ChallengeId)
+	if err != nil {
+		response.FailWithMessage(err.Error(), c)
+		return
+	}
+	u, err := userService.FindUserById(int(challenge.UserId))
+	if err != nil || u.Enable != 1 {
+		mfaService.FinishChallenge(req.ChallengeId)
+		response.FailWithMessage("用户被禁止登录", c)
+		return
+	}
+	// 两步验证失败同样计入登录锁定 锁定后不再校验
+	if err = loginLockoutService.Check(u.Username); err != nil {
+		mfaService.FinishChallenge(req.ChallengeId)
+		response.FailWithMessage(err.Error(), c)
+		return
+	}
+	if err = mfaService.AttemptChallenge(req.ChallengeId); err != nil {
+		response.FailWithMessage(err.Error(), c)
+		return
+	}
+	if err = passkeyService.FinishMfa(req.ChallengeId, req.Credential); err != nil {
+		global.GVA_LOG.Error("两步验证失败!", zap.String("username", u.Username), zap.Error(err))
+		loginLockoutService.Fail(u.Username, c.ClientIP(), c.Request.UserAgent(), "两步验证失败")
+		response.FailWithMessage(err.Error(), c)
+		return
+	}
+	mfaService.FinishChallenge(req.ChallengeId)
+	user, err := userService.GetUserInfo(u.UUID)
+	if err != nil {
+		global.GVA_LOG.Error("获取用户信息失败!", zap.Error(err))
+		response.FailWithMessage("获取用户信息失败", c)
+		return
+	}
+	b.loginNext(c, user, nil)
+}
+
+// BeginPasskeyRegistration
+// @Tags      SysUser
+// @Summary   开始注册通行密钥
+// @Security  ApiKeyAuth
+// @Produce   application/json
+// @Success   200  {object}  response.Response{data=systemRes.PasskeyCreationOptions,msg=string}  "返回navigator.credentials.create参数"
+// @Router    /user/beginPasskeyRegistration [post]
+func (b *BaseApi) BeginPasskeyRegistration(c *gin.Context) {
+	user, err := userService.FindUserById(int(utils.GetUserID(c)))
+	if err != nil {
+		global.GVA_LOG.Error("获取用户失败!", zap.Error(err))
+		response.FailWithMessage("获取用户失败", c)
+		return
+	}
+	options, err := passkeyService.BeginRegistration(*user, c.GetHeader("Origin"))
+	if err != nil {
+		global.GVA_LOG.Error("开始注册通行密钥失败!", zap.Error(err))
+		response.FailWithMessage(err.Error(), c)
+		return
+	}
+	response.OkWithDetailed(options, "获取成功", c)
+}
+
+// FinishPasskeyRegistration
+// @Tags      SysUser
+// @Summary   完成注册通行密钥
+// @Security  ApiKeyAuth
+// @Produce   application/json
+// @Param     data  body      systemReq.FinishPasskeyRegistration                      true  "名称, 浏览器返回的凭证"
+// @Success   200   {object}  response.Response{data=system.SysUserPasskey,msg=string}  "返回通行密钥"
+// @Router    /user/finishPasskeyRegistration [post]
+func (b *BaseApi) FinishPasskeyRegistration(c *gin.Context) {
+	var req systemReq.FinishPasskeyRegistration
+	err := c.ShouldBindJSON(&req)
+	if err != nil {
+		response.FailWithMessage(err.Error(), c)
+		return
+	}
+	passkey, err := passkeyService.FinishRegistration(utils.GetUserID(c), req)
+	if err != nil {
+		global.GVA_LOG.Error("注册通行密钥失败!", zap.Error(err))
+		response.FailWithMessage(err.Error(), c)
+		return
+	}
+	response.OkWithDetailed(passkey, "注册成功", c)
+}
+
+// GetPasskeyList
+// @Tags      SysUser
+// @Summary   获取自身的通行密钥
+// @Security  ApiKeyAuth
+// @Produce   application/json
+// @Success   200  {object}  response.Response{data=[]system.SysUserPasskey,msg=string}  "通行密钥列表"
+// @Router    /user/getPasskeyList [get]
+func (b *BaseApi) GetPasskeyList(c *gin.Context) {
+	list, err := passkeyService.GetPasskeyList(utils.GetUserID(c))
+	if err != nil {
+		global.GVA_LOG.Error("获取失败!", zap.Error(err))
+		response.FailWithMessage("获取失败", c)
+		return
+	}
+	response.OkWithDetailed(list, "获取成功", c)
+}
+
+// RenamePasskey
+// @Tags      SysUser
+// @Summary   修改通行密钥名称
+// @Security  ApiKeyAuth
+// @Produce   application/json
+// @Param     data  body      systemReq.RenamePasskey        true  "通行密钥ID, 名称"
+// @Success   200   {object}  response.Response{msg=string}  "修改通行密钥名称"
+// @Router    /user/renamePasskey [put]
+func (b *BaseApi) RenamePasskey(c *gin.Context) {
+	var req systemReq.RenamePasskey
+	err := c.ShouldBindJSON(&req)
+	if err != nil {
+		response.FailWithMessage(err.Error(), c)
+		return
+	}
+	err = utils.Verify(req, utils.RenamePasskeyVerify)
+	if err != nil {
+		response.FailWithMessage(err.Error(), c)
+		return
+	}
+	err = passkeyService.RenamePasskey(utils.GetUserID(c), req.ID, req.Name)
+	if err != nil {
+		global.GVA_LOG.Error("修改失败!", zap.Error(err))
+		response.FailWithMessage("修改失败", c)
+		return
+	}
+	response.OkWithMessage("修改成功", c)
+}
+
+// DeletePasskey
+// @Tags      SysUser
+// @Summary   删除通行密钥
+// @Security  ApiKeyAuth
+// @Produce   application/json
+// @Param     data  body      request.GetById                true  "通行密钥ID"
+// @Success   200   {object}  response.Response{msg=string}  "删除通行密钥"
+// @Router    /user/deletePasskey [delete]
+func (b *BaseApi) DeletePasskey(c *gin.Context) {
+	var reqId request.GetById
+	err := c.ShouldBindJSON(&reqId)
+	if err != nil {
+		response.FailWithMessage(err.Error(), c)
+		return
+	}
+	err = utils.Verify(reqId, utils.IdVerify)
+	if err != nil {
+		response.FailWithMessage(err.Error(), c)
+		return
+	}
+	err = passkeyService.DeletePasskey(utils.GetUserID(c), uint(reqId.ID))
+	if err != nil {
+		global.GVA_LOG.Error("删除失败!", zap.Error(err))
+		if passkeyService.IsLastPasskey(err) {
+			response.FailWithMessage(err.Error(), c)
+			return
+		}
+		response.FailWithMessage("删除失败", c)
+		return
+	}
+	response.OkWithMessage("删除成功", c)
+}
@@ -0,0 +1,90 @@
+package system
+
+import (
+	"github.com/flipped-aurora/gin-vue-admin/server/global"
+	"github.com/flipped-aurora/gin-vue-admin/server/model/common/request"
+	"github.com/flipped-aurora/gin-vue-admin/server/model/common/response"
+	"github.com/flipped-aurora/gin-vue-admin/server/utils"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// GetSessionList
+// @Tags      SysUser
+// @Summary   获取自身在线会话列表
+// @Security  ApiKeyAuth
+// @Produce   application/json
+// @Success   200  {object}  response.Response{data=[]system.SysUserSession,msg=string}  "在线会话列表"
+// @Router    /user/getSessionList [get]
+func (b *BaseApi) GetSessionList(c *gin.Context) {
+	claims := utils.GetUserInfo(c)
+	if claims == nil {
+		response.NoAuth("未登录或非法访问", c)
+		return
+	}
+	list, err := sessionService.GetSessionList(claims.BaseClaims.ID, claims.FamilyId)
+	if err != nil {
+		global.GVA_LOG.Error("获取失败!", zap.Error(err))
+		response.FailWithMessage("获取失败", c)
+		return
+	}
+	response.OkWithDetailed(list, "获取成功", c)
+}
+
+// RevokeSession
+// @Tags      SysUser
+// @Summary   下线自身的某个会话
+// @Security  ApiKeyAuth
+// @Produce   application/json
+// @Param     data  body      request.GetById                true  "会话ID"
+// @Success   200   {object}  response.Response{msg=string}  "下线会话"
+// @Router    /user/revokeSession [post]
+func (b *BaseApi) RevokeSession(c *gin.Context) {
+	var reqId request.GetById
+	err := c.ShouldBindJSON(&reqId)
+	if err != nil {
+		response.FailWithMessage(err.Error(), c)
+		return
+	}
+	err = utils.Verify(reqId, utils.IdVerify)
+	if err != nil {
+		response.FailWithMessage(err.Error(), c)
+		return
+	}
+	err = sessionService.RevokeSession(utils.GetUserID(c), uint(reqId.ID))
+	if err != nil {
+		global.GVA_LOG.Error("下线失败!", zap.Error(err))
+		response.FailWithMessage("下线失败:"+err.Error(), c)
+		return
+	}
+	response.OkWithMessage("下线成功", c)
+}
+
+// ForceLogout
+// @Tags      SysUser
+// @Summary   管理员强制下线用户的全部会话
+// @Security  ApiKeyAuth
+// @Produce   application/json
+// @Param     data  body      request.GetById                true  "用户ID"
+// @Success   200   {object}  response.Response{msg=string}  "强制下线"
+// @Router    /user/forceLogout [post]
+func (b *BaseApi) ForceLogout(c *gin.Context) {
+	var reqId request.GetById
+	err := c.ShouldBindJSON(&reqId)
+	if err != nil {
+		response.FailWithMessage(err.Error(), c)
+		return
+	}
+	err = utils.Verify(reqId, utils.IdVerify)
+	if err != nil {
+		response.FailWithMessage(err.Error(), c)
+		return
+	}
+	err = sessionService.RevokeUserSessions(uint(reqId.ID))
+	if err != nil {
+		global.GVA_LOG.Error("强制下线失败!", zap.Error(err))
+		response.FailWithMessage("强制下线失败", c)
+		return
+	}
+	response.OkWithMessage("强制下线成功", c)
+}
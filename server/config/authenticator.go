@@ -0,0 +1,25 @@
+package config
+
+type Authenticator struct {
+	Chain []string `mapstructure:"chain" json:"chain" yaml:"chain"` // 账号密码登录时依次尝试的认证方式 local为本地用户 其余为ldap或插件注册的认证器名称
+	Ldap  []Ldap   `mapstructure:"ldap" json:"ldap" yaml:"ldap"`    // LDAP/Active Directory 认证源列表
+}
+
+type Ldap struct {
+	Name               string `mapstructure:"name" json:"name" yaml:"name"`                                                 // 唯一标识 同时作为认证链中的名称和角色映射表中的provider
+	Url                string `mapstructure:"url" json:"url" yaml:"url"`                                                    // 服务地址 如 ldap://127.0.0.1:389 或 ldaps://ad.example.com:636
+	StartTLS           bool   `mapstructure:"start-tls" json:"start-tls" yaml:"start-tls"`                                  // ldap:// 连接后是否升级为TLS
+	InsecureSkipVerify bool   `mapstructure:"insecure-skip-verify" json:"insecure-skip-verify" yaml:"insecure-skip-verify"` // 跳过证书校验 仅用于测试环境
+	Timeout            string `mapstructure:"timeout" json:"timeout" yaml:"timeout"`                                        // 连接和查询超时时间 默认10s
+	BindDn             string `mapstructure:"bind-dn" json:"bind-dn" yaml:"bind-dn"`                                        // 用于查询用户的服务账号 为空时匿名查询
+	BindPassword       string `mapstructure:"bind-password" json:"bind-password" yaml:"bind-password"`                      // 服务账号密码
+	BaseDn             string `mapstructure:"base-dn" json:"base-dn" yaml:"base-dn"`                                        // 用户查询的起始节点
+	UserFilter         string `mapstructure:"user-filter" json:"user-filter" yaml:"user-filter"`                            // 用户查询条件 %s为登录名 默认(uid=%s) AD可使用(sAMAccountName=%s)
+	UsernameAttr       string `mapstructure:"username-attr" json:"username-attr" yaml:"username-attr"`                      // 作为本地用户名的属性 默认uid
+	SubjectAttr        string `mapstructure:"subject-attr" json:"subject-attr" yaml:"subject-attr"`                         // 用户唯一标识属性 如entryUUID 为空时使用DN
+	NickNameAttr       string `mapstructure:"nick-name-attr" json:"nick-name-attr" yaml:"nick-name-attr"`                   // 作为昵称的属性 默认cn
+	EmailAttr          string `mapstructure:"email-attr" json:"email-attr" yaml:"email-attr"`                               // 作为邮箱的属性 默认mail
+	GroupAttr          string `mapstructure:"group-attr" json:"group-attr" yaml:"group-attr"`                               // 用户所属组的属性 用于角色映射 默认memberOf
+	AutoCreate         bool   `mapstructure:"auto-create" json:"auto-create" yaml:"auto-create"`                            // 首次登录时自动创建用户
+	DefaultAuthorityId uint   `mapstructure:"default-authority-id" json:"default-authority-id" yaml:"default-authority-id"` // 没有匹配到角色映射时的默认角色 0为拒绝登录
+}
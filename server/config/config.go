@@ -9,6 +9,12 @@ type Server struct {
 	Email     Email   `mapstructure:"email" json:"email" yaml:"email"`
 	System    System  `mapstructure:"system" json:"system" yaml:"system"`
 	Captcha   Captcha `mapstructure:"captcha" json:"captcha" yaml:"captcha"`
+	Mfa       Mfa     `mapstructure:"mfa" json:"mfa" yaml:"mfa"`
+	Oidc      Oidc    `mapstructure:"oidc" json:"oidc" yaml:"oidc"`
+	// 账号密码认证链
+	Authenticator Authenticator `mapstructure:"authenticator" json:"authenticator" yaml:"authenticator"`
+	// 通行密钥
+	Webauthn Webauthn `mapstructure:"webauthn" json:"webauthn" yaml:"webauthn"`
 	// auto
 	AutoCode Autocode `mapstructure:"autocode" json:"autocode" yaml:"autocode"`
 	// gorm
@@ -1,8 +1,13 @@
 package config
 
 type JWT struct {
-	SigningKey  string `mapstructure:"signing-key" json:"signing-key" yaml:"signing-key"`    // jwt签名
-	ExpiresTime string `mapstructure:"expires-time" json:"expires-time" yaml:"expires-time"` // 过期时间
-	BufferTime  string `mapstructure:"buffer-time" json:"buffer-time" yaml:"buffer-time"`    // 缓冲时间
-	Issuer      string `mapstructure:"issuer" json:"issuer" yaml:"issuer"`                   // 签发者
+	SigningKey         string `mapstructure:"signing-key" json:"signing-key" yaml:"signing-key"`                            // jwt签名
+	SigningMethod      string `mapstructure:"signing-method" json:"signing-method" yaml:"signing-method"`                   // 签名算法 HS256 RS256 ES256 EdDSA
+	KeyRotation        string `mapstructure:"key-rotation" json:"key-rotation" yaml:"key-rotation"`                         // 非对称密钥轮换周期 为空不自动轮换
+	KeyGracePeriod     string `mapstructure:"key-grace-period" json:"key-grace-period" yaml:"key-grace-period"`             // 轮换后旧密钥继续验签的时长 不应小于访问令牌过期时间
+	HmacAcceptUntil    string `mapstructure:"hmac-accept-until" json:"hmac-accept-until" yaml:"hmac-accept-until"`          // 切换为非对称算法后 在此时间前继续接受对称签名的旧令牌 格式 2006-01-02 15:04:05 为空不接受
+	ExpiresTime        string `mapstructure:"expires-time" json:"expires-time" yaml:"expires-time"`                         // 访问令牌过期时间
+	RefreshExpiresTime string `mapstructure:"refresh-expires-time" json:"refresh-expires-time" yaml:"refresh-expires-time"` // 刷新令牌过期时间
+	ImpersonateExpires string `mapstructure:"impersonate-expires" json:"impersonate-expires" yaml:"impersonate-expires"`    // 代登录令牌有效期 到期后需重新发起
+	Issuer             string `mapstructure:"issuer" json:"issuer" yaml:"issuer"`                                           // 签发者
 }
@@ -0,0 +1,10 @@
+package config
+
+type Lockout struct {
+	MaxAttempts int    `mapstructure:"max-attempts" json:"max-attempts" yaml:"max-attempts"` // 连续失败多少次后锁定账号 0为不锁定
+	Window      string `mapstructure:"window" json:"window" yaml:"window"`                   // 两次失败间隔超过该时间后重新计数 如15m
+	Duration    string `mapstructure:"duration" json:"duration" yaml:"duration"`             // 锁定时长 如30m
+	DelayAfter  int    `mapstructure:"delay-after" json:"delay-after" yaml:"delay-after"`    // 连续失败超过该次数后 每次失败需等待递增的时间才能再次尝试 0为不延迟
+	MaxDelay    string `mapstructure:"max-delay" json:"max-delay" yaml:"max-delay"`          // 最长等待时间 默认30s
+	Notify      bool   `mapstructure:"notify" json:"notify" yaml:"notify"`                   // 锁定时向用户邮箱发送通知
+}
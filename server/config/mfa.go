@@ -0,0 +1,8 @@
+package config
+
+type Mfa struct {
+	Issuer           string `mapstructure:"issuer" json:"issuer" yaml:"issuer"`                                  // 验证器中显示的签发方名称
+	EncryptKey       string `mapstructure:"encrypt-key" json:"encrypt-key" yaml:"encrypt-key"`                   // 两步验证密钥的加密口令 为空时使用jwt签名 修改后已绑定的密钥将无法解密
+	ChallengeExpires string `mapstructure:"challenge-expires" json:"challenge-expires" yaml:"challenge-expires"` // 登录第二步挑战的有效期
+	Skew             int64  `mapstructure:"skew" json:"skew" yaml:"skew"`                                        // 允许的时间步偏差 每步30秒
+}
@@ -0,0 +1,20 @@
+package config
+
+type Oidc struct {
+	StateExpires string         `mapstructure:"state-expires" json:"state-expires" yaml:"state-expires"` // 授权请求state的有效期
+	Providers    []OidcProvider `mapstructure:"providers" json:"providers" yaml:"providers"`             // 身份提供方列表
+}
+
+type OidcProvider struct {
+	Name               string   `mapstructure:"name" json:"name" yaml:"name"`                                                 // 唯一标识 同时作为角色映射表中的provider
+	DisplayName        string   `mapstructure:"display-name" json:"display-name" yaml:"display-name"`                         // 登录页显示名称
+	Issuer             string   `mapstructure:"issuer" json:"issuer" yaml:"issuer"`                                           // issuer地址 通过 /.well-known/openid-configuration 自动发现
+	ClientId           string   `mapstructure:"client-id" json:"client-id" yaml:"client-id"`                                  // 客户端ID
+	ClientSecret       string   `mapstructure:"client-secret" json:"client-secret" yaml:"client-secret"`                      // 客户端密钥 公共客户端可为空
+	RedirectUrl        string   `mapstructure:"redirect-url" json:"redirect-url" yaml:"redirect-url"`                         // 回调地址 指向前端 如 http://localhost:8080/
+	Scopes             []string `mapstructure:"scopes" json:"scopes" yaml:"scopes"`                                           // 额外申请的scope openid会自动添加
+	UsernameClaim      string   `mapstructure:"username-claim" json:"username-claim" yaml:"username-claim"`                   // 作为用户名的claim 默认preferred_username
+	GroupsClaim        string   `mapstructure:"groups-claim" json:"groups-claim" yaml:"groups-claim"`                         // 用于角色映射的claim 默认groups
+	AutoCreate         bool     `mapstructure:"auto-create" json:"auto-create" yaml:"auto-create"`                            // 首次登录时自动创建用户
+	DefaultAuthorityId uint     `mapstructure:"default-authority-id" json:"default-authority-id" yaml:"default-authority-id"` // 没有匹配到角色映射时的默认角色 0为拒绝登录
+}
@@ -0,0 +1,14 @@
+package config
+
+type PasswordPolicy struct {
+	MinLength          int      `mapstructure:"min-length" json:"min-length" yaml:"min-length"`                               // 最小长度
+	RequireUpper       bool     `mapstructure:"require-upper" json:"require-upper" yaml:"require-upper"`                      // 必须包含大写字母
+	RequireLower       bool     `mapstructure:"require-lower" json:"require-lower" yaml:"require-lower"`                      // 必须包含小写字母
+	RequireDigit       bool     `mapstructure:"require-digit" json:"require-digit" yaml:"require-digit"`                      // 必须包含数字
+	RequireSymbol      bool     `mapstructure:"require-symbol" json:"require-symbol" yaml:"require-symbol"`                   // 必须包含特殊字符
+	BanCommonPasswords bool     `mapstructure:"ban-common-passwords" json:"ban-common-passwords" yaml:"ban-common-passwords"` // 禁止使用内置的常见弱密码
+	BannedPasswords    []string `mapstructure:"banned-passwords" json:"banned-passwords" yaml:"banned-passwords"`             // 额外禁止使用的密码 不区分大小写
+	DisallowUsername   bool     `mapstructure:"disallow-username" json:"disallow-username" yaml:"disallow-username"`          // 密码不能与用户名相同 不区分大小写
+	HistoryCount       int      `mapstructure:"history-count" json:"history-count" yaml:"history-count"`                      // 不能与最近N次使用过的密码相同 0为不限制
+	MaxAge             string   `mapstructure:"max-age" json:"max-age" yaml:"max-age"`                                        // 密码最长有效期 如90d 过期后登录时必须修改 为空不限制
+}
@@ -0,0 +1,9 @@
+package config
+
+type PasswordReset struct {
+	Enable  bool   `mapstructure:"enable" json:"enable" yaml:"enable"`    // 开启忘记密码 需先配置邮件插件
+	Url     string `mapstructure:"url" json:"url" yaml:"url"`             // 前端地址 邮件中的重置链接为该地址加上 resetToken 参数
+	Expires string `mapstructure:"expires" json:"expires" yaml:"expires"` // 重置链接有效期 如30m
+	Limit   int    `mapstructure:"limit" json:"limit" yaml:"limit"`       // 同一邮箱在 window 内最多发送的次数 0为不限制
+	Window  string `mapstructure:"window" json:"window" yaml:"window"`    // 发送次数的统计周期 如1h
+}
@@ -0,0 +1,11 @@
+package config
+
+type SignUp struct {
+	Enable          bool     `mapstructure:"enable" json:"enable" yaml:"enable"`                               // 开启自助注册
+	AuthorityId     uint     `mapstructure:"authority-id" json:"authority-id" yaml:"authority-id"`             // 新用户的默认角色
+	AllowDomains    []string `mapstructure:"allow-domains" json:"allow-domains" yaml:"allow-domains"`          // 允许注册的邮箱域名 为空不限制
+	VerifyEmail     bool     `mapstructure:"verify-email" json:"verify-email" yaml:"verify-email"`             // 需要通过邮件中的链接验证邮箱
+	RequireApproval bool     `mapstructure:"require-approval" json:"require-approval" yaml:"require-approval"` // 需要管理员审核后才能登录
+	Url             string   `mapstructure:"url" json:"url" yaml:"url"`                                        // 前端地址 验证链接为该地址加上 signUpToken 参数
+	Expires         string   `mapstructure:"expires" json:"expires" yaml:"expires"`                            // 验证链接有效期 如24h
+}
@@ -7,8 +7,19 @@ type System struct {
 	Addr          int    `mapstructure:"addr" json:"addr" yaml:"addr"` // 端口值
 	LimitCountIP  int    `mapstructure:"iplimit-count" json:"iplimit-count" yaml:"iplimit-count"`
 	LimitTimeIP   int    `mapstructure:"iplimit-time" json:"iplimit-time" yaml:"iplimit-time"`
-	UseMultipoint bool   `mapstructure:"use-multipoint" json:"use-multipoint" yaml:"use-multipoint"`    // 多点登录拦截
+	UseMultipoint bool   `mapstructure:"use-multipoint" json:"use-multipoint" yaml:"use-multipoint"`    // 多点登录拦截 角色未配置最大会话数时只允许一个会话
 	UseRedis      bool   `mapstructure:"use-redis" json:"use-redis" yaml:"use-redis"`                   // 使用redis
 	UseMongo      bool   `mapstructure:"use-mongo" json:"use-mongo" yaml:"use-mongo"`                   // 使用mongo
 	UseStrictAuth bool   `mapstructure:"use-strict-auth" json:"use-strict-auth" yaml:"use-strict-auth"` // 使用树形角色分配模式
+	UseUnionAuth  bool   `mapstructure:"use-union-auth" json:"use-union-auth" yaml:"use-union-auth"`    // 接口和菜单权限取用户全部角色的并集 关闭时只使用当前角色
+	// 密码策略 新增用户 修改密码时校验
+	PasswordPolicy PasswordPolicy `mapstructure:"password-policy" json:"password-policy" yaml:"password-policy"`
+	// 按用户名统计登录失败次数 递增延迟并临时锁定账号
+	Lockout Lockout `mapstructure:"lockout" json:"lockout" yaml:"lockout"`
+	// 忘记密码时通过邮件发送一次性重置链接
+	PasswordReset PasswordReset `mapstructure:"password-reset" json:"password-reset" yaml:"password-reset"`
+	// 自助注册 可要求验证邮箱和管理员审核
+	SignUp SignUp `mapstructure:"sign-up" json:"sign-up" yaml:"sign-up"`
+	// 多实例部署时同步casbin策略 开启redis时通过订阅通知 未开启时按该间隔轮询数据库 默认5s
+	CasbinPollInterval string `mapstructure:"casbin-poll-interval" json:"casbin-poll-interval" yaml:"casbin-poll-interval"`
 }
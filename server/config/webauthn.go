@@ -0,0 +1,9 @@
+package config
+
+type Webauthn struct {
+	RPID         string   `mapstructure:"rp-id" json:"rp-id" yaml:"rp-id"`                      // 依赖方ID 即前端域名 为空时使用请求来源的主机名
+	RPName       string   `mapstructure:"rp-name" json:"rp-name" yaml:"rp-name"`                // 认证器中显示的名称
+	Origins      []string `mapstructure:"origins" json:"origins" yaml:"origins"`                // 允许的前端来源 如 https://admin.example.com 为空时只校验主机名
+	Timeout      string   `mapstructure:"timeout" json:"timeout" yaml:"timeout"`                // 注册和登录操作的有效期
+	Passwordless bool     `mapstructure:"passwordless" json:"passwordless" yaml:"passwordless"` // 允许不输入密码直接使用通行密钥登录 要求验证 PIN 或生物识别
+}
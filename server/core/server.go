@@ -5,6 +5,7 @@ import (
 	"github.com/flipped-aurora/gin-vue-admin/server/global"
 	"github.com/flipped-aurora/gin-vue-admin/server/initialize"
 	"github.com/flipped-aurora/gin-vue-admin/server/service/system"
+	"github.com/flipped-aurora/gin-vue-admin/server/utils"
 	"go.uber.org/zap"
 )
 
@@ -13,7 +14,7 @@ type server interface {
 }
 
 func RunWindowsServer() {
-	if global.GVA_CONFIG.System.UseMultipoint || global.GVA_CONFIG.System.UseRedis {
+	if global.GVA_CONFIG.System.UseRedis {
 		// 初始化redis服务
 		initialize.Redis()
 		initialize.RedisList()
@@ -28,7 +29,17 @@ func RunWindowsServer() {
 	// 从db加载jwt数据
 	if global.GVA_DB != nil {
 		system.LoadAll()
+		// 多实例部署时通过redis订阅其他实例拉黑的令牌
+		if global.GVA_REDIS != nil {
+			go system.JwtServiceApp.SubscribeBlacklist()
+		}
+		// 多实例部署时其他实例修改策略后重新加载
+		if err := system.CasbinServiceApp.StartWatcher(); err != nil {
+			zap.L().Error("启动casbin策略同步失败!", zap.Error(err))
+		}
 	}
+	// 非对称签名密钥 首次签发或遇到未知kid时从db加载
+	utils.JWTKeys.SetLoader(system.JwtKeyServiceApp.LoadJwtKeys)
 
 	Router := initialize.Routers()
 
@@ -52,6 +52,25 @@ func (e *ensureTables) MigrateTable(ctx context.Context) (context.Context, error
 		sysModel.Condition{},
 		sysModel.JoinTemplate{},
 		sysModel.SysParams{},
+		sysModel.SysRefreshToken{},
+		sysModel.SysUserMfa{},
+		sysModel.SysUserRecoveryCode{},
+		sysModel.SysUserSession{},
+		sysModel.SysJwtKey{},
+		sysModel.SysUserIdentity{},
+		sysModel.SysAuthorityClaimMap{},
+		sysModel.SysApiKey{},
+		sysModel.SysPasswordHistory{},
+		sysModel.SysLoginAttempt{},
+		sysModel.SysLoginLockout{},
+		sysModel.SysPasswordReset{},
+		sysModel.SysSignUp{},
+		sysModel.SysUserPasskey{},
+		sysModel.SysCasbinCondition{},
+		sysModel.SysCasbinVersion{},
+		sysModel.SysDataScope{},
+		sysModel.SysFieldRule{},
+		sysModel.SysAuthorityVersion{},
 
 		adapter.CasbinRule{},
 
@@ -90,6 +109,25 @@ func (e *ensureTables) TableCreated(ctx context.Context) bool {
 		sysModel.SysExportTemplate{},
 		sysModel.Condition{},
 		sysModel.JoinTemplate{},
+		sysModel.SysRefreshToken{},
+		sysModel.SysUserMfa{},
+		sysModel.SysUserRecoveryCode{},
+		sysModel.SysUserSession{},
+		sysModel.SysJwtKey{},
+		sysModel.SysUserIdentity{},
+		sysModel.SysAuthorityClaimMap{},
+		sysModel.SysApiKey{},
+		sysModel.SysPasswordHistory{},
+		sysModel.SysLoginAttempt{},
+		sysModel.SysLoginLockout{},
+		sysModel.SysPasswordReset{},
+		sysModel.SysSignUp{},
+		sysModel.SysUserPasskey{},
+		sysModel.SysCasbinCondition{},
+		sysModel.SysCasbinVersion{},
+		sysModel.SysDataScope{},
+		sysModel.SysFieldRule{},
+		sysModel.SysAuthorityVersion{},
 
 		adapter.CasbinRule{},
 
@@ -6,6 +6,7 @@ import (
 	"github.com/flipped-aurora/gin-vue-admin/server/global"
 	"github.com/flipped-aurora/gin-vue-admin/server/model/example"
 	"github.com/flipped-aurora/gin-vue-admin/server/model/system"
+	systemService "github.com/flipped-aurora/gin-vue-admin/server/service/system"
 
 	"go.uber.org/zap"
 	"gorm.io/gorm"
@@ -34,6 +35,23 @@ func Gorm() *gorm.DB {
 	}
 }
 
+// GormPlugins 为系统库和业务库注册数据范围过滤和字段权限
+func GormPlugins() {
+	dbs := []*gorm.DB{global.GVA_DB}
+	for _, db := range global.GVA_DBList {
+		dbs = append(dbs, db)
+	}
+	for _, db := range dbs {
+		if db == nil {
+			continue
+		}
+		if err := systemService.UseGormPlugins(db); err != nil {
+			global.GVA_LOG.Error("register gorm plugin failed", zap.Error(err))
+			os.Exit(0)
+		}
+	}
+}
+
 func RegisterTables() {
 	db := global.GVA_DB
 	err := db.AutoMigrate(
@@ -56,6 +74,25 @@ func RegisterTables() {
 		system.Condition{},
 		system.JoinTemplate{},
 		system.SysParams{},
+		system.SysRefreshToken{},
+		system.SysUserMfa{},
+		system.SysUserRecoveryCode{},
+		system.SysUserSession{},
+		system.SysJwtKey{},
+		system.SysUserIdentity{},
+		system.SysAuthorityClaimMap{},
+		system.SysApiKey{},
+		system.SysPasswordHistory{},
+		system.SysLoginAttempt{},
+		system.SysLoginLockout{},
+		system.SysPasswordReset{},
+		system.SysSignUp{},
+		system.SysUserPasskey{},
+		system.SysCasbinCondition{},
+		system.SysCasbinVersion{},
+		system.SysDataScope{},
+		system.SysFieldRule{},
+		system.SysAuthorityVersion{},
 
 		example.ExaFile{},
 		example.ExaCustomer{},
@@ -5,6 +5,7 @@ import (
 	"github.com/songzhibin97/gkit/cache/local_cache"
 	"os"
 	"strings"
+	"time"
 
 	"github.com/flipped-aurora/gin-vue-admin/server/global"
 	"github.com/flipped-aurora/gin-vue-admin/server/utils"
@@ -15,10 +16,24 @@ func OtherInit() {
 	if err != nil {
 		panic(err)
 	}
-	_, err = utils.ParseDuration(global.GVA_CONFIG.JWT.BufferTime)
+	if global.GVA_CONFIG.JWT.RefreshExpiresTime == "" {
+		global.GVA_CONFIG.JWT.RefreshExpiresTime = "7d"
+	}
+	_, err = utils.ParseDuration(global.GVA_CONFIG.JWT.RefreshExpiresTime)
 	if err != nil {
 		panic(err)
 	}
+	if global.GVA_CONFIG.JWT.SigningMethod == "" {
+		global.GVA_CONFIG.JWT.SigningMethod = utils.JWTAlgHS256
+	}
+	if global.GVA_CONFIG.JWT.SigningMethod != utils.JWTAlgHS256 && !utils.IsAsymmetricJWTAlg(global.GVA_CONFIG.JWT.SigningMethod) {
+		panic("jwt.signing-method 仅支持 HS256 RS256 ES256 EdDSA")
+	}
+	if until := global.GVA_CONFIG.JWT.HmacAcceptUntil; until != "" {
+		if _, err = time.ParseInLocation(time.DateTime, until, time.Local); err != nil {
+			panic("jwt.hmac-accept-until 格式应为 2006-01-02 15:04:05")
+		}
+	}
 
 	global.BlackCache = local_cache.NewCache(
 		local_cache.SetDefaultExpire(dr),
@@ -72,6 +72,7 @@ func Routers() *gin.Engine {
 		})
 	}
 	{
+		systemRouter.InitJwksRouter(Router)      // jwt验签公钥 挂载在根路径
 		systemRouter.InitBaseRouter(PublicGroup) // 注册基础功能路由 不做鉴权
 		systemRouter.InitInitRouter(PublicGroup) // 自动初始化相关
 	}
@@ -92,6 +93,10 @@ func Routers() *gin.Engine {
 		systemRouter.InitAuthorityBtnRouterRouter(PrivateGroup)     // 按钮权限管理
 		systemRouter.InitSysExportTemplateRouter(PrivateGroup)      // 导出模板
 		systemRouter.InitSysParamsRouter(PrivateGroup, PublicGroup) // 参数管理
+		systemRouter.InitAuthorityClaimMapRouter(PrivateGroup)      // 外部组角色映射
+		systemRouter.InitApiKeyRouter(PrivateGroup)                 // API密钥
+		systemRouter.InitLoginAttemptRouter(PrivateGroup)           // 登录失败记录和账号解锁
+		systemRouter.InitSignUpRouter(PrivateGroup)                 // 自助注册审核
 		exampleRouter.InitCustomerRouter(PrivateGroup)              // 客户路由
 		exampleRouter.InitFileUploadAndDownloadRouter(PrivateGroup) // 文件上传下载功能路由
 
@@ -7,6 +7,7 @@ import (
 	"github.com/robfig/cron/v3"
 
 	"github.com/flipped-aurora/gin-vue-admin/server/global"
+	"github.com/flipped-aurora/gin-vue-admin/server/service/system"
 )
 
 func Timer() {
@@ -24,6 +25,17 @@ func Timer() {
 			fmt.Println("add timer error:", err)
 		}
 
+		// jwt非对称签名密钥轮换
+		_, err = global.GVA_Timer.AddTaskByFunc("JwtKeyRotation", "@hourly", func() {
+			err := system.JwtKeyServiceApp.RotateJwtKeyIfDue()
+			if err != nil {
+				fmt.Println("timer error:", err)
+			}
+		}, "定时轮换jwt签名密钥", option...)
+		if err != nil {
+			fmt.Println("add timer error:", err)
+		}
+
 		// 其他定时任务定在这里 参考上方使用方法
 
 		//_, err := global.GVA_Timer.AddTaskByFunc("定时任务标识", "corn表达式", func() {
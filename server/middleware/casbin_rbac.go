@@ -1,11 +1,11 @@
 package middleware
 
 import (
-	"strconv"
 	"strings"
 
 	"github.com/flipped-aurora/gin-vue-admin/server/global"
 	"github.com/flipped-aurora/gin-vue-admin/server/model/common/response"
+	"github.com/flipped-aurora/gin-vue-admin/server/model/system"
 	"github.com/flipped-aurora/gin-vue-admin/server/service"
 	"github.com/flipped-aurora/gin-vue-admin/server/utils"
 	"github.com/gin-gonic/gin"
@@ -16,18 +16,33 @@ var casbinService = service.ServiceGroupApp.SystemServiceGroup.CasbinService
 // CasbinHandler 拦截器
 func CasbinHandler() gin.HandlerFunc {
 	return func(c *gin.Context) {
-		waitUse, _ := utils.GetClaims(c)
+		// 优先使用 JWTAuth 写入上下文的信息 API密钥请求没有jwt
+		waitUse := utils.GetUserInfo(c)
+		if waitUse == nil {
+			response.NoAuth("未登录或非法访问", c)
+			c.Abort()
+			return
+		}
 		//获取请求的PATH
 		path := c.Request.URL.Path
 		obj := strings.TrimPrefix(path, global.GVA_CONFIG.System.RouterPrefix)
 		// 获取请求方法
 		act := c.Request.Method
-		// 获取用户的角色
-		sub := strconv.Itoa(int(waitUse.AuthorityId))
-		e := casbinService.Casbin() // 判断策略中是否存在
-		success, _ := e.Enforce(sub, obj, act)
+		// 判断用户的角色在策略中是否存在 开启 use-union-auth 时校验用户的全部角色
+		// 策略可附加IP范围和时间窗口条件 不满足时返回原因
+		success, reason := casbinService.Enforce(waitUse.BaseClaims.ID, waitUse.AuthorityId, obj, act, c.ClientIP())
 		if !success {
-			response.FailWithDetailed(gin.H{}, "权限不足", c)
+			msg := "权限不足"
+			if reason != "" {
+				msg += ": " + reason
+			}
+			response.FailWithDetailed(gin.H{"reason": reason}, msg, c)
+			c.Abort()
+			return
+		}
+		// API密钥限定了接口范围时 只能访问范围内且角色有权限的接口
+		if apiKey, ok := c.Get("apiKey"); ok && !apiKeyService.AllowScope(apiKey.(*system.SysApiKey), obj, act) {
+			response.FailWithDetailed(gin.H{}, "API密钥无权访问该接口", c)
 			c.Abort()
 			return
 		}
@@ -0,0 +1,67 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+
+	"github.com/flipped-aurora/gin-vue-admin/server/global"
+	"github.com/flipped-aurora/gin-vue-admin/server/model/common/response"
+	"github.com/flipped-aurora/gin-vue-admin/server/service"
+	"github.com/flipped-aurora/gin-vue-admin/server/utils"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+var fieldRuleService = service.ServiceGroupApp.SystemServiceGroup.FieldRuleService
+
+// FieldRule 按当前用户角色的字段权限处理json响应 删除隐藏字段 脱敏字段替换为脱敏后的值
+// resource 为资源表名 更新时忽略受限字段由 FieldRulePlugin 处理 需要服务使用 utils.DataScopeContext
+// 只处理响应中的字段 接口的查询参数不在此检查
+func FieldRule(resource string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		rules, err := fieldRuleService.FieldRules(utils.GetUserID(c), utils.GetUserAuthorityId(c), resource)
+		if err != nil {
+			global.GVA_LOG.Error("获取字段权限失败!", zap.Error(err))
+			response.FailWithMessage("获取字段权限失败", c)
+			c.Abort()
+			return
+		}
+		if len(rules) == 0 {
+			c.Next()
+			return
+		}
+		writer := &bufferedBodyWriter{ResponseWriter: c.Writer, body: &bytes.Buffer{}}
+		c.Writer = writer
+		c.Next()
+		c.Writer = writer.ResponseWriter
+
+		body := writer.body.Bytes()
+		if strings.Contains(writer.Header().Get("Content-Type"), "application/json") {
+			var resp map[string]interface{}
+			decoder := json.NewDecoder(bytes.NewReader(body))
+			decoder.UseNumber()
+			if err = decoder.Decode(&resp); err == nil {
+				resp["data"] = rules.Apply(resp["data"])
+				if masked, err := json.Marshal(resp); err == nil {
+					body = masked
+				}
+			}
+		}
+		_, _ = writer.ResponseWriter.Write(body)
+	}
+}
+
+// bufferedBodyWriter 暂存响应 处理完成后再写出
+type bufferedBodyWriter struct {
+	gin.ResponseWriter
+	body *bytes.Buffer
+}
+
+func (w *bufferedBodyWriter) Write(b []byte) (int, error) {
+	return w.body.Write(b)
+}
+
+func (w *bufferedBodyWriter) WriteString(s string) (int, error) {
+	return w.body.WriteString(s)
+}
@@ -0,0 +1,19 @@
+package middleware
+
+import (
+	"github.com/flipped-aurora/gin-vue-admin/server/model/common/response"
+	"github.com/flipped-aurora/gin-vue-admin/server/utils"
+	"github.com/gin-gonic/gin"
+)
+
+// NotImpersonating 代登录期间禁止的敏感操作 如修改密码 两步验证 签发凭据和再次代登录
+func NotImpersonating() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if claims := utils.GetUserInfo(c); claims != nil && claims.Actor != nil {
+			response.FailWithDetailed(gin.H{}, "代登录期间不允许该操作", c)
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
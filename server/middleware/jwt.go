@@ -2,35 +2,37 @@ package middleware
 
 import (
 	"errors"
-	"github.com/flipped-aurora/gin-vue-admin/server/global"
 	"github.com/flipped-aurora/gin-vue-admin/server/utils"
-	"github.com/golang-jwt/jwt/v4"
-	"strconv"
-	"time"
 
 	"github.com/flipped-aurora/gin-vue-admin/server/model/common/response"
+	systemReq "github.com/flipped-aurora/gin-vue-admin/server/model/system/request"
 	"github.com/flipped-aurora/gin-vue-admin/server/service"
 
 	"github.com/gin-gonic/gin"
 )
 
-var jwtService = service.ServiceGroupApp.SystemServiceGroup.JwtService
+var (
+	jwtService           = service.ServiceGroupApp.SystemServiceGroup.JwtService
+	refreshTokenService  = service.ServiceGroupApp.SystemServiceGroup.RefreshTokenService
+	sessionService       = service.ServiceGroupApp.SystemServiceGroup.SessionService
+	apiKeyService        = service.ServiceGroupApp.SystemServiceGroup.ApiKeyService
+	securityStampService = service.ServiceGroupApp.SystemServiceGroup.SecurityStampService
+)
 
 func JWTAuth() gin.HandlerFunc {
 	return func(c *gin.Context) {
-		// 我们这里jwt鉴权取头部信息 x-token 登录时回返回token信息 这里前端需要把token存储到cookie或者本地localStorage中 不过需要跟后端协商过期时间 可以约定刷新令牌或者重新登录
+		// 机器客户端使用 Authorization: Bearer gva_xxx 传递API密钥 不走jwt和会话逻辑
+		if key := utils.GetApiKey(c); key != "" {
+			apiKeyAuth(c, key)
+			return
+		}
+		// 我们这里jwt鉴权取头部信息 x-token 登录时回返回token信息 这里前端需要把token存储到cookie或者本地localStorage中 访问令牌过期后前端使用刷新令牌调用 /base/refresh 换取新令牌
 		token := utils.GetToken(c)
 		if token == "" {
 			response.NoAuth("未登录或非法访问", c)
 			c.Abort()
 			return
 		}
-		if jwtService.IsBlacklist(token) {
-			response.NoAuth("您的帐户异地登陆或令牌失效", c)
-			utils.ClearToken(c)
-			c.Abort()
-			return
-		}
 		j := utils.NewJWT()
 		// parseToken 解析token包含的信息
 		claims, err := j.ParseToken(token)
@@ -46,29 +48,32 @@ func JWTAuth() gin.HandlerFunc {
 			c.Abort()
 			return
 		}
-
-		// 已登录用户被管理员禁用 需要使该用户的jwt失效 此处比较消耗性能 如果需要 请自行打开
-		// 用户被删除的逻辑 需要优化 此处比较消耗性能 如果需要 请自行打开
-
-		//if user, err := userService.FindUserByUuid(claims.UUID.String()); err != nil || user.Enable == 2 {
-		//	_ = jwtService.JsonInBlacklist(system.JwtBlacklist{Jwt: token})
-		//	response.FailWithDetailed(gin.H{"reload": true}, err.Error(), c)
-		//	c.Abort()
-		//}
-		c.Set("claims", claims)
-		if claims.ExpiresAt.Unix()-time.Now().Unix() < claims.BufferTime {
-			dr, _ := utils.ParseDuration(global.GVA_CONFIG.JWT.ExpiresTime)
-			claims.ExpiresAt = jwt.NewNumericDate(time.Now().Add(dr))
-			newToken, _ := j.CreateTokenByOldToken(token, *claims)
-			newClaims, _ := j.ParseToken(newToken)
-			c.Header("new-token", newToken)
-			c.Header("new-expires-at", strconv.FormatInt(newClaims.ExpiresAt.Unix(), 10))
-			utils.SetToken(c, newToken, int(dr.Seconds()))
-			if global.GVA_CONFIG.System.UseMultipoint {
-				// 记录新的活跃jwt
-				_ = jwtService.SetRedisJWT(newToken, newClaims.Username)
+		// 黑名单按jti记录 开启redis时其他实例登出的令牌同样立即失效
+		if jwtService.IsBlacklist(jwtService.TokenKey(token, claims)) || refreshTokenService.IsFamilyRevoked(claims.FamilyId) {
+			response.NoAuth("您的帐户异地登陆或令牌失效", c)
+			utils.ClearToken(c)
+			c.Abort()
+			return
+		}
+		// 用户被禁用 删除 角色变更或修改密码后安全戳会更换 之前签发的令牌立即失效
+		// 安全戳有缓存 每个用户只需偶尔查询一次数据库
+		if err = securityStampService.Verify(claims.BaseClaims.ID, claims.SecurityStamp); err != nil {
+			response.NoAuth(err.Error(), c)
+			utils.ClearToken(c)
+			c.Abort()
+			return
+		}
+		// 代登录令牌同时校验实际操作人 操作人被禁用或修改密码后代登录立即结束
+		if claims.Actor != nil {
+			if err = securityStampService.Verify(claims.Actor.ID, claims.Actor.SecurityStamp); err != nil {
+				response.NoAuth(err.Error(), c)
+				utils.ClearToken(c)
+				c.Abort()
+				return
 			}
 		}
+		sessionService.TouchSession(claims.FamilyId, c.ClientIP())
+		c.Set("claims", claims)
 		c.Next()
 
 		if newToken, exists := c.Get("new-token"); exists {
@@ -79,3 +84,24 @@ func JWTAuth() gin.HandlerFunc {
 		}
 	}
 }
+
+// apiKeyAuth 校验API密钥 以密钥所属用户的身份继续请求 密钥本身记录在上下文中供 CasbinHandler 校验接口范围
+func apiKeyAuth(c *gin.Context, key string) {
+	apiKey, user, err := apiKeyService.Authenticate(key, c.ClientIP())
+	if err != nil {
+		response.NoAuth(err.Error(), c)
+		c.Abort()
+		return
+	}
+	c.Set("claims", &systemReq.CustomClaims{
+		BaseClaims: systemReq.BaseClaims{
+			UUID:        user.UUID,
+			ID:          user.ID,
+			Username:    user.Username,
+			NickName:    user.NickName,
+			AuthorityId: user.AuthorityId,
+		},
+	})
+	c.Set("apiKey", apiKey)
+	c.Next()
+}
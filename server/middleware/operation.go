@@ -56,7 +56,7 @@ func OperationRecord() gin.HandlerFunc {
 			}
 			body, _ = json.Marshal(&m)
 		}
-		claims, _ := utils.GetClaims(c)
+		claims := utils.GetUserInfo(c)
 		if claims != nil && claims.BaseClaims.ID != 0 {
 			userId = int(claims.BaseClaims.ID)
 		} else {
@@ -74,6 +74,10 @@ func OperationRecord() gin.HandlerFunc {
 			Body:   "",
 			UserID: userId,
 		}
+		// 代登录期间的操作同时记录实际操作人
+		if claims != nil && claims.Actor != nil {
+			record.ImpersonatorID = int(claims.Actor.ID)
+		}
 
 		// 上传文件时候 中间件日志进行裁断操作
 		if strings.Contains(c.GetHeader("Content-Type"), "multipart/form-data") {
@@ -13,3 +13,8 @@ type ExaCustomer struct {
 	SysUserAuthorityID uint           `json:"sysUserAuthorityID" form:"sysUserAuthorityID" gorm:"comment:管理角色ID"` // 管理角色ID
 	SysUser            system.SysUser `json:"sysUser" form:"sysUser" gorm:"comment:管理详情"`                         // 管理详情
 }
+
+// DataScopeColumns 按负责人和负责人角色过滤 见 system.DataScoped
+func (ExaCustomer) DataScopeColumns() (userColumn, authorityColumn string) {
+	return "sys_user_id", "sys_user_authority_id"
+}
@@ -8,10 +8,19 @@ import (
 // Custom claims structure
 type CustomClaims struct {
 	BaseClaims
-	BufferTime int64
+	FamilyId      string // 刷新令牌族ID 登出时据此作废对应的刷新令牌
+	SecurityStamp string // 签发时用户的安全戳 与用户当前安全戳不一致时令牌失效
+	Actor         *Actor `json:",omitempty"` // 管理员代登录时为实际操作人 本人登录为空
 	jwt.RegisteredClaims
 }
 
+// Actor 代登录令牌中记录的实际操作人
+type Actor struct {
+	ID            uint
+	Username      string
+	SecurityStamp string // 操作人的安全戳 操作人被禁用或修改密码后代登录令牌同时失效
+}
+
 type BaseClaims struct {
 	UUID        uuid.UUID
 	ID          uint
@@ -19,3 +28,8 @@ type BaseClaims struct {
 	NickName    string
 	AuthorityId uint
 }
+
+// RefreshToken 刷新令牌请求
+type RefreshToken struct {
+	RefreshToken string `json:"refreshToken"` // 刷新令牌
+}
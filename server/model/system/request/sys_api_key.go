@@ -0,0 +1,15 @@
+package request
+
+import (
+	"time"
+
+	"github.com/flipped-aurora/gin-vue-admin/server/model/system"
+)
+
+// CreateApiKey 创建API密钥
+type CreateApiKey struct {
+	Name       string               `json:"name"`       // 名称
+	ExpiresAt  *time.Time           `json:"expiresAt"`  // 过期时间 为空时永不过期
+	Scopes     []system.ApiKeyScope `json:"scopes"`     // 可访问的接口 为空时与用户角色权限一致
+	AllowedIps []string             `json:"allowedIps"` // IP白名单 支持IP和CIDR
+}
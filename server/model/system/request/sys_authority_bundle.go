@@ -0,0 +1,50 @@
+package request
+
+const (
+	AuthorityBundleVersion = 1
+
+	AuthorityBundleMerge   = "merge"   // 与已有权限合并 同一接口以导入包为准
+	AuthorityBundleReplace = "replace" // 以导入包替换已有权限
+)
+
+// AuthorityBundle 角色导出包 菜单按name 按钮按菜单name和按钮name 接口按路径和方法引用 不依赖数据库自增ID
+type AuthorityBundle struct {
+	Version          int                  `json:"version"`
+	Authority        AuthorityBundleInfo  `json:"authority"`
+	Policies         []CasbinInfo         `json:"policies"`             // casbin策略
+	Menus            []string             `json:"menus"`                // 菜单name
+	Btns             []AuthorityBundleBtn `json:"btns"`                 // 菜单按钮
+	DataAuthorityIds []uint               `json:"dataAuthorityIds"`     // 资源权限 可查看数据的角色ID
+	DataScopes       []DataScopeInfo      `json:"dataScopes,omitempty"` // 数据范围
+	FieldRules       []FieldRuleInfo      `json:"fieldRules,omitempty"` // 字段权限
+}
+
+// AuthorityBundleInfo 角色本身的字段
+type AuthorityBundleInfo struct {
+	AuthorityId   uint   `json:"authorityId"`
+	AuthorityName string `json:"authorityName"`
+	ParentId      uint   `json:"parentId"`
+	DefaultRouter string `json:"defaultRouter"`
+	RequireMfa    bool   `json:"requireMfa"`
+	MaxSessions   int    `json:"maxSessions"`
+	InheritParent bool   `json:"inheritParent"`
+}
+
+// AuthorityBundleBtn 角色拥有的按钮
+type AuthorityBundleBtn struct {
+	Menu string `json:"menu"` // 菜单name
+	Name string `json:"name"` // 按钮name
+}
+
+// ExportAuthorityBundle 导出角色
+type ExportAuthorityBundle struct {
+	AuthorityId uint   `json:"authorityId" form:"authorityId"`
+	Format      string `json:"format" form:"format"` // json(默认)|yaml
+}
+
+// ImportAuthorityBundle 导入角色
+type ImportAuthorityBundle struct {
+	Content string `json:"content"` // 导出的json或yaml内容
+	Mode    string `json:"mode"`    // merge(默认)|replace 角色已存在时的处理方式
+	DryRun  bool   `json:"dryRun"`  // 只返回差异 不修改数据
+}
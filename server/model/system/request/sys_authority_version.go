@@ -0,0 +1,41 @@
+package request
+
+import (
+	"github.com/flipped-aurora/gin-vue-admin/server/model/common/request"
+)
+
+// 权限修改方式 记录在历史版本中
+const (
+	AuthorityVersionInitial         = "initial"               // 首次修改前的权限
+	AuthorityVersionCreateAuthority = "createAuthority"       // 创建角色
+	AuthorityVersionCopyAuthority   = "copyAuthority"         // 拷贝角色
+	AuthorityVersionUpdateAuthority = "updateAuthority"       // 修改角色 包括父角色和继承
+	AuthorityVersionUpdateCasbin    = "updateCasbin"          // 修改接口权限
+	AuthorityVersionSetMenu         = "setMenuAuthority"      // 修改菜单权限
+	AuthorityVersionSetBtn          = "setAuthorityBtn"       // 修改按钮权限
+	AuthorityVersionSetData         = "setDataAuthority"      // 修改资源权限
+	AuthorityVersionSetDataScopes   = "setDataScopes"         // 修改数据范围
+	AuthorityVersionSetFieldRules   = "setFieldRules"         // 修改字段权限
+	AuthorityVersionUpdateApi       = "updateApi"             // 修改接口路径或方法
+	AuthorityVersionDeleteApi       = "deleteApi"             // 删除或同步删除接口
+	AuthorityVersionImport          = "importAuthorityBundle" // 导入角色
+	AuthorityVersionRollback        = "rollback"              // 恢复到历史版本
+)
+
+// AuthorityVersionSearch 角色权限历史版本列表
+type AuthorityVersionSearch struct {
+	AuthorityId uint `json:"authorityId" form:"authorityId"`
+	request.PageInfo
+}
+
+// AuthorityVersionReq 指定角色的某个版本
+type AuthorityVersionReq struct {
+	AuthorityId uint `json:"authorityId" form:"authorityId"`
+	Version     int  `json:"version" form:"version"`
+}
+
+// RollbackAuthorityVersion 将角色权限恢复到指定版本
+type RollbackAuthorityVersion struct {
+	AuthorityVersionReq
+	DryRun bool `json:"dryRun"` // 只返回与当前权限的差异 不修改数据
+}
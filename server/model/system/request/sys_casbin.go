@@ -1,9 +1,28 @@
 package request
 
+import "github.com/flipped-aurora/gin-vue-admin/server/model/system"
+
+const (
+	CasbinEffectAllow = "allow" // 允许
+	CasbinEffectDeny  = "deny"  // 拒绝 优先于允许
+	CasbinCondNone    = "*"     // 策略没有附加条件
+)
+
 // CasbinInfo Casbin info structure
 type CasbinInfo struct {
 	Path   string `json:"path"`   // 路径
 	Method string `json:"method"` // 方法
+	Effect string `json:"effect"` // 效果 allow(默认)|deny
+	// 附加条件 为空时不限制 拒绝规则同样只在条件满足时生效
+	Condition *system.CasbinCondition `json:"condition,omitempty"`
+}
+
+// GetEffect 未指定效果时为允许
+func (c CasbinInfo) GetEffect() string {
+	if c.Effect == CasbinEffectDeny {
+		return CasbinEffectDeny
+	}
+	return CasbinEffectAllow
 }
 
 // CasbinInReceive Casbin structure for input parameters
@@ -23,5 +42,34 @@ func DefaultCasbin() []CasbinInfo {
 		{Path: "/user/setSelfInfo", Method: "PUT"},
 		{Path: "/fileUploadAndDownload/upload", Method: "POST"},
 		{Path: "/sysDictionary/findSysDictionary", Method: "GET"},
+		{Path: "/user/getMfaStatus", Method: "GET"},
+		{Path: "/user/enrollMfa", Method: "POST"},
+		{Path: "/user/activateMfa", Method: "POST"},
+		{Path: "/user/disableMfa", Method: "POST"},
+		{Path: "/user/regenerateRecoveryCodes", Method: "POST"},
+		{Path: "/user/getPasskeyList", Method: "GET"},
+		{Path: "/user/beginPasskeyRegistration", Method: "POST"},
+		{Path: "/user/finishPasskeyRegistration", Method: "POST"},
+		{Path: "/user/renamePasskey", Method: "PUT"},
+		{Path: "/user/deletePasskey", Method: "DELETE"},
+		{Path: "/user/getSessionList", Method: "GET"},
+		{Path: "/user/revokeSession", Method: "POST"},
+		{Path: "/apiKey/createApiKey", Method: "POST"},
+		{Path: "/apiKey/deleteApiKey", Method: "DELETE"},
+		{Path: "/apiKey/getApiKeyList", Method: "GET"},
 	}
 }
+
+// CasbinSimulate 模拟用户或角色的权限 至少指定其中一个
+type CasbinSimulate struct {
+	UserId      uint   `json:"userId"`      // 用户ID 开启 use-union-auth 时包括用户的全部角色
+	AuthorityId uint   `json:"authorityId"` // 当前角色ID 为空时使用用户的当前角色
+	Ip          string `json:"ip"`          // 请求IP 为空时忽略策略条件
+}
+
+// CasbinExplain 模拟访问单个接口
+type CasbinExplain struct {
+	CasbinSimulate
+	Method string `json:"method"` // 方法
+	Path   string `json:"path"`   // 路径
+}
@@ -0,0 +1,13 @@
+package request
+
+// SetDataScopes 设置角色的数据范围 以传入的配置替换已有配置
+type SetDataScopes struct {
+	AuthorityId uint            `json:"authorityId"`
+	Scopes      []DataScopeInfo `json:"scopes"`
+}
+
+// DataScopeInfo 单个资源的数据范围
+type DataScopeInfo struct {
+	Resource string `json:"resource"` // 资源表名 *为默认
+	Scope    string `json:"scope"`    // self|authority|subtree|listed|all
+}
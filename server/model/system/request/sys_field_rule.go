@@ -0,0 +1,15 @@
+package request
+
+// SetFieldRules 设置角色的字段权限 以传入的配置替换已有配置
+type SetFieldRules struct {
+	AuthorityId uint            `json:"authorityId"`
+	Rules       []FieldRuleInfo `json:"rules"`
+}
+
+// FieldRuleInfo 单个字段的权限
+type FieldRuleInfo struct {
+	Resource string `json:"resource"` // 资源表名
+	Field    string `json:"field"`    // 字段json名或列名
+	Action   string `json:"action"`   // hidden|masked|readonly
+	Pattern  string `json:"pattern"`  // 脱敏规则
+}
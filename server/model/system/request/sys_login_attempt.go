@@ -0,0 +1,16 @@
+package request
+
+import (
+	"github.com/flipped-aurora/gin-vue-admin/server/model/common/request"
+)
+
+type SysLoginAttemptSearch struct {
+	Username string `json:"username" form:"username"`
+	Ip       string `json:"ip" form:"ip"`
+	request.PageInfo
+}
+
+// UnlockUser 解除账号锁定
+type UnlockUser struct {
+	Username string `json:"username"`
+}
@@ -0,0 +1,20 @@
+package request
+
+type OidcAuthUrl struct {
+	Provider string `json:"provider" form:"provider"` // 身份源标识
+}
+
+type OidcLogin struct {
+	State string `json:"state"` // 授权请求的state
+	Code  string `json:"code"`  // 身份源回调返回的授权码
+}
+
+type ClaimMapSearch struct {
+	AuthorityId uint `json:"authorityId" form:"authorityId"` // 角色ID 为0时查询全部
+}
+
+// ProvisionOptions 外部用户首次登录时的开通策略
+type ProvisionOptions struct {
+	AutoCreate         bool // 是否自动创建本地用户
+	DefaultAuthorityId uint // 没有匹配到角色映射时的默认角色 0为拒绝
+}
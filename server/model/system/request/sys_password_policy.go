@@ -0,0 +1,18 @@
+package request
+
+// ChangeExpiredPassword 登录时修改过期或被重置的密码
+type ChangeExpiredPassword struct {
+	ChallengeId string `json:"challengeId"` // 登录返回的挑战ID
+	NewPassword string `json:"newPassword"` // 新密码
+}
+
+// ForgotPassword 忘记密码 向邮箱发送重置链接
+type ForgotPassword struct {
+	Email string `json:"email"` // 用户邮箱
+}
+
+// ResetPasswordByToken 通过邮件中的重置令牌设置新密码
+type ResetPasswordByToken struct {
+	Token       string `json:"token"`       // 重置令牌
+	NewPassword string `json:"newPassword"` // 新密码
+}
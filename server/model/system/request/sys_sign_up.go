@@ -0,0 +1,35 @@
+package request
+
+import (
+	"github.com/flipped-aurora/gin-vue-admin/server/model/common/request"
+)
+
+// SignUp 自助注册
+type SignUp struct {
+	Username  string `json:"userName"`  // 用户名
+	Password  string `json:"passWord"`  // 密码
+	NickName  string `json:"nickName"`  // 用户昵称
+	Email     string `json:"email"`     // 邮箱 用于验证和接收审核结果
+	Phone     string `json:"phone"`     // 手机号
+	Captcha   string `json:"captcha"`   // 验证码
+	CaptchaId string `json:"captchaId"` // 验证码ID
+}
+
+// VerifySignUp 通过邮件中的链接验证邮箱
+type VerifySignUp struct {
+	Token string `json:"token"` // 验证令牌
+}
+
+type SysSignUpSearch struct {
+	Username string `json:"username" form:"username"`
+	Email    string `json:"email" form:"email"`
+	Status   int    `json:"status" form:"status"`
+	request.PageInfo
+}
+
+// ReviewSignUp 审核自助注册申请
+type ReviewSignUp struct {
+	ID      uint   `json:"id"`      // 申请ID
+	Approve bool   `json:"approve"` // 是否通过
+	Remark  string `json:"remark"`  // 审核意见
+}
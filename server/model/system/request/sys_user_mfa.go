@@ -0,0 +1,17 @@
+package request
+
+// MfaCode 两步验证码 也可以填写恢复码
+type MfaCode struct {
+	Code string `json:"code"` // TOTP验证码或恢复码
+}
+
+// MfaChallenge 登录挑战
+type MfaChallenge struct {
+	ChallengeId string `json:"challengeId"` // 登录第一步返回的挑战ID
+}
+
+// MfaLogin 登录第二步
+type MfaLogin struct {
+	ChallengeId string `json:"challengeId"` // 登录第一步返回的挑战ID
+	Code        string `json:"code"`        // TOTP验证码或恢复码
+}
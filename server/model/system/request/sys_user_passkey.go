@@ -0,0 +1,41 @@
+package request
+
+// PasskeyCredential 浏览器返回的 PublicKeyCredential 二进制字段均为 base64url 编码
+type PasskeyCredential struct {
+	Id       string          `json:"id"`       // 凭证ID
+	Type     string          `json:"type"`     // 固定为 public-key
+	Response PasskeyResponse `json:"response"` // 认证器响应
+}
+
+type PasskeyResponse struct {
+	ClientDataJSON    string   `json:"clientDataJSON"`    // 客户端数据
+	AttestationObject string   `json:"attestationObject"` // 注册时返回的证明数据
+	AuthenticatorData string   `json:"authenticatorData"` // 登录时返回的认证器数据
+	Signature         string   `json:"signature"`         // 登录时返回的签名
+	UserHandle        string   `json:"userHandle"`        // 登录时返回的用户标识
+	Transports        []string `json:"transports"`        // 注册时返回的传输方式
+}
+
+// FinishPasskeyRegistration 完成通行密钥注册
+type FinishPasskeyRegistration struct {
+	Name       string            `json:"name"`       // 名称
+	Credential PasskeyCredential `json:"credential"` // 浏览器返回的凭证
+}
+
+// PasskeyLogin 使用通行密钥登录
+type PasskeyLogin struct {
+	SessionId  string            `json:"sessionId"`  // 开始登录时返回的会话ID
+	Credential PasskeyCredential `json:"credential"` // 浏览器返回的凭证
+}
+
+// MfaPasskeyLogin 登录第二步 使用通行密钥代替验证码
+type MfaPasskeyLogin struct {
+	ChallengeId string            `json:"challengeId"` // 登录第一步返回的挑战ID
+	Credential  PasskeyCredential `json:"credential"`  // 浏览器返回的凭证
+}
+
+// RenamePasskey 修改通行密钥名称
+type RenamePasskey struct {
+	ID   uint   `json:"id"`   // 通行密钥ID
+	Name string `json:"name"` // 名称
+}
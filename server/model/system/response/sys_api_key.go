@@ -0,0 +1,8 @@
+package response
+
+import "github.com/flipped-aurora/gin-vue-admin/server/model/system"
+
+type ApiKeyResponse struct {
+	Key    string           `json:"key"` // 明文密钥 只在创建时返回一次
+	ApiKey system.SysApiKey `json:"apiKey"`
+}
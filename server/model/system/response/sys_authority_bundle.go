@@ -0,0 +1,33 @@
+package response
+
+import (
+	"github.com/flipped-aurora/gin-vue-admin/server/model/system/request"
+)
+
+// AuthorityBundleExport 导出的角色文件
+type AuthorityBundleExport struct {
+	Filename string `json:"filename"`
+	Content  string `json:"content"`
+}
+
+// AuthorityBundleDiff 导入前后的差异 存在冲突时不会导入
+type AuthorityBundleDiff struct {
+	AuthorityId            uint                         `json:"authorityId"`
+	Create                 bool                         `json:"create"` // 角色不存在 将新建
+	Fields                 []string                     `json:"fields"` // 变化的角色字段
+	AddPolicies            []request.CasbinInfo         `json:"addPolicies"`
+	RemovePolicies         []request.CasbinInfo         `json:"removePolicies"`
+	AddMenus               []string                     `json:"addMenus"`
+	RemoveMenus            []string                     `json:"removeMenus"`
+	AddBtns                []request.AuthorityBundleBtn `json:"addBtns"`
+	RemoveBtns             []request.AuthorityBundleBtn `json:"removeBtns"`
+	AddDataAuthorityIds    []uint                       `json:"addDataAuthorityIds"`
+	RemoveDataAuthorityIds []uint                       `json:"removeDataAuthorityIds"`
+	AddDataScopes          []request.DataScopeInfo      `json:"addDataScopes"`
+	RemoveDataScopes       []request.DataScopeInfo      `json:"removeDataScopes"`
+	AddFieldRules          []request.FieldRuleInfo      `json:"addFieldRules"`
+	RemoveFieldRules       []request.FieldRuleInfo      `json:"removeFieldRules"`
+	Conflicts              []string                     `json:"conflicts"` // 目标环境缺少的菜单 按钮 角色等
+	Warnings               []string                     `json:"warnings"`  // 不影响导入的提示 如接口不在接口列表中
+	Applied                bool                         `json:"applied"`   // 是否已导入
+}
@@ -0,0 +1,13 @@
+package response
+
+import (
+	"github.com/flipped-aurora/gin-vue-admin/server/model/system"
+	"github.com/flipped-aurora/gin-vue-admin/server/model/system/request"
+)
+
+// AuthorityVersionDetail 历史版本的快照及与上一版本的差异
+type AuthorityVersionDetail struct {
+	system.SysAuthorityVersion
+	Bundle request.AuthorityBundle `json:"bundle"`
+	Diff   AuthorityBundleDiff     `json:"diff"` // 第一个版本与空权限比较
+}
@@ -1,9 +1,41 @@
 package response
 
 import (
+	"github.com/flipped-aurora/gin-vue-admin/server/model/system"
 	"github.com/flipped-aurora/gin-vue-admin/server/model/system/request"
 )
 
 type PolicyPathResponse struct {
 	Paths []request.CasbinInfo `json:"paths"`
 }
+
+// CasbinPolicy 命中的策略 继承时所属角色为父角色
+type CasbinPolicy struct {
+	AuthorityId string `json:"authorityId"` // 策略所属角色
+	request.CasbinInfo
+}
+
+// CasbinDecision 单个接口的校验结果
+type CasbinDecision struct {
+	Method      string        `json:"method"`
+	Path        string        `json:"path"`
+	Allowed     bool          `json:"allowed"`
+	Reason      string        `json:"reason"`      // 未通过的原因
+	AuthorityId uint          `json:"authorityId"` // 做出判断的角色
+	Policy      *CasbinPolicy `json:"policy"`      // 命中的策略 没有匹配时为空
+}
+
+// CasbinExplainResponse 单个接口的校验过程和用户可见的菜单按钮
+type CasbinExplainResponse struct {
+	CasbinDecision
+	ActiveAuthorityId uint             `json:"activeAuthorityId"` // 当前角色
+	AuthorityIds      []uint           `json:"authorityIds"`      // 参与计算的全部角色
+	Menus             []system.SysMenu `json:"menus"`             // 可见的菜单 含按钮
+}
+
+// CasbinSimulateResponse 全部路由的校验结果
+type CasbinSimulateResponse struct {
+	ActiveAuthorityId uint             `json:"activeAuthorityId"`
+	AuthorityIds      []uint           `json:"authorityIds"`
+	Routes            []CasbinDecision `json:"routes"`
+}
@@ -0,0 +1,10 @@
+package response
+
+type OidcProvider struct {
+	Name        string `json:"name"`
+	DisplayName string `json:"displayName"`
+}
+
+type OidcAuthUrlResponse struct {
+	AuthUrl string `json:"authUrl"`
+}
@@ -0,0 +1,8 @@
+package response
+
+type PasswordChangeChallengeResponse struct {
+	NeedChangePassword bool     `json:"needChangePassword"`      // 需要修改密码后才能完成登录
+	ChallengeId        string   `json:"challengeId"`             // 修改密码时携带的挑战ID
+	Reason             string   `json:"reason"`                  // 需要修改密码的原因
+	RecoveryCodes      []string `json:"recoveryCodes,omitempty"` // 登录时完成两步验证绑定 返回一次性恢复码
+}
@@ -9,7 +9,10 @@ type SysUserResponse struct {
 }
 
 type LoginResponse struct {
-	User      system.SysUser `json:"user"`
-	Token     string         `json:"token"`
-	ExpiresAt int64          `json:"expiresAt"`
+	User             system.SysUser `json:"user"`
+	Token            string         `json:"token"`
+	ExpiresAt        int64          `json:"expiresAt"`
+	RefreshToken     string         `json:"refreshToken"`            // 刷新令牌 只能使用一次
+	RefreshExpiresAt int64          `json:"refreshExpiresAt"`        // 刷新令牌过期时间
+	RecoveryCodes    []string       `json:"recoveryCodes,omitempty"` // 登录时完成两步验证绑定 返回一次性恢复码
 }
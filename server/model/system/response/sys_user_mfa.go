@@ -0,0 +1,21 @@
+package response
+
+type MfaChallengeResponse struct {
+	ChallengeId string `json:"challengeId"` // 登录挑战ID 第二步提交验证码时携带
+	NeedEnroll  bool   `json:"needEnroll"`  // 角色要求两步验证但用户尚未绑定 需先绑定验证器
+	Passkey     bool   `json:"passkey"`     // 可以使用通行密钥完成第二步
+}
+
+type MfaEnrollResponse struct {
+	Secret     string `json:"secret"`     // base32 密钥 供无法扫码时手动输入
+	OtpAuthUri string `json:"otpAuthUri"` // otpauth URI 前端转换为二维码
+}
+
+type MfaRecoveryCodesResponse struct {
+	RecoveryCodes []string `json:"recoveryCodes"` // 恢复码明文 仅显示一次
+}
+
+type MfaStatusResponse struct {
+	Enabled  bool `json:"enabled"`  // 是否已开启
+	Required bool `json:"required"` // 角色是否要求开启
+}
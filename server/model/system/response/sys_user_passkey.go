@@ -0,0 +1,56 @@
+package response
+
+// 以下结构与 WebAuthn 规范的 JSON 形式一致 二进制字段均为 base64url 编码 前端转换后传给 navigator.credentials
+
+type PasskeyRelyingParty struct {
+	ID   string `json:"id,omitempty"`
+	Name string `json:"name,omitempty"`
+}
+
+type PasskeyUser struct {
+	ID          string `json:"id"`
+	Name        string `json:"name"`
+	DisplayName string `json:"displayName"`
+}
+
+type PasskeyCredentialParam struct {
+	Type string `json:"type"`
+	Alg  int    `json:"alg"`
+}
+
+type PasskeyDescriptor struct {
+	Type       string   `json:"type"`
+	ID         string   `json:"id"`
+	Transports []string `json:"transports,omitempty"`
+}
+
+type PasskeyAuthenticatorSelection struct {
+	ResidentKey      string `json:"residentKey"`
+	UserVerification string `json:"userVerification"`
+}
+
+// PasskeyCreationOptions navigator.credentials.create 的 publicKey 参数
+type PasskeyCreationOptions struct {
+	Rp                     PasskeyRelyingParty           `json:"rp"`
+	User                   PasskeyUser                   `json:"user"`
+	Challenge              string                        `json:"challenge"`
+	PubKeyCredParams       []PasskeyCredentialParam      `json:"pubKeyCredParams"`
+	Timeout                int64                         `json:"timeout"`
+	ExcludeCredentials     []PasskeyDescriptor           `json:"excludeCredentials"`
+	AuthenticatorSelection PasskeyAuthenticatorSelection `json:"authenticatorSelection"`
+	Attestation            string                        `json:"attestation"`
+}
+
+// PasskeyRequestOptions navigator.credentials.get 的 publicKey 参数
+type PasskeyRequestOptions struct {
+	Challenge        string              `json:"challenge"`
+	RpId             string              `json:"rpId"`
+	Timeout          int64               `json:"timeout"`
+	AllowCredentials []PasskeyDescriptor `json:"allowCredentials"`
+	UserVerification string              `json:"userVerification"`
+}
+
+type PasskeyLoginOptionsResponse struct {
+	SessionId string                `json:"sessionId"` // 完成登录时携带
+	Options   PasskeyRequestOptions `json:"options"`
+}
@@ -0,0 +1,31 @@
+package system
+
+import (
+	"time"
+
+	"github.com/flipped-aurora/gin-vue-admin/server/global"
+)
+
+// SysApiKey 用户创建的API密钥 供脚本和CI等机器客户端调用接口 只保存密钥哈希
+type SysApiKey struct {
+	global.GVA_MODEL
+	UserId     uint          `json:"userId" gorm:"index;comment:用户ID"`                          // 所属用户ID
+	Name       string        `json:"name" gorm:"size:64;comment:名称"`                            // 名称 用于区分用途
+	Prefix     string        `json:"prefix" gorm:"size:16;comment:密钥前缀"`                        // 明文前缀 用于识别密钥
+	KeyHash    string        `json:"-" gorm:"uniqueIndex;size:64;comment:密钥哈希"`                 // 密钥sha256
+	Scopes     []ApiKeyScope `json:"scopes" gorm:"serializer:json;type:text;comment:可访问的接口"`    // 可访问的接口 为空时与用户角色权限一致
+	AllowedIps []string      `json:"allowedIps" gorm:"serializer:json;type:text;comment:IP白名单"` // 允许调用的IP或CIDR 为空时不限制
+	ExpiresAt  *time.Time    `json:"expiresAt" gorm:"comment:过期时间"`                             // 过期时间 为空时永不过期
+	LastUsedAt *time.Time    `json:"lastUsedAt" gorm:"comment:最近使用时间"`                          // 最近使用时间
+	LastUsedIp string        `json:"lastUsedIp" gorm:"size:64;comment:最近使用IP"`                  // 最近使用IP
+}
+
+// ApiKeyScope API密钥可访问的接口 只能是用户角色已有权限的子集
+type ApiKeyScope struct {
+	Path   string `json:"path"`   // 路径 支持与casbin策略相同的 :id 和 * 通配
+	Method string `json:"method"` // 方法
+}
+
+func (SysApiKey) TableName() string {
+	return "sys_api_keys"
+}
@@ -16,6 +16,9 @@ type SysAuthority struct {
 	SysBaseMenus    []SysBaseMenu   `json:"menus" gorm:"many2many:sys_authority_menus;"`
 	Users           []SysUser       `json:"-" gorm:"many2many:sys_user_authority;"`
 	DefaultRouter   string          `json:"defaultRouter" gorm:"comment:默认菜单;default:dashboard"` // 默认菜单(默认dashboard)
+	RequireMfa      bool            `json:"requireMfa" gorm:"default:false;comment:是否强制两步验证"`    // 拥有该角色的用户登录时必须通过两步验证
+	MaxSessions     int             `json:"maxSessions" gorm:"default:0;comment:最大同时在线会话数"`      // 0为不限制 超出时最早的会话被下线
+	InheritParent   bool            `json:"inheritParent" gorm:"default:false;comment:继承父角色权限"`  // 通过casbin的g规则继承父角色的接口权限
 }
 
 func (SysAuthority) TableName() string {
@@ -0,0 +1,17 @@
+package system
+
+import (
+	"github.com/flipped-aurora/gin-vue-admin/server/global"
+)
+
+// SysAuthorityClaimMap 外部身份源的组/claim 与本地角色的映射
+type SysAuthorityClaimMap struct {
+	global.GVA_MODEL
+	Provider    string `json:"provider" form:"provider" gorm:"index;size:64;comment:身份源"`   // 身份源标识 为空表示对全部身份源生效
+	ClaimValue  string `json:"claimValue" form:"claimValue" gorm:"size:191;comment:claim值"` // 组名等claim值
+	AuthorityId uint   `json:"authorityId" form:"authorityId" gorm:"index;comment:角色ID"`    // 映射到的角色
+}
+
+func (SysAuthorityClaimMap) TableName() string {
+	return "sys_authority_claim_maps"
+}
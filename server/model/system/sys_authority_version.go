@@ -0,0 +1,20 @@
+package system
+
+import (
+	"github.com/flipped-aurora/gin-vue-admin/server/global"
+)
+
+// SysAuthorityVersion 角色权限的历史版本 每次修改接口 菜单 按钮 资源权限后保存一份快照
+type SysAuthorityVersion struct {
+	global.GVA_MODEL
+	AuthorityId  uint   `json:"authorityId" gorm:"uniqueIndex:idx_authority_version;comment:角色ID"`
+	Version      int    `json:"version" gorm:"uniqueIndex:idx_authority_version;comment:版本号"`
+	Action       string `json:"action" gorm:"size:64;comment:修改方式"`
+	OperatorId   uint   `json:"operatorId" gorm:"comment:操作人ID"`
+	OperatorName string `json:"operatorName" gorm:"size:64;comment:操作人"`
+	Snapshot     string `json:"-" gorm:"type:text;comment:权限快照 与角色导出包格式相同"`
+}
+
+func (SysAuthorityVersion) TableName() string {
+	return "sys_authority_versions"
+}
@@ -0,0 +1,30 @@
+package system
+
+import (
+	"github.com/flipped-aurora/gin-vue-admin/server/global"
+)
+
+// SysCasbinCondition casbin策略的附加条件 策略的 v4 字段保存条件键 相同条件的策略共用一条记录
+type SysCasbinCondition struct {
+	global.GVA_MODEL
+	Key string `json:"key" gorm:"uniqueIndex;size:32;comment:条件键"` // 条件内容的sha256前缀
+	CasbinCondition
+}
+
+// CasbinCondition 策略生效的条件 各项均满足时策略才参与匹配 为空的项不限制
+type CasbinCondition struct {
+	Cidrs     []string `json:"cidrs" gorm:"serializer:json;type:text;comment:IP范围"`  // 允许的IP或CIDR
+	Weekdays  []int    `json:"weekdays" gorm:"serializer:json;type:text;comment:星期"` // 0为周日 1-6为周一至周六
+	StartTime string   `json:"startTime" gorm:"size:5;comment:开始时间"`                 // HH:MM
+	EndTime   string   `json:"endTime" gorm:"size:5;comment:结束时间"`                   // HH:MM 早于开始时间时跨越午夜
+	Timezone  string   `json:"timezone" gorm:"size:64;comment:时区"`                   // 如 Asia/Shanghai 为空时使用服务器时区
+}
+
+// IsEmpty 没有任何限制
+func (c CasbinCondition) IsEmpty() bool {
+	return len(c.Cidrs) == 0 && len(c.Weekdays) == 0 && c.StartTime == "" && c.EndTime == ""
+}
+
+func (SysCasbinCondition) TableName() string {
+	return "sys_casbin_conditions"
+}
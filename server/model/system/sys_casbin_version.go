@@ -0,0 +1,14 @@
+package system
+
+import "time"
+
+// SysCasbinVersion casbin策略版本 只有一行 未开启redis时各实例轮询版本号判断策略是否变化
+type SysCasbinVersion struct {
+	ID        uint      `gorm:"primarykey"`
+	Version   int64     `json:"version" gorm:"comment:策略版本"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+func (SysCasbinVersion) TableName() string {
+	return "sys_casbin_versions"
+}
@@ -0,0 +1,35 @@
+package system
+
+import (
+	"github.com/flipped-aurora/gin-vue-admin/server/global"
+)
+
+// 数据范围
+const (
+	DataScopeSelf      = "self"      // 本人创建的数据
+	DataScopeAuthority = "authority" // 本角色的数据
+	DataScopeSubtree   = "subtree"   // 本角色及下级角色的数据
+	DataScopeListed    = "listed"    // 资源权限中勾选的角色的数据 未配置时的默认值
+	DataScopeAll       = "all"       // 全部数据
+
+	DataScopeDefaultResource = "*" // 角色对未单独配置的资源使用的范围
+)
+
+// DataScoped 实现该接口的模型在带有当前用户的ctx下查询 更新 删除时自动按数据范围过滤
+// 见 utils.DataScopeContext
+type DataScoped interface {
+	// DataScopeColumns 记录创建人ID和创建人角色ID的列 为空时按该列过滤的范围视为无数据
+	DataScopeColumns() (userColumn, authorityColumn string)
+}
+
+// SysDataScope 角色对某一资源的数据范围
+type SysDataScope struct {
+	global.GVA_MODEL
+	AuthorityId uint   `json:"authorityId" gorm:"uniqueIndex:idx_data_scope;comment:角色ID"`
+	Resource    string `json:"resource" gorm:"uniqueIndex:idx_data_scope;size:64;comment:资源表名 *为默认"`
+	Scope       string `json:"scope" gorm:"size:16;comment:数据范围"`
+}
+
+func (SysDataScope) TableName() string {
+	return "sys_data_scopes"
+}
@@ -0,0 +1,26 @@
+package system
+
+import (
+	"github.com/flipped-aurora/gin-vue-admin/server/global"
+)
+
+// 字段权限 按限制程度从低到高排列
+const (
+	FieldRuleReadonly = "readonly" // 可见不可修改
+	FieldRuleMasked   = "masked"   // 脱敏显示 不可修改
+	FieldRuleHidden   = "hidden"   // 不返回 不可修改
+)
+
+// SysFieldRule 角色对某一资源字段的权限 未配置的字段不受限制
+type SysFieldRule struct {
+	global.GVA_MODEL
+	AuthorityId uint   `json:"authorityId" gorm:"uniqueIndex:idx_field_rule;comment:角色ID"`
+	Resource    string `json:"resource" gorm:"uniqueIndex:idx_field_rule;size:64;comment:资源表名"`
+	Field       string `json:"field" gorm:"uniqueIndex:idx_field_rule;size:64;comment:字段json名或列名"`
+	Action      string `json:"action" gorm:"size:16;comment:hidden|masked|readonly"`
+	Pattern     string `json:"pattern" gorm:"size:64;comment:脱敏规则"` // 如 3,4 保留前3位和后4位 为空时全部替换为* 其他内容直接替换
+}
+
+func (SysFieldRule) TableName() string {
+	return "sys_field_rules"
+}
@@ -1,10 +1,14 @@
 package system
 
 import (
+	"time"
+
 	"github.com/flipped-aurora/gin-vue-admin/server/global"
 )
 
+// JwtBlacklist 作废的访问令牌 只保存令牌jti(旧令牌无jti时保存摘要) 令牌过期后记录随之失效
 type JwtBlacklist struct {
 	global.GVA_MODEL
-	Jwt string `gorm:"type:text;comment:jwt"`
+	Jti       string    `gorm:"uniqueIndex;size:96;comment:令牌jti或摘要"`
+	ExpiresAt time.Time `gorm:"index;comment:令牌过期时间"`
 }
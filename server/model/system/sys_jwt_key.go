@@ -0,0 +1,22 @@
+package system
+
+import (
+	"time"
+
+	"github.com/flipped-aurora/gin-vue-admin/server/global"
+)
+
+// SysJwtKey jwt非对称签名密钥 多实例部署时通过数据库共享
+type SysJwtKey struct {
+	global.GVA_MODEL
+	Kid        string     `json:"kid" gorm:"uniqueIndex;size:64;comment:密钥ID"` // 密钥ID 写入jwt header
+	Alg        string     `json:"alg" gorm:"size:16;comment:签名算法"`             // RS256 ES256 EdDSA
+	PrivateKey string     `json:"-" gorm:"type:text;comment:加密后的私钥"`           // PKCS8 PEM 使用 jwt.signing-key 加密保存
+	PublicKey  string     `json:"publicKey" gorm:"type:text;comment:公钥"`       // PKIX PEM
+	RetiredAt  *time.Time `json:"retiredAt" gorm:"comment:停止签名时间"`             // 轮换后不再用于签名
+	ExpiresAt  *time.Time `json:"expiresAt" gorm:"index;comment:停止验签时间"`       // 宽限期结束后不再用于验签
+}
+
+func (SysJwtKey) TableName() string {
+	return "sys_jwt_keys"
+}
@@ -0,0 +1,34 @@
+package system
+
+import (
+	"time"
+
+	"github.com/flipped-aurora/gin-vue-admin/server/global"
+)
+
+// SysLoginAttempt 登录失败记录
+type SysLoginAttempt struct {
+	global.GVA_MODEL
+	Username  string `json:"username" gorm:"index;size:191;comment:登录用户名"` // 登录时提交的用户名
+	Ip        string `json:"ip" gorm:"size:64;comment:IP"`                 // 请求IP
+	UserAgent string `json:"userAgent" gorm:"type:text;comment:设备"`        // 登录设备
+	Reason    string `json:"reason" gorm:"comment:失败原因"`                   // 失败原因
+	Locked    bool   `json:"locked" gorm:"comment:是否触发锁定"`                 // 本次失败是否触发了账号锁定
+}
+
+func (SysLoginAttempt) TableName() string {
+	return "sys_login_attempts"
+}
+
+// SysLoginLockout 未开启redis时按用户名记录的连续失败次数和锁定状态
+type SysLoginLockout struct {
+	global.GVA_MODEL
+	Username     string     `json:"username" gorm:"uniqueIndex;size:191;comment:登录用户名"` // 登录用户名 小写
+	Failures     int        `json:"failures" gorm:"comment:连续失败次数"`                     // 连续失败次数
+	LastFailedAt time.Time  `json:"lastFailedAt" gorm:"comment:最近失败时间"`                 // 最近失败时间
+	LockedUntil  *time.Time `json:"lockedUntil" gorm:"comment:锁定截止时间"`                  // 锁定截止时间
+}
+
+func (SysLoginLockout) TableName() string {
+	return "sys_login_lockouts"
+}
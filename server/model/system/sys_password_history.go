@@ -0,0 +1,16 @@
+package system
+
+import (
+	"github.com/flipped-aurora/gin-vue-admin/server/global"
+)
+
+// SysPasswordHistory 用户使用过的密码哈希 用于禁止重复使用最近的密码
+type SysPasswordHistory struct {
+	global.GVA_MODEL
+	UserId       uint   `json:"userId" gorm:"index;comment:用户ID"` // 用户ID
+	PasswordHash string `json:"-" gorm:"comment:密码哈希"`            // bcrypt哈希
+}
+
+func (SysPasswordHistory) TableName() string {
+	return "sys_password_histories"
+}
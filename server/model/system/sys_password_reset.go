@@ -0,0 +1,22 @@
+package system
+
+import (
+	"time"
+
+	"github.com/flipped-aurora/gin-vue-admin/server/global"
+)
+
+// SysPasswordReset 忘记密码时发送的一次性重置令牌
+type SysPasswordReset struct {
+	global.GVA_MODEL
+	UserId    uint       `json:"userId" gorm:"index;comment:用户ID"`            // 用户ID
+	Email     string     `json:"email" gorm:"index;size:191;comment:接收邮箱"`    // 接收邮箱 用于按邮箱限制发送频率
+	TokenHash string     `json:"-" gorm:"uniqueIndex;size:64;comment:重置令牌哈希"` // 重置令牌sha256 不保存明文
+	Ip        string     `json:"ip" gorm:"size:64;comment:申请重置的IP"`           // 申请重置的IP
+	ExpiresAt time.Time  `json:"expiresAt" gorm:"index;comment:过期时间"`         // 过期时间
+	UsedAt    *time.Time `json:"usedAt" gorm:"comment:使用时间"`                  // 使用时间 使用后或用户已重置密码后失效
+}
+
+func (SysPasswordReset) TableName() string {
+	return "sys_password_resets"
+}
@@ -0,0 +1,22 @@
+package system
+
+import (
+	"time"
+
+	"github.com/flipped-aurora/gin-vue-admin/server/global"
+)
+
+// SysRefreshToken 刷新令牌 同一次登录签发的刷新令牌属于同一个令牌族(FamilyId)
+type SysRefreshToken struct {
+	global.GVA_MODEL
+	UserId    uint       `json:"userId" gorm:"index;comment:用户ID"`            // 用户ID
+	FamilyId  string     `json:"familyId" gorm:"index;size:64;comment:令牌族ID"` // 令牌族ID 每次登录生成 轮换时保持不变
+	TokenHash string     `json:"-" gorm:"uniqueIndex;size:64;comment:刷新令牌哈希"` // 刷新令牌sha256 不保存明文
+	ExpiresAt time.Time  `json:"expiresAt" gorm:"index;comment:过期时间"`         // 过期时间
+	UsedAt    *time.Time `json:"usedAt" gorm:"comment:轮换时间"`                  // 已被轮换的时间 再次使用视为重放
+	RevokedAt *time.Time `json:"revokedAt" gorm:"comment:作废时间"`               // 作废时间
+}
+
+func (SysRefreshToken) TableName() string {
+	return "sys_refresh_tokens"
+}
@@ -0,0 +1,35 @@
+package system
+
+import (
+	"time"
+
+	"github.com/flipped-aurora/gin-vue-admin/server/global"
+)
+
+// 自助注册申请状态
+const (
+	SignUpStatusUnverified = 1 // 待验证邮箱
+	SignUpStatusPending    = 2 // 待审核
+	SignUpStatusApproved   = 3 // 已通过
+	SignUpStatusRejected   = 4 // 已拒绝
+)
+
+// SysSignUp 自助注册申请 用户通过验证和审核前保持禁用状态
+type SysSignUp struct {
+	global.GVA_MODEL
+	UserId     uint       `json:"userId" gorm:"index;comment:用户ID"`                   // 用户ID 拒绝后用户被删除
+	Username   string     `json:"username" gorm:"index;size:191;comment:用户名"`         // 用户名
+	NickName   string     `json:"nickName" gorm:"comment:用户昵称"`                       // 用户昵称
+	Email      string     `json:"email" gorm:"index;size:191;comment:注册邮箱"`           // 注册邮箱
+	Ip         string     `json:"ip" gorm:"size:64;comment:注册IP"`                     // 注册IP
+	Status     int        `json:"status" gorm:"index;comment:状态 1待验证 2待审核 3已通过 4已拒绝"` // 状态 1待验证 2待审核 3已通过 4已拒绝
+	VerifiedAt *time.Time `json:"verifiedAt" gorm:"comment:邮箱验证时间"`                   // 邮箱验证时间
+	ReviewerId uint       `json:"reviewerId" gorm:"comment:审核人ID"`                    // 审核人ID
+	Reviewer   SysUser    `json:"reviewer" gorm:"foreignKey:ReviewerId"`              // 审核人
+	ReviewedAt *time.Time `json:"reviewedAt" gorm:"comment:审核时间"`                     // 审核时间
+	Remark     string     `json:"remark" gorm:"comment:审核意见"`                         // 审核意见 拒绝时通知用户
+}
+
+func (SysSignUp) TableName() string {
+	return "sys_sign_ups"
+}
@@ -0,0 +1,21 @@
+package system
+
+import (
+	"time"
+
+	"github.com/flipped-aurora/gin-vue-admin/server/global"
+)
+
+// SysUserIdentity 用户在外部身份源(OIDC等)中的身份 用于外部登录时找到对应的本地用户
+type SysUserIdentity struct {
+	global.GVA_MODEL
+	UserId      uint       `json:"userId" gorm:"index;comment:用户ID"`                                                 // 本地用户ID
+	Provider    string     `json:"provider" gorm:"uniqueIndex:idx_identity_provider_subject;size:64;comment:身份源"`    // 身份源标识
+	Subject     string     `json:"subject" gorm:"uniqueIndex:idx_identity_provider_subject;size:191;comment:外部用户标识"` // 身份源中的唯一标识 如OIDC的sub
+	Email       string     `json:"email" gorm:"comment:邮箱"`                                                          // 外部身份的邮箱
+	LastLoginAt *time.Time `json:"lastLoginAt" gorm:"comment:最近登录时间"`                                                // 最近一次通过该身份登录的时间
+}
+
+func (SysUserIdentity) TableName() string {
+	return "sys_user_identities"
+}
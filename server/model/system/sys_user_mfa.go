@@ -0,0 +1,32 @@
+package system
+
+import (
+	"time"
+
+	"github.com/flipped-aurora/gin-vue-admin/server/global"
+)
+
+// SysUserMfa 用户两步验证(TOTP)信息
+type SysUserMfa struct {
+	global.GVA_MODEL
+	UserId       uint   `json:"userId" gorm:"uniqueIndex;comment:用户ID"`     // 用户ID
+	Secret       string `json:"-" gorm:"comment:加密后的TOTP密钥"`                // 加密后的TOTP密钥
+	Enabled      bool   `json:"enabled" gorm:"default:false;comment:是否已启用"` // 绑定后需验证一次才会启用
+	LastUsedStep int64  `json:"-" gorm:"comment:最后一次使用的时间步"`                // 防止同一验证码在有效期内被重放
+}
+
+func (SysUserMfa) TableName() string {
+	return "sys_user_mfas"
+}
+
+// SysUserRecoveryCode 两步验证恢复码 每个只能使用一次
+type SysUserRecoveryCode struct {
+	global.GVA_MODEL
+	UserId   uint       `json:"userId" gorm:"index;comment:用户ID"` // 用户ID
+	CodeHash string     `json:"-" gorm:"size:64;comment:恢复码哈希"`   // 恢复码sha256
+	UsedAt   *time.Time `json:"usedAt" gorm:"comment:使用时间"`       // 使用时间
+}
+
+func (SysUserRecoveryCode) TableName() string {
+	return "sys_user_recovery_codes"
+}
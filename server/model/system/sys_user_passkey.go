@@ -0,0 +1,24 @@
+package system
+
+import (
+	"time"
+
+	"github.com/flipped-aurora/gin-vue-admin/server/global"
+)
+
+// SysUserPasskey 用户的通行密钥(WebAuthn凭证) 每个用户可以注册多个
+type SysUserPasskey struct {
+	global.GVA_MODEL
+	UserId       uint       `json:"userId" gorm:"index;comment:用户ID"`           // 用户ID
+	Name         string     `json:"name" gorm:"size:64;comment:名称"`             // 名称 便于用户区分不同设备
+	CredentialId string     `json:"-" gorm:"uniqueIndex;size:255;comment:凭证ID"` // 凭证ID base64url编码
+	PublicKey    []byte     `json:"-" gorm:"comment:COSE编码的公钥"`                 // COSE编码的公钥
+	SignCount    uint32     `json:"signCount" gorm:"comment:签名计数"`              // 签名计数 每次登录校验是否递增
+	Aaguid       string     `json:"aaguid" gorm:"size:36;comment:认证器型号"`        // 认证器型号
+	Transports   string     `json:"transports" gorm:"size:64;comment:传输方式"`     // 传输方式 逗号分隔 如 usb,nfc,internal
+	LastUsedAt   *time.Time `json:"lastUsedAt" gorm:"comment:最后使用时间"`           // 最后使用时间
+}
+
+func (SysUserPasskey) TableName() string {
+	return "sys_user_passkeys"
+}
@@ -0,0 +1,25 @@
+package system
+
+import (
+	"time"
+
+	"github.com/flipped-aurora/gin-vue-admin/server/global"
+)
+
+// SysUserSession 用户登录会话 一次登录对应一个会话 与刷新令牌族一一对应
+type SysUserSession struct {
+	global.GVA_MODEL
+	UserId     uint       `json:"userId" gorm:"index;comment:用户ID"`           // 用户ID
+	FamilyId   string     `json:"-" gorm:"uniqueIndex;size:64;comment:令牌族ID"` // 令牌族ID
+	Jti        string     `json:"jti" gorm:"size:64;comment:当前访问令牌ID"`        // 最近一次签发的访问令牌jti
+	UserAgent  string     `json:"userAgent" gorm:"type:text;comment:设备"`      // 登录设备
+	Ip         string     `json:"ip" gorm:"size:64;comment:IP"`               // 最近一次访问IP
+	LastSeenAt time.Time  `json:"lastSeenAt" gorm:"comment:最近活跃时间"`           // 最近活跃时间
+	ExpiresAt  time.Time  `json:"expiresAt" gorm:"index;comment:过期时间"`        // 会话过期时间 随刷新令牌轮换延长
+	RevokedAt  *time.Time `json:"revokedAt" gorm:"comment:下线时间"`              // 被注销或强制下线的时间
+	Current    bool       `json:"current" gorm:"-"`                           // 是否为当前请求所在会话
+}
+
+func (SysUserSession) TableName() string {
+	return "sys_user_sessions"
+}
@@ -5,6 +5,7 @@ import (
 
 	"github.com/flipped-aurora/gin-vue-admin/server/global"
 	"github.com/flipped-aurora/gin-vue-admin/server/model/system"
+	systemService "github.com/flipped-aurora/gin-vue-admin/server/service/system"
 )
 
 func RegisterApis(apis ...system.SysApi) {
@@ -48,3 +49,10 @@ func RegisterMenus(menus ...system.SysBaseMenu) {
 		fmt.Println(err)
 	}
 }
+
+// RegisterAuthenticators 注册插件提供的账号密码认证器 需要在 authenticator.chain 中配置名称后才会参与登录认证
+func RegisterAuthenticators(authenticators ...systemService.Authenticator) {
+	for i := range authenticators {
+		systemService.RegisterAuthenticator(authenticators[i])
+	}
+}
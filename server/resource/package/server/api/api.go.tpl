@@ -21,6 +21,11 @@ import (
 
 type {{.StructName}}Api struct {}
 
+{{- $ctx := "" }}
+{{- if .AutoCreateResource }}
+{{- $ctx = "utils.DataScopeContext(c), " }}
+{{- end }}
+
 {{if not .OnlyTemplate}}
 
 // Create{{.StructName}} 创建{{.Description}}
@@ -41,8 +46,9 @@ func ({{.Abbreviation}}Api *{{.StructName}}Api) Create{{.StructName}}(c *gin.Con
 	}
 	{{- if .AutoCreateResource }}
     {{.Abbreviation}}.CreatedBy = utils.GetUserID(c)
+    {{.Abbreviation}}.CreatedAuthorityId = utils.GetUserAuthorityId(c)
 	{{- end }}
-	err = {{.Abbreviation}}Service.Create{{.StructName}}(&{{.Abbreviation}})
+	err = {{.Abbreviation}}Service.Create{{.StructName}}({{$ctx}}&{{.Abbreviation}})
 	if err != nil {
         global.GVA_LOG.Error("创建失败!", zap.Error(err))
 		response.FailWithMessage("创建失败:" + err.Error(), c)
@@ -65,7 +71,7 @@ func ({{.Abbreviation}}Api *{{.StructName}}Api) Delete{{.StructName}}(c *gin.Con
 		{{- if .AutoCreateResource }}
     userID := utils.GetUserID(c)
         {{- end }}
-	err := {{.Abbreviation}}Service.Delete{{.StructName}}({{.PrimaryField.FieldJson}} {{- if .AutoCreateResource -}},userID{{- end -}})
+	err := {{.Abbreviation}}Service.Delete{{.StructName}}({{$ctx}}{{.PrimaryField.FieldJson}} {{- if .AutoCreateResource -}},userID{{- end -}})
 	if err != nil {
         global.GVA_LOG.Error("删除失败!", zap.Error(err))
 		response.FailWithMessage("删除失败:" + err.Error(), c)
@@ -87,7 +93,7 @@ func ({{.Abbreviation}}Api *{{.StructName}}Api) Delete{{.StructName}}ByIds(c *gi
     	{{- if .AutoCreateResource }}
     userID := utils.GetUserID(c)
         {{- end }}
-	err := {{.Abbreviation}}Service.Delete{{.StructName}}ByIds({{.PrimaryField.FieldJson}}s{{- if .AutoCreateResource }},userID{{- end }})
+	err := {{.Abbreviation}}Service.Delete{{.StructName}}ByIds({{$ctx}}{{.PrimaryField.FieldJson}}s{{- if .AutoCreateResource }},userID{{- end }})
 	if err != nil {
         global.GVA_LOG.Error("批量删除失败!", zap.Error(err))
 		response.FailWithMessage("批量删除失败:" + err.Error(), c)
@@ -115,7 +121,7 @@ func ({{.Abbreviation}}Api *{{.StructName}}Api) Update{{.StructName}}(c *gin.Con
 	    {{- if .AutoCreateResource }}
     {{.Abbreviation}}.UpdatedBy = utils.GetUserID(c)
         {{- end }}
-	err = {{.Abbreviation}}Service.Update{{.StructName}}({{.Abbreviation}})
+	err = {{.Abbreviation}}Service.Update{{.StructName}}({{$ctx}}{{.Abbreviation}})
 	if err != nil {
         global.GVA_LOG.Error("更新失败!", zap.Error(err))
 		response.FailWithMessage("更新失败:" + err.Error(), c)
@@ -135,7 +141,7 @@ func ({{.Abbreviation}}Api *{{.StructName}}Api) Update{{.StructName}}(c *gin.Con
 // @Router /{{.Abbreviation}}/find{{.StructName}} [get]
 func ({{.Abbreviation}}Api *{{.StructName}}Api) Find{{.StructName}}(c *gin.Context) {
 	{{.PrimaryField.FieldJson}} := c.Query("{{.PrimaryField.FieldJson}}")
-	re{{.Abbreviation}}, err := {{.Abbreviation}}Service.Get{{.StructName}}({{.PrimaryField.FieldJson}})
+	re{{.Abbreviation}}, err := {{.Abbreviation}}Service.Get{{.StructName}}({{$ctx}}{{.PrimaryField.FieldJson}})
 	if err != nil {
         global.GVA_LOG.Error("查询失败!", zap.Error(err))
 		response.FailWithMessage("查询失败:" + err.Error(), c)
@@ -154,7 +160,7 @@ func ({{.Abbreviation}}Api *{{.StructName}}Api) Find{{.StructName}}(c *gin.Conte
 // @Success 200 {object} response.Response{data=response.PageResult,msg=string} "获取成功"
 // @Router /{{.Abbreviation}}/get{{.StructName}}List [get]
 func ({{.Abbreviation}}Api *{{.StructName}}Api) Get{{.StructName}}List(c *gin.Context) {
-	list, err := {{.Abbreviation}}Service.Get{{.StructName}}InfoList()
+	list, err := {{.Abbreviation}}Service.Get{{.StructName}}InfoList({{- if .AutoCreateResource }}utils.DataScopeContext(c){{ end }})
 	if err != nil {
 	    global.GVA_LOG.Error("获取失败!", zap.Error(err))
         response.FailWithMessage("获取失败:" + err.Error(), c)
@@ -179,7 +185,7 @@ func ({{.Abbreviation}}Api *{{.StructName}}Api) Get{{.StructName}}List(c *gin.Co
 		response.FailWithMessage(err.Error(), c)
 		return
 	}
-	list, total, err := {{.Abbreviation}}Service.Get{{.StructName}}InfoList(pageInfo)
+	list, total, err := {{.Abbreviation}}Service.Get{{.StructName}}InfoList({{$ctx}}pageInfo)
 	if err != nil {
 	    global.GVA_LOG.Error("获取失败!", zap.Error(err))
         response.FailWithMessage("获取失败:" + err.Error(), c)
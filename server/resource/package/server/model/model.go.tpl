@@ -71,6 +71,7 @@ type {{.StructName}} struct {
     CreatedBy  uint   `gorm:"column:created_by;comment:创建者"`
     UpdatedBy  uint   `gorm:"column:updated_by;comment:更新者"`
     DeletedBy  uint   `gorm:"column:deleted_by;comment:删除者"`
+    CreatedAuthorityId  uint   `gorm:"column:created_authority_id;comment:创建者角色"`
     {{- end }}
     {{- if .IsTree }}
     Children   []*{{.StructName}} `json:"children" gorm:"-"`     //子节点
@@ -86,6 +87,13 @@ func ({{.StructName}}) TableName() string {
 }
 {{ end }}
 
+{{ if and .AutoCreateResource (not .OnlyTemplate) }}
+// DataScopeColumns 按创建者和创建者角色过滤数据范围 实现system.DataScoped接口
+func ({{.StructName}}) DataScopeColumns() (userColumn, authorityColumn string) {
+    return "created_by", "created_authority_id"
+}
+{{ end }}
+
 {{if .IsTree }}
 // GetChildren 实现TreeNode接口
 func (s *{{.StructName}}) GetChildren() []*{{.StructName}} {
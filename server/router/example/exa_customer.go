@@ -8,8 +8,8 @@ import (
 type CustomerRouter struct{}
 
 func (e *CustomerRouter) InitCustomerRouter(Router *gin.RouterGroup) {
-	customerRouter := Router.Group("customer").Use(middleware.OperationRecord())
-	customerRouterWithoutRecord := Router.Group("customer")
+	customerRouter := Router.Group("customer").Use(middleware.OperationRecord(), middleware.FieldRule("exa_customers"))
+	customerRouterWithoutRecord := Router.Group("customer").Use(middleware.FieldRule("exa_customers"))
 	{
 		customerRouter.POST("customer", exaCustomerApi.CreateExaCustomer)   // 创建客户
 		customerRouter.PUT("customer", exaCustomerApi.UpdateExaCustomer)    // 更新客户
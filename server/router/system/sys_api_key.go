@@ -0,0 +1,23 @@
+package system
+
+import (
+	"github.com/flipped-aurora/gin-vue-admin/server/middleware"
+	"github.com/gin-gonic/gin"
+)
+
+type ApiKeyRouter struct{}
+
+func (s *ApiKeyRouter) InitApiKeyRouter(Router *gin.RouterGroup) {
+	apiKeyRouter := Router.Group("apiKey").Use(middleware.OperationRecord())
+	apiKeyRouterWithoutRecord := Router.Group("apiKey")
+	apiKeyRouterNotImpersonating := Router.Group("apiKey").Use(middleware.OperationRecord(), middleware.NotImpersonating())
+	{
+		apiKeyRouter.DELETE("deleteApiKey", apiKeyApi.DeleteApiKey) // 吊销API密钥
+	}
+	{
+		apiKeyRouterNotImpersonating.POST("createApiKey", apiKeyApi.CreateApiKey) // 创建API密钥 代登录期间禁止
+	}
+	{
+		apiKeyRouterWithoutRecord.GET("getApiKeyList", apiKeyApi.GetApiKeyList) // 获取自身的API密钥列表
+	}
+}
@@ -20,4 +20,21 @@ func (s *AuthorityRouter) InitAuthorityRouter(Router *gin.RouterGroup) {
 	{
 		authorityRouterWithoutRecord.POST("getAuthorityList", authorityApi.GetAuthorityList) // 获取角色列表
 	}
+	{
+		authorityRouter.POST("importAuthorityBundle", authorityApi.ImportAuthorityBundle)             // 导入角色
+		authorityRouterWithoutRecord.GET("exportAuthorityBundle", authorityApi.ExportAuthorityBundle) // 导出角色
+	}
+	{
+		authorityRouter.POST("setDataScopes", authorityApi.SetDataScopes)             // 设置角色数据范围
+		authorityRouterWithoutRecord.GET("getDataScopes", authorityApi.GetDataScopes) // 获取角色数据范围
+	}
+	{
+		authorityRouter.POST("setFieldRules", authorityApi.SetFieldRules)             // 设置角色字段权限
+		authorityRouterWithoutRecord.GET("getFieldRules", authorityApi.GetFieldRules) // 获取角色字段权限
+	}
+	{
+		authorityRouter.POST("rollbackAuthorityVersion", authorityApi.RollbackAuthorityVersion)           // 恢复角色权限到历史版本
+		authorityRouterWithoutRecord.GET("getAuthorityVersionList", authorityApi.GetAuthorityVersionList) // 获取角色权限历史版本
+		authorityRouterWithoutRecord.GET("getAuthorityVersion", authorityApi.GetAuthorityVersion)         // 获取角色权限历史版本详情
+	}
 }
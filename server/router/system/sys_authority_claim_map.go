@@ -0,0 +1,20 @@
+package system
+
+import (
+	"github.com/flipped-aurora/gin-vue-admin/server/middleware"
+	"github.com/gin-gonic/gin"
+)
+
+type AuthorityClaimMapRouter struct{}
+
+func (s *AuthorityClaimMapRouter) InitAuthorityClaimMapRouter(Router *gin.RouterGroup) {
+	claimMapRouter := Router.Group("authorityClaimMap").Use(middleware.OperationRecord())
+	claimMapRouterWithoutRecord := Router.Group("authorityClaimMap")
+	{
+		claimMapRouter.POST("createClaimMap", authorityClaimMapApi.CreateClaimMap)   // 新增外部组映射
+		claimMapRouter.DELETE("deleteClaimMap", authorityClaimMapApi.DeleteClaimMap) // 删除外部组映射
+	}
+	{
+		claimMapRouterWithoutRecord.GET("getClaimMapList", authorityClaimMapApi.GetClaimMapList) // 获取外部组映射
+	}
+}
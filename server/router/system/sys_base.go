@@ -11,6 +11,21 @@ func (s *BaseRouter) InitBaseRouter(Router *gin.RouterGroup) (R gin.IRoutes) {
 	{
 		baseRouter.POST("login", baseApi.Login)
 		baseRouter.POST("captcha", baseApi.Captcha)
+		baseRouter.POST("refresh", baseApi.RefreshToken)
+		baseRouter.POST("mfaEnroll", baseApi.MfaEnroll)
+		baseRouter.POST("mfaLogin", baseApi.MfaLogin)
+		baseRouter.POST("mfaPasskeyBegin", baseApi.MfaPasskeyBegin)
+		baseRouter.POST("mfaPasskeyLogin", baseApi.MfaPasskeyLogin)
+		baseRouter.POST("passkeyLoginBegin", baseApi.PasskeyLoginBegin)
+		baseRouter.POST("passkeyLogin", baseApi.PasskeyLogin)
+		baseRouter.GET("oidcProviders", baseApi.GetOidcProviders)
+		baseRouter.GET("oidcAuthUrl", baseApi.GetOidcAuthUrl)
+		baseRouter.POST("oidcLogin", baseApi.OidcLogin)
+		baseRouter.POST("changeExpiredPassword", baseApi.ChangeExpiredPassword)
+		baseRouter.POST("forgotPassword", baseApi.ForgotPassword)
+		baseRouter.POST("resetPasswordByToken", baseApi.ResetPasswordByToken)
+		baseRouter.POST("signUp", signUpApi.SignUp)
+		baseRouter.POST("verifySignUp", signUpApi.VerifySignUp)
 	}
 	return baseRouter
 }
@@ -16,4 +16,8 @@ func (s *CasbinRouter) InitCasbinRouter(Router *gin.RouterGroup) {
 	{
 		casbinRouterWithoutRecord.POST("getPolicyPathByAuthorityId", casbinApi.GetPolicyPathByAuthorityId)
 	}
+	{
+		casbinRouterWithoutRecord.POST("explain", casbinApi.ExplainCasbin)   // 模拟访问单个接口
+		casbinRouterWithoutRecord.POST("simulate", casbinApi.SimulateCasbin) // 模拟访问全部路由
+	}
 }
@@ -12,3 +12,8 @@ func (s *JwtRouter) InitJwtRouter(Router *gin.RouterGroup) {
 		jwtRouter.POST("jsonInBlacklist", jwtApi.JsonInBlacklist) // jwt加入黑名单
 	}
 }
+
+// InitJwksRouter 公钥发布地址 遵循约定挂载在根路径 不做鉴权
+func (s *JwtRouter) InitJwksRouter(Router gin.IRoutes) {
+	Router.GET("/.well-known/jwks.json", jwtApi.Jwks)
+}
@@ -0,0 +1,19 @@
+package system
+
+import (
+	"github.com/flipped-aurora/gin-vue-admin/server/middleware"
+	"github.com/gin-gonic/gin"
+)
+
+type LoginAttemptRouter struct{}
+
+func (s *LoginAttemptRouter) InitLoginAttemptRouter(Router *gin.RouterGroup) {
+	loginAttemptRouter := Router.Group("loginAttempt").Use(middleware.OperationRecord())
+	loginAttemptRouterWithoutRecord := Router.Group("loginAttempt")
+	{
+		loginAttemptRouter.POST("unlockUser", loginAttemptApi.UnlockUser) // 解除账号锁定
+	}
+	{
+		loginAttemptRouterWithoutRecord.GET("getLoginAttemptList", loginAttemptApi.GetLoginAttemptList) // 分页获取登录失败记录
+	}
+}
@@ -0,0 +1,19 @@
+package system
+
+import (
+	"github.com/flipped-aurora/gin-vue-admin/server/middleware"
+	"github.com/gin-gonic/gin"
+)
+
+type SignUpRouter struct{}
+
+func (s *SignUpRouter) InitSignUpRouter(Router *gin.RouterGroup) {
+	signUpRouter := Router.Group("signUp").Use(middleware.OperationRecord())
+	signUpRouterWithoutRecord := Router.Group("signUp")
+	{
+		signUpRouter.POST("reviewSignUp", signUpApi.ReviewSignUp) // 审核注册申请
+	}
+	{
+		signUpRouterWithoutRecord.GET("getSignUpList", signUpApi.GetSignUpList) // 分页获取注册申请
+	}
+}
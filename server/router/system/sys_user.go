@@ -10,9 +10,11 @@ type UserRouter struct{}
 func (s *UserRouter) InitUserRouter(Router *gin.RouterGroup) {
 	userRouter := Router.Group("user").Use(middleware.OperationRecord())
 	userRouterWithoutRecord := Router.Group("user")
+	// 修改密码 两步验证和代登录等敏感操作在代登录期间禁止
+	userRouterNotImpersonating := Router.Group("user").Use(middleware.OperationRecord(), middleware.NotImpersonating())
+	userRouterNotImpersonatingWithoutRecord := Router.Group("user").Use(middleware.NotImpersonating())
 	{
 		userRouter.POST("admin_register", baseApi.Register)               // 管理员注册账号
-		userRouter.POST("changePassword", baseApi.ChangePassword)         // 用户修改密码
 		userRouter.POST("setUserAuthority", baseApi.SetUserAuthority)     // 设置用户权限
 		userRouter.DELETE("deleteUser", baseApi.DeleteUser)               // 删除用户
 		userRouter.PUT("setUserInfo", baseApi.SetUserInfo)                // 设置用户信息
@@ -20,9 +22,32 @@ func (s *UserRouter) InitUserRouter(Router *gin.RouterGroup) {
 		userRouter.POST("setUserAuthorities", baseApi.SetUserAuthorities) // 设置用户权限组
 		userRouter.POST("resetPassword", baseApi.ResetPassword)           // 设置用户权限组
 		userRouter.PUT("setSelfSetting", baseApi.SetSelfSetting)          // 用户界面配置
+		userRouter.POST("resetMfa", baseApi.ResetMfa)                     // 管理员重置两步验证
+		userRouter.POST("revokeSession", baseApi.RevokeSession)           // 下线自身会话
+		userRouter.POST("forceLogout", baseApi.ForceLogout)               // 管理员强制下线用户
 	}
 	{
-		userRouterWithoutRecord.POST("getUserList", baseApi.GetUserList) // 分页获取用户列表
-		userRouterWithoutRecord.GET("getUserInfo", baseApi.GetUserInfo)  // 获取自身信息
+		userRouterWithoutRecord.POST("getUserList", baseApi.GetUserList)      // 分页获取用户列表
+		userRouterWithoutRecord.GET("getUserInfo", baseApi.GetUserInfo)       // 获取自身信息
+		userRouterWithoutRecord.GET("getMfaStatus", baseApi.GetMfaStatus)     // 获取两步验证状态
+		userRouterWithoutRecord.GET("getSessionList", baseApi.GetSessionList) // 获取自身在线会话
+		userRouterWithoutRecord.GET("getPasskeyList", baseApi.GetPasskeyList) // 获取自身通行密钥
+	}
+	{
+		userRouterNotImpersonating.POST("changePassword", baseApi.ChangePassword) // 用户修改密码
+		userRouterNotImpersonating.POST("disableMfa", baseApi.DisableMfa)         // 关闭两步验证
+	}
+	{
+		userRouterNotImpersonatingWithoutRecord.POST("enrollMfa", baseApi.EnrollMfa)                             // 绑定两步验证 响应含密钥不记录操作日志
+		userRouterNotImpersonatingWithoutRecord.POST("activateMfa", baseApi.ActivateMfa)                         // 启用两步验证 响应含恢复码不记录操作日志
+		userRouterNotImpersonatingWithoutRecord.POST("regenerateRecoveryCodes", baseApi.RegenerateRecoveryCodes) // 重新生成恢复码
+		userRouterNotImpersonatingWithoutRecord.POST("impersonate", baseApi.Impersonate)                         // 管理员代登录 响应含令牌 由接口自行记录操作日志
+	}
+	{
+		// 通行密钥 注册参数含挑战不记录操作日志
+		userRouterNotImpersonatingWithoutRecord.POST("beginPasskeyRegistration", baseApi.BeginPasskeyRegistration) // 开始注册通行密钥
+		userRouterNotImpersonating.POST("finishPasskeyRegistration", baseApi.FinishPasskeyRegistration)            // 完成注册通行密钥
+		userRouterNotImpersonating.PUT("renamePasskey", baseApi.RenamePasskey)                                     // 修改通行密钥名称
+		userRouterNotImpersonating.DELETE("deletePasskey", baseApi.DeletePasskey)                                  // 删除通行密钥
 	}
 }
@@ -1,11 +1,12 @@
 package example
 
 import (
+	"context"
+
 	"github.com/flipped-aurora/gin-vue-admin/server/global"
 	"github.com/flipped-aurora/gin-vue-admin/server/model/common/request"
 	"github.com/flipped-aurora/gin-vue-admin/server/model/example"
-	"github.com/flipped-aurora/gin-vue-admin/server/model/system"
-	systemService "github.com/flipped-aurora/gin-vue-admin/server/service/system"
+	"gorm.io/gorm"
 )
 
 type CustomerService struct{}
@@ -26,62 +27,59 @@ func (exa *CustomerService) CreateExaCustomer(e example.ExaCustomer) (err error)
 //@author: [piexlmax](https://github.com/piexlmax)
 //@function: DeleteFileChunk
 //@description: 删除客户
-//@param: e model.ExaCustomer
+//@param: ctx context.Context, e model.ExaCustomer
 //@return: err error
 
-func (exa *CustomerService) DeleteExaCustomer(e example.ExaCustomer) (err error) {
-	err = global.GVA_DB.Delete(&e).Error
-	return err
+func (exa *CustomerService) DeleteExaCustomer(ctx context.Context, e example.ExaCustomer) (err error) {
+	db := global.GVA_DB.WithContext(ctx).Delete(&e)
+	if db.Error == nil && db.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return db.Error
 }
 
 //@author: [piexlmax](https://github.com/piexlmax)
 //@function: UpdateExaCustomer
 //@description: 更新客户
-//@param: e *model.ExaCustomer
+//@param: ctx context.Context, e *model.ExaCustomer
 //@return: err error
 
-func (exa *CustomerService) UpdateExaCustomer(e *example.ExaCustomer) (err error) {
-	err = global.GVA_DB.Save(e).Error
-	return err
+func (exa *CustomerService) UpdateExaCustomer(ctx context.Context, e *example.ExaCustomer) (err error) {
+	// 不使用 Save 超出数据范围时 Save 会转为插入
+	db := global.GVA_DB.WithContext(ctx).Select("*").Updates(e)
+	if db.Error == nil && db.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return db.Error
 }
 
 //@author: [piexlmax](https://github.com/piexlmax)
 //@function: GetExaCustomer
 //@description: 获取客户信息
-//@param: id uint
+//@param: ctx context.Context, id uint
 //@return: customer model.ExaCustomer, err error
 
-func (exa *CustomerService) GetExaCustomer(id uint) (customer example.ExaCustomer, err error) {
-	err = global.GVA_DB.Where("id = ?", id).First(&customer).Error
+func (exa *CustomerService) GetExaCustomer(ctx context.Context, id uint) (customer example.ExaCustomer, err error) {
+	err = global.GVA_DB.WithContext(ctx).Where("id = ?", id).First(&customer).Error
 	return
 }
 
 //@author: [piexlmax](https://github.com/piexlmax)
 //@function: GetCustomerInfoList
-//@description: 分页获取客户列表
-//@param: sysUserAuthorityID string, info request.PageInfo
+//@description: 分页获取客户列表 按ctx中用户的数据范围过滤
+//@param: ctx context.Context, info request.PageInfo
 //@return: list interface{}, total int64, err error
 
-func (exa *CustomerService) GetCustomerInfoList(sysUserAuthorityID uint, info request.PageInfo) (list interface{}, total int64, err error) {
+func (exa *CustomerService) GetCustomerInfoList(ctx context.Context, info request.PageInfo) (list interface{}, total int64, err error) {
 	limit := info.PageSize
 	offset := info.PageSize * (info.Page - 1)
-	db := global.GVA_DB.Model(&example.ExaCustomer{})
-	var a system.SysAuthority
-	a.AuthorityId = sysUserAuthorityID
-	auth, err := systemService.AuthorityServiceApp.GetAuthorityInfo(a)
-	if err != nil {
-		return
-	}
-	var dataId []uint
-	for _, v := range auth.DataAuthorityId {
-		dataId = append(dataId, v.AuthorityId)
-	}
+	db := global.GVA_DB.WithContext(ctx).Model(&example.ExaCustomer{})
 	var CustomerList []example.ExaCustomer
-	err = db.Where("sys_user_authority_id in ?", dataId).Count(&total).Error
+	err = db.Count(&total).Error
 	if err != nil {
 		return CustomerList, total, err
 	} else {
-		err = db.Limit(limit).Offset(offset).Preload("SysUser").Where("sys_user_authority_id in ?", dataId).Find(&CustomerList).Error
+		err = db.Limit(limit).Offset(offset).Preload("SysUser").Find(&CustomerList).Error
 	}
 	return CustomerList, total, err
 }
@@ -75,7 +75,7 @@ func (s *autoCodeHistory) RollBack(ctx context.Context, info request.SysAutoHist
 	}
 	if info.DeleteApi {
 		ids := info.ApiIds(history)
-		err = ApiServiceApp.DeleteApisByIds(ids)
+		err = ApiServiceApp.DeleteApisByIds(0, "", ids)
 		if err != nil {
 			global.GVA_LOG.Error("ClearTag DeleteApiByIds:", zap.Error(err))
 		}
@@ -17,6 +17,25 @@ type ServiceGroup struct {
 	AuthorityBtnService
 	SysExportTemplateService
 	SysParamsService
+	RefreshTokenService
+	MfaService
+	SessionService
+	JwtKeyService
+	OidcService
+	UserIdentityService
+	AuthorityClaimMapService
+	AuthenticatorService
+	ApiKeyService
+	PasswordPolicyService
+	LoginLockoutService
+	SecurityStampService
+	ImpersonateService
+	PasswordResetService
+	SignUpService
+	PasskeyService
+	DataScopeService
+	FieldRuleService
+	AuthorityVersionService
 	AutoCodePlugin   autoCodePlugin
 	AutoCodePackage  autoCodePackage
 	AutoCodeHistory  autoCodeHistory
@@ -2,83 +2,197 @@ package system
 
 import (
 	"context"
+	"errors"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
 
+	"github.com/redis/go-redis/v9"
 	"go.uber.org/zap"
+	"gorm.io/gorm/clause"
 
 	"github.com/flipped-aurora/gin-vue-admin/server/global"
 	"github.com/flipped-aurora/gin-vue-admin/server/model/system"
+	systemReq "github.com/flipped-aurora/gin-vue-admin/server/model/system/request"
 	"github.com/flipped-aurora/gin-vue-admin/server/utils"
 )
 
+const (
+	jwtBlacklistPrefix  = "jwt:black:"
+	jwtBlacklistChannel = "gva:jwt:blacklist"
+)
+
+// blacklistSynced 订阅黑名单频道正常时为true 此时本地缓存与其他实例一致 无需再查询redis
+var blacklistSynced atomic.Bool
+
 type JwtService struct{}
 
 var JwtServiceApp = new(JwtService)
 
+// TokenKey 黑名单中标识令牌的键 优先使用jti 旧令牌没有jti时使用令牌摘要
+func (jwtService *JwtService) TokenKey(token string, claims *systemReq.CustomClaims) string {
+	if claims != nil && claims.RegisteredClaims.ID != "" {
+		return "jti:" + claims.RegisteredClaims.ID
+	}
+	return "sha:" + utils.SHA256V(token)
+}
+
 //@author: [piexlmax](https://github.com/piexlmax)
 //@function: JsonInBlacklist
-//@description: 拉黑jwt
+//@description: 拉黑jwt 记录在令牌过期时自动失效 开启redis时通知其他实例
 //@param: jwtList model.JwtBlacklist
 //@return: err error
 
 func (jwtService *JwtService) JsonInBlacklist(jwtList system.JwtBlacklist) (err error) {
-	err = global.GVA_DB.Create(&jwtList).Error
+	if jwtList.Jti == "" {
+		return errors.New("令牌标识不能为空")
+	}
+	if !jwtList.ExpiresAt.After(time.Now()) {
+		return nil
+	}
+	err = global.GVA_DB.Clauses(clause.OnConflict{DoNothing: true}).Create(&jwtList).Error
 	if err != nil {
 		return
 	}
-	global.BlackCache.SetDefault(jwtList.Jwt, struct{}{})
-	return
+	return jwtService.addBlacklist(jwtList.Jti, jwtList.ExpiresAt)
 }
 
 //@author: [piexlmax](https://github.com/piexlmax)
 //@function: IsBlacklist
 //@description: 判断JWT是否在黑名单内部
-//@param: jwt string
+//@param: key string
 //@return: bool
 
-func (jwtService *JwtService) IsBlacklist(jwt string) bool {
-	_, ok := global.BlackCache.Get(jwt)
-	return ok
-	// err := global.GVA_DB.Where("jwt = ?", jwt).First(&system.JwtBlacklist{}).Error
-	// isNotFound := errors.Is(err, gorm.ErrRecordNotFound)
-	// return !isNotFound
+func (jwtService *JwtService) IsBlacklist(key string) bool {
+	if _, ok := global.BlackCache.Get(jwtBlacklistPrefix + key); ok {
+		return true
+	}
+	// 订阅断开期间可能错过其他实例的通知 直接查询redis
+	if useRedisBlacklist() && !blacklistSynced.Load() {
+		n, err := global.GVA_REDIS.Exists(context.Background(), jwtBlacklistPrefix+key).Result()
+		if err != nil {
+			global.GVA_LOG.Warn("查询jwt黑名单失败!", zap.Error(err))
+			return false
+		}
+		return n > 0
+	}
+	return false
 }
 
-//@author: [piexlmax](https://github.com/piexlmax)
-//@function: GetRedisJWT
-//@description: 从redis取jwt
-//@param: userName string
-//@return: redisJWT string, err error
-
-func (jwtService *JwtService) GetRedisJWT(userName string) (redisJWT string, err error) {
-	redisJWT, err = global.GVA_REDIS.Get(context.Background(), userName).Result()
-	return redisJWT, err
+// addBlacklist 写入本地缓存 开启redis时同时写入redis并广播给其他实例
+func (jwtService *JwtService) addBlacklist(key string, expiresAt time.Time) error {
+	ttl := time.Until(expiresAt)
+	if ttl <= 0 {
+		return nil
+	}
+	global.BlackCache.Set(jwtBlacklistPrefix+key, struct{}{}, ttl)
+	if !useRedisBlacklist() {
+		return nil
+	}
+	ctx := context.Background()
+	_, err := global.GVA_REDIS.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.Set(ctx, jwtBlacklistPrefix+key, 1, ttl)
+		pipe.Publish(ctx, jwtBlacklistChannel, key+"|"+strconv.FormatInt(expiresAt.UnixMilli(), 10))
+		return nil
+	})
+	return err
 }
 
-//@author: [piexlmax](https://github.com/piexlmax)
-//@function: SetRedisJWT
-//@description: jwt存入redis并设置过期时间
-//@param: jwt string, userName string
-//@return: err error
-
-func (jwtService *JwtService) SetRedisJWT(jwt string, userName string) (err error) {
-	// 此处过期时间等于jwt过期时间
-	dr, err := utils.ParseDuration(global.GVA_CONFIG.JWT.ExpiresTime)
-	if err != nil {
-		return err
+// SubscribeBlacklist 订阅其他实例拉黑的令牌 每次(重新)订阅成功后从数据库重新加载 补上断开期间错过的通知
+func (jwtService *JwtService) SubscribeBlacklist() {
+	ctx := context.Background()
+	pubsub := global.GVA_REDIS.Subscribe(ctx, jwtBlacklistChannel)
+	defer pubsub.Close()
+	for {
+		msg, err := pubsub.Receive(ctx)
+		if err != nil {
+			if blacklistSynced.Swap(false) {
+				global.GVA_LOG.Warn("jwt黑名单订阅断开, 改为直接查询redis", zap.Error(err))
+			}
+			time.Sleep(time.Second)
+			continue
+		}
+		switch m := msg.(type) {
+		case *redis.Subscription:
+			loadBlacklist()
+			blacklistSynced.Store(true)
+		case *redis.Message:
+			key, expires, ok := strings.Cut(m.Payload, "|")
+			ms, err := strconv.ParseInt(expires, 10, 64)
+			if !ok || err != nil {
+				continue
+			}
+			if ttl := time.Until(time.UnixMilli(ms)); ttl > 0 {
+				global.BlackCache.Set(jwtBlacklistPrefix+key, struct{}{}, ttl)
+			}
+		}
 	}
-	timer := dr
-	err = global.GVA_REDIS.Set(context.Background(), userName, jwt, timer).Err()
-	return err
 }
 
+func useRedisBlacklist() bool {
+	return global.GVA_CONFIG.System.UseRedis && global.GVA_REDIS != nil
+}
+
+// LoadAll 启动时迁移旧版本保存的完整令牌 并将未过期的黑名单加载到本地缓存
 func LoadAll() {
-	var data []string
-	err := global.GVA_DB.Model(&system.JwtBlacklist{}).Select("jwt").Find(&data).Error
+	migrateLegacyBlacklist()
+	loadBlacklist()
+}
+
+func loadBlacklist() {
+	var list []system.JwtBlacklist
+	err := global.GVA_DB.Select("jti", "expires_at").Where("expires_at > ?", time.Now()).Find(&list).Error
 	if err != nil {
 		global.GVA_LOG.Error("加载数据库jwt黑名单失败!", zap.Error(err))
 		return
 	}
-	for i := 0; i < len(data); i++ {
-		global.BlackCache.SetDefault(data[i], struct{}{})
-	} // jwt黑名单 加入 BlackCache 中
+	for i := range list {
+		global.BlackCache.Set(jwtBlacklistPrefix+list[i].Jti, struct{}{}, time.Until(list[i].ExpiresAt))
+	}
+
+	// 已作废的刷新令牌族 其访问令牌在有效期内仍需拦截
+	dr := accessTokenExpires()
+	var tokens []system.SysRefreshToken
+	err = global.GVA_DB.Select("family_id", "revoked_at").Where("revoked_at > ?", time.Now().Add(-dr)).Find(&tokens).Error
+	if err != nil {
+		global.GVA_LOG.Error("加载已作废的刷新令牌失败!", zap.Error(err))
+		return
+	}
+	for i := range tokens {
+		global.BlackCache.Set(jwtBlacklistPrefix+refreshFamilyCachePrefix+tokens[i].FamilyId, struct{}{}, time.Until(tokens[i].RevokedAt.Add(dr)))
+	}
+}
+
+// migrateLegacyBlacklist 旧版本在jwt列保存完整令牌 转换为jti和过期时间后删除该列
+func migrateLegacyBlacklist() {
+	migrator := global.GVA_DB.Migrator()
+	if !migrator.HasColumn(&system.JwtBlacklist{}, "jwt") {
+		return
+	}
+	var rows []struct {
+		ID  uint
+		Jwt string
+	}
+	err := global.GVA_DB.Model(&system.JwtBlacklist{}).Select("id", "jwt").Where("jti IS NULL OR jti = ''").Scan(&rows).Error
+	if err != nil {
+		global.GVA_LOG.Error("迁移jwt黑名单失败!", zap.Error(err))
+		return
+	}
+	j := utils.NewJWT()
+	for i := range rows {
+		claims, err := j.ParseTokenUnverified(rows[i].Jwt)
+		if err == nil && claims.ExpiresAt != nil && claims.ExpiresAt.After(time.Now()) {
+			err = global.GVA_DB.Model(&system.JwtBlacklist{}).Where("id = ?", rows[i].ID).
+				Updates(map[string]interface{}{"jti": JwtServiceApp.TokenKey(rows[i].Jwt, claims), "expires_at": claims.ExpiresAt.Time}).Error
+			if err == nil {
+				continue
+			}
+		}
+		// 已过期 无法解析或同一令牌重复拉黑的记录直接删除
+		global.GVA_DB.Unscoped().Delete(&system.JwtBlacklist{}, rows[i].ID)
+	}
+	if err = migrator.DropColumn(&system.JwtBlacklist{}, "jwt"); err != nil {
+		global.GVA_LOG.Error("删除jwt黑名单旧字段失败!", zap.Error(err))
+	}
 }
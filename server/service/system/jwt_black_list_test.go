@@ -0,0 +1,89 @@
+package system
+
+import (
+	"testing"
+	"time"
+
+	"github.com/flipped-aurora/gin-vue-admin/server/global"
+	"github.com/flipped-aurora/gin-vue-admin/server/model/system"
+	systemReq "github.com/flipped-aurora/gin-vue-admin/server/model/system/request"
+	"github.com/flipped-aurora/gin-vue-admin/server/utils"
+	jwt "github.com/golang-jwt/jwt/v4"
+	"github.com/songzhibin97/gkit/cache/local_cache"
+)
+
+func setupJwtBlacklistTest(t *testing.T) {
+	setupTestDB(t, &system.JwtBlacklist{}, &system.SysRefreshToken{}, &system.SysUserSession{})
+}
+
+func TestJwtService_TokenKey(t *testing.T) {
+	s := &JwtService{}
+	claims := &systemReq.CustomClaims{RegisteredClaims: jwt.RegisteredClaims{ID: "abc"}}
+	if got := s.TokenKey("token", claims); got != "jti:abc" {
+		t.Fatalf("TokenKey() = %s, want jti:abc", got)
+	}
+	if got := s.TokenKey("token", &systemReq.CustomClaims{}); got != "sha:"+utils.SHA256V("token") {
+		t.Fatalf("TokenKey() without jti = %s", got)
+	}
+}
+
+func TestJwtService_Blacklist(t *testing.T) {
+	setupJwtBlacklistTest(t)
+	s := &JwtService{}
+	if err := s.JsonInBlacklist(system.JwtBlacklist{Jti: "jti:a", ExpiresAt: time.Now().Add(time.Hour)}); err != nil {
+		t.Fatalf("JsonInBlacklist() error = %v", err)
+	}
+	// 重复拉黑不报错
+	if err := s.JsonInBlacklist(system.JwtBlacklist{Jti: "jti:a", ExpiresAt: time.Now().Add(time.Hour)}); err != nil {
+		t.Fatalf("JsonInBlacklist() again error = %v", err)
+	}
+	// 已过期的令牌无需记录
+	if err := s.JsonInBlacklist(system.JwtBlacklist{Jti: "jti:b", ExpiresAt: time.Now().Add(-time.Minute)}); err != nil {
+		t.Fatalf("JsonInBlacklist() expired error = %v", err)
+	}
+	if !s.IsBlacklist("jti:a") || s.IsBlacklist("jti:b") {
+		t.Fatalf("IsBlacklist() = %v, %v, want true, false", s.IsBlacklist("jti:a"), s.IsBlacklist("jti:b"))
+	}
+	var count int64
+	global.GVA_DB.Model(&system.JwtBlacklist{}).Count(&count)
+	if count != 1 {
+		t.Fatalf("blacklist rows = %d, want 1", count)
+	}
+
+	// 重启后从数据库恢复 作废的令牌族一并恢复
+	now := time.Now()
+	global.GVA_DB.Create(&system.JwtBlacklist{Jti: "jti:c", ExpiresAt: now.Add(-time.Second)})
+	global.GVA_DB.Create(&system.SysRefreshToken{FamilyId: "f1", TokenHash: "h1", ExpiresAt: now.Add(time.Hour), RevokedAt: &now})
+	global.BlackCache = local_cache.NewCache()
+	LoadAll()
+	if !s.IsBlacklist("jti:a") || s.IsBlacklist("jti:c") {
+		t.Fatalf("IsBlacklist() after reload = %v, %v, want true, false", s.IsBlacklist("jti:a"), s.IsBlacklist("jti:c"))
+	}
+	if !RefreshTokenServiceApp.IsFamilyRevoked("f1") || RefreshTokenServiceApp.IsFamilyRevoked("f2") {
+		t.Fatalf("IsFamilyRevoked() after reload unexpected")
+	}
+}
+
+func TestMigrateLegacyBlacklist(t *testing.T) {
+	setupJwtBlacklistTest(t)
+	global.GVA_DB.Exec("ALTER TABLE jwt_blacklists ADD COLUMN jwt text")
+	sign := func(jti string, exp time.Time) string {
+		claims := systemReq.CustomClaims{RegisteredClaims: jwt.RegisteredClaims{ID: jti, ExpiresAt: jwt.NewNumericDate(exp)}}
+		token, _ := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte("key"))
+		return token
+	}
+	valid := sign("a", time.Now().Add(time.Hour))
+	for _, token := range []string{valid, valid, sign("b", time.Now().Add(-time.Hour)), "not-a-token"} {
+		global.GVA_DB.Exec("INSERT INTO jwt_blacklists (jwt, created_at) VALUES (?, ?)", token, time.Now())
+	}
+
+	LoadAll()
+	var list []system.JwtBlacklist
+	global.GVA_DB.Find(&list)
+	if len(list) != 1 || list[0].Jti != "jti:a" {
+		t.Fatalf("migrated blacklist = %+v, want only jti:a", list)
+	}
+	if !JwtServiceApp.IsBlacklist("jti:a") {
+		t.Fatalf("IsBlacklist() migrated token = false, want true")
+	}
+}
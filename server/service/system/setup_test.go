@@ -0,0 +1,63 @@
+package system
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/flipped-aurora/gin-vue-admin/server/global"
+	"github.com/glebarez/sqlite"
+	"github.com/songzhibin97/gkit/cache/local_cache"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+var testDBSeq atomic.Int64
+
+// setupTestDB 为测试创建独立的内存数据库并迁移传入的表 替换 global.GVA_DB
+// 使用命名的共享缓存 连接池中的各连接看到同一个数据库 测试结束后关闭数据库并恢复全局的数据库和配置
+// casbin 执行器绑定创建时的数据库 前后都重置 使用时按当前测试的数据库重新创建
+func setupTestDB(t *testing.T, models ...interface{}) *gorm.DB {
+	t.Helper()
+	db, cfg := global.GVA_DB, global.GVA_CONFIG
+	resetCasbinEnforcer()
+	t.Cleanup(func() {
+		resetCasbinEnforcer()
+		global.GVA_DB, global.GVA_CONFIG = db, cfg
+	})
+	testDB := openTestDB(t, models...)
+	global.GVA_DB = testDB
+	// 被测代码会启动写日志的后台协程 日志只在为空时设置一次 不随测试替换
+	if global.GVA_LOG == nil {
+		global.GVA_LOG = zap.NewNop()
+	}
+	global.BlackCache = local_cache.NewCache()
+	global.GVA_CONFIG.System.UseRedis = false
+	return testDB
+}
+
+// openTestDB 创建独立的内存数据库并迁移传入的表 不替换全局的数据库 测试结束后关闭
+func openTestDB(t *testing.T, models ...interface{}) *gorm.DB {
+	t.Helper()
+	name := strings.NewReplacer("/", "_", " ", "_").Replace(t.Name())
+	dsn := fmt.Sprintf("file:%s_%d?mode=memory&cache=shared", name, testDBSeq.Add(1))
+	db, err := gorm.Open(sqlite.Open(dsn), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("open sqlite failed: %v", err)
+	}
+	t.Cleanup(func() {
+		if sqlDB, err := db.DB(); err == nil {
+			_ = sqlDB.Close()
+		}
+	})
+	if err = db.AutoMigrate(models...); err != nil {
+		t.Fatalf("migrate failed: %v", err)
+	}
+	return db
+}
+
+func resetCasbinEnforcer() {
+	syncedCachedEnforcer, once = nil, sync.Once{}
+}
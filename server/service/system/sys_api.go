@@ -3,11 +3,14 @@ package system
 import (
 	"errors"
 	"fmt"
+	"strconv"
 	"strings"
 
+	gormadapter "github.com/casbin/gorm-adapter/v3"
 	"github.com/flipped-aurora/gin-vue-admin/server/global"
 	"github.com/flipped-aurora/gin-vue-admin/server/model/common/request"
 	"github.com/flipped-aurora/gin-vue-admin/server/model/system"
+	systemReq "github.com/flipped-aurora/gin-vue-admin/server/model/system/request"
 	systemRes "github.com/flipped-aurora/gin-vue-admin/server/model/system/response"
 	"gorm.io/gorm"
 )
@@ -133,7 +136,18 @@ func (apiService *ApiService) IgnoreApi(ignoreApi system.SysIgnoreApi) (err erro
 	return global.GVA_DB.Unscoped().Delete(&ignoreApi, "path = ? AND method = ?", ignoreApi.Path, ignoreApi.Method).Error
 }
 
-func (apiService *ApiService) EnterSyncApi(syncApis systemRes.SysSyncApis) (err error) {
+// EnterSyncApi 确认同步api 删除的接口同时清除角色的接口权限 并保存受影响角色的权限版本
+func (apiService *ApiService) EnterSyncApi(operatorId uint, operatorName string, syncApis systemRes.SysSyncApis) (err error) {
+	authorityIds, err := apiService.authorityIdsByApis(syncApis.DeleteApis)
+	if err != nil {
+		return err
+	}
+	return AuthorityVersionServiceApp.TrackAll(authorityIds, operatorId, operatorName, systemReq.AuthorityVersionDeleteApi, func() error {
+		return apiService.enterSyncApi(syncApis)
+	})
+}
+
+func (apiService *ApiService) enterSyncApi(syncApis systemRes.SysSyncApis) (err error) {
 	return global.GVA_DB.Transaction(func(tx *gorm.DB) error {
 		var txErr error
 		if len(syncApis.NewApis) > 0 {
@@ -155,11 +169,21 @@ func (apiService *ApiService) EnterSyncApi(syncApis systemRes.SysSyncApis) (err
 
 //@author: [piexlmax](https://github.com/piexlmax)
 //@function: DeleteApi
-//@description: 删除基础api
-//@param: api model.SysApi
+//@description: 删除基础api 并保存受影响角色的权限版本
+//@param: operatorId uint, operatorName string, api model.SysApi
 //@return: err error
 
-func (apiService *ApiService) DeleteApi(api system.SysApi) (err error) {
+func (apiService *ApiService) DeleteApi(operatorId uint, operatorName string, api system.SysApi) (err error) {
+	authorityIds, err := apiService.authorityIdsByApiIds([]int{int(api.ID)})
+	if err != nil {
+		return err
+	}
+	return AuthorityVersionServiceApp.TrackAll(authorityIds, operatorId, operatorName, systemReq.AuthorityVersionDeleteApi, func() error {
+		return apiService.deleteApi(api)
+	})
+}
+
+func (apiService *ApiService) deleteApi(api system.SysApi) (err error) {
 	var entity system.SysApi
 	err = global.GVA_DB.First(&entity, "id = ?", api.ID).Error // 根据id查询api记录
 	if errors.Is(err, gorm.ErrRecordNotFound) {                // api记录不存在
@@ -256,6 +280,39 @@ func (apiService *ApiService) GetAllApis(authorityID uint) (apis []system.SysApi
 	return authApis, err
 }
 
+// authorityIdsByApiIds 拥有指定接口权限的角色 修改或删除接口前查询 用于保存角色权限版本
+func (apiService *ApiService) authorityIdsByApiIds(ids []int) (authorityIds []uint, err error) {
+	var apis []system.SysApi
+	if err = global.GVA_DB.Find(&apis, "id in ?", ids).Error; err != nil {
+		return nil, err
+	}
+	return apiService.authorityIdsByApis(apis)
+}
+
+// authorityIdsByApis 按路径和方法查询拥有接口权限的角色
+func (apiService *ApiService) authorityIdsByApis(apis []system.SysApi) (authorityIds []uint, err error) {
+	if len(apis) == 0 {
+		return nil, nil
+	}
+	db := global.GVA_DB.Model(&gormadapter.CasbinRule{}).Where("ptype = ?", "p")
+	match := global.GVA_DB
+	for _, api := range apis {
+		match = match.Or("v1 = ? AND v2 = ?", api.Path, api.Method)
+	}
+	var subjects []string
+	if err = db.Where(match).Distinct().Order("v0").Pluck("v0", &subjects).Error; err != nil {
+		return nil, err
+	}
+	for _, subject := range subjects {
+		id, err := strconv.ParseUint(subject, 10, 64)
+		if err != nil {
+			continue
+		}
+		authorityIds = append(authorityIds, uint(id))
+	}
+	return authorityIds, nil
+}
+
 //@author: [piexlmax](https://github.com/piexlmax)
 //@function: GetApiById
 //@description: 根据id获取api
@@ -269,11 +326,21 @@ func (apiService *ApiService) GetApiById(id int) (api system.SysApi, err error)
 
 //@author: [piexlmax](https://github.com/piexlmax)
 //@function: UpdateApi
-//@description: 根据id更新api
-//@param: api model.SysApi
+//@description: 根据id更新api 并保存受影响角色的权限版本
+//@param: operatorId uint, operatorName string, api model.SysApi
 //@return: err error
 
-func (apiService *ApiService) UpdateApi(api system.SysApi) (err error) {
+func (apiService *ApiService) UpdateApi(operatorId uint, operatorName string, api system.SysApi) (err error) {
+	authorityIds, err := apiService.authorityIdsByApiIds([]int{int(api.ID)})
+	if err != nil {
+		return err
+	}
+	return AuthorityVersionServiceApp.TrackAll(authorityIds, operatorId, operatorName, systemReq.AuthorityVersionUpdateApi, func() error {
+		return apiService.updateApi(api)
+	})
+}
+
+func (apiService *ApiService) updateApi(api system.SysApi) (err error) {
 	var oldA system.SysApi
 	err = global.GVA_DB.First(&oldA, "id = ?", api.ID).Error
 	if oldA.Path != api.Path || oldA.Method != api.Method {
@@ -303,11 +370,21 @@ func (apiService *ApiService) UpdateApi(api system.SysApi) (err error) {
 
 //@author: [piexlmax](https://github.com/piexlmax)
 //@function: DeleteApisByIds
-//@description: 删除选中API
-//@param: apis []model.SysApi
+//@description: 删除选中API 并保存受影响角色的权限版本
+//@param: operatorId uint, operatorName string, ids request.IdsReq
 //@return: err error
 
-func (apiService *ApiService) DeleteApisByIds(ids request.IdsReq) (err error) {
+func (apiService *ApiService) DeleteApisByIds(operatorId uint, operatorName string, ids request.IdsReq) (err error) {
+	authorityIds, err := apiService.authorityIdsByApiIds(ids.Ids)
+	if err != nil {
+		return err
+	}
+	return AuthorityVersionServiceApp.TrackAll(authorityIds, operatorId, operatorName, systemReq.AuthorityVersionDeleteApi, func() error {
+		return apiService.deleteApisByIds(ids)
+	})
+}
+
+func (apiService *ApiService) deleteApisByIds(ids request.IdsReq) (err error) {
 	return global.GVA_DB.Transaction(func(tx *gorm.DB) error {
 		var apis []system.SysApi
 		err = tx.Find(&apis, "id in ?", ids.Ids).Error
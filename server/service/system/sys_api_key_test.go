@@ -0,0 +1,113 @@
+package system
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/flipped-aurora/gin-vue-admin/server/global"
+	"github.com/flipped-aurora/gin-vue-admin/server/model/system"
+	systemReq "github.com/flipped-aurora/gin-vue-admin/server/model/system/request"
+	"github.com/flipped-aurora/gin-vue-admin/server/utils"
+)
+
+func TestApiKeyService_Authenticate(t *testing.T) {
+	db := setupTestDB(t, &system.SysUser{}, &system.SysApiKey{})
+	var err error
+	db.Create(&system.SysUser{GVA_MODEL: global.GVA_MODEL{ID: 1}, Username: "ci", AuthorityId: 888, Enable: 1})
+	db.Create(&system.SysUser{GVA_MODEL: global.GVA_MODEL{ID: 2}, Username: "disabled", AuthorityId: 888, Enable: 2})
+
+	s := &ApiKeyService{}
+	past := time.Now().Add(-time.Hour)
+	if _, _, err = s.CreateApiKey(1, 888, systemReq.CreateApiKey{Name: "expired", ExpiresAt: &past}); err == nil {
+		t.Fatalf("CreateApiKey() with past expiry should fail")
+	}
+	if _, _, err = s.CreateApiKey(1, 888, systemReq.CreateApiKey{Name: "bad-ip", AllowedIps: []string{"not-an-ip"}}); err == nil {
+		t.Fatalf("CreateApiKey() with invalid ip should fail")
+	}
+	key, apiKey, err := s.CreateApiKey(1, 888, systemReq.CreateApiKey{Name: "ci", AllowedIps: []string{"10.0.0.0/8", "192.168.1.10"}})
+	if err != nil {
+		t.Fatalf("CreateApiKey() error = %v", err)
+	}
+	if !strings.HasPrefix(key, utils.ApiKeyPrefix) || apiKey.KeyHash == key || !strings.HasPrefix(key, apiKey.Prefix) {
+		t.Fatalf("CreateApiKey() got key %s record %+v", key, apiKey)
+	}
+
+	tests := []struct {
+		name    string
+		key     string
+		ip      string
+		wantErr bool
+	}{
+		{name: "CIDR白名单", key: key, ip: "10.1.2.3"},
+		{name: "IP白名单", key: key, ip: "192.168.1.10"},
+		{name: "不在白名单", key: key, ip: "192.168.1.11", wantErr: true},
+		{name: "密钥错误", key: key + "x", ip: "10.1.2.3", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gotKey, user, err := s.Authenticate(tt.key, tt.ip)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Authenticate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err == nil && (gotKey.ID != apiKey.ID || user.ID != 1) {
+				t.Fatalf("Authenticate() got key %d user %d", gotKey.ID, user.ID)
+			}
+		})
+	}
+	var used system.SysApiKey
+	db.First(&used, apiKey.ID)
+	if used.LastUsedAt == nil || used.LastUsedIp != "10.1.2.3" {
+		t.Fatalf("last used not recorded: %+v", used)
+	}
+
+	// 过期的密钥和被禁用用户的密钥不可用
+	disabledKey, _, _ := s.CreateApiKey(2, 888, systemReq.CreateApiKey{Name: "disabled"})
+	if _, _, err = s.Authenticate(disabledKey, "127.0.0.1"); !errors.Is(err, ErrApiKeyInvalid) {
+		t.Fatalf("Authenticate() disabled user error = %v", err)
+	}
+	future := time.Now().Add(time.Hour)
+	expiringKey, expiring, _ := s.CreateApiKey(1, 888, systemReq.CreateApiKey{Name: "expiring", ExpiresAt: &future})
+	db.Model(&expiring).Update("expires_at", past)
+	if _, _, err = s.Authenticate(expiringKey, "127.0.0.1"); !errors.Is(err, ErrApiKeyInvalid) {
+		t.Fatalf("Authenticate() expired key error = %v", err)
+	}
+
+	// 只能吊销自己的密钥
+	if err = s.DeleteApiKey(2, apiKey.ID); err == nil {
+		t.Fatalf("DeleteApiKey() of other user should fail")
+	}
+	if err = s.DeleteApiKey(1, apiKey.ID); err != nil {
+		t.Fatalf("DeleteApiKey() error = %v", err)
+	}
+	if _, _, err = s.Authenticate(key, "10.1.2.3"); !errors.Is(err, ErrApiKeyInvalid) {
+		t.Fatalf("Authenticate() revoked key error = %v", err)
+	}
+}
+
+func TestApiKeyService_AllowScope(t *testing.T) {
+	apiKey := &system.SysApiKey{Scopes: []system.ApiKeyScope{
+		{Path: "/user/getUserList", Method: "POST"},
+		{Path: "/sysDictionary/*", Method: "GET"},
+	}}
+	tests := []struct {
+		path   string
+		method string
+		want   bool
+	}{
+		{path: "/user/getUserList", method: "POST", want: true},
+		{path: "/user/getUserList", method: "GET", want: false},
+		{path: "/sysDictionary/findSysDictionary", method: "GET", want: true},
+		{path: "/user/deleteUser", method: "DELETE", want: false},
+	}
+	s := &ApiKeyService{}
+	for _, tt := range tests {
+		if got := s.AllowScope(apiKey, tt.path, tt.method); got != tt.want {
+			t.Errorf("AllowScope(%s %s) = %v, want %v", tt.method, tt.path, got, tt.want)
+		}
+	}
+	if !s.AllowScope(&system.SysApiKey{}, "/user/deleteUser", "DELETE") {
+		t.Errorf("AllowScope() without scopes should allow")
+	}
+}
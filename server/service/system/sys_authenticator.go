@@ -0,0 +1,111 @@
+package system
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	"github.com/flipped-aurora/gin-vue-admin/server/global"
+	"github.com/flipped-aurora/gin-vue-admin/server/model/system"
+	"go.uber.org/zap"
+)
+
+const LocalAuthenticatorName = "local"
+
+var (
+	// ErrInvalidCredentials 认证器不认识该用户或密码错误 认证链继续尝试下一个认证器
+	ErrInvalidCredentials = errors.New("用户名不存在或者密码错误")
+	// ErrAuthenticatorUnavailable 认证源无法访问 具体原因记录在日志中
+	ErrAuthenticatorUnavailable = errors.New("认证服务暂不可用, 请稍后再试")
+)
+
+// Authenticator 账号密码认证器 认证成功返回对应的本地用户
+// 无法识别用户或密码错误时应返回 ErrInvalidCredentials 以便认证链继续尝试
+type Authenticator interface {
+	Name() string
+	Authenticate(ctx context.Context, username string, password string) (*system.SysUser, error)
+}
+
+var authenticators sync.Map
+
+// RegisterAuthenticator 注册自定义认证器 插件在初始化时调用 需要在 authenticator.chain 中配置名称后才会启用
+func RegisterAuthenticator(authenticator Authenticator) {
+	authenticators.Store(authenticator.Name(), authenticator)
+}
+
+type AuthenticatorService struct{}
+
+// IsInvalidCredentials 是否为用户名或密码错误 只有这类失败计入登录锁定
+func (authenticatorService *AuthenticatorService) IsInvalidCredentials(err error) bool {
+	return errors.Is(err, ErrInvalidCredentials)
+}
+
+var AuthenticatorServiceApp = new(AuthenticatorService)
+
+// Authenticate 按 authenticator.chain 的顺序依次认证 第一个成功的认证器决定登录用户
+// 认证源不可用或拒绝开通等错误不会中断认证链 全部失败时优先返回这类错误
+func (authenticatorService *AuthenticatorService) Authenticate(ctx context.Context, username string, password string) (*system.SysUser, error) {
+	if global.GVA_DB == nil {
+		return nil, errors.New("db not init")
+	}
+	var firstErr error
+	for _, authenticator := range authenticatorService.Chain() {
+		user, err := authenticator.Authenticate(ctx, username, password)
+		if err == nil {
+			return user, nil
+		}
+		if !errors.Is(err, ErrInvalidCredentials) {
+			global.GVA_LOG.Warn("认证器认证失败", zap.String("authenticator", authenticator.Name()), zap.String("username", username), zap.Error(err))
+			if firstErr == nil {
+				firstErr = err
+			}
+		}
+	}
+	if firstErr != nil {
+		return nil, firstErr
+	}
+	return nil, ErrInvalidCredentials
+}
+
+// Chain 获取当前配置的认证链 未配置时只使用本地用户认证
+func (authenticatorService *AuthenticatorService) Chain() []Authenticator {
+	names := global.GVA_CONFIG.Authenticator.Chain
+	if len(names) == 0 {
+		names = []string{LocalAuthenticatorName}
+	}
+	chain := make([]Authenticator, 0, len(names))
+	for _, name := range names {
+		if authenticator := authenticatorService.lookup(name); authenticator != nil {
+			chain = append(chain, authenticator)
+			continue
+		}
+		global.GVA_LOG.Warn("认证器不存在, 已跳过", zap.String("authenticator", name))
+	}
+	return chain
+}
+
+func (authenticatorService *AuthenticatorService) lookup(name string) Authenticator {
+	if name == LocalAuthenticatorName {
+		return localAuthenticator{}
+	}
+	for _, cfg := range global.GVA_CONFIG.Authenticator.Ldap {
+		if cfg.Name == name {
+			return &ldapAuthenticator{config: cfg}
+		}
+	}
+	if v, ok := authenticators.Load(name); ok {
+		return v.(Authenticator)
+	}
+	return nil
+}
+
+// localAuthenticator 使用 sys_users 中的密码认证
+type localAuthenticator struct{}
+
+func (localAuthenticator) Name() string {
+	return LocalAuthenticatorName
+}
+
+func (localAuthenticator) Authenticate(_ context.Context, username string, password string) (*system.SysUser, error) {
+	return UserServiceApp.Login(&system.SysUser{Username: username, Password: password})
+}
@@ -0,0 +1,164 @@
+package system
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/flipped-aurora/gin-vue-admin/server/config"
+	"github.com/flipped-aurora/gin-vue-admin/server/global"
+	"github.com/flipped-aurora/gin-vue-admin/server/model/system"
+	systemReq "github.com/flipped-aurora/gin-vue-admin/server/model/system/request"
+	"github.com/flipped-aurora/gin-vue-admin/server/utils"
+	"github.com/go-ldap/ldap/v3"
+	"go.uber.org/zap"
+)
+
+// ldapAuthenticator 通过 LDAP/Active Directory 绑定校验密码
+// 先用服务账号查询用户条目 再以用户DN和密码绑定 成功后按外部身份登录或开通本地用户
+type ldapAuthenticator struct {
+	config config.Ldap
+}
+
+func (a *ldapAuthenticator) Name() string {
+	return a.config.Name
+}
+
+func (a *ldapAuthenticator) Authenticate(_ context.Context, username string, password string) (*system.SysUser, error) {
+	// 空密码会被多数服务端视为匿名绑定而成功 必须在本地拒绝
+	if username == "" || password == "" {
+		return nil, ErrInvalidCredentials
+	}
+	conn, err := a.dial()
+	if err != nil {
+		return nil, a.unavailable(err)
+	}
+	defer conn.Close()
+
+	if a.config.BindDn != "" {
+		if err = conn.Bind(a.config.BindDn, a.config.BindPassword); err != nil {
+			return nil, a.unavailable(err)
+		}
+	}
+	entry, err := a.search(conn, username)
+	if err != nil {
+		return nil, err
+	}
+	if err = conn.Bind(entry.DN, password); err != nil {
+		if ldap.IsErrorWithCode(err, ldap.LDAPResultInvalidCredentials) {
+			return nil, ErrInvalidCredentials
+		}
+		return nil, a.unavailable(err)
+	}
+
+	ext := ExternalUser{
+		Provider: a.config.Name,
+		Subject:  entry.DN,
+		Username: entry.GetAttributeValue(a.attr(a.config.UsernameAttr, "uid")),
+		NickName: entry.GetAttributeValue(a.attr(a.config.NickNameAttr, "cn")),
+		Email:    entry.GetAttributeValue(a.attr(a.config.EmailAttr, "mail")),
+		Groups:   ldapGroups(entry.GetAttributeValues(a.attr(a.config.GroupAttr, "memberOf"))),
+	}
+	if a.config.SubjectAttr != "" {
+		ext.Subject = entry.GetAttributeValue(a.config.SubjectAttr)
+	}
+	if ext.Username == "" {
+		ext.Username = username
+	}
+	return UserIdentityServiceApp.LoginExternalUser(ext, systemReq.ProvisionOptions{
+		AutoCreate:         a.config.AutoCreate,
+		DefaultAuthorityId: a.config.DefaultAuthorityId,
+	})
+}
+
+func (a *ldapAuthenticator) dial() (*ldap.Conn, error) {
+	timeout := a.timeout()
+	tlsConfig := &tls.Config{InsecureSkipVerify: a.config.InsecureSkipVerify}
+	conn, err := ldap.DialURL(a.config.Url,
+		ldap.DialWithDialer(&net.Dialer{Timeout: timeout}),
+		ldap.DialWithTLSConfig(tlsConfig),
+	)
+	if err != nil {
+		return nil, err
+	}
+	conn.SetTimeout(timeout)
+	if a.config.StartTLS {
+		if err = conn.StartTLS(tlsConfig); err != nil {
+			conn.Close()
+			return nil, err
+		}
+	}
+	return conn, nil
+}
+
+// search 按登录名查询唯一的用户条目 查询不到或匹配到多个时视为认证失败
+func (a *ldapAuthenticator) search(conn *ldap.Conn, username string) (*ldap.Entry, error) {
+	filter := a.config.UserFilter
+	if filter == "" {
+		filter = "(uid=%s)"
+	}
+	attributes := []string{
+		a.attr(a.config.UsernameAttr, "uid"),
+		a.attr(a.config.NickNameAttr, "cn"),
+		a.attr(a.config.EmailAttr, "mail"),
+		a.attr(a.config.GroupAttr, "memberOf"),
+	}
+	if a.config.SubjectAttr != "" {
+		attributes = append(attributes, a.config.SubjectAttr)
+	}
+	result, err := conn.Search(ldap.NewSearchRequest(
+		a.config.BaseDn, ldap.ScopeWholeSubtree, ldap.NeverDerefAliases, 2, int(a.timeout()/time.Second), false,
+		strings.ReplaceAll(filter, "%s", ldap.EscapeFilter(username)), attributes, nil,
+	))
+	if err != nil {
+		if ldap.IsErrorWithCode(err, ldap.LDAPResultNoSuchObject) {
+			return nil, ErrInvalidCredentials
+		}
+		return nil, a.unavailable(err)
+	}
+	if len(result.Entries) != 1 {
+		if len(result.Entries) > 1 {
+			global.GVA_LOG.Warn("ldap用户查询匹配到多个条目", zap.String("ldap", a.config.Name), zap.String("username", username))
+		}
+		return nil, ErrInvalidCredentials
+	}
+	return result.Entries[0], nil
+}
+
+func (a *ldapAuthenticator) unavailable(err error) error {
+	global.GVA_LOG.Error("ldap认证源访问失败!", zap.String("ldap", a.config.Name), zap.Error(err))
+	return fmt.Errorf("%s: %w", a.config.Name, ErrAuthenticatorUnavailable)
+}
+
+func (a *ldapAuthenticator) timeout() time.Duration {
+	if dr, err := utils.ParseDuration(a.config.Timeout); err == nil && dr > 0 {
+		return dr
+	}
+	return 10 * time.Second
+}
+
+func (a *ldapAuthenticator) attr(name string, def string) string {
+	if name != "" {
+		return name
+	}
+	return def
+}
+
+// ldapGroups 组DN同时提供完整DN和首个RDN的值 角色映射中可以填写 cn=admins,ou=groups,dc=example,dc=com 或 admins
+func ldapGroups(values []string) []string {
+	groups := make([]string, 0, len(values)*2)
+	for _, v := range values {
+		groups = append(groups, v)
+		dn, err := ldap.ParseDN(v)
+		if err != nil || len(dn.RDNs) == 0 || len(dn.RDNs[0].Attributes) == 0 {
+			continue
+		}
+		if name := dn.RDNs[0].Attributes[0].Value; name != v {
+			groups = append(groups, name)
+		}
+	}
+	return groups
+}
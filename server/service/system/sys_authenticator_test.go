@@ -0,0 +1,231 @@
+package system
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+
+	"github.com/flipped-aurora/gin-vue-admin/server/config"
+	"github.com/flipped-aurora/gin-vue-admin/server/global"
+	"github.com/flipped-aurora/gin-vue-admin/server/model/system"
+	"github.com/flipped-aurora/gin-vue-admin/server/utils"
+	ber "github.com/go-asn1-ber/asn1-ber"
+	"github.com/go-ldap/ldap/v3"
+)
+
+// mockLdapEntry 模拟目录中的用户条目
+type mockLdapEntry struct {
+	dn       string
+	password string
+	attrs    map[string][]string
+}
+
+// mockLdap 进程内的最小LDAP服务 仅支持简单绑定和按过滤条件精确匹配的查询
+type mockLdap struct {
+	listener net.Listener
+	bindDn   string
+	bindPass string
+	entries  map[string]mockLdapEntry // 过滤条件 -> 条目
+}
+
+func newMockLdap(t *testing.T) *mockLdap {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen failed: %v", err)
+	}
+	m := &mockLdap{listener: l, entries: map[string]mockLdapEntry{}}
+	t.Cleanup(func() { _ = l.Close() })
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go m.serve(conn)
+		}
+	}()
+	return m
+}
+
+func (m *mockLdap) url() string {
+	return "ldap://" + m.listener.Addr().String()
+}
+
+func (m *mockLdap) serve(conn net.Conn) {
+	defer conn.Close()
+	for {
+		packet, err := ber.ReadPacket(conn)
+		if err != nil || len(packet.Children) < 2 {
+			return
+		}
+		id := packet.Children[0].Value.(int64)
+		op := packet.Children[1]
+		switch op.Tag {
+		case ldap.ApplicationBindRequest:
+			dn, pass := op.Children[1].Data.String(), op.Children[2].Data.String()
+			code := uint16(ldap.LDAPResultInvalidCredentials)
+			if dn == m.bindDn && pass == m.bindPass {
+				code = ldap.LDAPResultSuccess
+			}
+			for _, entry := range m.entries {
+				if entry.dn == dn && entry.password == pass {
+					code = ldap.LDAPResultSuccess
+				}
+			}
+			m.write(conn, id, m.result(ldap.ApplicationBindResponse, code))
+		case ldap.ApplicationSearchRequest:
+			filter, _ := ldap.DecompileFilter(op.Children[6])
+			if entry, ok := m.entries[filter]; ok {
+				m.write(conn, id, m.entry(entry))
+			}
+			m.write(conn, id, m.result(ldap.ApplicationSearchResultDone, ldap.LDAPResultSuccess))
+		default:
+			return
+		}
+	}
+}
+
+func (m *mockLdap) write(conn net.Conn, id int64, op *ber.Packet) {
+	packet := ber.Encode(ber.ClassUniversal, ber.TypeConstructed, ber.TagSequence, nil, "")
+	packet.AppendChild(ber.NewInteger(ber.ClassUniversal, ber.TypePrimitive, ber.TagInteger, id, ""))
+	packet.AppendChild(op)
+	_, _ = conn.Write(packet.Bytes())
+}
+
+func (m *mockLdap) result(tag ber.Tag, code uint16) *ber.Packet {
+	op := ber.Encode(ber.ClassApplication, ber.TypeConstructed, tag, nil, "")
+	op.AppendChild(ber.NewInteger(ber.ClassUniversal, ber.TypePrimitive, ber.TagEnumerated, int64(code), ""))
+	op.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, "", ""))
+	op.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, "", ""))
+	return op
+}
+
+func (m *mockLdap) entry(entry mockLdapEntry) *ber.Packet {
+	op := ber.Encode(ber.ClassApplication, ber.TypeConstructed, ldap.ApplicationSearchResultEntry, nil, "")
+	op.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, entry.dn, ""))
+	attrs := ber.Encode(ber.ClassUniversal, ber.TypeConstructed, ber.TagSequence, nil, "")
+	for name, values := range entry.attrs {
+		attr := ber.Encode(ber.ClassUniversal, ber.TypeConstructed, ber.TagSequence, nil, "")
+		attr.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, name, ""))
+		set := ber.Encode(ber.ClassUniversal, ber.TypeConstructed, ber.TagSet, nil, "")
+		for _, v := range values {
+			set.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, v, ""))
+		}
+		attr.AppendChild(set)
+		attrs.AppendChild(attr)
+	}
+	op.AppendChild(attrs)
+	return op
+}
+
+// staticAuthenticator 模拟插件注册的认证器
+type staticAuthenticator struct {
+	name     string
+	password string
+	user     *system.SysUser
+}
+
+func (a *staticAuthenticator) Name() string {
+	return a.name
+}
+
+func (a *staticAuthenticator) Authenticate(_ context.Context, _ string, password string) (*system.SysUser, error) {
+	if password != a.password {
+		return nil, ErrInvalidCredentials
+	}
+	return a.user, nil
+}
+
+func TestAuthenticatorService_Authenticate(t *testing.T) {
+	db := setupTestDB(t, &system.SysUser{}, &system.SysAuthority{}, &system.SysUserIdentity{}, &system.SysAuthorityClaimMap{})
+	var err error
+	db.Create(&system.SysAuthority{AuthorityId: 888, AuthorityName: "admin"})
+	db.Create(&system.SysAuthority{AuthorityId: 9528, AuthorityName: "staff"})
+	db.Create(&system.SysAuthorityClaimMap{Provider: "corp", ClaimValue: "admins", AuthorityId: 888})
+	db.Create(&system.SysUser{Username: "admin", Password: utils.BcryptHash("local-pass"), AuthorityId: 888, Enable: 1})
+
+	directory := newMockLdap(t)
+	directory.bindDn, directory.bindPass = "cn=readonly,dc=example,dc=com", "readonly"
+	directory.entries["(uid=alice)"] = mockLdapEntry{
+		dn:       "uid=alice,ou=people,dc=example,dc=com",
+		password: "ldap-pass",
+		attrs: map[string][]string{
+			"uid":      {"alice"},
+			"cn":       {"Alice"},
+			"mail":     {"alice@example.com"},
+			"memberOf": {"cn=admins,ou=groups,dc=example,dc=com"},
+		},
+	}
+	directory.entries["(uid=admin)"] = mockLdapEntry{dn: "uid=admin,ou=people,dc=example,dc=com", password: "ldap-admin"}
+	global.GVA_CONFIG.Authenticator = config.Authenticator{
+		Chain: []string{"local", "corp", "static"},
+		Ldap: []config.Ldap{{
+			Name:               "corp",
+			Url:                directory.url(),
+			BindDn:             directory.bindDn,
+			BindPassword:       directory.bindPass,
+			BaseDn:             "ou=people,dc=example,dc=com",
+			AutoCreate:         true,
+			DefaultAuthorityId: 9528,
+		}},
+	}
+	RegisterAuthenticator(&staticAuthenticator{name: "static", password: "static-pass", user: &system.SysUser{Username: "static"}})
+
+	s := &AuthenticatorService{}
+	ctx := context.Background()
+	tests := []struct {
+		name      string
+		username  string
+		password  string
+		wantUser  string
+		wantErr   error
+		authority uint
+	}{
+		{name: "本地用户", username: "admin", password: "local-pass", wantUser: "admin", authority: 888},
+		{name: "ldap首次登录自动开通并映射角色", username: "alice", password: "ldap-pass", wantUser: "alice", authority: 888},
+		{name: "ldap再次登录", username: "alice", password: "ldap-pass", wantUser: "alice", authority: 888},
+		{name: "ldap密码错误", username: "alice", password: "wrong", wantErr: ErrInvalidCredentials},
+		{name: "空密码不允许匿名绑定", username: "alice", password: "", wantErr: ErrInvalidCredentials},
+		{name: "用户不存在", username: "nobody", password: "ldap-pass", wantErr: ErrInvalidCredentials},
+		{name: "插件认证器", username: "anyone", password: "static-pass", wantUser: "static"},
+		// ldap中的同名用户不会接管本地用户
+		{name: "同名用户冲突", username: "admin", password: "ldap-admin"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			user, err := s.Authenticate(ctx, tt.username, tt.password)
+			if tt.wantUser == "" {
+				if err == nil {
+					t.Fatalf("Authenticate() got user %s, want error", user.Username)
+				}
+				if tt.wantErr != nil && !errors.Is(err, tt.wantErr) {
+					t.Fatalf("Authenticate() error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Authenticate() error = %v", err)
+			}
+			if user.Username != tt.wantUser || (tt.authority != 0 && user.AuthorityId != tt.authority) {
+				t.Fatalf("Authenticate() got user %s authority %d", user.Username, user.AuthorityId)
+			}
+		})
+	}
+
+	var count int64
+	db.Model(&system.SysUser{}).Where("username = ?", "alice").Count(&count)
+	if count != 1 {
+		t.Fatalf("ldap user provisioned %d times, want 1", count)
+	}
+
+	// ldap不可用时不影响后续认证器
+	global.GVA_CONFIG.Authenticator.Ldap[0].Url = "ldap://127.0.0.1:1"
+	global.GVA_CONFIG.Authenticator.Chain = []string{"corp", "local"}
+	if _, err = s.Authenticate(ctx, "admin", "local-pass"); err != nil {
+		t.Fatalf("Authenticate() with ldap down error = %v", err)
+	}
+	if _, err = s.Authenticate(ctx, "alice", "ldap-pass"); !errors.Is(err, ErrAuthenticatorUnavailable) {
+		t.Fatalf("Authenticate() with ldap down error = %v, want %v", err, ErrAuthenticatorUnavailable)
+	}
+}
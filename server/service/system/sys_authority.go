@@ -17,19 +17,30 @@ var ErrRoleExistence = errors.New("存在相同角色id")
 
 //@author: [piexlmax](https://github.com/piexlmax)
 //@function: CreateAuthority
-//@description: 创建一个角色
-//@param: auth model.SysAuthority
+//@description: 创建一个角色 并保存角色权限的版本
+//@param: operatorId uint, operatorName string, auth model.SysAuthority
 //@return: authority system.SysAuthority, err error
 
 type AuthorityService struct{}
 
 var AuthorityServiceApp = new(AuthorityService)
 
-func (authorityService *AuthorityService) CreateAuthority(auth system.SysAuthority) (authority system.SysAuthority, err error) {
+func (authorityService *AuthorityService) CreateAuthority(operatorId uint, operatorName string, auth system.SysAuthority) (authority system.SysAuthority, err error) {
+	err = AuthorityVersionServiceApp.Track(auth.AuthorityId, operatorId, operatorName, systemReq.AuthorityVersionCreateAuthority, func() error {
+		authority, err = authorityService.createAuthority(auth)
+		return err
+	})
+	return authority, err
+}
 
+func (authorityService *AuthorityService) createAuthority(auth system.SysAuthority) (authority system.SysAuthority, err error) {
 	if err = global.GVA_DB.Where("authority_id = ?", auth.AuthorityId).First(&system.SysAuthority{}).Error; !errors.Is(err, gorm.ErrRecordNotFound) {
 		return auth, ErrRoleExistence
 	}
+	// 先校验继承关系 角色 接口权限和继承规则在同一事务中写入 提交后刷新casbin
+	if err = CasbinServiceApp.checkAuthorityInherit(auth.AuthorityId, authorityParentId(auth), auth.InheritParent); err != nil {
+		return auth, err
+	}
 
 	e := global.GVA_DB.Transaction(func(tx *gorm.DB) error {
 
@@ -45,21 +56,34 @@ func (authorityService *AuthorityService) CreateAuthority(auth system.SysAuthori
 		authorityId := strconv.Itoa(int(auth.AuthorityId))
 		rules := [][]string{}
 		for _, v := range casbinInfos {
-			rules = append(rules, []string{authorityId, v.Path, v.Method})
+			rules = append(rules, []string{authorityId, v.Path, v.Method, v.GetEffect()})
 		}
-		return CasbinServiceApp.AddPolicies(tx, rules)
+		if err = CasbinServiceApp.AddPolicies(tx, rules); err != nil {
+			return err
+		}
+		return CasbinServiceApp.SyncAuthorityInherit(tx, auth.AuthorityId, authorityParentId(auth), auth.InheritParent)
 	})
-
-	return auth, e
+	if e != nil {
+		return auth, e
+	}
+	return auth, CasbinServiceApp.FreshCasbin()
 }
 
 //@author: [piexlmax](https://github.com/piexlmax)
 //@function: CopyAuthority
-//@description: 复制一个角色
-//@param: copyInfo response.SysAuthorityCopyResponse
+//@description: 复制一个角色 并保存角色权限的版本
+//@param: adminAuthorityID, operatorId uint, operatorName string, copyInfo response.SysAuthorityCopyResponse
 //@return: authority system.SysAuthority, err error
 
-func (authorityService *AuthorityService) CopyAuthority(adminAuthorityID uint, copyInfo response.SysAuthorityCopyResponse) (authority system.SysAuthority, err error) {
+func (authorityService *AuthorityService) CopyAuthority(adminAuthorityID, operatorId uint, operatorName string, copyInfo response.SysAuthorityCopyResponse) (authority system.SysAuthority, err error) {
+	err = AuthorityVersionServiceApp.Track(copyInfo.Authority.AuthorityId, operatorId, operatorName, systemReq.AuthorityVersionCopyAuthority, func() error {
+		authority, err = authorityService.copyAuthority(adminAuthorityID, copyInfo)
+		return err
+	})
+	return authority, err
+}
+
+func (authorityService *AuthorityService) copyAuthority(adminAuthorityID uint, copyInfo response.SysAuthorityCopyResponse) (authority system.SysAuthority, err error) {
 	var authorityBox system.SysAuthority
 	if !errors.Is(global.GVA_DB.Where("authority_id = ?", copyInfo.Authority.AuthorityId).First(&authorityBox).Error, gorm.ErrRecordNotFound) {
 		return authority, ErrRoleExistence
@@ -76,50 +100,131 @@ func (authorityService *AuthorityService) CopyAuthority(adminAuthorityID uint, c
 		baseMenu = append(baseMenu, v.SysBaseMenu)
 	}
 	copyInfo.Authority.SysBaseMenus = baseMenu
-	err = global.GVA_DB.Create(&copyInfo.Authority).Error
-	if err != nil {
+
+	// 先校验父角色 继承关系和接口权限 全部数据在同一事务中写入 提交后刷新casbin
+	authorityId, parentId := copyInfo.Authority.AuthorityId, authorityParentId(copyInfo.Authority)
+	// 开启严格角色模式时 新角色须是操作人的下级角色 与 CheckAuthorityIDAuth 对新角色的校验一致
+	if global.GVA_CONFIG.System.UseStrictAuth && parentId != adminAuthorityID {
+		return authority, errors.New("您提交的角色ID不合法")
+	}
+	if err = CasbinServiceApp.checkAuthorityInherit(authorityId, parentId, copyInfo.Authority.InheritParent); err != nil {
 		return
 	}
-
-	var btns []system.SysAuthorityBtn
-
-	err = global.GVA_DB.Find(&btns, "authority_id = ?", copyInfo.OldAuthorityId).Error
+	paths, err := CasbinServiceApp.checkCasbinInfos(adminAuthorityID, CasbinServiceApp.GetPolicyPathByAuthorityId(copyInfo.OldAuthorityId))
 	if err != nil {
 		return
 	}
-	if len(btns) > 0 {
-		for i := range btns {
-			btns[i].AuthorityId = copyInfo.Authority.AuthorityId
+	err = global.GVA_DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&copyInfo.Authority).Error; err != nil {
+			return err
 		}
-		err = global.GVA_DB.Create(&btns).Error
-
+		var btns []system.SysAuthorityBtn
+		if err := tx.Find(&btns, "authority_id = ?", copyInfo.OldAuthorityId).Error; err != nil {
+			return err
+		}
+		if len(btns) > 0 {
+			for i := range btns {
+				btns[i].AuthorityId = authorityId
+			}
+			if err := tx.Create(&btns).Error; err != nil {
+				return err
+			}
+		}
+		var scopes []system.SysDataScope
+		if err := tx.Find(&scopes, "authority_id = ?", copyInfo.OldAuthorityId).Error; err != nil {
+			return err
+		}
+		if len(scopes) > 0 {
+			for i := range scopes {
+				scopes[i].ID, scopes[i].AuthorityId = 0, authorityId
+			}
+			if err := tx.Create(&scopes).Error; err != nil {
+				return err
+			}
+		}
+		var fieldRules []system.SysFieldRule
+		if err := tx.Find(&fieldRules, "authority_id = ?", copyInfo.OldAuthorityId).Error; err != nil {
+			return err
+		}
+		if len(fieldRules) > 0 {
+			for i := range fieldRules {
+				fieldRules[i].ID, fieldRules[i].AuthorityId = 0, authorityId
+			}
+			if err := tx.Create(&fieldRules).Error; err != nil {
+				return err
+			}
+		}
+		rules, err := CasbinServiceApp.casbinRules(tx, authorityId, paths)
 		if err != nil {
-			return
+			return err
 		}
-	}
-	paths := CasbinServiceApp.GetPolicyPathByAuthorityId(copyInfo.OldAuthorityId)
-	err = CasbinServiceApp.UpdateCasbin(adminAuthorityID, copyInfo.Authority.AuthorityId, paths)
+		if err = CasbinServiceApp.SyncPolicy(tx, strconv.Itoa(int(authorityId)), rules); err != nil {
+			return err
+		}
+		return CasbinServiceApp.SyncAuthorityInherit(tx, authorityId, parentId, copyInfo.Authority.InheritParent)
+	})
 	if err != nil {
-		_ = authorityService.DeleteAuthority(&copyInfo.Authority)
+		return
 	}
+	err = CasbinServiceApp.FreshCasbin()
 	return copyInfo.Authority, err
 }
 
 //@author: [piexlmax](https://github.com/piexlmax)
 //@function: UpdateAuthority
-//@description: 更改一个角色
-//@param: auth model.SysAuthority
+//@description: 更改一个角色 并保存角色权限的版本
+//@param: operatorId uint, operatorName string, auth model.SysAuthority
 //@return: authority system.SysAuthority, err error
 
-func (authorityService *AuthorityService) UpdateAuthority(auth system.SysAuthority) (authority system.SysAuthority, err error) {
+func (authorityService *AuthorityService) UpdateAuthority(operatorId uint, operatorName string, auth system.SysAuthority) (authority system.SysAuthority, err error) {
+	err = AuthorityVersionServiceApp.Track(auth.AuthorityId, operatorId, operatorName, systemReq.AuthorityVersionUpdateAuthority, func() error {
+		authority, err = authorityService.updateAuthority(auth)
+		return err
+	})
+	return authority, err
+}
+
+func (authorityService *AuthorityService) updateAuthority(auth system.SysAuthority) (authority system.SysAuthority, err error) {
 	var oldAuthority system.SysAuthority
 	err = global.GVA_DB.Where("authority_id = ?", auth.AuthorityId).First(&oldAuthority).Error
 	if err != nil {
 		global.GVA_LOG.Debug(err.Error())
 		return system.SysAuthority{}, errors.New("查询角色数据失败")
 	}
-	err = global.GVA_DB.Model(&oldAuthority).Updates(&auth).Error
-	return auth, err
+	parentId := authorityParentId(oldAuthority)
+	if auth.ParentId != nil {
+		parentId = *auth.ParentId
+	}
+	// 先校验继承关系 存在循环继承时不修改角色 角色和继承规则在同一事务中写入 提交后刷新casbin
+	if err = CasbinServiceApp.checkAuthorityInherit(auth.AuthorityId, parentId, auth.InheritParent); err != nil {
+		return auth, err
+	}
+	err = global.GVA_DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&oldAuthority).Updates(&auth).Error; err != nil {
+			return err
+		}
+		// Updates 会忽略零值 开关类字段单独更新
+		err := tx.Model(&oldAuthority).Updates(map[string]interface{}{
+			"require_mfa":    auth.RequireMfa,
+			"max_sessions":   auth.MaxSessions,
+			"inherit_parent": auth.InheritParent,
+		}).Error
+		if err != nil {
+			return err
+		}
+		return CasbinServiceApp.SyncAuthorityInherit(tx, auth.AuthorityId, parentId, auth.InheritParent)
+	})
+	if err != nil {
+		return auth, err
+	}
+	return auth, CasbinServiceApp.FreshCasbin()
+}
+
+func authorityParentId(auth system.SysAuthority) uint {
+	if auth.ParentId == nil {
+		return 0
+	}
+	return *auth.ParentId
 }
 
 //@author: [piexlmax](https://github.com/piexlmax)
@@ -166,9 +271,22 @@ func (authorityService *AuthorityService) DeleteAuthority(auth *system.SysAuthor
 		if err = tx.Where("authority_id = ?", auth.AuthorityId).Delete(&[]system.SysAuthorityBtn{}).Error; err != nil {
 			return err
 		}
+		if err = tx.Unscoped().Where("authority_id = ?", auth.AuthorityId).Delete(&[]system.SysAuthorityClaimMap{}).Error; err != nil {
+			return err
+		}
+		if err = tx.Unscoped().Where("authority_id = ?", auth.AuthorityId).Delete(&[]system.SysDataScope{}).Error; err != nil {
+			return err
+		}
+		if err = tx.Unscoped().Where("authority_id = ?", auth.AuthorityId).Delete(&[]system.SysFieldRule{}).Error; err != nil {
+			return err
+		}
+		if err = tx.Unscoped().Where("authority_id = ?", auth.AuthorityId).Delete(&[]system.SysAuthorityVersion{}).Error; err != nil {
+			return err
+		}
 
 		authorityId := strconv.Itoa(int(auth.AuthorityId))
 
+		// 同时删除接口权限和继承父角色的 g 规则 存在子角色时不允许删除 不会有其他角色继承该角色
 		if err = CasbinServiceApp.RemoveFilteredPolicy(tx, authorityId); err != nil {
 			return err
 		}
@@ -267,11 +385,17 @@ func (authorityService *AuthorityService) GetAuthorityInfo(auth system.SysAuthor
 
 //@author: [piexlmax](https://github.com/piexlmax)
 //@function: SetDataAuthority
-//@description: 设置角色资源权限
-//@param: auth model.SysAuthority
+//@description: 设置角色资源权限 并保存角色权限的版本
+//@param: adminAuthorityID, operatorId uint, operatorName string, auth model.SysAuthority
 //@return: error
 
-func (authorityService *AuthorityService) SetDataAuthority(adminAuthorityID uint, auth system.SysAuthority) error {
+func (authorityService *AuthorityService) SetDataAuthority(adminAuthorityID, operatorId uint, operatorName string, auth system.SysAuthority) error {
+	return AuthorityVersionServiceApp.Track(auth.AuthorityId, operatorId, operatorName, systemReq.AuthorityVersionSetData, func() error {
+		return authorityService.setDataAuthority(adminAuthorityID, auth)
+	})
+}
+
+func (authorityService *AuthorityService) setDataAuthority(adminAuthorityID uint, auth system.SysAuthority) error {
 	var checkIDs []uint
 	checkIDs = append(checkIDs, auth.AuthorityId)
 	for i := range auth.DataAuthorityId {
@@ -27,7 +27,14 @@ func (a *AuthorityBtnService) GetAuthorityBtn(req request.SysAuthorityBtnReq) (r
 	return res, err
 }
 
-func (a *AuthorityBtnService) SetAuthorityBtn(req request.SysAuthorityBtnReq) (err error) {
+// SetAuthorityBtn 设置角色在菜单下的按钮 并保存角色权限的版本
+func (a *AuthorityBtnService) SetAuthorityBtn(operatorId uint, operatorName string, req request.SysAuthorityBtnReq) (err error) {
+	return AuthorityVersionServiceApp.Track(req.AuthorityId, operatorId, operatorName, request.AuthorityVersionSetBtn, func() error {
+		return a.setAuthorityBtn(req)
+	})
+}
+
+func (a *AuthorityBtnService) setAuthorityBtn(req request.SysAuthorityBtnReq) (err error) {
 	return global.GVA_DB.Transaction(func(tx *gorm.DB) error {
 		var authorityBtn []system.SysAuthorityBtn
 		err = tx.Delete(&[]system.SysAuthorityBtn{}, "authority_id = ? and sys_menu_id = ?", req.AuthorityId, req.MenuID).Error
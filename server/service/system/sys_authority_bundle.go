@@ -0,0 +1,490 @@
+package system
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+	"gorm.io/gorm"
+
+	"github.com/flipped-aurora/gin-vue-admin/server/global"
+	"github.com/flipped-aurora/gin-vue-admin/server/model/system"
+	"github.com/flipped-aurora/gin-vue-admin/server/model/system/request"
+	systemRes "github.com/flipped-aurora/gin-vue-admin/server/model/system/response"
+)
+
+var ErrAuthorityBundleConflict = errors.New("导入包与当前环境存在冲突, 未导入")
+
+// IsBundleConflict 是否因导入包与当前环境冲突未导入 此时返回的差异中带有冲突项
+func (authorityService *AuthorityService) IsBundleConflict(err error) bool {
+	return errors.Is(err, ErrAuthorityBundleConflict)
+}
+
+// ExportAuthorityBundle 导出角色及其接口 菜单 按钮 资源权限 数据范围和字段权限 格式为json或yaml
+func (authorityService *AuthorityService) ExportAuthorityBundle(adminAuthorityID uint, req request.ExportAuthorityBundle) (res systemRes.AuthorityBundleExport, err error) {
+	if err = authorityService.CheckAuthorityIDAuth(adminAuthorityID, req.AuthorityId); err != nil {
+		return res, err
+	}
+	bundle, err := authorityService.authorityBundle(req.AuthorityId)
+	if err != nil {
+		return res, err
+	}
+	var b []byte
+	ext := "json"
+	if req.Format == "yaml" {
+		ext = "yaml"
+		// 先转为json再转yaml 保持与json相同的字段名
+		var v interface{}
+		if b, err = json.Marshal(bundle); err == nil {
+			if err = json.Unmarshal(b, &v); err == nil {
+				b, err = yaml.Marshal(v)
+			}
+		}
+	} else {
+		b, err = json.MarshalIndent(bundle, "", "  ")
+	}
+	if err != nil {
+		return res, err
+	}
+	res.Filename = fmt.Sprintf("authority-%d.%s", req.AuthorityId, ext)
+	res.Content = string(b)
+	return res, nil
+}
+
+// ImportAuthorityBundle 导入角色 返回与当前数据的差异
+// 角色已存在时 merge 与已有权限合并 同一接口以导入包为准 replace 以导入包替换
+// 引用的菜单 按钮或角色在当前环境不存在 接口权限或继承关系校验不通过时不导入 dryRun 时只返回差异
+// 角色 菜单 按钮 资源权限 数据范围 字段权限和接口权限在同一事务中写入 提交后重新加载casbin 并保存导入后的版本
+func (authorityService *AuthorityService) ImportAuthorityBundle(adminAuthorityID, operatorId uint, operatorName string, req request.ImportAuthorityBundle) (diff systemRes.AuthorityBundleDiff, err error) {
+	bundle, err := decodeAuthorityBundle(req.Content)
+	if err != nil || req.DryRun {
+		return authorityService.importAuthorityBundle(adminAuthorityID, req)
+	}
+	err = AuthorityVersionServiceApp.Track(bundle.Authority.AuthorityId, operatorId, operatorName, request.AuthorityVersionImport, func() error {
+		diff, err = authorityService.importAuthorityBundle(adminAuthorityID, req)
+		return err
+	})
+	return diff, err
+}
+
+func (authorityService *AuthorityService) importAuthorityBundle(adminAuthorityID uint, req request.ImportAuthorityBundle) (diff systemRes.AuthorityBundleDiff, err error) {
+	bundle, err := decodeAuthorityBundle(req.Content)
+	if err != nil {
+		return diff, err
+	}
+	mode := req.Mode
+	if mode == "" {
+		mode = request.AuthorityBundleMerge
+	}
+	if mode != request.AuthorityBundleMerge && mode != request.AuthorityBundleReplace {
+		return diff, errors.New("导入方式只能是merge或replace")
+	}
+	id := bundle.Authority.AuthorityId
+	diff.AuthorityId = id
+
+	var current request.AuthorityBundle
+	err = global.GVA_DB.Select("authority_id").First(&system.SysAuthority{}, "authority_id = ?", id).Error
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		diff.Create = true
+		current.Authority.AuthorityId = id
+		err = nil
+		// 与创建角色相同 开启严格角色模式时没有父角色的新角色挂到操作人的角色下
+		if bundle.Authority.ParentId == 0 && global.GVA_CONFIG.System.UseStrictAuth {
+			bundle.Authority.ParentId = adminAuthorityID
+		} else if bundle.Authority.ParentId != 0 {
+			err = authorityService.CheckAuthorityIDAuth(adminAuthorityID, bundle.Authority.ParentId)
+		}
+	case err == nil:
+		if err = authorityService.CheckAuthorityIDAuth(adminAuthorityID, id); err == nil {
+			current, err = authorityService.authorityBundle(id)
+		}
+	}
+	if err != nil {
+		return diff, err
+	}
+	for _, dataId := range bundle.DataAuthorityIds {
+		if err = authorityService.CheckAuthorityIDAuth(adminAuthorityID, dataId); err != nil {
+			return diff, err
+		}
+	}
+
+	target := bundle
+	if !diff.Create && mode == request.AuthorityBundleMerge {
+		target = mergeAuthorityBundle(current, bundle)
+	}
+	// 接口权限和继承关系使用与单独修改时相同的校验 预览时同样校验
+	if policies, err := CasbinServiceApp.checkCasbinInfos(adminAuthorityID, target.Policies); err != nil {
+		diff.Conflicts = append(diff.Conflicts, err.Error())
+	} else {
+		target.Policies = policies
+	}
+	info := target.Authority
+	if err = authorityService.checkBundleParent(id, info.ParentId); err != nil {
+		diff.Conflicts = append(diff.Conflicts, err.Error())
+	}
+	if err = CasbinServiceApp.checkAuthorityInherit(id, info.ParentId, info.InheritParent); err != nil {
+		diff.Conflicts = append(diff.Conflicts, err.Error())
+	}
+	resolved := authorityService.resolveAuthorityBundle(&target, &diff)
+	scopes, err := dataScopeRows(id, target.DataScopes)
+	if err != nil {
+		diff.Conflicts = append(diff.Conflicts, err.Error())
+	}
+	fieldRules, err := fieldRuleRows(id, target.FieldRules)
+	if err != nil {
+		diff.Conflicts = append(diff.Conflicts, err.Error())
+	}
+	diffAuthorityBundle(current, target, &diff)
+	if len(diff.Conflicts) > 0 {
+		return diff, ErrAuthorityBundleConflict
+	}
+	if req.DryRun {
+		return diff, nil
+	}
+
+	auth := system.SysAuthority{AuthorityId: id}
+	err = global.GVA_DB.Transaction(func(tx *gorm.DB) error {
+		if diff.Create {
+			auth = system.SysAuthority{
+				AuthorityId:   id,
+				AuthorityName: info.AuthorityName,
+				ParentId:      &info.ParentId,
+				DefaultRouter: info.DefaultRouter,
+				RequireMfa:    info.RequireMfa,
+				MaxSessions:   info.MaxSessions,
+				InheritParent: info.InheritParent,
+			}
+			if err := tx.Create(&auth).Error; err != nil {
+				return err
+			}
+		} else {
+			err := tx.Model(&auth).Where("authority_id = ?", id).Updates(map[string]interface{}{
+				"authority_name": info.AuthorityName,
+				"parent_id":      info.ParentId,
+				"default_router": info.DefaultRouter,
+				"require_mfa":    info.RequireMfa,
+				"max_sessions":   info.MaxSessions,
+				"inherit_parent": info.InheritParent,
+			}).Error
+			if err != nil {
+				return err
+			}
+		}
+		if err := tx.Model(&auth).Association("SysBaseMenus").Replace(resolved.menus); err != nil {
+			return err
+		}
+		if err := tx.Model(&auth).Association("DataAuthorityId").Replace(resolved.dataAuthorities); err != nil {
+			return err
+		}
+		if err := tx.Delete(&[]system.SysAuthorityBtn{}, "authority_id = ?", id).Error; err != nil {
+			return err
+		}
+		if len(resolved.btns) > 0 {
+			if err := tx.Create(&resolved.btns).Error; err != nil {
+				return err
+			}
+		}
+		if err := replaceDataScopes(tx, id, scopes); err != nil {
+			return err
+		}
+		if err := replaceFieldRules(tx, id, fieldRules); err != nil {
+			return err
+		}
+		rules, err := CasbinServiceApp.casbinRules(tx, id, target.Policies)
+		if err != nil {
+			return err
+		}
+		if len(rules) > 0 {
+			err = CasbinServiceApp.SyncPolicy(tx, strconv.Itoa(int(id)), rules)
+		} else {
+			err = CasbinServiceApp.RemoveFilteredPolicy(tx, strconv.Itoa(int(id)))
+		}
+		if err != nil {
+			return err
+		}
+		return CasbinServiceApp.SyncAuthorityInherit(tx, id, info.ParentId, info.InheritParent)
+	})
+	if err != nil {
+		return diff, err
+	}
+	diff.Applied = true
+	return diff, CasbinServiceApp.FreshCasbin()
+}
+
+// checkBundleParent 父角色不能是角色自身或其下级角色
+func (authorityService *AuthorityService) checkBundleParent(authorityId, parentId uint) error {
+	if parentId == 0 {
+		return nil
+	}
+	ids, err := DataScopeServiceApp.subtree(global.GVA_DB, []uint{authorityId})
+	if err != nil {
+		return err
+	}
+	if slices.Contains(ids, parentId) {
+		return errors.New("父角色不能是角色自身或其下级角色: " + strconv.Itoa(int(parentId)))
+	}
+	return nil
+}
+
+// authorityBundle 读取角色当前的权限 菜单和按钮按名称排序
+func (authorityService *AuthorityService) authorityBundle(authorityId uint) (bundle request.AuthorityBundle, err error) {
+	var auth system.SysAuthority
+	err = global.GVA_DB.Preload("DataAuthorityId").Preload("SysBaseMenus").First(&auth, "authority_id = ?", authorityId).Error
+	if err != nil {
+		return bundle, err
+	}
+	bundle = request.AuthorityBundle{
+		Version: request.AuthorityBundleVersion,
+		Authority: request.AuthorityBundleInfo{
+			AuthorityId:   auth.AuthorityId,
+			AuthorityName: auth.AuthorityName,
+			ParentId:      authorityParentId(auth),
+			DefaultRouter: auth.DefaultRouter,
+			RequireMfa:    auth.RequireMfa,
+			MaxSessions:   auth.MaxSessions,
+			InheritParent: auth.InheritParent,
+		},
+		Policies:         CasbinServiceApp.GetPolicyPathByAuthorityId(authorityId),
+		Menus:            []string{},
+		Btns:             []request.AuthorityBundleBtn{},
+		DataAuthorityIds: []uint{},
+	}
+	menuNames := make(map[uint]string, len(auth.SysBaseMenus))
+	for _, menu := range auth.SysBaseMenus {
+		menuNames[menu.ID] = menu.Name
+		bundle.Menus = append(bundle.Menus, menu.Name)
+	}
+	slices.Sort(bundle.Menus)
+	var btns []system.SysAuthorityBtn
+	if err = global.GVA_DB.Preload("SysBaseMenuBtn").Find(&btns, "authority_id = ?", authorityId).Error; err != nil {
+		return bundle, err
+	}
+	for _, btn := range btns {
+		bundle.Btns = append(bundle.Btns, request.AuthorityBundleBtn{Menu: menuNames[btn.SysMenuID], Name: btn.SysBaseMenuBtn.Name})
+	}
+	slices.SortFunc(bundle.Btns, compareBundleBtn)
+	for _, data := range auth.DataAuthorityId {
+		bundle.DataAuthorityIds = append(bundle.DataAuthorityIds, data.AuthorityId)
+	}
+	slices.Sort(bundle.DataAuthorityIds)
+	if bundle.DataScopes, err = DataScopeServiceApp.GetDataScopes(authorityId); err != nil {
+		return bundle, err
+	}
+	if bundle.FieldRules, err = FieldRuleServiceApp.GetFieldRules(authorityId); err != nil {
+		return bundle, err
+	}
+	if bundle.Policies == nil {
+		bundle.Policies = []request.CasbinInfo{}
+	}
+	return bundle, nil
+}
+
+// resolvedAuthorityBundle 导入包中按名称引用的数据在当前环境的记录
+type resolvedAuthorityBundle struct {
+	menus           []system.SysBaseMenu
+	btns            []system.SysAuthorityBtn
+	dataAuthorities []*system.SysAuthority
+}
+
+// resolveAuthorityBundle 按名称查找菜单 按钮和角色 找不到的记为冲突
+func (authorityService *AuthorityService) resolveAuthorityBundle(bundle *request.AuthorityBundle, diff *systemRes.AuthorityBundleDiff) (res resolvedAuthorityBundle) {
+	id := bundle.Authority.AuthorityId
+	if parent := bundle.Authority.ParentId; parent != 0 {
+		if err := global.GVA_DB.Select("authority_id").First(&system.SysAuthority{}, "authority_id = ?", parent).Error; err != nil {
+			diff.Conflicts = append(diff.Conflicts, "父角色不存在: "+strconv.Itoa(int(parent)))
+		}
+	}
+
+	if len(bundle.Menus) > 0 {
+		global.GVA_DB.Where("name IN ?", bundle.Menus).Find(&res.menus)
+	}
+	menuIds := make(map[string]uint, len(res.menus))
+	for _, menu := range res.menus {
+		menuIds[menu.Name] = menu.ID
+	}
+	for _, name := range bundle.Menus {
+		if _, ok := menuIds[name]; !ok {
+			diff.Conflicts = append(diff.Conflicts, "菜单不存在: "+name)
+		}
+	}
+	if bundle.Authority.DefaultRouter != "" && !slices.Contains(bundle.Menus, bundle.Authority.DefaultRouter) {
+		diff.Warnings = append(diff.Warnings, "默认菜单不在角色的菜单中: "+bundle.Authority.DefaultRouter)
+	}
+
+	for _, btn := range bundle.Btns {
+		menuId, ok := menuIds[btn.Menu]
+		if !ok {
+			diff.Conflicts = append(diff.Conflicts, "按钮所在菜单未分配给角色: "+btn.Menu)
+			continue
+		}
+		var baseBtn system.SysBaseMenuBtn
+		if err := global.GVA_DB.First(&baseBtn, "sys_base_menu_id = ? AND name = ?", menuId, btn.Name).Error; err != nil {
+			diff.Conflicts = append(diff.Conflicts, "按钮不存在: "+btn.Menu+"/"+btn.Name)
+			continue
+		}
+		res.btns = append(res.btns, system.SysAuthorityBtn{AuthorityId: id, SysMenuID: menuId, SysBaseMenuBtnID: baseBtn.ID})
+	}
+
+	for _, dataId := range bundle.DataAuthorityIds {
+		if dataId != id {
+			if err := global.GVA_DB.Select("authority_id").First(&system.SysAuthority{}, "authority_id = ?", dataId).Error; err != nil {
+				diff.Conflicts = append(diff.Conflicts, "资源权限的角色不存在: "+strconv.Itoa(int(dataId)))
+				continue
+			}
+		}
+		res.dataAuthorities = append(res.dataAuthorities, &system.SysAuthority{AuthorityId: dataId})
+	}
+
+	for _, policy := range bundle.Policies {
+		if strings.ContainsAny(policy.Path, "*:") {
+			continue
+		}
+		if errors.Is(global.GVA_DB.First(&system.SysApi{}, "path = ? AND method = ?", policy.Path, policy.Method).Error, gorm.ErrRecordNotFound) {
+			diff.Warnings = append(diff.Warnings, "接口不在接口列表中: "+policy.Method+" "+policy.Path)
+		}
+	}
+	return res
+}
+
+// mergeAuthorityBundle 合并已有权限和导入包 角色字段和同一接口的策略 同一资源的数据范围和同一字段的权限以导入包为准
+func mergeAuthorityBundle(current, bundle request.AuthorityBundle) request.AuthorityBundle {
+	merged := bundle
+	imported := make(map[string]bool, len(bundle.Policies))
+	for _, p := range bundle.Policies {
+		imported[p.Method+" "+p.Path] = true
+	}
+	merged.Policies = nil
+	for _, p := range current.Policies {
+		if !imported[p.Method+" "+p.Path] {
+			merged.Policies = append(merged.Policies, p)
+		}
+	}
+	merged.Policies = append(merged.Policies, bundle.Policies...)
+	merged.Menus = union(current.Menus, bundle.Menus)
+	merged.Btns = union(current.Btns, bundle.Btns)
+	merged.DataAuthorityIds = union(current.DataAuthorityIds, bundle.DataAuthorityIds)
+	merged.DataScopes = mergeBy(current.DataScopes, bundle.DataScopes, dataScopeKey)
+	merged.FieldRules = mergeBy(current.FieldRules, bundle.FieldRules, fieldRuleKey)
+	return merged
+}
+
+// mergeBy 合并两个列表 键相同时以后者为准
+func mergeBy[T any](current, imported []T, key func(T) string) []T {
+	keys := make(map[string]bool, len(imported))
+	for _, v := range imported {
+		keys[key(v)] = true
+	}
+	var res []T
+	for _, v := range current {
+		if !keys[key(v)] {
+			res = append(res, v)
+		}
+	}
+	return append(res, imported...)
+}
+
+func dataScopeKey(s request.DataScopeInfo) string { return s.Resource }
+
+func fieldRuleKey(r request.FieldRuleInfo) string { return r.Resource + "." + r.Field }
+
+// diffAuthorityBundle 比较导入前后的角色字段 策略 菜单 按钮 资源权限 数据范围和字段权限
+func diffAuthorityBundle(current, target request.AuthorityBundle, diff *systemRes.AuthorityBundleDiff) {
+	if !diff.Create {
+		fields := []struct {
+			name     string
+			old, new interface{}
+		}{
+			{"authorityName", current.Authority.AuthorityName, target.Authority.AuthorityName},
+			{"parentId", current.Authority.ParentId, target.Authority.ParentId},
+			{"defaultRouter", current.Authority.DefaultRouter, target.Authority.DefaultRouter},
+			{"requireMfa", current.Authority.RequireMfa, target.Authority.RequireMfa},
+			{"maxSessions", current.Authority.MaxSessions, target.Authority.MaxSessions},
+			{"inheritParent", current.Authority.InheritParent, target.Authority.InheritParent},
+		}
+		for _, f := range fields {
+			if f.old != f.new {
+				diff.Fields = append(diff.Fields, fmt.Sprintf("%s: %v -> %v", f.name, f.old, f.new))
+			}
+		}
+	}
+	policyKey := func(p request.CasbinInfo) string {
+		cond, _ := json.Marshal(p.Condition)
+		return p.Method + " " + p.Path + " " + p.GetEffect() + " " + string(cond)
+	}
+	diff.AddPolicies, diff.RemovePolicies = diffBy(current.Policies, target.Policies, policyKey)
+	diff.AddMenus, diff.RemoveMenus = diffBy(current.Menus, target.Menus, func(s string) string { return s })
+	diff.AddBtns, diff.RemoveBtns = diffBy(current.Btns, target.Btns, func(b request.AuthorityBundleBtn) string { return b.Menu + "/" + b.Name })
+	diff.AddDataAuthorityIds, diff.RemoveDataAuthorityIds = diffBy(current.DataAuthorityIds, target.DataAuthorityIds, func(id uint) string { return strconv.Itoa(int(id)) })
+	diff.AddDataScopes, diff.RemoveDataScopes = diffBy(current.DataScopes, target.DataScopes, func(s request.DataScopeInfo) string { return s.Resource + " " + s.Scope })
+	diff.AddFieldRules, diff.RemoveFieldRules = diffBy(current.FieldRules, target.FieldRules, func(r request.FieldRuleInfo) string {
+		return fieldRuleKey(r) + " " + r.Action + " " + r.Pattern
+	})
+}
+
+// diffBy 按键比较 返回只在新列表和只在旧列表中的元素
+func diffBy[T any](before, after []T, key func(T) string) (added, removed []T) {
+	beforeKeys := make(map[string]bool, len(before))
+	for _, v := range before {
+		beforeKeys[key(v)] = true
+	}
+	afterKeys := make(map[string]bool, len(after))
+	for _, v := range after {
+		afterKeys[key(v)] = true
+		if !beforeKeys[key(v)] {
+			added = append(added, v)
+		}
+	}
+	for _, v := range before {
+		if !afterKeys[key(v)] {
+			removed = append(removed, v)
+		}
+	}
+	return added, removed
+}
+
+func union[T comparable](a, b []T) []T {
+	res := slices.Clone(a)
+	for _, v := range b {
+		if !slices.Contains(res, v) {
+			res = append(res, v)
+		}
+	}
+	return res
+}
+
+func compareBundleBtn(a, b request.AuthorityBundleBtn) int {
+	if c := strings.Compare(a.Menu, b.Menu); c != 0 {
+		return c
+	}
+	return strings.Compare(a.Name, b.Name)
+}
+
+// decodeAuthorityBundle 解析json或yaml格式的导入包
+func decodeAuthorityBundle(content string) (bundle request.AuthorityBundle, err error) {
+	content = strings.TrimSpace(content)
+	b := []byte(content)
+	if !strings.HasPrefix(content, "{") {
+		var v interface{}
+		if err = yaml.Unmarshal(b, &v); err != nil {
+			return bundle, errors.New("导入内容不是有效的json或yaml")
+		}
+		if b, err = json.Marshal(v); err != nil {
+			return bundle, err
+		}
+	}
+	if err = json.Unmarshal(b, &bundle); err != nil {
+		return bundle, errors.New("导入内容格式错误: " + err.Error())
+	}
+	if bundle.Version == 0 || bundle.Version > request.AuthorityBundleVersion {
+		return bundle, errors.New("不支持的导入包版本: " + strconv.Itoa(bundle.Version))
+	}
+	if bundle.Authority.AuthorityId == 0 || bundle.Authority.AuthorityName == "" {
+		return bundle, errors.New("导入包缺少角色ID或角色名")
+	}
+	return bundle, nil
+}
@@ -0,0 +1,223 @@
+package system
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	gormadapter "github.com/casbin/gorm-adapter/v3"
+	"github.com/flipped-aurora/gin-vue-admin/server/global"
+	"github.com/flipped-aurora/gin-vue-admin/server/model/system"
+	"github.com/flipped-aurora/gin-vue-admin/server/model/system/request"
+	"github.com/flipped-aurora/gin-vue-admin/server/utils"
+	"gorm.io/gorm"
+)
+
+func setupAuthorityBundleTest(t *testing.T) *AuthorityService {
+	setupUnionAuthTest(t)
+	db := global.GVA_DB
+	if err := db.AutoMigrate(&system.SysApi{}, &system.SysDataScope{}, &system.SysFieldRule{}); err != nil {
+		t.Fatalf("migrate failed: %v", err)
+	}
+	db.Create(&system.SysApi{Path: "/report/list", Method: "GET"})
+	db.Create(&[]system.SysAuthority{
+		{AuthorityId: 150, AuthorityName: "审计员", ParentId: utils.Pointer[uint](0), DefaultRouter: "dashboard"},
+		{AuthorityId: 9528, AuthorityName: "测试角色", ParentId: utils.Pointer[uint](0)},
+	})
+	s := &AuthorityService{}
+	auth := system.SysAuthority{AuthorityId: 150}
+	db.Model(&auth).Association("SysBaseMenus").Replace([]system.SysBaseMenu{{GVA_MODEL: global.GVA_MODEL{ID: 1}}, {GVA_MODEL: global.GVA_MODEL{ID: 2}}})
+	db.Model(&auth).Association("DataAuthorityId").Replace([]*system.SysAuthority{{AuthorityId: 150}, {AuthorityId: 9528}})
+	db.Create(&system.SysAuthorityBtn{AuthorityId: 150, SysMenuID: 2, SysBaseMenuBtnID: 1})
+	err := CasbinServiceApp.UpdateCasbin(0, 0, "", 150, []request.CasbinInfo{
+		{Path: "/report/list", Method: "GET", Condition: &system.CasbinCondition{Cidrs: []string{"10.0.0.0/8"}}},
+		{Path: "/report/*", Method: "DELETE", Effect: request.CasbinEffectDeny},
+	})
+	if err != nil {
+		t.Fatalf("UpdateCasbin() error = %v", err)
+	}
+	return s
+}
+
+func TestAuthorityService_ExportAuthorityBundle(t *testing.T) {
+	s := setupAuthorityBundleTest(t)
+	res, err := s.ExportAuthorityBundle(0, request.ExportAuthorityBundle{AuthorityId: 150})
+	if err != nil || res.Filename != "authority-150.json" {
+		t.Fatalf("ExportAuthorityBundle() = %+v, %v", res, err)
+	}
+	bundle, err := decodeAuthorityBundle(res.Content)
+	if err != nil {
+		t.Fatalf("decodeAuthorityBundle() error = %v", err)
+	}
+	if bundle.Authority.AuthorityName != "审计员" || !reflect.DeepEqual(bundle.Menus, []string{"dashboard", "report"}) ||
+		!reflect.DeepEqual(bundle.Btns, []request.AuthorityBundleBtn{{Menu: "report", Name: "export"}}) ||
+		!reflect.DeepEqual(bundle.DataAuthorityIds, []uint{150, 9528}) || len(bundle.Policies) != 2 || bundle.Policies[0].Condition == nil {
+		t.Fatalf("exported bundle = %+v", bundle)
+	}
+
+	// yaml 与 json 内容一致
+	res, err = s.ExportAuthorityBundle(0, request.ExportAuthorityBundle{AuthorityId: 150, Format: "yaml"})
+	if err != nil || !strings.Contains(res.Content, "authorityName: 审计员") {
+		t.Fatalf("ExportAuthorityBundle(yaml) = %+v, %v", res, err)
+	}
+	fromYaml, err := decodeAuthorityBundle(res.Content)
+	if err != nil || !reflect.DeepEqual(fromYaml, bundle) {
+		t.Fatalf("decodeAuthorityBundle(yaml) = %+v, %v", fromYaml, err)
+	}
+}
+
+func TestAuthorityService_ImportAuthorityBundle(t *testing.T) {
+	s := setupAuthorityBundleTest(t)
+	exported, _ := s.ExportAuthorityBundle(0, request.ExportAuthorityBundle{AuthorityId: 150})
+	bundle, _ := decodeAuthorityBundle(exported.Content)
+
+	// 作为新角色导入 预览时不修改数据
+	bundle.Authority.AuthorityId, bundle.Authority.AuthorityName = 151, "审计员副本"
+	content, _ := json.Marshal(bundle)
+	diff, err := s.ImportAuthorityBundle(0, 0, "", request.ImportAuthorityBundle{Content: string(content), DryRun: true})
+	if err != nil || !diff.Create || diff.Applied || len(diff.AddPolicies) != 2 || len(diff.AddMenus) != 2 || len(diff.Warnings) != 0 {
+		t.Fatalf("ImportAuthorityBundle(dryRun) = %+v, %v", diff, err)
+	}
+	if !errors.Is(global.GVA_DB.First(&system.SysAuthority{}, "authority_id = ?", 151).Error, gorm.ErrRecordNotFound) {
+		t.Fatalf("dry run should not create authority")
+	}
+	diff, err = s.ImportAuthorityBundle(0, 0, "", request.ImportAuthorityBundle{Content: string(content)})
+	if err != nil || !diff.Applied {
+		t.Fatalf("ImportAuthorityBundle() = %+v, %v", diff, err)
+	}
+	imported, err := s.authorityBundle(151)
+	if err != nil || !reflect.DeepEqual(imported, bundle) {
+		t.Fatalf("imported bundle = %+v, want %+v, %v", imported, bundle, err)
+	}
+
+	// 已存在的角色 merge 保留已有权限 replace 以导入包为准
+	change := request.AuthorityBundle{
+		Version:   request.AuthorityBundleVersion,
+		Authority: request.AuthorityBundleInfo{AuthorityId: 151, AuthorityName: "审计员副本", DefaultRouter: "dashboard"},
+		Policies:  []request.CasbinInfo{{Path: "/report/list", Method: "GET"}, {Path: "/report/export", Method: "POST"}},
+		Menus:     []string{"dashboard"},
+	}
+	content, _ = json.Marshal(change)
+	diff, err = s.ImportAuthorityBundle(0, 0, "", request.ImportAuthorityBundle{Content: string(content), Mode: request.AuthorityBundleMerge, DryRun: true})
+	if err != nil || diff.Create || len(diff.AddPolicies) != 2 || len(diff.RemovePolicies) != 1 || len(diff.RemoveMenus) != 0 || len(diff.RemoveBtns) != 0 {
+		t.Fatalf("ImportAuthorityBundle(merge) = %+v, %v", diff, err)
+	}
+	if len(diff.Warnings) != 1 || !strings.Contains(diff.Warnings[0], "/report/export") {
+		t.Fatalf("ImportAuthorityBundle(merge) warnings = %v", diff.Warnings)
+	}
+	diff, err = s.ImportAuthorityBundle(0, 0, "", request.ImportAuthorityBundle{Content: string(content), Mode: request.AuthorityBundleReplace})
+	if err != nil || !diff.Applied || !reflect.DeepEqual(diff.RemoveMenus, []string{"report"}) || len(diff.RemoveBtns) != 1 || len(diff.RemoveDataAuthorityIds) != 2 {
+		t.Fatalf("ImportAuthorityBundle(replace) = %+v, %v", diff, err)
+	}
+	imported, _ = s.authorityBundle(151)
+	if len(imported.Policies) != 2 || !reflect.DeepEqual(imported.Menus, []string{"dashboard"}) || len(imported.Btns) != 0 {
+		t.Fatalf("replaced bundle = %+v", imported)
+	}
+
+	// 当前环境缺少菜单或角色时不导入
+	change.Menus = []string{"dashboard", "ghost"}
+	change.DataAuthorityIds = []uint{404}
+	content, _ = json.Marshal(change)
+	diff, err = s.ImportAuthorityBundle(0, 0, "", request.ImportAuthorityBundle{Content: string(content)})
+	if !errors.Is(err, ErrAuthorityBundleConflict) || len(diff.Conflicts) != 2 || diff.Applied {
+		t.Fatalf("ImportAuthorityBundle(conflict) = %+v, %v", diff, err)
+	}
+	if _, err = s.ImportAuthorityBundle(0, 0, "", request.ImportAuthorityBundle{Content: "version: 9"}); err == nil {
+		t.Fatalf("ImportAuthorityBundle() unsupported version should fail")
+	}
+
+	// 接口权限冲突和继承循环在写入前校验 预览和导入都返回冲突 不写入任何数据
+	change.Menus, change.DataAuthorityIds = []string{"report"}, nil
+	change.Policies = []request.CasbinInfo{{Path: "/report/list", Method: "GET"}, {Path: "/report/list", Method: "GET", Effect: request.CasbinEffectDeny}}
+	change.Authority.ParentId, change.Authority.InheritParent = 151, true
+	content, _ = json.Marshal(change)
+	for _, dryRun := range []bool{true, false} {
+		diff, err = s.ImportAuthorityBundle(0, 0, "", request.ImportAuthorityBundle{Content: string(content), Mode: request.AuthorityBundleReplace, DryRun: dryRun})
+		if !errors.Is(err, ErrAuthorityBundleConflict) || len(diff.Conflicts) != 3 || diff.Applied {
+			t.Fatalf("ImportAuthorityBundle(invalid, dryRun=%v) = %+v, %v", dryRun, diff, err)
+		}
+	}
+	imported, _ = s.authorityBundle(151)
+	if len(imported.Policies) != 2 || !reflect.DeepEqual(imported.Menus, []string{"dashboard"}) || imported.Authority.ParentId != 0 {
+		t.Fatalf("bundle after rejected import = %+v", imported)
+	}
+}
+
+func TestAuthorityService_ImportAuthorityBundleStrictAuth(t *testing.T) {
+	s := setupAuthorityBundleTest(t)
+	global.GVA_CONFIG.System.UseStrictAuth = true
+	bundle := request.AuthorityBundle{
+		Version:   request.AuthorityBundleVersion,
+		Authority: request.AuthorityBundleInfo{AuthorityId: 152, AuthorityName: "新角色"},
+		Policies:  []request.CasbinInfo{{Path: "/report/list", Method: "GET"}},
+	}
+	content, _ := json.Marshal(bundle)
+	// 与创建角色相同 没有父角色时挂到操作人的角色下
+	diff, err := s.ImportAuthorityBundle(150, 0, "", request.ImportAuthorityBundle{Content: string(content)})
+	if err != nil || !diff.Applied {
+		t.Fatalf("ImportAuthorityBundle() = %+v, %v", diff, err)
+	}
+	imported, err := s.authorityBundle(152)
+	if err != nil || imported.Authority.ParentId != 150 || len(imported.Policies) != 1 {
+		t.Fatalf("imported bundle = %+v, %v", imported, err)
+	}
+
+	// 操作人没有的接口不能导入
+	bundle.Authority.AuthorityId = 153
+	bundle.Policies = append(bundle.Policies, request.CasbinInfo{Path: "/report/export", Method: "POST"})
+	content, _ = json.Marshal(bundle)
+	diff, err = s.ImportAuthorityBundle(150, 0, "", request.ImportAuthorityBundle{Content: string(content), DryRun: true})
+	if !errors.Is(err, ErrAuthorityBundleConflict) || len(diff.Conflicts) != 1 {
+		t.Fatalf("ImportAuthorityBundle(strict) = %+v, %v", diff, err)
+	}
+}
+
+func TestAuthorityService_UpdateAuthorityInherit(t *testing.T) {
+	s := setupAuthorityBundleTest(t)
+	db := global.GVA_DB
+	e := CasbinServiceApp.Casbin()
+	update := system.SysAuthority{AuthorityId: 9528, AuthorityName: "测试角色", ParentId: utils.Pointer[uint](150), InheritParent: true}
+
+	// 修改角色失败时 继承规则保持不变
+	errUpdate := errors.New("update failed")
+	if err := db.Callback().Update().Before("gorm:update").Register("test:fail", func(tx *gorm.DB) {
+		if tx.Statement.Table == "sys_authorities" {
+			_ = tx.AddError(errUpdate)
+		}
+	}); err != nil {
+		t.Fatalf("register callback failed: %v", err)
+	}
+	if _, err := s.UpdateAuthority(0, "", update); !errors.Is(err, errUpdate) {
+		t.Fatalf("UpdateAuthority() error = %v, want %v", err, errUpdate)
+	}
+	var count int64
+	db.Model(&gormadapter.CasbinRule{}).Where("ptype = ? AND v0 = ?", "g", "9528").Count(&count)
+	if roles, _ := e.GetRolesForUser("9528"); count != 0 || len(roles) != 0 {
+		t.Fatalf("inherit rules after failed update = %d, %v", count, roles)
+	}
+	if err := db.Callback().Update().Remove("test:fail"); err != nil {
+		t.Fatalf("remove callback failed: %v", err)
+	}
+
+	if _, err := s.UpdateAuthority(0, "", update); err != nil {
+		t.Fatalf("UpdateAuthority() error = %v", err)
+	}
+	var auth system.SysAuthority
+	db.First(&auth, "authority_id = ?", 9528)
+	if roles, _ := e.GetRolesForUser("9528"); *auth.ParentId != 150 || !auth.InheritParent || !reflect.DeepEqual(roles, []string{"150"}) {
+		t.Fatalf("after update authority = %+v, roles = %v", auth, roles)
+	}
+
+	// 循环继承时不修改角色
+	cycle := system.SysAuthority{AuthorityId: 150, AuthorityName: "审计员", ParentId: utils.Pointer[uint](9528), InheritParent: true}
+	if _, err := s.UpdateAuthority(0, "", cycle); err == nil {
+		t.Fatalf("UpdateAuthority() cycle should fail")
+	}
+	var parent system.SysAuthority
+	db.First(&parent, "authority_id = ?", 150)
+	if *parent.ParentId != 0 || parent.InheritParent {
+		t.Fatalf("authority changed by rejected update: %+v", parent)
+	}
+}
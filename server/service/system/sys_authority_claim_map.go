@@ -0,0 +1,51 @@
+package system
+
+import (
+	"errors"
+
+	"github.com/flipped-aurora/gin-vue-admin/server/global"
+	"github.com/flipped-aurora/gin-vue-admin/server/model/system"
+	"gorm.io/gorm"
+)
+
+type AuthorityClaimMapService struct{}
+
+var AuthorityClaimMapServiceApp = new(AuthorityClaimMapService)
+
+// CreateClaimMap 新增外部组到角色的映射
+func (claimMapService *AuthorityClaimMapService) CreateClaimMap(m system.SysAuthorityClaimMap) error {
+	if errors.Is(global.GVA_DB.Where("authority_id = ?", m.AuthorityId).First(&system.SysAuthority{}).Error, gorm.ErrRecordNotFound) {
+		return errors.New("角色不存在")
+	}
+	if !errors.Is(global.GVA_DB.Where("provider = ? AND claim_value = ? AND authority_id = ?", m.Provider, m.ClaimValue, m.AuthorityId).
+		First(&system.SysAuthorityClaimMap{}).Error, gorm.ErrRecordNotFound) {
+		return errors.New("映射已存在")
+	}
+	return global.GVA_DB.Create(&m).Error
+}
+
+// DeleteClaimMap 删除映射
+func (claimMapService *AuthorityClaimMapService) DeleteClaimMap(id uint) error {
+	return global.GVA_DB.Delete(&system.SysAuthorityClaimMap{}, id).Error
+}
+
+// GetClaimMapList 获取映射列表 authorityId 为0时返回全部
+func (claimMapService *AuthorityClaimMapService) GetClaimMapList(authorityId uint) (list []system.SysAuthorityClaimMap, err error) {
+	db := global.GVA_DB.Model(&system.SysAuthorityClaimMap{})
+	if authorityId != 0 {
+		db = db.Where("authority_id = ?", authorityId)
+	}
+	err = db.Order("provider, claim_value").Find(&list).Error
+	return list, err
+}
+
+// MapAuthorities 将外部身份源返回的组映射为本地角色ID
+func (claimMapService *AuthorityClaimMapService) MapAuthorities(provider string, values []string) (authorityIds []uint, err error) {
+	if len(values) == 0 {
+		return nil, nil
+	}
+	err = global.GVA_DB.Model(&system.SysAuthorityClaimMap{}).
+		Where("(provider = ? OR provider = '') AND claim_value IN ?", provider, values).
+		Distinct().Order("authority_id").Pluck("authority_id", &authorityIds).Error
+	return authorityIds, err
+}
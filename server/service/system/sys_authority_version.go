@@ -0,0 +1,171 @@
+package system
+
+import (
+	"encoding/json"
+	"errors"
+	"slices"
+	"strings"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/flipped-aurora/gin-vue-admin/server/global"
+	"github.com/flipped-aurora/gin-vue-admin/server/model/system"
+	"github.com/flipped-aurora/gin-vue-admin/server/model/system/request"
+	systemRes "github.com/flipped-aurora/gin-vue-admin/server/model/system/response"
+)
+
+type AuthorityVersionService struct{}
+
+// authorityVersionRecordAttempts 保存版本冲突时的最多尝试次数
+const authorityVersionRecordAttempts = 3
+
+var AuthorityVersionServiceApp = new(AuthorityVersionService)
+
+// Track 执行权限修改并保存修改后的版本 角色还没有历史版本时先保存修改前的权限
+// 修改成功后保存版本失败只记录日志 不影响修改结果
+func (authorityVersionService *AuthorityVersionService) Track(authorityId, operatorId uint, operatorName, action string, change func() error) error {
+	return authorityVersionService.TrackAll([]uint{authorityId}, operatorId, operatorName, action, change)
+}
+
+// TrackAll 同 Track 用于修改接口等同时影响多个角色的操作
+func (authorityVersionService *AuthorityVersionService) TrackAll(authorityIds []uint, operatorId uint, operatorName, action string, change func() error) error {
+	for _, id := range authorityIds {
+		if err := authorityVersionService.Record(id, 0, "", request.AuthorityVersionInitial); err != nil {
+			global.GVA_LOG.Error("保存角色权限版本失败!", zap.Uint("authorityId", id), zap.Error(err))
+		}
+	}
+	if err := change(); err != nil {
+		return err
+	}
+	for _, id := range authorityIds {
+		if err := authorityVersionService.Record(id, operatorId, operatorName, action); err != nil {
+			global.GVA_LOG.Error("保存角色权限版本失败!", zap.Uint("authorityId", id), zap.Error(err))
+		}
+	}
+	return nil
+}
+
+// Record 保存角色当前权限的快照 与最新版本相同时不保存 角色不存在时忽略
+// initial 只在角色没有任何版本时保存
+func (authorityVersionService *AuthorityVersionService) Record(authorityId, operatorId uint, operatorName, action string) error {
+	bundle, err := AuthorityServiceApp.authorityBundle(authorityId)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	// 策略的顺序与写入顺序有关 排序后比较
+	slices.SortFunc(bundle.Policies, func(a, b request.CasbinInfo) int {
+		if c := strings.Compare(a.Path, b.Path); c != 0 {
+			return c
+		}
+		return strings.Compare(a.Method, b.Method)
+	})
+	b, err := json.Marshal(bundle)
+	if err != nil {
+		return err
+	}
+	// 锁定角色行 同一角色的版本号依次递增 不支持行锁的数据库并发冲突时重试
+	for i := 1; ; i++ {
+		err = global.GVA_DB.Transaction(func(tx *gorm.DB) error {
+			if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Select("authority_id").
+				First(&system.SysAuthority{}, "authority_id = ?", authorityId).Error; err != nil {
+				return err
+			}
+			var latest system.SysAuthorityVersion
+			if err := tx.Where("authority_id = ?", authorityId).Order("version desc").Limit(1).Find(&latest).Error; err != nil {
+				return err
+			}
+			if latest.Version > 0 && (action == request.AuthorityVersionInitial || latest.Snapshot == string(b)) {
+				return nil
+			}
+			return tx.Create(&system.SysAuthorityVersion{
+				AuthorityId:  authorityId,
+				Version:      latest.Version + 1,
+				Action:       action,
+				OperatorId:   operatorId,
+				OperatorName: operatorName,
+				Snapshot:     string(b),
+			}).Error
+		})
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil
+		}
+		if err == nil || i == authorityVersionRecordAttempts {
+			return err
+		}
+	}
+}
+
+// GetAuthorityVersionList 分页获取角色权限的历史版本 新版本在前
+func (authorityVersionService *AuthorityVersionService) GetAuthorityVersionList(adminAuthorityId uint, info request.AuthorityVersionSearch) (list []system.SysAuthorityVersion, total int64, err error) {
+	if err = AuthorityServiceApp.CheckAuthorityIDAuth(adminAuthorityId, info.AuthorityId); err != nil {
+		return nil, 0, err
+	}
+	db := global.GVA_DB.Model(&system.SysAuthorityVersion{}).Where("authority_id = ?", info.AuthorityId)
+	if err = db.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	err = db.Scopes(info.Paginate()).Order("version desc").Find(&list).Error
+	return list, total, err
+}
+
+// GetAuthorityVersion 获取历史版本的快照及相对上一版本的变化
+func (authorityVersionService *AuthorityVersionService) GetAuthorityVersion(adminAuthorityId uint, req request.AuthorityVersionReq) (res systemRes.AuthorityVersionDetail, err error) {
+	if err = AuthorityServiceApp.CheckAuthorityIDAuth(adminAuthorityId, req.AuthorityId); err != nil {
+		return res, err
+	}
+	if res.SysAuthorityVersion, res.Bundle, err = authorityVersionService.version(req); err != nil {
+		return res, err
+	}
+	prev := request.AuthorityBundle{Authority: request.AuthorityBundleInfo{AuthorityId: req.AuthorityId}}
+	var row system.SysAuthorityVersion
+	err = global.GVA_DB.Where("authority_id = ? AND version < ?", req.AuthorityId, req.Version).Order("version desc").First(&row).Error
+	switch {
+	case err == nil:
+		if err = json.Unmarshal([]byte(row.Snapshot), &prev); err != nil {
+			return res, err
+		}
+	case !errors.Is(err, gorm.ErrRecordNotFound):
+		return res, err
+	}
+	res.Diff.AuthorityId = req.AuthorityId
+	diffAuthorityBundle(prev, res.Bundle, &res.Diff)
+	return res, nil
+}
+
+// RollbackAuthorityVersion 以历史版本替换角色当前的权限 并保存为新版本
+// 版本中的菜单 按钮或角色已被删除时不恢复 dryRun 时只返回与当前权限的差异
+func (authorityVersionService *AuthorityVersionService) RollbackAuthorityVersion(adminAuthorityId, operatorId uint, operatorName string, req request.RollbackAuthorityVersion) (diff systemRes.AuthorityBundleDiff, err error) {
+	if err = AuthorityServiceApp.CheckAuthorityIDAuth(adminAuthorityId, req.AuthorityId); err != nil {
+		return diff, err
+	}
+	row, _, err := authorityVersionService.version(req.AuthorityVersionReq)
+	if err != nil {
+		return diff, err
+	}
+	restore := request.ImportAuthorityBundle{Content: row.Snapshot, Mode: request.AuthorityBundleReplace, DryRun: req.DryRun}
+	if req.DryRun {
+		return AuthorityServiceApp.importAuthorityBundle(adminAuthorityId, restore)
+	}
+	err = authorityVersionService.Track(req.AuthorityId, operatorId, operatorName, request.AuthorityVersionRollback, func() error {
+		diff, err = AuthorityServiceApp.importAuthorityBundle(adminAuthorityId, restore)
+		return err
+	})
+	return diff, err
+}
+
+func (authorityVersionService *AuthorityVersionService) version(req request.AuthorityVersionReq) (row system.SysAuthorityVersion, bundle request.AuthorityBundle, err error) {
+	err = global.GVA_DB.Where("authority_id = ? AND version = ?", req.AuthorityId, req.Version).First(&row).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return row, bundle, errors.New("版本不存在")
+	}
+	if err != nil {
+		return row, bundle, err
+	}
+	err = json.Unmarshal([]byte(row.Snapshot), &bundle)
+	return row, bundle, err
+}
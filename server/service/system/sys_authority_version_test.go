@@ -0,0 +1,223 @@
+package system
+
+import (
+	"errors"
+	"reflect"
+	"sync"
+	"testing"
+
+	"github.com/flipped-aurora/gin-vue-admin/server/global"
+	"github.com/flipped-aurora/gin-vue-admin/server/model/system"
+	"github.com/flipped-aurora/gin-vue-admin/server/model/system/request"
+)
+
+func TestAuthorityVersionService(t *testing.T) {
+	setupAuthorityBundleTest(t)
+	db := global.GVA_DB
+	if err := db.AutoMigrate(&system.SysAuthorityVersion{}); err != nil {
+		t.Fatalf("migrate failed: %v", err)
+	}
+	s := AuthorityVersionServiceApp
+	list := func() []system.SysAuthorityVersion {
+		t.Helper()
+		res, total, err := s.GetAuthorityVersionList(0, request.AuthorityVersionSearch{AuthorityId: 150})
+		if err != nil || int(total) != len(res) {
+			t.Fatalf("GetAuthorityVersionList() = %v, %d, %v", res, total, err)
+		}
+		return res
+	}
+	updateCasbin := func() error {
+		return CasbinServiceApp.UpdateCasbin(0, 1, "admin", 150, []request.CasbinInfo{{Path: "/report/list", Method: "GET"}})
+	}
+
+	// 第一次修改时先保存修改前的权限
+	if err := updateCasbin(); err != nil {
+		t.Fatalf("UpdateCasbin() error = %v", err)
+	}
+	versions := list()
+	if len(versions) != 2 || versions[0].Version != 2 || versions[0].Action != request.AuthorityVersionUpdateCasbin ||
+		versions[0].OperatorName != "admin" || versions[1].Action != request.AuthorityVersionInitial {
+		t.Fatalf("versions = %+v", versions)
+	}
+
+	// 没有变化或修改失败时不保存
+	if err := updateCasbin(); err != nil {
+		t.Fatalf("UpdateCasbin() error = %v", err)
+	}
+	errChange := errors.New("change failed")
+	if err := s.Track(150, 1, "admin", request.AuthorityVersionSetMenu, func() error { return errChange }); !errors.Is(err, errChange) {
+		t.Fatalf("Track() error = %v, want %v", err, errChange)
+	}
+	if got := len(list()); got != 2 {
+		t.Fatalf("unchanged permission saved, versions = %d", got)
+	}
+
+	err := s.Track(150, 1, "admin", request.AuthorityVersionSetMenu, func() error {
+		return db.Model(&system.SysAuthority{AuthorityId: 150}).Association("SysBaseMenus").Delete(&system.SysBaseMenu{GVA_MODEL: global.GVA_MODEL{ID: 1}})
+	})
+	if err != nil {
+		t.Fatalf("Track() error = %v", err)
+	}
+
+	// 各版本与上一版本的差异 条件变化的策略视为删除后新增
+	detail, err := s.GetAuthorityVersion(0, request.AuthorityVersionReq{AuthorityId: 150, Version: 2})
+	if err != nil || len(detail.Diff.RemovePolicies) != 2 || len(detail.Diff.AddPolicies) != 1 || len(detail.Bundle.Policies) != 1 {
+		t.Fatalf("GetAuthorityVersion(2) = %+v, %v", detail, err)
+	}
+	detail, err = s.GetAuthorityVersion(0, request.AuthorityVersionReq{AuthorityId: 150, Version: 3})
+	if err != nil || !reflect.DeepEqual(detail.Diff.RemoveMenus, []string{"dashboard"}) || len(detail.Diff.RemovePolicies) != 0 {
+		t.Fatalf("GetAuthorityVersion(3) = %+v, %v", detail, err)
+	}
+	detail, err = s.GetAuthorityVersion(0, request.AuthorityVersionReq{AuthorityId: 150, Version: 1})
+	if err != nil || len(detail.Diff.AddPolicies) != 2 || len(detail.Diff.AddMenus) != 2 {
+		t.Fatalf("GetAuthorityVersion(1) = %+v, %v", detail, err)
+	}
+
+	// 预览恢复时只返回与当前权限的差异
+	req := request.RollbackAuthorityVersion{AuthorityVersionReq: request.AuthorityVersionReq{AuthorityId: 150, Version: 1}, DryRun: true}
+	diff, err := s.RollbackAuthorityVersion(0, 1, "admin", req)
+	if err != nil || diff.Applied || len(diff.AddPolicies) != 2 || !reflect.DeepEqual(diff.AddMenus, []string{"dashboard"}) {
+		t.Fatalf("RollbackAuthorityVersion(dryRun) = %+v, %v", diff, err)
+	}
+	if got := len(list()); got != 3 {
+		t.Fatalf("dry run saved a version, versions = %d", got)
+	}
+
+	req.DryRun = false
+	diff, err = s.RollbackAuthorityVersion(0, 1, "admin", req)
+	if err != nil || !diff.Applied {
+		t.Fatalf("RollbackAuthorityVersion() = %+v, %v", diff, err)
+	}
+	bundle, err := AuthorityServiceApp.authorityBundle(150)
+	if err != nil || len(bundle.Policies) != 2 || !reflect.DeepEqual(bundle.Menus, []string{"dashboard", "report"}) {
+		t.Fatalf("after rollback bundle = %+v, %v", bundle, err)
+	}
+	versions = list()
+	if len(versions) != 4 || versions[0].Action != request.AuthorityVersionRollback || versions[0].Snapshot != versions[3].Snapshot {
+		t.Fatalf("after rollback versions = %+v", versions)
+	}
+
+	req.Version = 99
+	if _, err = s.RollbackAuthorityVersion(0, 1, "admin", req); err == nil {
+		t.Fatalf("RollbackAuthorityVersion() missing version should fail")
+	}
+}
+
+func TestAuthorityVersionService_TrackApis(t *testing.T) {
+	setupAuthorityBundleTest(t)
+	db := global.GVA_DB
+	if err := db.AutoMigrate(&system.SysAuthorityVersion{}); err != nil {
+		t.Fatalf("migrate failed: %v", err)
+	}
+	if err := CasbinServiceApp.updateCasbin(0, 9528, []request.CasbinInfo{{Path: "/report/list", Method: "GET"}}); err != nil {
+		t.Fatalf("updateCasbin() error = %v", err)
+	}
+	s := AuthorityVersionServiceApp
+
+	// 修改接口影响所有拥有该接口权限的角色
+	var api system.SysApi
+	db.First(&api, "path = ? AND method = ?", "/report/list", "GET")
+	ids, err := ApiServiceApp.authorityIdsByApiIds([]int{int(api.ID)})
+	if err != nil || !reflect.DeepEqual(ids, []uint{150, 9528}) {
+		t.Fatalf("authorityIdsByApiIds() = %v, %v", ids, err)
+	}
+	api.Path = "/report/page"
+	if err = ApiServiceApp.UpdateApi(1, "admin", api); err != nil {
+		t.Fatalf("UpdateApi() error = %v", err)
+	}
+	for _, id := range ids {
+		var versions []system.SysAuthorityVersion
+		db.Where("authority_id = ?", id).Order("version desc").Find(&versions)
+		if len(versions) != 2 || versions[0].Action != request.AuthorityVersionUpdateApi || versions[0].OperatorName != "admin" {
+			t.Fatalf("authority %d versions = %+v", id, versions)
+		}
+	}
+
+	// 并发保存同一角色相同的权限 只保存一个版本
+	if err = CasbinServiceApp.updateCasbin(0, 9528, nil); err != nil {
+		t.Fatalf("updateCasbin() error = %v", err)
+	}
+	var wg sync.WaitGroup
+	errs := make(chan error, 8)
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs <- s.Record(9528, 1, "admin", request.AuthorityVersionUpdateCasbin)
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Fatalf("concurrent Record() error = %v", err)
+		}
+	}
+	var count int64
+	db.Model(&system.SysAuthorityVersion{}).Where("authority_id = ?", 9528).Count(&count)
+	if count != 3 {
+		t.Fatalf("versions after concurrent record = %d, want 3", count)
+	}
+}
+
+func TestAuthorityVersionService_RecordInServices(t *testing.T) {
+	setupAuthorityBundleTest(t)
+	db := global.GVA_DB
+	if err := db.AutoMigrate(&system.SysAuthorityVersion{}); err != nil {
+		t.Fatalf("migrate failed: %v", err)
+	}
+	s := AuthorityVersionServiceApp
+	latest := func() system.SysAuthorityVersion {
+		t.Helper()
+		var row system.SysAuthorityVersion
+		if err := db.Where("authority_id = ?", 150).Order("version desc").First(&row).Error; err != nil {
+			t.Fatalf("latest version error = %v", err)
+		}
+		return row
+	}
+
+	// 数据范围和字段权限的修改同样保存版本
+	err := DataScopeServiceApp.SetDataScopes(0, 1, "admin", request.SetDataScopes{AuthorityId: 150, Scopes: []request.DataScopeInfo{
+		{Resource: "exa_customers", Scope: system.DataScopeSelf},
+	}})
+	if err != nil {
+		t.Fatalf("SetDataScopes() error = %v", err)
+	}
+	if row := latest(); row.Version != 2 || row.Action != request.AuthorityVersionSetDataScopes || row.OperatorName != "admin" {
+		t.Fatalf("after SetDataScopes latest = %+v", row)
+	}
+	err = FieldRuleServiceApp.SetFieldRules(0, 1, "admin", request.SetFieldRules{AuthorityId: 150, Rules: []request.FieldRuleInfo{
+		{Resource: "exa_customers", Field: "phone", Action: system.FieldRuleMasked, Pattern: "3,4"},
+	}})
+	if err != nil {
+		t.Fatalf("SetFieldRules() error = %v", err)
+	}
+	if row := latest(); row.Version != 3 || row.Action != request.AuthorityVersionSetFieldRules {
+		t.Fatalf("after SetFieldRules latest = %+v", row)
+	}
+
+	// 系统内部调用服务时同样保存 没有操作人
+	var api system.SysApi
+	db.First(&api, "path = ? AND method = ?", "/report/list", "GET")
+	if err = ApiServiceApp.DeleteApi(0, "", api); err != nil {
+		t.Fatalf("DeleteApi() error = %v", err)
+	}
+	if row := latest(); row.Version != 4 || row.Action != request.AuthorityVersionDeleteApi || row.OperatorId != 0 {
+		t.Fatalf("after DeleteApi latest = %+v", row)
+	}
+
+	// 恢复版本时一并恢复数据范围和字段权限
+	req := request.RollbackAuthorityVersion{AuthorityVersionReq: request.AuthorityVersionReq{AuthorityId: 150, Version: 1}}
+	diff, err := s.RollbackAuthorityVersion(0, 1, "admin", req)
+	if err != nil || len(diff.RemoveDataScopes) != 1 || len(diff.RemoveFieldRules) != 1 {
+		t.Fatalf("RollbackAuthorityVersion() = %+v, %v", diff, err)
+	}
+	scopes, _ := DataScopeServiceApp.GetDataScopes(150)
+	rules, _ := FieldRuleServiceApp.GetFieldRules(150)
+	if len(scopes) != 0 || len(rules) != 0 {
+		t.Fatalf("after rollback scopes = %v, rules = %v", scopes, rules)
+	}
+	if row := latest(); row.Version != 5 || row.Action != request.AuthorityVersionRollback {
+		t.Fatalf("after rollback latest = %+v", row)
+	}
+}
@@ -2,44 +2,85 @@ package system
 
 import (
 	"errors"
+	"slices"
 	"strconv"
+	"strings"
 	"sync"
+	"time"
 
 	"gorm.io/gorm"
 
 	"github.com/casbin/casbin/v2"
 	"github.com/casbin/casbin/v2/model"
+	"github.com/casbin/casbin/v2/persist"
 	gormadapter "github.com/casbin/gorm-adapter/v3"
 	"github.com/flipped-aurora/gin-vue-admin/server/global"
+	"github.com/flipped-aurora/gin-vue-admin/server/model/system"
 	"github.com/flipped-aurora/gin-vue-admin/server/model/system/request"
+	"github.com/flipped-aurora/gin-vue-admin/server/utils"
 	_ "github.com/go-sql-driver/mysql"
 	"go.uber.org/zap"
 )
 
 //@author: [piexlmax](https://github.com/piexlmax)
 //@function: UpdateCasbin
-//@description: 更新casbin权限
-//@param: authorityId string, casbinInfos []request.CasbinInfo
+//@description: 更新casbin权限 并保存角色权限的版本
+//@param: adminAuthorityID, operatorId uint, operatorName string, AuthorityID uint, casbinInfos []request.CasbinInfo
 //@return: error
 
 type CasbinService struct{}
 
+const (
+	userAuthorityCachePrefix  = "authorities:"
+	userAuthorityCacheExpires = 5 * time.Minute
+)
+
 var CasbinServiceApp = new(CasbinService)
 
-func (casbinService *CasbinService) UpdateCasbin(adminAuthorityID, AuthorityID uint, casbinInfos []request.CasbinInfo) error {
+func (casbinService *CasbinService) UpdateCasbin(adminAuthorityID, operatorId uint, operatorName string, AuthorityID uint, casbinInfos []request.CasbinInfo) error {
+	return AuthorityVersionServiceApp.Track(AuthorityID, operatorId, operatorName, request.AuthorityVersionUpdateCasbin, func() error {
+		return casbinService.updateCasbin(adminAuthorityID, AuthorityID, casbinInfos)
+	})
+}
 
+func (casbinService *CasbinService) updateCasbin(adminAuthorityID, AuthorityID uint, casbinInfos []request.CasbinInfo) error {
 	err := AuthorityServiceApp.CheckAuthorityIDAuth(adminAuthorityID, AuthorityID)
 	if err != nil {
 		return err
 	}
+	if casbinInfos, err = casbinService.checkCasbinInfos(adminAuthorityID, casbinInfos); err != nil {
+		return err
+	}
+	rules, err := casbinService.casbinRules(global.GVA_DB, AuthorityID, casbinInfos)
+	if err != nil {
+		return err
+	}
+	casbinService.ClearCasbin(0, strconv.Itoa(int(AuthorityID)))
+	if len(rules) == 0 {
+		return nil
+	} // 设置空权限无需调用 AddPolicies 方法
+	e := casbinService.Casbin()
+	success, _ := e.AddPolicies(rules)
+	if !success {
+		return errors.New("存在相同api,添加失败,请联系管理员")
+	}
+	return nil
+}
 
+// checkCasbinInfos 校验并规范化接口权限 只校验不写入 可在事务前和预览时调用
+// 开启严格角色模式时允许的接口必须在操作人的接口列表中 同一接口不能同时允许和拒绝
+func (casbinService *CasbinService) checkCasbinInfos(adminAuthorityID uint, casbinInfos []request.CasbinInfo) ([]request.CasbinInfo, error) {
 	if global.GVA_CONFIG.System.UseStrictAuth {
 		apis, e := ApiServiceApp.GetAllApis(adminAuthorityID)
 		if e != nil {
-			return e
+			return nil, e
 		}
 
 		for i := range casbinInfos {
+			// 拒绝规则只会收窄权限 无需校验
+			if casbinInfos[i].GetEffect() == request.CasbinEffectDeny {
+				continue
+			}
 			hasApi := false
 			for j := range apis {
 				if apis[j].Path == casbinInfos[i].Path && apis[j].Method == casbinInfos[i].Method {
@@ -48,32 +89,49 @@ func (casbinService *CasbinService) UpdateCasbin(adminAuthorityID, AuthorityID u
 				}
 			}
 			if !hasApi {
-				return errors.New("存在api不在权限列表中")
+				return nil, errors.New("存在api不在权限列表中")
 			}
 		}
 	}
 
+	res := make([]request.CasbinInfo, 0, len(casbinInfos))
+	effectMap := make(map[string]string)
+	for _, v := range casbinInfos {
+		cond, err := normalizeCasbinCondition(v.Condition)
+		if err != nil {
+			return nil, errors.New("接口 " + v.Method + " " + v.Path + " " + err.Error())
+		}
+		key := v.Path + v.Method
+		if effect, ok := effectMap[key]; ok && effect != v.GetEffect() {
+			return nil, errors.New("接口 " + v.Method + " " + v.Path + " 不能同时允许和拒绝")
+		}
+		effectMap[key] = v.GetEffect()
+		v.Condition = cond
+		v.Effect = v.GetEffect()
+		res = append(res, v)
+	}
+	return res, nil
+}
+
+// casbinRules 保存策略条件 生成去重后的规则 条件不同的规则各自生效
+// 传入的接口权限需先经过 checkCasbinInfos 校验
+func (casbinService *CasbinService) casbinRules(db *gorm.DB, AuthorityID uint, casbinInfos []request.CasbinInfo) ([][]string, error) {
 	authorityId := strconv.Itoa(int(AuthorityID))
-	casbinService.ClearCasbin(0, authorityId)
 	rules := [][]string{}
-	//做权限去重处理
 	deduplicateMap := make(map[string]bool)
 	for _, v := range casbinInfos {
-		key := authorityId + v.Path + v.Method
-		if _, ok := deduplicateMap[key]; !ok {
-			deduplicateMap[key] = true
-			rules = append(rules, []string{authorityId, v.Path, v.Method})
+		condKey, err := saveCasbinCondition(db, v.Condition)
+		if err != nil {
+			return nil, err
 		}
+		key := v.Path + v.Method + condKey
+		if deduplicateMap[key] {
+			continue
+		}
+		deduplicateMap[key] = true
+		rules = append(rules, []string{authorityId, v.Path, v.Method, v.GetEffect(), condKey})
 	}
-	if len(rules) == 0 {
-		return nil
-	} // 设置空权限无需调用 AddPolicies 方法
-	e := casbinService.Casbin()
-	success, _ := e.AddPolicies(rules)
-	if !success {
-		return errors.New("存在相同api,添加失败,请联系管理员")
-	}
-	return nil
+	return rules, nil
 }
 
 //@author: [piexlmax](https://github.com/piexlmax)
@@ -91,8 +149,7 @@ func (casbinService *CasbinService) UpdateCasbinApi(oldPath string, newPath stri
 		return err
 	}
 
-	e := casbinService.Casbin()
-	return e.LoadPolicy()
+	return casbinService.FreshCasbin()
 }
 
 //@author: [piexlmax](https://github.com/piexlmax)
@@ -106,10 +163,17 @@ func (casbinService *CasbinService) GetPolicyPathByAuthorityId(AuthorityID uint)
 	authorityId := strconv.Itoa(int(AuthorityID))
 	list, _ := e.GetFilteredPolicy(0, authorityId)
 	for _, v := range list {
-		pathMaps = append(pathMaps, request.CasbinInfo{
+		info := request.CasbinInfo{
 			Path:   v[1],
 			Method: v[2],
-		})
+			Effect: v[3],
+		}
+		if len(v) > 4 && v[4] != request.CasbinCondNone {
+			if cond, ok := getCasbinCondition(v[4]); ok {
+				info.Condition = &cond
+			}
+		}
+		pathMaps = append(pathMaps, info)
 	}
 	return pathMaps
 }
@@ -159,22 +223,227 @@ func (casbinService *CasbinService) SyncPolicy(db *gorm.DB, authorityId string,
 func (casbinService *CasbinService) AddPolicies(db *gorm.DB, rules [][]string) error {
 	var casbinRules []gormadapter.CasbinRule
 	for i := range rules {
+		effect, cond := request.CasbinEffectAllow, request.CasbinCondNone
+		if len(rules[i]) > 3 {
+			effect = rules[i][3]
+		}
+		if len(rules[i]) > 4 {
+			cond = rules[i][4]
+		}
 		casbinRules = append(casbinRules, gormadapter.CasbinRule{
 			Ptype: "p",
 			V0:    rules[i][0],
 			V1:    rules[i][1],
 			V2:    rules[i][2],
+			V3:    effect,
+			V4:    cond,
 		})
 	}
 	return db.Create(&casbinRules).Error
 }
 
+// FreshCasbin 从数据库重新加载策略 直接修改数据库中的策略后调用 同时通知其他实例重新加载
 func (casbinService *CasbinService) FreshCasbin() (err error) {
+	if err = migrateCasbinPolicy(global.GVA_DB); err != nil {
+		return err
+	}
+	if err = casbinService.reloadCasbin(); err != nil {
+		return err
+	}
+	if policyWatcher != nil {
+		if e := policyWatcher.Update(); e != nil {
+			global.GVA_LOG.Error("通知其他实例刷新casbin失败!", zap.Error(e))
+		}
+	}
+	return nil
+}
+
+// reloadCasbin 重新加载策略条件和策略 并清除缓存的校验结果
+func (casbinService *CasbinService) reloadCasbin() error {
+	e := casbinService.Casbin()
+	if err := loadCasbinConditions(global.GVA_DB); err != nil {
+		return err
+	}
+	if err := e.LoadPolicy(); err != nil {
+		return err
+	}
+	return e.InvalidateCache()
+}
+
+// StartWatcher 多实例部署时同步策略 开启redis时通过发布订阅通知 否则轮询数据库中的策略版本
+// 通过enforcer修改策略时自动通知 其他实例收到后重新加载
+func (casbinService *CasbinService) StartWatcher() error {
 	e := casbinService.Casbin()
-	err = e.LoadPolicy()
+	if e == nil {
+		return errors.New("casbin未初始化")
+	}
+	var w persist.Watcher
+	if global.GVA_CONFIG.System.UseRedis && global.GVA_REDIS != nil {
+		w = newRedisCasbinWatcher(global.GVA_REDIS)
+	} else {
+		interval := parseDurationOr(global.GVA_CONFIG.System.CasbinPollInterval, casbinPollIntervalDefault)
+		dw, err := newDBCasbinWatcher(global.GVA_DB, interval)
+		if err != nil {
+			return err
+		}
+		w = dw
+	}
+	if err := e.SetWatcher(w); err != nil {
+		w.Close()
+		return err
+	}
+	// 默认回调只重新加载策略 还需要加载条件和清除缓存
+	_ = w.SetUpdateCallback(func(string) {
+		if err := casbinService.reloadCasbin(); err != nil {
+			global.GVA_LOG.Error("同步casbin策略失败!", zap.Error(err))
+		}
+	})
+	policyWatcher = w
+	return nil
+}
+
+// migrateCasbinPolicy 旧版本的策略没有效果和条件字段 分别迁移为允许和无条件 否则无法加载到带 eft cond 的模型中
+func migrateCasbinPolicy(db *gorm.DB) error {
+	err := db.Model(&gormadapter.CasbinRule{}).Where("ptype = ? AND (v3 = ? OR v3 IS NULL)", "p", "").
+		Update("v3", request.CasbinEffectAllow).Error
+	if err != nil {
+		return err
+	}
+	return db.Model(&gormadapter.CasbinRule{}).Where("ptype = ? AND (v4 = ? OR v4 IS NULL)", "p", "").
+		Update("v4", request.CasbinCondNone).Error
+}
+
+// UpdateAuthorityInherit 同步角色的继承关系 开启继承且存在父角色时写入 g 子角色 父角色 规则
+// 子角色自动拥有父角色及其祖先角色的接口权限 无需复制接口权限
+func (casbinService *CasbinService) UpdateAuthorityInherit(authorityId uint, parentId uint, inherit bool) error {
+	if err := casbinService.checkAuthorityInherit(authorityId, parentId, inherit); err != nil {
+		return err
+	}
+	e := casbinService.Casbin()
+	sub := strconv.Itoa(int(authorityId))
+	parent := strconv.Itoa(int(parentId))
+	inherit = inherit && parentId != 0
+	if _, err := e.RemoveFilteredGroupingPolicy(0, sub); err != nil {
+		return err
+	}
+	// 继承关系变更不会清除缓存的校验结果 需要全部清除
+	defer func() { _ = e.InvalidateCache() }()
+	if !inherit {
+		return nil
+	}
+	_, err := e.AddGroupingPolicy(sub, parent)
 	return err
 }
 
+// checkAuthorityInherit 开启继承时校验父角色及其祖先角色中不包含该角色 避免形成循环
+func (casbinService *CasbinService) checkAuthorityInherit(authorityId uint, parentId uint, inherit bool) error {
+	if !inherit || parentId == 0 {
+		return nil
+	}
+	sub := strconv.Itoa(int(authorityId))
+	parent := strconv.Itoa(int(parentId))
+	ancestors, err := casbinService.Casbin().GetImplicitRolesForUser(parent)
+	if err != nil {
+		return err
+	}
+	if parent == sub || slices.Contains(ancestors, sub) {
+		return errors.New("角色继承关系存在循环")
+	}
+	return nil
+}
+
+// SyncAuthorityInherit 使用数据库方法写入角色的继承规则 此方法需要调用FreshCasbin方法才可以在系统中即刻生效
+func (casbinService *CasbinService) SyncAuthorityInherit(db *gorm.DB, authorityId uint, parentId uint, inherit bool) error {
+	sub := strconv.Itoa(int(authorityId))
+	if err := db.Delete(&gormadapter.CasbinRule{}, "ptype = ? AND v0 = ?", "g", sub).Error; err != nil {
+		return err
+	}
+	if !inherit || parentId == 0 {
+		return nil
+	}
+	return db.Create(&gormadapter.CasbinRule{Ptype: "g", V0: sub, V1: strconv.Itoa(int(parentId))}).Error
+}
+
+// Enforce 校验用户能否访问接口 开启 use-union-auth 时任一角色拥有权限即可
+// 拒绝规则只作用于所在角色及继承该角色的子角色 ip 为 * 时忽略策略条件
+// 因策略条件不满足被拒绝时返回原因
+func (casbinService *CasbinService) Enforce(userId, authorityId uint, obj, act, ip string) (bool, string) {
+	e := casbinService.Casbin()
+	ids := casbinService.EffectiveAuthorityIds(userId, authorityId)
+	for _, id := range ids {
+		if ok, _ := e.Enforce(strconv.Itoa(int(id)), obj, act, ip); ok {
+			return true, ""
+		}
+	}
+	_, _, reason := casbinService.conditionDenied(ids, obj, act, ip)
+	return false, reason
+}
+
+// conditionDenied 忽略条件后能命中允许规则 说明是该规则的条件不满足 返回规则所在角色 规则和原因
+func (casbinService *CasbinService) conditionDenied(ids []uint, obj, act, ip string) (uint, []string, string) {
+	e := casbinService.Casbin()
+	for _, id := range ids {
+		ok, rule, _ := e.EnforceEx(strconv.Itoa(int(id)), obj, act, request.CasbinCondNone)
+		if !ok || len(rule) < 5 || rule[4] == request.CasbinCondNone {
+			continue
+		}
+		if cond, found := getCasbinCondition(rule[4]); found {
+			if reason := checkCasbinCondition(cond, ip, casbinNow()); reason != "" {
+				return id, rule, reason
+			}
+		}
+	}
+	return 0, nil, ""
+}
+
+// EffectiveAuthorityIds 参与权限计算的角色 当前角色排在首位
+// 未开启 use-union-auth 时只有当前角色 查询失败时同样退回当前角色
+func (casbinService *CasbinService) EffectiveAuthorityIds(userId, authorityId uint) []uint {
+	ids := []uint{authorityId}
+	if !global.GVA_CONFIG.System.UseUnionAuth || userId == 0 {
+		return ids
+	}
+	all, err := casbinService.UserAuthorityIds(userId)
+	if err != nil {
+		global.GVA_LOG.Error("获取用户角色失败!", zap.Error(err))
+		return ids
+	}
+	for _, id := range all {
+		if id != authorityId {
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
+
+// UserAuthorityIds 用户拥有的全部角色 开启redis时缓存结果
+// 角色变更时会更换安全戳 同时清除该缓存 进程内缓存无法被其他实例清除 未开启redis时每次查询数据库
+func (casbinService *CasbinService) UserAuthorityIds(userId uint) (ids []uint, err error) {
+	key := userAuthorityCachePrefix + strconv.Itoa(int(userId))
+	shared := utils.CacheShared()
+	if v, ok := utils.CacheGet(key); shared && ok {
+		for _, s := range strings.Split(v, ",") {
+			if id, e := strconv.Atoi(s); e == nil {
+				ids = append(ids, uint(id))
+			}
+		}
+		return ids, nil
+	}
+	err = global.GVA_DB.Model(&system.SysUserAuthority{}).Where("sys_user_id = ?", userId).
+		Order("sys_authority_authority_id").Pluck("sys_authority_authority_id", &ids).Error
+	if err != nil {
+		return nil, err
+	}
+	values := make([]string, 0, len(ids))
+	for _, id := range ids {
+		values = append(values, strconv.Itoa(int(id)))
+	}
+	if shared {
+		_ = utils.CacheSet(key, strings.Join(values, ","), userAuthorityCacheExpires)
+	}
+	return ids, nil
+}
+
 //@author: [piexlmax](https://github.com/piexlmax)
 //@function: Casbin
 //@description: 持久化到数据库  引入自定义规则
@@ -183,6 +452,7 @@ func (casbinService *CasbinService) FreshCasbin() (err error) {
 var (
 	syncedCachedEnforcer *casbin.SyncedCachedEnforcer
 	once                 sync.Once
+	policyWatcher        persist.Watcher
 )
 
 func (casbinService *CasbinService) Casbin() *casbin.SyncedCachedEnforcer {
@@ -194,26 +464,33 @@ func (casbinService *CasbinService) Casbin() *casbin.SyncedCachedEnforcer {
 		}
 		text := `
 		[request_definition]
-		r = sub, obj, act
+		r = sub, obj, act, ip
 		
 		[policy_definition]
-		p = sub, obj, act
+		p = sub, obj, act, eft, cond
 		
 		[role_definition]
 		g = _, _
 		
 		[policy_effect]
-		e = some(where (p.eft == allow))
+		e = some(where (p.eft == allow)) && !some(where (p.eft == deny))
 		
 		[matchers]
-		m = r.sub == p.sub && keyMatch2(r.obj,p.obj) && r.act == p.act
+		m = g(r.sub, p.sub) && keyMatch2(r.obj,p.obj) && r.act == p.act && casbinCond(p.cond, r.ip)
 		`
 		m, err := model.NewModelFromString(text)
 		if err != nil {
 			zap.L().Error("字符串加载模型失败!", zap.Error(err))
 			return
 		}
+		if err = migrateCasbinPolicy(global.GVA_DB); err != nil {
+			zap.L().Error("迁移casbin策略失败!", zap.Error(err))
+		}
+		if err = loadCasbinConditions(global.GVA_DB); err != nil {
+			zap.L().Error("加载casbin策略条件失败!", zap.Error(err))
+		}
 		syncedCachedEnforcer, _ = casbin.NewSyncedCachedEnforcer(m, a)
+		syncedCachedEnforcer.AddFunction("casbinCond", casbinCondFunc)
 		syncedCachedEnforcer.SetExpireTime(60 * 60)
 		_ = syncedCachedEnforcer.LoadPolicy()
 	})
@@ -0,0 +1,188 @@
+package system
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"net"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/flipped-aurora/gin-vue-admin/server/global"
+	"github.com/flipped-aurora/gin-vue-admin/server/model/system"
+	"github.com/flipped-aurora/gin-vue-admin/server/model/system/request"
+	"gorm.io/gorm"
+)
+
+const casbinConditionTimeLayout = "15:04"
+
+// casbinNow 校验时间窗口使用的当前时间 测试时替换
+var casbinNow = time.Now
+
+// casbinConditions 条件键到条件的缓存 条件按内容寻址 写入后不会变化
+var casbinConditions = struct {
+	sync.RWMutex
+	m map[string]system.CasbinCondition
+}{m: map[string]system.CasbinCondition{}}
+
+// normalizeCasbinCondition 校验并整理策略条件 没有任何限制时返回nil
+func normalizeCasbinCondition(c *system.CasbinCondition) (*system.CasbinCondition, error) {
+	if c == nil {
+		return nil, nil
+	}
+	cond := system.CasbinCondition{Timezone: strings.TrimSpace(c.Timezone)}
+	for _, ip := range c.Cidrs {
+		ip = strings.TrimSpace(ip)
+		if ip == "" {
+			continue
+		}
+		if _, _, err := net.ParseCIDR(ip); err != nil && net.ParseIP(ip) == nil {
+			return nil, errors.New("IP范围格式错误: " + ip)
+		}
+		if !slices.Contains(cond.Cidrs, ip) {
+			cond.Cidrs = append(cond.Cidrs, ip)
+		}
+	}
+	for _, day := range c.Weekdays {
+		if day < 0 || day > 6 {
+			return nil, errors.New("星期只能是0-6 0为周日")
+		}
+		if !slices.Contains(cond.Weekdays, day) {
+			cond.Weekdays = append(cond.Weekdays, day)
+		}
+	}
+	slices.Sort(cond.Weekdays)
+	cond.StartTime = strings.TrimSpace(c.StartTime)
+	cond.EndTime = strings.TrimSpace(c.EndTime)
+	if (cond.StartTime == "") != (cond.EndTime == "") {
+		return nil, errors.New("开始时间和结束时间需要同时设置")
+	}
+	if cond.StartTime != "" {
+		start, err := time.Parse(casbinConditionTimeLayout, cond.StartTime)
+		if err != nil {
+			return nil, errors.New("开始时间格式应为HH:MM")
+		}
+		end, err := time.Parse(casbinConditionTimeLayout, cond.EndTime)
+		if err != nil {
+			return nil, errors.New("结束时间格式应为HH:MM")
+		}
+		if start.Equal(end) {
+			return nil, errors.New("开始时间和结束时间不能相同")
+		}
+		cond.StartTime, cond.EndTime = start.Format(casbinConditionTimeLayout), end.Format(casbinConditionTimeLayout)
+	}
+	if cond.Timezone != "" {
+		if _, err := time.LoadLocation(cond.Timezone); err != nil {
+			return nil, errors.New("时区不存在: " + cond.Timezone)
+		}
+	}
+	if cond.IsEmpty() {
+		return nil, nil
+	}
+	return &cond, nil
+}
+
+// saveCasbinCondition 保存条件并返回写入策略 v4 字段的条件键 没有条件时为 *
+func saveCasbinCondition(db *gorm.DB, c *system.CasbinCondition) (string, error) {
+	if c == nil {
+		return request.CasbinCondNone, nil
+	}
+	b, err := json.Marshal(c)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(b)
+	key := hex.EncodeToString(sum[:16])
+	record := system.SysCasbinCondition{Key: key, CasbinCondition: *c}
+	if err = db.Where(&system.SysCasbinCondition{Key: key}).FirstOrCreate(&record).Error; err != nil {
+		return "", err
+	}
+	casbinConditions.Lock()
+	casbinConditions.m[key] = *c
+	casbinConditions.Unlock()
+	return key, nil
+}
+
+// loadCasbinConditions 重新加载全部条件 随策略一起刷新
+func loadCasbinConditions(db *gorm.DB) error {
+	var list []system.SysCasbinCondition
+	if err := db.Find(&list).Error; err != nil {
+		return err
+	}
+	m := make(map[string]system.CasbinCondition, len(list))
+	for _, v := range list {
+		m[v.Key] = v.CasbinCondition
+	}
+	casbinConditions.Lock()
+	casbinConditions.m = m
+	casbinConditions.Unlock()
+	return nil
+}
+
+// getCasbinCondition 按条件键获取条件 其他实例新增的条件不在缓存中时回源数据库
+func getCasbinCondition(key string) (system.CasbinCondition, bool) {
+	casbinConditions.RLock()
+	c, ok := casbinConditions.m[key]
+	casbinConditions.RUnlock()
+	if ok {
+		return c, true
+	}
+	var record system.SysCasbinCondition
+	if err := global.GVA_DB.Where(&system.SysCasbinCondition{Key: key}).First(&record).Error; err != nil {
+		return c, false
+	}
+	casbinConditions.Lock()
+	casbinConditions.m[key] = record.CasbinCondition
+	casbinConditions.Unlock()
+	return record.CasbinCondition, true
+}
+
+// checkCasbinCondition 校验请求是否满足条件 不满足时返回原因
+func checkCasbinCondition(c system.CasbinCondition, ip string, now time.Time) string {
+	if !allowIp(c.Cidrs, ip) {
+		return "当前IP " + ip + " 不在允许的范围内"
+	}
+	if c.Timezone != "" {
+		loc, err := time.LoadLocation(c.Timezone)
+		if err != nil {
+			return "策略时区配置错误"
+		}
+		now = now.In(loc)
+	}
+	if len(c.Weekdays) > 0 && !slices.Contains(c.Weekdays, int(now.Weekday())) {
+		return "当前日期不在允许的星期内"
+	}
+	if c.StartTime != "" {
+		cur := now.Format(casbinConditionTimeLayout)
+		// 字符串比较即可 HH:MM 格式等长
+		in := cur >= c.StartTime && cur < c.EndTime
+		if c.StartTime > c.EndTime {
+			in = cur >= c.StartTime || cur < c.EndTime
+		}
+		if !in {
+			return "当前时间不在允许的时段 " + c.StartTime + "-" + c.EndTime + " 内"
+		}
+	}
+	return ""
+}
+
+// casbinCondFunc 注册到模型的匹配函数 casbinCond(p.cond, r.ip)
+// 请求ip为 * 时忽略条件 用于创建API密钥等只关心静态权限的场景 条件不存在时视为不满足
+func casbinCondFunc(args ...interface{}) (interface{}, error) {
+	if len(args) != 2 {
+		return false, errors.New("casbinCond 需要两个参数")
+	}
+	key, _ := args[0].(string)
+	ip, _ := args[1].(string)
+	if key == "" || key == request.CasbinCondNone || ip == request.CasbinCondNone {
+		return true, nil
+	}
+	c, ok := getCasbinCondition(key)
+	if !ok {
+		return false, nil
+	}
+	return checkCasbinCondition(c, ip, casbinNow()) == "", nil
+}
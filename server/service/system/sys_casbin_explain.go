@@ -0,0 +1,130 @@
+package system
+
+import (
+	"errors"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/flipped-aurora/gin-vue-admin/server/global"
+	"github.com/flipped-aurora/gin-vue-admin/server/model/system"
+	"github.com/flipped-aurora/gin-vue-admin/server/model/system/request"
+	systemRes "github.com/flipped-aurora/gin-vue-admin/server/model/system/response"
+	"github.com/gin-gonic/gin"
+)
+
+// Explain 模拟用户或角色访问接口 返回是否通过 命中的策略或未通过的原因 以及可见的菜单按钮
+func (casbinService *CasbinService) Explain(adminAuthorityId uint, req request.CasbinExplain) (res systemRes.CasbinExplainResponse, err error) {
+	active, ids, err := casbinService.simulateAuthorities(adminAuthorityId, req.CasbinSimulate)
+	if err != nil {
+		return res, err
+	}
+	res.ActiveAuthorityId, res.AuthorityIds = active, ids
+	res.CasbinDecision = casbinService.decide(ids, req.Path, strings.ToUpper(req.Method), simulateIp(req.Ip))
+	res.Menus, err = MenuServiceApp.GetMenuTree(active, ids...)
+	return res, err
+}
+
+// Simulate 模拟用户或角色访问全部已注册的路由
+func (casbinService *CasbinService) Simulate(adminAuthorityId uint, req request.CasbinSimulate) (res systemRes.CasbinSimulateResponse, err error) {
+	active, ids, err := casbinService.simulateAuthorities(adminAuthorityId, req)
+	if err != nil {
+		return res, err
+	}
+	res.ActiveAuthorityId, res.AuthorityIds = active, ids
+	routes := slices.Clone(global.GVA_ROUTERS)
+	slices.SortFunc(routes, func(a, b gin.RouteInfo) int {
+		if c := strings.Compare(a.Path, b.Path); c != 0 {
+			return c
+		}
+		return strings.Compare(a.Method, b.Method)
+	})
+	ip := simulateIp(req.Ip)
+	for _, route := range routes {
+		obj := strings.TrimPrefix(route.Path, global.GVA_CONFIG.System.RouterPrefix)
+		res.Routes = append(res.Routes, casbinService.decide(ids, obj, route.Method, ip))
+	}
+	return res, nil
+}
+
+// simulateAuthorities 确定模拟使用的当前角色和参与计算的全部角色 与 CasbinHandler 的计算方式一致
+func (casbinService *CasbinService) simulateAuthorities(adminAuthorityId uint, req request.CasbinSimulate) (uint, []uint, error) {
+	active := req.AuthorityId
+	if req.UserId != 0 {
+		var user system.SysUser
+		if err := global.GVA_DB.Select("id", "authority_id").First(&user, req.UserId).Error; err != nil {
+			return 0, nil, errors.New("用户不存在")
+		}
+		if active == 0 {
+			active = user.AuthorityId
+		} else if all, err := casbinService.UserAuthorityIds(req.UserId); err != nil {
+			return 0, nil, err
+		} else if !slices.Contains(all, active) {
+			return 0, nil, errors.New("用户没有该角色")
+		}
+	}
+	if active == 0 {
+		return 0, nil, errors.New("请指定用户或角色")
+	}
+	if err := AuthorityServiceApp.CheckAuthorityIDAuth(adminAuthorityId, active); err != nil {
+		return 0, nil, err
+	}
+	return active, casbinService.EffectiveAuthorityIds(req.UserId, active), nil
+}
+
+// decide 依次使用各角色校验 任一角色通过即通过 都不通过时说明命中的拒绝规则或不满足的条件
+func (casbinService *CasbinService) decide(ids []uint, obj, act, ip string) (d systemRes.CasbinDecision) {
+	d = systemRes.CasbinDecision{Method: act, Path: obj, AuthorityId: ids[0]}
+	e := casbinService.Casbin()
+	var deny *systemRes.CasbinPolicy
+	var denyId uint
+	for _, id := range ids {
+		ok, rule, err := e.EnforceEx(strconv.Itoa(int(id)), obj, act, ip)
+		if err != nil {
+			d.Reason = err.Error()
+			return d
+		}
+		if ok {
+			d.Allowed, d.AuthorityId, d.Policy = true, id, casbinPolicy(rule)
+			return d
+		}
+		if deny == nil && len(rule) > 0 {
+			deny, denyId = casbinPolicy(rule), id
+		}
+	}
+	if deny != nil {
+		d.AuthorityId, d.Policy = denyId, deny
+		d.Reason = "命中拒绝规则"
+		return d
+	}
+	if id, rule, reason := casbinService.conditionDenied(ids, obj, act, ip); reason != "" {
+		d.AuthorityId, d.Policy, d.Reason = id, casbinPolicy(rule), reason
+		return d
+	}
+	d.Reason = "没有匹配的策略"
+	return d
+}
+
+// simulateIp 未指定IP时忽略策略条件
+func simulateIp(ip string) string {
+	if ip = strings.TrimSpace(ip); ip == "" {
+		return request.CasbinCondNone
+	}
+	return ip
+}
+
+func casbinPolicy(rule []string) *systemRes.CasbinPolicy {
+	if len(rule) < 3 {
+		return nil
+	}
+	p := &systemRes.CasbinPolicy{AuthorityId: rule[0], CasbinInfo: request.CasbinInfo{Path: rule[1], Method: rule[2], Effect: request.CasbinEffectAllow}}
+	if len(rule) > 3 {
+		p.Effect = rule[3]
+	}
+	if len(rule) > 4 && rule[4] != request.CasbinCondNone {
+		if cond, ok := getCasbinCondition(rule[4]); ok {
+			p.Condition = &cond
+		}
+	}
+	return p
+}
@@ -0,0 +1,361 @@
+package system
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	gormadapter "github.com/casbin/gorm-adapter/v3"
+	"github.com/flipped-aurora/gin-vue-admin/server/global"
+	"github.com/flipped-aurora/gin-vue-admin/server/model/system"
+	"github.com/flipped-aurora/gin-vue-admin/server/model/system/request"
+	"github.com/gin-gonic/gin"
+)
+
+func setupUnionAuthTest(t *testing.T) {
+	db := setupTestDB(t, &system.SysUser{}, &system.SysAuthority{}, &system.SysUserAuthority{}, &system.SysBaseMenu{},
+		&system.SysBaseMenuParameter{}, &system.SysBaseMenuBtn{}, &system.SysAuthorityBtn{}, &system.SysCasbinCondition{})
+	global.GVA_CONFIG.System.UseUnionAuth = false
+
+	db.Create(&system.SysUser{Username: "alice", AuthorityId: 888})
+	db.Create(&[]system.SysUserAuthority{{SysUserId: 1, SysAuthorityAuthorityId: 888}, {SysUserId: 1, SysAuthorityAuthorityId: 9528}})
+	db.Create(&[]system.SysBaseMenu{{MenuLevel: 0, Name: "dashboard"}, {MenuLevel: 0, Name: "report"}, {MenuLevel: 0, Name: "admin"}})
+	db.Create(&[]system.SysAuthorityMenu{{MenuId: "1", AuthorityId: "888"}, {MenuId: "1", AuthorityId: "9528"}, {MenuId: "2", AuthorityId: "9528"}, {MenuId: "3", AuthorityId: "8881"}})
+	db.Create(&system.SysBaseMenuBtn{Name: "export", SysBaseMenuID: 2})
+	db.Create(&system.SysAuthorityBtn{AuthorityId: 9528, SysMenuID: 2, SysBaseMenuBtnID: 1})
+}
+
+// enforce 从固定IP发起请求 只关心结果
+func enforce(s *CasbinService, userId, authorityId uint, obj, act string) bool {
+	ok, _ := s.Enforce(userId, authorityId, obj, act, "10.0.0.1")
+	return ok
+}
+
+func menuNames(menus []system.SysMenu) []string {
+	names := make([]string, 0, len(menus))
+	for _, m := range menus {
+		names = append(names, m.Name)
+	}
+	return names
+}
+
+func TestCasbinService_UnionAuth(t *testing.T) {
+	setupUnionAuthTest(t)
+	s := &CasbinService{}
+	e := s.Casbin()
+	if e == nil {
+		t.Fatalf("Casbin() returned nil")
+	}
+	if _, err := e.AddPolicies([][]string{{"888", "/user/getUserInfo", "GET", "allow", "*"}, {"9528", "/report/list", "GET", "allow", "*"}}); err != nil {
+		t.Fatalf("AddPolicies() error = %v", err)
+	}
+
+	// 默认只使用当前角色
+	if got := s.EffectiveAuthorityIds(1, 888); !reflect.DeepEqual(got, []uint{888}) {
+		t.Fatalf("EffectiveAuthorityIds() = %v", got)
+	}
+	if enforce(s, 1, 888, "/report/list", "GET") {
+		t.Fatalf("Enforce() should only check the active authority")
+	}
+	menus, err := MenuServiceApp.GetMenuTree(888, s.EffectiveAuthorityIds(1, 888)...)
+	if err != nil || !reflect.DeepEqual(menuNames(menus), []string{"dashboard"}) {
+		t.Fatalf("GetMenuTree() = %v, %v", menuNames(menus), err)
+	}
+
+	global.GVA_CONFIG.System.UseUnionAuth = true
+	if got := s.EffectiveAuthorityIds(1, 888); !reflect.DeepEqual(got, []uint{888, 9528}) {
+		t.Fatalf("EffectiveAuthorityIds() union = %v", got)
+	}
+	tests := []struct {
+		name string
+		obj  string
+		want bool
+	}{
+		{name: "当前角色的接口", obj: "/user/getUserInfo", want: true},
+		{name: "其他角色的接口", obj: "/report/list", want: true},
+		{name: "未分配的接口", obj: "/api/deleteApi", want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := enforce(s, 1, 888, tt.obj, "GET"); got != tt.want {
+				t.Fatalf("Enforce(%s) = %v, want %v", tt.obj, got, tt.want)
+			}
+		})
+	}
+	menus, err = MenuServiceApp.GetMenuTree(888, s.EffectiveAuthorityIds(1, 888)...)
+	if err != nil || !reflect.DeepEqual(menuNames(menus), []string{"dashboard", "report"}) {
+		t.Fatalf("GetMenuTree() union = %v, %v", menuNames(menus), err)
+	}
+	if _, ok := menus[1].Btns["export"]; !ok {
+		t.Fatalf("GetMenuTree() union btns = %v", menus[1].Btns)
+	}
+
+	// 未开启redis时不使用进程内缓存 其他实例修改的角色立即生效
+	global.GVA_DB.Delete(&system.SysUserAuthority{}, "sys_user_id = ? AND sys_authority_authority_id = ?", 1, 9528)
+	if enforce(s, 1, 888, "/report/list", "GET") {
+		t.Fatalf("Enforce() should reload authorities without shared cache")
+	}
+	if err = SecurityStampServiceApp.Bump(1); err != nil {
+		t.Fatalf("Bump() error = %v", err)
+	}
+	if enforce(s, 1, 888, "/report/list", "GET") {
+		t.Fatalf("Enforce() should reload authorities after bump")
+	}
+}
+
+func TestCasbinService_UpdateAuthorityInherit(t *testing.T) {
+	setupUnionAuthTest(t)
+	s := &CasbinService{}
+	e := s.Casbin()
+	if _, err := e.AddPolicies([][]string{{"100", "/report/list", "GET", "allow", "*"}, {"110", "/report/export", "GET", "allow", "*"}}); err != nil {
+		t.Fatalf("AddPolicies() error = %v", err)
+	}
+	if err := s.UpdateAuthorityInherit(110, 100, true); err != nil {
+		t.Fatalf("UpdateAuthorityInherit() error = %v", err)
+	}
+	if err := s.UpdateAuthorityInherit(111, 110, true); err != nil {
+		t.Fatalf("UpdateAuthorityInherit() error = %v", err)
+	}
+	tests := []struct {
+		name        string
+		authorityId uint
+		obj         string
+		want        bool
+	}{
+		{name: "继承父角色", authorityId: 110, obj: "/report/list", want: true},
+		{name: "继承祖先角色", authorityId: 111, obj: "/report/list", want: true},
+		{name: "继承父角色自身的权限", authorityId: 111, obj: "/report/export", want: true},
+		{name: "父角色不继承子角色", authorityId: 100, obj: "/report/export", want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := enforce(s, 0, tt.authorityId, tt.obj, "GET"); got != tt.want {
+				t.Fatalf("Enforce(%d, %s) = %v, want %v", tt.authorityId, tt.obj, got, tt.want)
+			}
+		})
+	}
+
+	if err := s.UpdateAuthorityInherit(100, 111, true); err == nil {
+		t.Fatalf("UpdateAuthorityInherit() cycle should fail")
+	}
+	if ok, _ := e.HasGroupingPolicy("110", "100"); !ok {
+		t.Fatalf("cycle check should keep existing rules")
+	}
+
+	// 关闭继承后子角色及其下级不再拥有父角色的权限
+	if err := s.UpdateAuthorityInherit(110, 100, false); err != nil {
+		t.Fatalf("UpdateAuthorityInherit() disable error = %v", err)
+	}
+	if enforce(s, 0, 110, "/report/list", "GET") || enforce(s, 0, 111, "/report/list", "GET") {
+		t.Fatalf("Enforce() should not inherit after disable")
+	}
+	if !enforce(s, 0, 111, "/report/export", "GET") {
+		t.Fatalf("Enforce() should keep inheriting from 110")
+	}
+}
+
+func TestCasbinService_DenyPolicy(t *testing.T) {
+	setupUnionAuthTest(t)
+	s := &CasbinService{}
+	err := s.UpdateCasbin(0, 0, "", 120, []request.CasbinInfo{
+		{Path: "/user/*", Method: "POST"},
+		{Path: "/user/deleteUser", Method: "POST", Effect: request.CasbinEffectDeny},
+	})
+	if err != nil {
+		t.Fatalf("UpdateCasbin() error = %v", err)
+	}
+	if err = s.UpdateAuthorityInherit(121, 120, true); err != nil {
+		t.Fatalf("UpdateAuthorityInherit() error = %v", err)
+	}
+	tests := []struct {
+		name        string
+		authorityId uint
+		obj         string
+		want        bool
+	}{
+		{name: "通配符允许", authorityId: 120, obj: "/user/setUserInfo", want: true},
+		{name: "拒绝优先", authorityId: 120, obj: "/user/deleteUser", want: false},
+		{name: "子角色继承拒绝", authorityId: 121, obj: "/user/deleteUser", want: false},
+		{name: "子角色继承允许", authorityId: 121, obj: "/user/setUserInfo", want: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := enforce(s, 0, tt.authorityId, tt.obj, "POST"); got != tt.want {
+				t.Fatalf("Enforce(%d, %s) = %v, want %v", tt.authorityId, tt.obj, got, tt.want)
+			}
+		})
+	}
+
+	paths := s.GetPolicyPathByAuthorityId(120)
+	if len(paths) != 2 || paths[1].Effect != request.CasbinEffectDeny {
+		t.Fatalf("GetPolicyPathByAuthorityId() = %+v", paths)
+	}
+	err = s.UpdateCasbin(0, 0, "", 120, []request.CasbinInfo{
+		{Path: "/user/deleteUser", Method: "POST"},
+		{Path: "/user/deleteUser", Method: "POST", Effect: request.CasbinEffectDeny},
+	})
+	if err == nil {
+		t.Fatalf("UpdateCasbin() conflicting effects should fail")
+	}
+}
+
+func TestMigrateCasbinPolicy(t *testing.T) {
+	setupUnionAuthTest(t)
+	db := global.GVA_DB
+	if err := db.AutoMigrate(&gormadapter.CasbinRule{}); err != nil {
+		t.Fatalf("migrate failed: %v", err)
+	}
+	db.Create(&[]gormadapter.CasbinRule{
+		{Ptype: "p", V0: "888", V1: "/user/getUserInfo", V2: "GET"},
+		{Ptype: "p", V0: "888", V1: "/user/deleteUser", V2: "DELETE", V3: "deny"},
+		{Ptype: "g", V0: "110", V1: "100"},
+	})
+	if err := migrateCasbinPolicy(db); err != nil {
+		t.Fatalf("migrateCasbinPolicy() error = %v", err)
+	}
+	var rules []gormadapter.CasbinRule
+	db.Order("id").Find(&rules)
+	got := []string{rules[0].V3, rules[1].V3, rules[2].V3}
+	if !reflect.DeepEqual(got, []string{"allow", "deny", ""}) {
+		t.Fatalf("migrated effects = %v", got)
+	}
+	got = []string{rules[0].V4, rules[1].V4, rules[2].V4}
+	if !reflect.DeepEqual(got, []string{"*", "*", ""}) {
+		t.Fatalf("migrated conditions = %v", got)
+	}
+}
+
+func TestCasbinService_ConditionPolicy(t *testing.T) {
+	setupUnionAuthTest(t)
+	s := &CasbinService{}
+	defer func() { casbinNow = time.Now }()
+	office := &system.CasbinCondition{Cidrs: []string{"10.0.0.0/8"}, Weekdays: []int{5, 1, 2, 3, 4}, StartTime: "09:00", EndTime: "18:00", Timezone: "Asia/Shanghai"}
+	err := s.UpdateCasbin(0, 0, "", 130, []request.CasbinInfo{
+		{Path: "/report/list", Method: "GET", Condition: office},
+		{Path: "/report/night", Method: "GET", Condition: &system.CasbinCondition{StartTime: "22:00", EndTime: "06:00", Timezone: "UTC"}},
+		{Path: "/order/*", Method: "GET"},
+		{Path: "/order/export", Method: "GET", Effect: request.CasbinEffectDeny, Condition: &system.CasbinCondition{Cidrs: []string{"192.168.0.0/16"}}},
+	})
+	if err != nil {
+		t.Fatalf("UpdateCasbin() error = %v", err)
+	}
+	monday := time.Date(2026, 10, 19, 2, 0, 0, 0, time.UTC) // 上海时间周一10点
+	tests := []struct {
+		name   string
+		now    time.Time
+		obj    string
+		ip     string
+		want   bool
+		reason string
+	}{
+		{name: "满足全部条件", now: monday, obj: "/report/list", ip: "10.1.2.3", want: true},
+		{name: "IP不在范围内", now: monday, obj: "/report/list", ip: "192.168.1.1", reason: "192.168.1.1"},
+		{name: "周末", now: monday.AddDate(0, 0, 5), obj: "/report/list", ip: "10.1.2.3", reason: "星期"},
+		{name: "下班时间", now: monday.Add(10 * time.Hour), obj: "/report/list", ip: "10.1.2.3", reason: "09:00-18:00"},
+		{name: "跨越午夜的时段内", now: monday, obj: "/report/night", ip: "10.1.2.3", want: true},
+		{name: "跨越午夜的时段外", now: monday.Add(10 * time.Hour), obj: "/report/night", ip: "10.1.2.3", reason: "22:00-06:00"},
+		{name: "忽略条件", now: monday.Add(10 * time.Hour), obj: "/report/night", ip: request.CasbinCondNone, want: true},
+		{name: "拒绝规则条件不满足", now: monday, obj: "/order/export", ip: "10.1.2.3", want: true},
+		{name: "拒绝规则条件满足", now: monday, obj: "/order/export", ip: "192.168.1.1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			casbinNow = func() time.Time { return tt.now }
+			ok, reason := s.Enforce(0, 130, tt.obj, "GET", tt.ip)
+			if ok != tt.want || !strings.Contains(reason, tt.reason) || (tt.reason == "") != (reason == "") {
+				t.Fatalf("Enforce(%s, %s) = %v, %q, want %v, %q", tt.obj, tt.ip, ok, reason, tt.want, tt.reason)
+			}
+		})
+	}
+
+	paths := s.GetPolicyPathByAuthorityId(130)
+	if len(paths) != 4 || paths[0].Condition == nil || !reflect.DeepEqual(paths[0].Condition.Weekdays, []int{1, 2, 3, 4, 5}) || paths[2].Condition != nil {
+		t.Fatalf("GetPolicyPathByAuthorityId() = %+v", paths)
+	}
+
+	invalid := []*system.CasbinCondition{
+		{Cidrs: []string{"10.0.0.300"}},
+		{Weekdays: []int{7}},
+		{StartTime: "09:00"},
+		{StartTime: "9点", EndTime: "18:00"},
+		{StartTime: "09:00", EndTime: "18:00", Timezone: "Mars/Base"},
+	}
+	for _, cond := range invalid {
+		if err = s.UpdateCasbin(0, 0, "", 130, []request.CasbinInfo{{Path: "/report/list", Method: "GET", Condition: cond}}); err == nil {
+			t.Fatalf("UpdateCasbin(%+v) should fail", cond)
+		}
+	}
+	if got := s.GetPolicyPathByAuthorityId(130); len(got) != 4 {
+		t.Fatalf("invalid condition should keep existing rules, got %+v", got)
+	}
+}
+
+func TestCasbinService_Explain(t *testing.T) {
+	setupUnionAuthTest(t)
+	s := &CasbinService{}
+	err := s.UpdateCasbin(0, 0, "", 140, []request.CasbinInfo{
+		{Path: "/order/*", Method: "GET"},
+		{Path: "/order/secret", Method: "GET", Effect: request.CasbinEffectDeny},
+		{Path: "/report/list", Method: "GET", Condition: &system.CasbinCondition{Cidrs: []string{"10.0.0.0/8"}}},
+	})
+	if err != nil {
+		t.Fatalf("UpdateCasbin() error = %v", err)
+	}
+	tests := []struct {
+		name    string
+		path    string
+		ip      string
+		allowed bool
+		policy  string
+		reason  string
+	}{
+		{name: "通配符允许", path: "/order/list", allowed: true, policy: "/order/*"},
+		{name: "命中拒绝规则", path: "/order/secret", policy: "/order/secret", reason: "命中拒绝规则"},
+		{name: "条件不满足", path: "/report/list", ip: "192.168.1.1", policy: "/report/list", reason: "192.168.1.1"},
+		{name: "未指定IP忽略条件", path: "/report/list", allowed: true, policy: "/report/list"},
+		{name: "没有匹配的策略", path: "/user/list", reason: "没有匹配的策略"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := request.CasbinExplain{CasbinSimulate: request.CasbinSimulate{AuthorityId: 140, Ip: tt.ip}, Method: "get", Path: tt.path}
+			res, err := s.Explain(0, req)
+			if err != nil {
+				t.Fatalf("Explain() error = %v", err)
+			}
+			policy := ""
+			if res.Policy != nil {
+				policy = res.Policy.Path
+			}
+			if res.Allowed != tt.allowed || policy != tt.policy || !strings.Contains(res.Reason, tt.reason) || res.ActiveAuthorityId != 140 {
+				t.Fatalf("Explain(%s) = %+v", tt.path, res.CasbinDecision)
+			}
+		})
+	}
+
+	// 按用户模拟时使用用户的当前角色 只能指定用户拥有的角色
+	global.GVA_CONFIG.System.UseUnionAuth = true
+	res, err := s.Explain(0, request.CasbinExplain{CasbinSimulate: request.CasbinSimulate{UserId: 1}, Method: "GET", Path: "/user/list"})
+	if err != nil || res.ActiveAuthorityId != 888 || !reflect.DeepEqual(res.AuthorityIds, []uint{888, 9528}) {
+		t.Fatalf("Explain() by user = %+v, %v", res, err)
+	}
+	if !reflect.DeepEqual(menuNames(res.Menus), []string{"dashboard", "report"}) {
+		t.Fatalf("Explain() menus = %v", menuNames(res.Menus))
+	}
+	if _, err = s.Explain(0, request.CasbinExplain{CasbinSimulate: request.CasbinSimulate{UserId: 1, AuthorityId: 140}}); err == nil {
+		t.Fatalf("Explain() should reject authority the user does not have")
+	}
+	if _, err = s.Explain(0, request.CasbinExplain{}); err == nil {
+		t.Fatalf("Explain() without user or authority should fail")
+	}
+
+	global.GVA_ROUTERS = gin.RoutesInfo{{Method: "GET", Path: "/order/secret"}, {Method: "GET", Path: "/order/list"}}
+	defer func() { global.GVA_ROUTERS = nil }()
+	routes, err := s.Simulate(0, request.CasbinSimulate{AuthorityId: 140})
+	if err != nil || len(routes.Routes) != 2 {
+		t.Fatalf("Simulate() = %+v, %v", routes, err)
+	}
+	if routes.Routes[0].Path != "/order/list" || !routes.Routes[0].Allowed || routes.Routes[1].Allowed {
+		t.Fatalf("Simulate() routes = %+v", routes.Routes)
+	}
+}
@@ -0,0 +1,155 @@
+package system
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/gofrs/uuid/v5"
+	"github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+
+	"github.com/flipped-aurora/gin-vue-admin/server/global"
+	"github.com/flipped-aurora/gin-vue-admin/server/model/system"
+)
+
+const (
+	casbinPolicyChannel            = "gva:casbin:policy"
+	casbinPollIntervalDefault      = 5 * time.Second
+	casbinVersionRowId        uint = 1
+)
+
+// casbinInstanceId 当前实例的标识 订阅时忽略自己发出的通知
+var casbinInstanceId = uuid.Must(uuid.NewV4()).String()
+
+// casbinWatcher 两种同步方式共用的回调管理 实现 persist.Watcher 的 SetUpdateCallback 和 Close
+type casbinWatcher struct {
+	mu       sync.RWMutex
+	callback func(string)
+	stop     chan struct{}
+	once     sync.Once
+}
+
+func (w *casbinWatcher) SetUpdateCallback(callback func(string)) error {
+	w.mu.Lock()
+	w.callback = callback
+	w.mu.Unlock()
+	return nil
+}
+
+func (w *casbinWatcher) notify(msg string) {
+	w.mu.RLock()
+	callback := w.callback
+	w.mu.RUnlock()
+	if callback != nil {
+		callback(msg)
+	}
+}
+
+func (w *casbinWatcher) Close() {
+	w.once.Do(func() { close(w.stop) })
+}
+
+// redisCasbinWatcher 策略变化时通过redis广播 其他实例收到后重新加载
+type redisCasbinWatcher struct {
+	casbinWatcher
+	client redis.UniversalClient
+}
+
+func newRedisCasbinWatcher(client redis.UniversalClient) *redisCasbinWatcher {
+	w := &redisCasbinWatcher{casbinWatcher: casbinWatcher{stop: make(chan struct{})}, client: client}
+	go w.run()
+	return w
+}
+
+func (w *redisCasbinWatcher) Update() error {
+	return w.client.Publish(context.Background(), casbinPolicyChannel, casbinInstanceId).Err()
+}
+
+// run 订阅策略变化 断开后重新订阅成功时重新加载一次 补上断开期间错过的通知
+func (w *redisCasbinWatcher) run() {
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		<-w.stop
+		cancel()
+	}()
+	pubsub := w.client.Subscribe(ctx, casbinPolicyChannel)
+	defer pubsub.Close()
+	subscribed, disconnected := false, false
+	for {
+		msg, err := pubsub.Receive(ctx)
+		if err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			if !disconnected {
+				disconnected = true
+				global.GVA_LOG.Warn("casbin策略订阅断开, 等待重新订阅", zap.Error(err))
+			}
+			time.Sleep(time.Second)
+			continue
+		}
+		switch m := msg.(type) {
+		case *redis.Subscription:
+			if subscribed && disconnected {
+				w.notify("resubscribe")
+			}
+			subscribed, disconnected = true, false
+		case *redis.Message:
+			if m.Payload != casbinInstanceId {
+				w.notify(m.Payload)
+			}
+		}
+	}
+}
+
+// dbCasbinWatcher 未开启redis时使用 策略变化时递增数据库中的版本号 各实例定时轮询
+type dbCasbinWatcher struct {
+	casbinWatcher
+	db       *gorm.DB
+	interval time.Duration
+	version  int64
+}
+
+func newDBCasbinWatcher(db *gorm.DB, interval time.Duration) (*dbCasbinWatcher, error) {
+	row := system.SysCasbinVersion{ID: casbinVersionRowId}
+	if err := db.FirstOrCreate(&row, system.SysCasbinVersion{ID: casbinVersionRowId}).Error; err != nil {
+		return nil, err
+	}
+	w := &dbCasbinWatcher{casbinWatcher: casbinWatcher{stop: make(chan struct{})}, db: db, interval: interval, version: row.Version}
+	go w.run()
+	return w, nil
+}
+
+func (w *dbCasbinWatcher) Update() error {
+	return w.db.Model(&system.SysCasbinVersion{}).Where("id = ?", casbinVersionRowId).
+		Update("version", gorm.Expr("version + ?", 1)).Error
+}
+
+func (w *dbCasbinWatcher) run() {
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-w.stop:
+			return
+		case <-ticker.C:
+			w.poll()
+		}
+	}
+}
+
+// poll 版本号变化时重新加载 本实例自己的修改也会触发一次 不影响结果
+func (w *dbCasbinWatcher) poll() {
+	var row system.SysCasbinVersion
+	if err := w.db.Select("version").First(&row, casbinVersionRowId).Error; err != nil {
+		global.GVA_LOG.Error("查询casbin策略版本失败!", zap.Error(err))
+		return
+	}
+	if row.Version == w.version {
+		return
+	}
+	w.version = row.Version
+	w.notify("poll")
+}
@@ -0,0 +1,47 @@
+package system
+
+import (
+	"testing"
+	"time"
+
+	"github.com/flipped-aurora/gin-vue-admin/server/model/system"
+)
+
+func TestDBCasbinWatcher(t *testing.T) {
+	db := setupTestDB(t, &system.SysCasbinVersion{})
+
+	// 两个实例共用同一个数据库 定时轮询交给测试手动触发
+	a, err := newDBCasbinWatcher(db, time.Hour)
+	if err != nil {
+		t.Fatalf("newDBCasbinWatcher() error = %v", err)
+	}
+	defer a.Close()
+	b, err := newDBCasbinWatcher(db, time.Hour)
+	if err != nil {
+		t.Fatalf("newDBCasbinWatcher() error = %v", err)
+	}
+	defer b.Close()
+	reloads := 0
+	_ = b.SetUpdateCallback(func(string) { reloads++ })
+
+	b.poll()
+	if reloads != 0 {
+		t.Fatalf("poll() without changes reloads = %d", reloads)
+	}
+	if err = a.Update(); err != nil {
+		t.Fatalf("Update() error = %v", err)
+	}
+	if err = a.Update(); err != nil {
+		t.Fatalf("Update() error = %v", err)
+	}
+	b.poll()
+	b.poll()
+	if reloads != 1 {
+		t.Fatalf("poll() after other instance updates reloads = %d, want 1", reloads)
+	}
+	var row system.SysCasbinVersion
+	db.First(&row, casbinVersionRowId)
+	if row.Version != 2 || b.version != 2 {
+		t.Fatalf("version = %d, watcher version = %d", row.Version, b.version)
+	}
+}
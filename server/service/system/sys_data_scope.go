@@ -0,0 +1,267 @@
+package system
+
+import (
+	"errors"
+	"reflect"
+	"slices"
+	"strings"
+
+	"github.com/flipped-aurora/gin-vue-admin/server/global"
+	"github.com/flipped-aurora/gin-vue-admin/server/model/system"
+	"github.com/flipped-aurora/gin-vue-admin/server/model/system/request"
+	"github.com/flipped-aurora/gin-vue-admin/server/utils"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/schema"
+)
+
+var dataScopes = []string{system.DataScopeSelf, system.DataScopeAuthority, system.DataScopeSubtree, system.DataScopeListed, system.DataScopeAll}
+
+type DataScopeService struct{}
+
+var DataScopeServiceApp = new(DataScopeService)
+
+// GetDataScopes 获取角色配置的数据范围
+func (dataScopeService *DataScopeService) GetDataScopes(authorityId uint) (list []request.DataScopeInfo, err error) {
+	var rows []system.SysDataScope
+	err = global.GVA_DB.Where("authority_id = ?", authorityId).Order("resource").Find(&rows).Error
+	for _, row := range rows {
+		list = append(list, request.DataScopeInfo{Resource: row.Resource, Scope: row.Scope})
+	}
+	return list, err
+}
+
+// SetDataScopes 以传入的配置替换角色的数据范围 并保存角色权限的版本
+func (dataScopeService *DataScopeService) SetDataScopes(adminAuthorityId, operatorId uint, operatorName string, req request.SetDataScopes) error {
+	return AuthorityVersionServiceApp.Track(req.AuthorityId, operatorId, operatorName, request.AuthorityVersionSetDataScopes, func() error {
+		return dataScopeService.setDataScopes(adminAuthorityId, req)
+	})
+}
+
+func (dataScopeService *DataScopeService) setDataScopes(adminAuthorityId uint, req request.SetDataScopes) error {
+	if err := AuthorityServiceApp.CheckAuthorityIDAuth(adminAuthorityId, req.AuthorityId); err != nil {
+		return err
+	}
+	rows, err := dataScopeRows(req.AuthorityId, req.Scopes)
+	if err != nil {
+		return err
+	}
+	return global.GVA_DB.Transaction(func(tx *gorm.DB) error {
+		return replaceDataScopes(tx, req.AuthorityId, rows)
+	})
+}
+
+// dataScopeRows 校验数据范围配置 转为角色的数据范围记录
+func dataScopeRows(authorityId uint, scopes []request.DataScopeInfo) ([]system.SysDataScope, error) {
+	rows := make([]system.SysDataScope, 0, len(scopes))
+	seen := map[string]bool{}
+	for _, v := range scopes {
+		resource := strings.TrimSpace(v.Resource)
+		if resource == "" {
+			return nil, errors.New("资源不能为空")
+		}
+		if !slices.Contains(dataScopes, v.Scope) {
+			return nil, errors.New("数据范围不存在: " + v.Scope)
+		}
+		if seen[resource] {
+			return nil, errors.New("资源重复: " + resource)
+		}
+		seen[resource] = true
+		rows = append(rows, system.SysDataScope{AuthorityId: authorityId, Resource: resource, Scope: v.Scope})
+	}
+	return rows, nil
+}
+
+// replaceDataScopes 在事务中以传入的记录替换角色的数据范围
+func replaceDataScopes(tx *gorm.DB, authorityId uint, rows []system.SysDataScope) error {
+	if err := tx.Unscoped().Where("authority_id = ?", authorityId).Delete(&system.SysDataScope{}).Error; err != nil {
+		return err
+	}
+	if len(rows) == 0 {
+		return nil
+	}
+	return tx.Create(&rows).Error
+}
+
+// Condition 用户访问资源时的过滤条件 返回nil表示不过滤
+// 开启角色权限并集时 用户各角色可见的数据取并集
+// 数据范围配置和角色树始终从系统库读取 插件注册在业务库上时业务库中没有这些表
+func (dataScopeService *DataScopeService) Condition(user utils.DataScopeUser, resource, userColumn, authorityColumn string) (clause.Expression, error) {
+	db := global.GVA_DB
+	ids := CasbinServiceApp.EffectiveAuthorityIds(user.UserId, user.AuthorityId)
+	var rows []system.SysDataScope
+	err := db.Where("authority_id in ? AND resource in ?", ids, []string{resource, system.DataScopeDefaultResource}).Find(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+	scopes := make(map[uint]string, len(rows))
+	for _, row := range rows {
+		if row.Resource == resource || scopes[row.AuthorityId] == "" {
+			scopes[row.AuthorityId] = row.Scope
+		}
+	}
+	self := false
+	var authorityIds, subtree, listed []uint
+	for _, id := range ids {
+		switch scopes[id] {
+		case system.DataScopeAll:
+			return nil, nil
+		case system.DataScopeSelf:
+			self = true
+		case system.DataScopeAuthority:
+			authorityIds = append(authorityIds, id)
+		case system.DataScopeSubtree:
+			subtree = append(subtree, id)
+		default:
+			listed = append(listed, id)
+		}
+	}
+	if len(subtree) > 0 {
+		children, err := dataScopeService.subtree(db, subtree)
+		if err != nil {
+			return nil, err
+		}
+		authorityIds = append(authorityIds, children...)
+	}
+	if len(listed) > 0 {
+		var authorities []system.SysAuthority
+		if err = db.Preload("DataAuthorityId").Find(&authorities, "authority_id in ?", listed).Error; err != nil {
+			return nil, err
+		}
+		for _, a := range authorities {
+			for _, d := range a.DataAuthorityId {
+				authorityIds = append(authorityIds, d.AuthorityId)
+			}
+		}
+	}
+
+	var exprs []clause.Expression
+	if self && userColumn != "" {
+		exprs = append(exprs, clause.Eq{Column: clause.Column{Table: clause.CurrentTable, Name: userColumn}, Value: user.UserId})
+	}
+	if len(authorityIds) > 0 && authorityColumn != "" {
+		slices.Sort(authorityIds)
+		values := make([]interface{}, 0, len(authorityIds))
+		for _, id := range slices.Compact(authorityIds) {
+			values = append(values, id)
+		}
+		exprs = append(exprs, clause.IN{Column: clause.Column{Table: clause.CurrentTable, Name: authorityColumn}, Values: values})
+	}
+	switch len(exprs) {
+	case 0:
+		return clause.Expr{SQL: "1 <> 1"}, nil
+	case 1:
+		return exprs[0], nil
+	}
+	return clause.Or(exprs...), nil
+}
+
+// subtree 角色及其全部下级角色
+func (dataScopeService *DataScopeService) subtree(db *gorm.DB, roots []uint) ([]uint, error) {
+	var authorities []system.SysAuthority
+	if err := db.Select("authority_id", "parent_id").Find(&authorities).Error; err != nil {
+		return nil, err
+	}
+	children := map[uint][]uint{}
+	for _, a := range authorities {
+		children[authorityParentId(a)] = append(children[authorityParentId(a)], a.AuthorityId)
+	}
+	ids := slices.Clone(roots)
+	for i := 0; i < len(ids); i++ {
+		for _, child := range children[ids[i]] {
+			if !slices.Contains(ids, child) {
+				ids = append(ids, child)
+			}
+		}
+	}
+	return ids, nil
+}
+
+// UseGormPlugins 注册数据范围过滤和字段权限插件 已注册时跳过
+func UseGormPlugins(db *gorm.DB) error {
+	for _, plugin := range []gorm.Plugin{DataScopePlugin{}, FieldRulePlugin{}} {
+		if err := db.Use(plugin); err != nil && !errors.Is(err, gorm.ErrRegistered) {
+			return err
+		}
+	}
+	return nil
+}
+
+// DataScopePlugin 为实现了 system.DataScoped 的模型自动追加数据范围条件
+// 只对带有当前用户的ctx生效 系统任务等不带用户的查询不受影响
+type DataScopePlugin struct{}
+
+func (DataScopePlugin) Name() string {
+	return "gva:data_scope"
+}
+
+func (DataScopePlugin) Initialize(db *gorm.DB) error {
+	name := "gva:data_scope"
+	if err := db.Callback().Query().Before("gorm:query").Register(name, dataScopeQuery); err != nil {
+		return err
+	}
+	if err := db.Callback().Row().Before("gorm:row").Register(name, dataScopeQuery); err != nil {
+		return err
+	}
+	if err := db.Callback().Update().Before("gorm:update").Register(name, dataScopeMutation); err != nil {
+		return err
+	}
+	return db.Callback().Delete().Before("gorm:delete").Register(name, dataScopeMutation)
+}
+
+func dataScopeQuery(db *gorm.DB) {
+	applyDataScope(db, false)
+}
+
+func dataScopeMutation(db *gorm.DB) {
+	applyDataScope(db, true)
+}
+
+func applyDataScope(db *gorm.DB, mutation bool) {
+	stmt := db.Statement
+	if db.Error != nil || stmt.Schema == nil || stmt.SQL.Len() > 0 {
+		return
+	}
+	user, ok := utils.DataScopeFromContext(stmt.Context)
+	if !ok {
+		return
+	}
+	scoped, ok := reflect.New(stmt.Schema.ModelType).Interface().(system.DataScoped)
+	if !ok {
+		return
+	}
+	// 更新和删除时先按主键补上条件 避免追加数据范围后绕过gorm对缺少条件的检查
+	if _, ok = stmt.Clauses["WHERE"]; mutation && !ok && !db.AllowGlobalUpdate {
+		for _, value := range []reflect.Value{stmt.ReflectValue, reflect.ValueOf(stmt.Model)} {
+			if !value.IsValid() {
+				continue
+			}
+			_, queryValues := schema.GetIdentityFieldValuesMap(stmt.Context, reflect.Indirect(value), stmt.Schema.PrimaryFields)
+			column, values := schema.ToQueryValues(stmt.Table, stmt.Schema.PrimaryFieldDBNames, queryValues)
+			if len(values) > 0 {
+				stmt.AddClause(clause.Where{Exprs: []clause.Expression{clause.IN{Column: column, Values: values}}})
+				break
+			}
+		}
+		if _, ok = stmt.Clauses["WHERE"]; !ok {
+			return
+		}
+	}
+	userColumn, authorityColumn := scoped.DataScopeColumns()
+	cond, err := DataScopeServiceApp.Condition(user, stmt.Schema.Table, userColumn, authorityColumn)
+	if err != nil {
+		_ = db.AddError(err)
+		return
+	}
+	if cond == nil {
+		return
+	}
+	// 已有条件整体加括号 避免其中的 OR 与数据范围条件优先级混淆
+	c, ok := stmt.Clauses["WHERE"]
+	if where, isWhere := c.Expression.(clause.Where); ok && isWhere && len(where.Exprs) > 0 {
+		c.Expression = clause.Where{Exprs: []clause.Expression{clause.AndConditions{Exprs: where.Exprs}, cond}}
+		stmt.Clauses["WHERE"] = c
+		return
+	}
+	stmt.AddClause(clause.Where{Exprs: []clause.Expression{cond}})
+}
@@ -0,0 +1,151 @@
+package system
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+
+	"github.com/flipped-aurora/gin-vue-admin/server/global"
+	"github.com/flipped-aurora/gin-vue-admin/server/model/system"
+	"github.com/flipped-aurora/gin-vue-admin/server/model/system/request"
+	"github.com/flipped-aurora/gin-vue-admin/server/utils"
+	"gorm.io/gorm"
+)
+
+type dataScopeRecord struct {
+	ID          uint
+	Name        string
+	CreatedBy   uint
+	AuthorityId uint
+}
+
+func (dataScopeRecord) DataScopeColumns() (string, string) {
+	return "created_by", "authority_id"
+}
+
+func setupDataScopeTest(t *testing.T) {
+	setupUnionAuthTest(t)
+	db := global.GVA_DB
+	if err := db.Use(DataScopePlugin{}); err != nil {
+		t.Fatalf("register plugin failed: %v", err)
+	}
+	if err := db.AutoMigrate(&system.SysDataScope{}, &dataScopeRecord{}); err != nil {
+		t.Fatalf("migrate failed: %v", err)
+	}
+	db.Create(&[]system.SysAuthority{
+		{AuthorityId: 150, AuthorityName: "部门", ParentId: utils.Pointer[uint](0)},
+		{AuthorityId: 151, AuthorityName: "小组", ParentId: utils.Pointer[uint](150)},
+		{AuthorityId: 152, AuthorityName: "组员", ParentId: utils.Pointer[uint](151)},
+		{AuthorityId: 160, AuthorityName: "其他部门", ParentId: utils.Pointer[uint](0)},
+	})
+	db.Model(&system.SysAuthority{AuthorityId: 150}).Association("DataAuthorityId").Replace([]*system.SysAuthority{{AuthorityId: 150}, {AuthorityId: 160}})
+	db.Create(&[]dataScopeRecord{
+		{Name: "r1", CreatedBy: 1, AuthorityId: 150},
+		{Name: "r2", CreatedBy: 2, AuthorityId: 151},
+		{Name: "r3", CreatedBy: 3, AuthorityId: 152},
+		{Name: "r4", CreatedBy: 4, AuthorityId: 160},
+	})
+}
+
+func dataScopeNames(t *testing.T, db *gorm.DB) []string {
+	t.Helper()
+	var records []dataScopeRecord
+	if err := db.Order("id").Find(&records).Error; err != nil {
+		t.Fatalf("Find() error = %v", err)
+	}
+	var names []string
+	for _, r := range records {
+		names = append(names, r.Name)
+	}
+	return names
+}
+
+func TestDataScopePlugin(t *testing.T) {
+	setupDataScopeTest(t)
+	s := DataScopeServiceApp
+	ctx := utils.WithDataScope(context.Background(), 1, 150)
+	db := func() *gorm.DB { return global.GVA_DB.WithContext(ctx) }
+	setScopes := func(authorityId uint, scopes ...request.DataScopeInfo) {
+		t.Helper()
+		if err := s.SetDataScopes(0, 0, "", request.SetDataScopes{AuthorityId: authorityId, Scopes: scopes}); err != nil {
+			t.Fatalf("SetDataScopes() error = %v", err)
+		}
+	}
+
+	tests := []struct {
+		name   string
+		scopes []request.DataScopeInfo
+		want   []string
+	}{
+		{"未配置时使用资源权限", nil, []string{"r1", "r4"}},
+		{"本人", []request.DataScopeInfo{{Resource: "*", Scope: system.DataScopeSelf}}, []string{"r1"}},
+		{"资源配置优先于默认", []request.DataScopeInfo{{Resource: "*", Scope: system.DataScopeSelf}, {Resource: "data_scope_records", Scope: system.DataScopeAuthority}}, []string{"r1"}},
+		{"本角色及下级", []request.DataScopeInfo{{Resource: "data_scope_records", Scope: system.DataScopeSubtree}}, []string{"r1", "r2", "r3"}},
+		{"全部", []request.DataScopeInfo{{Resource: "*", Scope: system.DataScopeAll}}, []string{"r1", "r2", "r3", "r4"}},
+	}
+	for _, tt := range tests {
+		setScopes(150, tt.scopes...)
+		if got := dataScopeNames(t, db()); !slices.Equal(got, tt.want) {
+			t.Errorf("%s: got %v, want %v", tt.name, got, tt.want)
+		}
+	}
+
+	// 不带用户的查询不过滤 已有的 OR 条件不会绕过数据范围
+	setScopes(150, request.DataScopeInfo{Resource: "*", Scope: system.DataScopeAuthority})
+	if got := dataScopeNames(t, global.GVA_DB); len(got) != 4 {
+		t.Fatalf("without user got %v", got)
+	}
+	if got := dataScopeNames(t, db().Where("name = ? OR name = ?", "r2", "r4")); len(got) != 0 {
+		t.Fatalf("or condition got %v", got)
+	}
+	var total int64
+	if err := db().Model(&dataScopeRecord{}).Count(&total).Error; err != nil || total != 1 {
+		t.Fatalf("Count() = %d, %v", total, err)
+	}
+
+	// 范围外的数据不能更新和删除 缺少条件时仍由gorm拦截
+	if n := db().Model(&dataScopeRecord{ID: 4}).Update("name", "x").RowsAffected; n != 0 {
+		t.Fatalf("update out of scope affected %d", n)
+	}
+	if n := db().Delete(&dataScopeRecord{ID: 2}).RowsAffected; n != 0 {
+		t.Fatalf("delete out of scope affected %d", n)
+	}
+	if err := db().Delete(&dataScopeRecord{}).Error; !errors.Is(err, gorm.ErrMissingWhereClause) {
+		t.Fatalf("delete without condition error = %v", err)
+	}
+	if n := db().Delete(&dataScopeRecord{ID: 1}).RowsAffected; n != 1 {
+		t.Fatalf("delete in scope affected %d", n)
+	}
+
+	// 开启角色权限并集时取各角色范围的并集
+	global.GVA_CONFIG.System.UseUnionAuth = true
+	defer func() { global.GVA_CONFIG.System.UseUnionAuth = false }()
+	setScopes(888, request.DataScopeInfo{Resource: "*", Scope: system.DataScopeSelf})
+	setScopes(9528, request.DataScopeInfo{Resource: "*", Scope: system.DataScopeAll})
+	ctx = utils.WithDataScope(context.Background(), 1, 888)
+	if got := dataScopeNames(t, db()); len(got) != 3 {
+		t.Fatalf("union got %v", got)
+	}
+
+	if err := s.SetDataScopes(0, 0, "", request.SetDataScopes{AuthorityId: 150, Scopes: []request.DataScopeInfo{{Resource: "*", Scope: "bad"}}}); err == nil {
+		t.Fatalf("SetDataScopes() invalid scope should fail")
+	}
+}
+
+func TestDataScopePlugin_BusinessDB(t *testing.T) {
+	setupDataScopeTest(t)
+	if err := DataScopeServiceApp.SetDataScopes(0, 0, "", request.SetDataScopes{AuthorityId: 150, Scopes: []request.DataScopeInfo{{Resource: "*", Scope: system.DataScopeSelf}}}); err != nil {
+		t.Fatalf("SetDataScopes() error = %v", err)
+	}
+	// 业务库只有业务表 数据范围配置和角色从系统库读取
+	biz := openTestDB(t, &dataScopeRecord{})
+	if err := biz.Use(DataScopePlugin{}); err != nil {
+		t.Fatalf("register plugin failed: %v", err)
+	}
+	biz.Create(&[]dataScopeRecord{{Name: "b1", CreatedBy: 1}, {Name: "b2", CreatedBy: 2}})
+	ctx := utils.WithDataScope(context.Background(), 1, 150)
+	if got := dataScopeNames(t, biz.WithContext(ctx)); !slices.Equal(got, []string{"b1"}) {
+		t.Fatalf("business db got %v", got)
+	}
+}
@@ -2,10 +2,14 @@ package system
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"mime/multipart"
 	"net/url"
+	"regexp"
+	"slices"
 	"strconv"
 	"strings"
 	"time"
@@ -22,6 +26,16 @@ import (
 type SysExportTemplateService struct {
 }
 
+var (
+	ErrExportNoColumns    = errors.New("没有可导出的列")
+	ErrExportFilterDenied = errors.New("隐藏和脱敏的字段不能作为导出条件")
+)
+
+// IsFieldRuleDenied 是否因字段权限无法导出 错误信息可直接提示用户
+func (sysExportTemplateService *SysExportTemplateService) IsFieldRuleDenied(err error) bool {
+	return errors.Is(err, ErrExportNoColumns) || errors.Is(err, ErrExportFilterDenied)
+}
+
 var SysExportTemplateServiceApp = new(SysExportTemplateService)
 
 // CreateSysExportTemplate 创建导出模板记录
@@ -124,9 +138,9 @@ func (sysExportTemplateService *SysExportTemplateService) GetSysExportTemplateIn
 	return sysExportTemplates, total, err
 }
 
-// ExportExcel 导出Excel
+// ExportExcel 导出Excel ctx中带有当前用户时按其字段权限隐藏或脱敏列
 // Author [piexlmax](https://github.com/piexlmax)
-func (sysExportTemplateService *SysExportTemplateService) ExportExcel(templateID string, values url.Values) (file *bytes.Buffer, name string, err error) {
+func (sysExportTemplateService *SysExportTemplateService) ExportExcel(ctx context.Context, templateID string, values url.Values) (file *bytes.Buffer, name string, err error) {
 	var template system.SysExportTemplate
 	err = global.GVA_DB.Preload("Conditions").Preload("JoinTemplate").First(&template, "template_id = ?", templateID).Error
 	if err != nil {
@@ -153,12 +167,24 @@ func (sysExportTemplateService *SysExportTemplateService) ExportExcel(templateID
 	if err != nil {
 		return nil, "", err
 	}
+	rules := exportFieldRules{}
+	if user, ok := utils.DataScopeFromContext(ctx); ok {
+		if rules, err = newExportFieldRules(user.UserId, user.AuthorityId, template); err != nil {
+			return nil, "", err
+		}
+	}
 	var tableTitle []string
 	var selectKeyFmt []string
 	for _, key := range columns {
+		if rule, ok := rules.Lookup(key); ok && rule.Action == system.FieldRuleHidden {
+			continue
+		}
 		selectKeyFmt = append(selectKeyFmt, key)
 		tableTitle = append(tableTitle, templateInfoMap[key])
 	}
+	if len(selectKeyFmt) == 0 {
+		return nil, "", ErrExportNoColumns
+	}
 
 	selects := strings.Join(selectKeyFmt, ", ")
 	var tableMap []map[string]interface{}
@@ -179,6 +205,10 @@ func (sysExportTemplateService *SysExportTemplateService) ExportExcel(templateID
 		for _, condition := range template.Conditions {
 			sql := fmt.Sprintf("%s %s ?", condition.Column, condition.Operator)
 			value := values.Get(condition.From)
+			// 隐藏和脱敏的字段不能作为导出条件
+			if value != "" && !rules.Filterable(condition.Column) {
+				return nil, "", fmt.Errorf("%w: %s", ErrExportFilterDenied, condition.Column)
+			}
 			if value != "" {
 				if condition.Operator == "LIKE" {
 					value = "%" + value + "%"
@@ -256,7 +286,9 @@ func (sysExportTemplateService *SysExportTemplateService) ExportExcel(templateID
 	rows = append(rows, tableTitle)
 	for _, exTable := range tableMap {
 		var row []string
-		for _, column := range columns {
+		for _, column := range selectKeyFmt {
+			rule, masked := rules.Lookup(column)
+			masked = masked && rule.Action == system.FieldRuleMasked
 			column = strings.ReplaceAll(column, "\"", "")
 			column = strings.ReplaceAll(column, "`", "")
 			if len(template.JoinTemplate) > 0 {
@@ -271,11 +303,14 @@ func (sysExportTemplateService *SysExportTemplateService) ExportExcel(templateID
 				}
 			}
 			// 需要对时间类型特殊处理
+			cell := fmt.Sprintf("%v", exTable[column])
 			if t, ok := exTable[column].(time.Time); ok {
-				row = append(row, t.Format("2006-01-02 15:04:05"))
-			} else {
-				row = append(row, fmt.Sprintf("%v", exTable[column]))
+				cell = t.Format("2006-01-02 15:04:05")
+			}
+			if masked {
+				cell = MaskFieldValue(cell, rule.Pattern)
 			}
+			row = append(row, cell)
 		}
 		rows = append(rows, row)
 	}
@@ -296,6 +331,74 @@ func (sysExportTemplateService *SysExportTemplateService) ExportExcel(templateID
 	return file, template.Name, nil
 }
 
+// exportFieldRules 导出涉及的各表的字段权限 键为表名和关联表的别名
+type exportFieldRules map[string]FieldRules
+
+var (
+	exportAlias      = regexp.MustCompile(`(?i)\s+as\s+[^\s]+\s*$`)
+	exportLiteral    = regexp.MustCompile(`'[^']*'`)
+	exportIdentifier = regexp.MustCompile(`[A-Za-z_][A-Za-z0-9_]*(\.[A-Za-z_][A-Za-z0-9_]*)?`)
+)
+
+// newExportFieldRules 读取主表和关联表的字段权限
+func newExportFieldRules(userId, authorityId uint, template system.SysExportTemplate) (exportFieldRules, error) {
+	tables := map[string]string{template.TableName: template.TableName}
+	for _, join := range template.JoinTemplate {
+		// 关联表可以写成 表名 别名 或 表名 AS 别名
+		fields := strings.Fields(strings.NewReplacer("\"", "", "`", "").Replace(join.Table))
+		if len(fields) == 0 {
+			continue
+		}
+		tables[fields[0]] = fields[0]
+		if len(fields) > 1 {
+			tables[fields[len(fields)-1]] = fields[0]
+		}
+	}
+	rules := exportFieldRules{}
+	for key, table := range tables {
+		tableRules, err := FieldRuleServiceApp.FieldRules(userId, authorityId, table)
+		if err != nil {
+			return nil, err
+		}
+		rules[key] = tableRules
+	}
+	return rules, nil
+}
+
+// Lookup 导出列或条件中引用的字段的权限 按去掉别名和表名后的字段名查找
+// 表达式引用多个字段时取限制最多的 未写表名或表名未知时按所有表的字段权限查找
+func (rules exportFieldRules) Lookup(expr string) (rule systemReq.FieldRuleInfo, found bool) {
+	expr = strings.NewReplacer("\"", "", "`", "").Replace(expr)
+	expr = exportLiteral.ReplaceAllString(exportAlias.ReplaceAllString(expr, ""), "")
+	for _, ident := range exportIdentifier.FindAllString(expr, -1) {
+		candidates := make([]FieldRules, 0, len(rules))
+		table, column, ok := strings.Cut(ident, ".")
+		if !ok {
+			column = table
+		}
+		if tableRules, known := rules[table]; ok && known {
+			candidates = append(candidates, tableRules)
+		} else {
+			for _, tableRules := range rules {
+				candidates = append(candidates, tableRules)
+			}
+		}
+		for _, tableRules := range candidates {
+			r, ok := tableRules.Lookup(column)
+			if ok && (!found || slices.Index(fieldRuleActions, r.Action) > slices.Index(fieldRuleActions, rule.Action)) {
+				rule, found = r, true
+			}
+		}
+	}
+	return rule, found
+}
+
+// Filterable 导出条件能否使用该列 引用了隐藏或脱敏字段时不能
+func (rules exportFieldRules) Filterable(expr string) bool {
+	rule, ok := rules.Lookup(expr)
+	return !ok || rule.Action == system.FieldRuleReadonly
+}
+
 // ExportTemplate 导出Excel模板
 // Author [piexlmax](https://github.com/piexlmax)
 func (sysExportTemplateService *SysExportTemplateService) ExportTemplate(templateID string) (file *bytes.Buffer, name string, err error) {
@@ -0,0 +1,56 @@
+package system
+
+import (
+	"testing"
+
+	"github.com/flipped-aurora/gin-vue-admin/server/global"
+	"github.com/flipped-aurora/gin-vue-admin/server/model/system"
+	"github.com/flipped-aurora/gin-vue-admin/server/model/system/request"
+)
+
+func TestExportFieldRules(t *testing.T) {
+	setupUnionAuthTest(t)
+	if err := global.GVA_DB.AutoMigrate(&system.SysFieldRule{}); err != nil {
+		t.Fatalf("migrate failed: %v", err)
+	}
+	err := FieldRuleServiceApp.SetFieldRules(0, 0, "", request.SetFieldRules{AuthorityId: 888, Rules: []request.FieldRuleInfo{
+		{Resource: "exa_customers", Field: "phone", Action: system.FieldRuleMasked, Pattern: "3,4"},
+		{Resource: "exa_customers", Field: "id_card", Action: system.FieldRuleHidden},
+		{Resource: "sys_users", Field: "email", Action: system.FieldRuleHidden},
+	}})
+	if err != nil {
+		t.Fatalf("SetFieldRules() error = %v", err)
+	}
+	template := system.SysExportTemplate{
+		TableName:    "exa_customers",
+		JoinTemplate: []system.JoinTemplate{{JOINS: "LEFT JOIN", Table: "sys_users AS u", ON: "u.id = exa_customers.sys_user_id"}},
+	}
+	rules, err := newExportFieldRules(1, 888, template)
+	if err != nil {
+		t.Fatalf("newExportFieldRules() error = %v", err)
+	}
+
+	tests := []struct {
+		expr       string
+		action     string
+		filterable bool
+	}{
+		{expr: "phone", action: system.FieldRuleMasked},
+		{expr: "phone as p", action: system.FieldRuleMasked},
+		{expr: "`exa_customers`.`phone` AS p", action: system.FieldRuleMasked},
+		{expr: "CONCAT(id_card, '') as c", action: system.FieldRuleHidden},
+		{expr: "u.email as e", action: system.FieldRuleHidden},
+		{expr: "sys_users.email", action: system.FieldRuleHidden},
+		{expr: "email", action: system.FieldRuleHidden},
+		{expr: "customer_name as phone", filterable: true},
+		{expr: "u.username", filterable: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			rule, _ := rules.Lookup(tt.expr)
+			if rule.Action != tt.action || rules.Filterable(tt.expr) != tt.filterable {
+				t.Fatalf("Lookup(%q) = %q filterable %v, want %q filterable %v", tt.expr, rule.Action, rules.Filterable(tt.expr), tt.action, tt.filterable)
+			}
+		})
+	}
+}
@@ -0,0 +1,225 @@
+package system
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/flipped-aurora/gin-vue-admin/server/global"
+	"github.com/flipped-aurora/gin-vue-admin/server/model/system"
+	"github.com/flipped-aurora/gin-vue-admin/server/model/system/request"
+	"github.com/flipped-aurora/gin-vue-admin/server/utils"
+	"gorm.io/gorm"
+	"gorm.io/gorm/schema"
+)
+
+// fieldRuleActions 按限制程度从低到高排列
+var fieldRuleActions = []string{system.FieldRuleReadonly, system.FieldRuleMasked, system.FieldRuleHidden}
+
+// fieldMaskKeep 脱敏规则 前N位,后M位
+var fieldMaskKeep = regexp.MustCompile(`^(\d+),(\d+)$`)
+
+var fieldNaming = schema.NamingStrategy{}
+
+// FieldRules 字段名到字段权限 字段名为json名或列名
+type FieldRules map[string]request.FieldRuleInfo
+
+// Lookup 依次按传入的名称查找 json名未配置时再按其列名查找
+func (rules FieldRules) Lookup(names ...string) (request.FieldRuleInfo, bool) {
+	for _, name := range names {
+		if name == "" {
+			continue
+		}
+		if rule, ok := rules[name]; ok {
+			return rule, true
+		}
+		if rule, ok := rules[fieldNaming.ColumnName("", name)]; ok {
+			return rule, true
+		}
+	}
+	return request.FieldRuleInfo{}, false
+}
+
+// Filterable 字段能否作为查询条件 隐藏和脱敏的字段不能 避免通过条件逐步猜出原值
+func (rules FieldRules) Filterable(names ...string) bool {
+	rule, ok := rules.Lookup(names...)
+	return !ok || rule.Action == system.FieldRuleReadonly
+}
+
+// Apply 处理json解码后的数据 删除隐藏字段 脱敏字段替换为脱敏后的字符串 对各层级的同名字段都生效
+func (rules FieldRules) Apply(v interface{}) interface{} {
+	switch value := v.(type) {
+	case map[string]interface{}:
+		for k, item := range value {
+			rule, ok := rules.Lookup(k)
+			switch {
+			case ok && rule.Action == system.FieldRuleHidden:
+				delete(value, k)
+			case ok && rule.Action == system.FieldRuleMasked:
+				if item != nil {
+					value[k] = MaskFieldValue(fmt.Sprint(item), rule.Pattern)
+				}
+			default:
+				value[k] = rules.Apply(item)
+			}
+		}
+	case []interface{}:
+		for i := range value {
+			value[i] = rules.Apply(value[i])
+		}
+	}
+	return v
+}
+
+// MaskFieldValue 按脱敏规则处理字段值 3,4 保留前3位和后4位 为空时全部替换为* 其他内容直接作为结果
+func MaskFieldValue(value, pattern string) string {
+	if m := fieldMaskKeep.FindStringSubmatch(pattern); m != nil {
+		prefix, _ := strconv.Atoi(m[1])
+		suffix, _ := strconv.Atoi(m[2])
+		r := []rune(value)
+		if len(r) <= prefix+suffix {
+			return strings.Repeat("*", len(r))
+		}
+		return string(r[:prefix]) + strings.Repeat("*", len(r)-prefix-suffix) + string(r[len(r)-suffix:])
+	}
+	if pattern == "" {
+		return strings.Repeat("*", utf8.RuneCountInString(value))
+	}
+	return pattern
+}
+
+type FieldRuleService struct{}
+
+var FieldRuleServiceApp = new(FieldRuleService)
+
+// GetFieldRules 获取角色配置的字段权限
+func (fieldRuleService *FieldRuleService) GetFieldRules(authorityId uint) (list []request.FieldRuleInfo, err error) {
+	var rows []system.SysFieldRule
+	err = global.GVA_DB.Where("authority_id = ?", authorityId).Order("resource, field").Find(&rows).Error
+	for _, row := range rows {
+		list = append(list, request.FieldRuleInfo{Resource: row.Resource, Field: row.Field, Action: row.Action, Pattern: row.Pattern})
+	}
+	return list, err
+}
+
+// SetFieldRules 以传入的配置替换角色的字段权限 并保存角色权限的版本
+func (fieldRuleService *FieldRuleService) SetFieldRules(adminAuthorityId, operatorId uint, operatorName string, req request.SetFieldRules) error {
+	return AuthorityVersionServiceApp.Track(req.AuthorityId, operatorId, operatorName, request.AuthorityVersionSetFieldRules, func() error {
+		return fieldRuleService.setFieldRules(adminAuthorityId, req)
+	})
+}
+
+func (fieldRuleService *FieldRuleService) setFieldRules(adminAuthorityId uint, req request.SetFieldRules) error {
+	if err := AuthorityServiceApp.CheckAuthorityIDAuth(adminAuthorityId, req.AuthorityId); err != nil {
+		return err
+	}
+	rows, err := fieldRuleRows(req.AuthorityId, req.Rules)
+	if err != nil {
+		return err
+	}
+	return global.GVA_DB.Transaction(func(tx *gorm.DB) error {
+		return replaceFieldRules(tx, req.AuthorityId, rows)
+	})
+}
+
+// fieldRuleRows 校验字段权限配置 转为角色的字段权限记录
+func fieldRuleRows(authorityId uint, rules []request.FieldRuleInfo) ([]system.SysFieldRule, error) {
+	rows := make([]system.SysFieldRule, 0, len(rules))
+	seen := map[string]bool{}
+	for _, v := range rules {
+		resource, field := strings.TrimSpace(v.Resource), strings.TrimSpace(v.Field)
+		if resource == "" || field == "" {
+			return nil, errors.New("资源和字段不能为空")
+		}
+		if !slices.Contains(fieldRuleActions, v.Action) {
+			return nil, errors.New("字段权限不存在: " + v.Action)
+		}
+		if seen[resource+"."+field] {
+			return nil, errors.New("字段重复: " + resource + "." + field)
+		}
+		seen[resource+"."+field] = true
+		rows = append(rows, system.SysFieldRule{AuthorityId: authorityId, Resource: resource, Field: field, Action: v.Action, Pattern: strings.TrimSpace(v.Pattern)})
+	}
+	return rows, nil
+}
+
+// replaceFieldRules 在事务中以传入的记录替换角色的字段权限
+func replaceFieldRules(tx *gorm.DB, authorityId uint, rows []system.SysFieldRule) error {
+	if err := tx.Unscoped().Where("authority_id = ?", authorityId).Delete(&system.SysFieldRule{}).Error; err != nil {
+		return err
+	}
+	if len(rows) == 0 {
+		return nil
+	}
+	return tx.Create(&rows).Error
+}
+
+// FieldRules 用户对资源生效的字段权限 字段权限保存在系统库
+// 开启角色权限并集时 任一角色未限制的字段不受限制 都有限制时取限制最少的
+func (fieldRuleService *FieldRuleService) FieldRules(userId, authorityId uint, resource string) (FieldRules, error) {
+	ids := CasbinServiceApp.EffectiveAuthorityIds(userId, authorityId)
+	var rows []system.SysFieldRule
+	if err := global.GVA_DB.Where("authority_id in ? AND resource = ?", ids, resource).Find(&rows).Error; err != nil {
+		return nil, err
+	}
+	byField := map[string][]system.SysFieldRule{}
+	for _, row := range rows {
+		byField[row.Field] = append(byField[row.Field], row)
+	}
+	rules := FieldRules{}
+	for field, list := range byField {
+		if len(list) < len(ids) {
+			continue
+		}
+		rule := slices.MinFunc(list, func(a, b system.SysFieldRule) int {
+			return slices.Index(fieldRuleActions, a.Action) - slices.Index(fieldRuleActions, b.Action)
+		})
+		rules[field] = request.FieldRuleInfo{Resource: resource, Field: field, Action: rule.Action, Pattern: rule.Pattern}
+	}
+	return rules, nil
+}
+
+// FieldRulePlugin 带有当前用户的ctx更新数据时 忽略只读 脱敏和隐藏的字段
+// 避免脱敏后的值或缺失的隐藏字段覆盖原数据 可注册在业务库上 字段权限从系统库读取
+// 只处理更新 服务未使用 utils.DataScopeContext 时不生效 查询条件需由服务用 FieldRules.Filterable 自行检查
+type FieldRulePlugin struct{}
+
+func (FieldRulePlugin) Name() string {
+	return "gva:field_rule"
+}
+
+func (FieldRulePlugin) Initialize(db *gorm.DB) error {
+	return db.Callback().Update().Before("gorm:update").Register("gva:field_rule", fieldRuleUpdate)
+}
+
+func fieldRuleUpdate(db *gorm.DB) {
+	stmt := db.Statement
+	if db.Error != nil || stmt.Schema == nil {
+		return
+	}
+	user, ok := utils.DataScopeFromContext(stmt.Context)
+	if !ok {
+		return
+	}
+	rules, err := FieldRuleServiceApp.FieldRules(user.UserId, user.AuthorityId, stmt.Schema.Table)
+	if err != nil {
+		_ = db.AddError(err)
+		return
+	}
+	if len(rules) == 0 {
+		return
+	}
+	for _, field := range stmt.Schema.Fields {
+		if field.DBName == "" {
+			continue
+		}
+		jsonName, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if _, ok = rules.Lookup(jsonName, field.DBName); ok {
+			stmt.Omits = append(stmt.Omits, field.DBName)
+		}
+	}
+}
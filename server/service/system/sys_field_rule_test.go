@@ -0,0 +1,149 @@
+package system
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/flipped-aurora/gin-vue-admin/server/global"
+	"github.com/flipped-aurora/gin-vue-admin/server/model/system"
+	"github.com/flipped-aurora/gin-vue-admin/server/model/system/request"
+	"github.com/flipped-aurora/gin-vue-admin/server/utils"
+)
+
+type fieldRuleRecord struct {
+	ID     uint   `json:"ID"`
+	Name   string `json:"name"`
+	Phone  string `json:"phone"`
+	IdCard string `json:"idCard"`
+	Remark string `json:"remark"`
+}
+
+func TestMaskFieldValue(t *testing.T) {
+	tests := []struct {
+		value, pattern, want string
+	}{
+		{"13812341234", "3,4", "138****1234"},
+		{"张三丰", "1,0", "张**"},
+		{"1234", "3,4", "****"},
+		{"secret", "", "******"},
+		{"secret", "***", "***"},
+	}
+	for _, tt := range tests {
+		if got := MaskFieldValue(tt.value, tt.pattern); got != tt.want {
+			t.Errorf("MaskFieldValue(%q, %q) = %q, want %q", tt.value, tt.pattern, got, tt.want)
+		}
+	}
+}
+
+func TestFieldRules_Apply(t *testing.T) {
+	rules := FieldRules{
+		"phone":   {Action: system.FieldRuleMasked, Pattern: "3,4"},
+		"id_card": {Action: system.FieldRuleHidden},
+		"name":    {Action: system.FieldRuleReadonly},
+	}
+	data := map[string]interface{}{
+		"total": 1,
+		"list": []interface{}{
+			map[string]interface{}{"name": "a", "phone": "13812341234", "idCard": "110101", "remark": nil},
+		},
+		"phone": nil,
+	}
+	want := map[string]interface{}{
+		"total": 1,
+		"list": []interface{}{
+			map[string]interface{}{"name": "a", "phone": "138****1234", "remark": nil},
+		},
+		"phone": nil,
+	}
+	if got := rules.Apply(data); !reflect.DeepEqual(got, want) {
+		t.Fatalf("Apply() = %v, want %v", got, want)
+	}
+}
+
+func TestFieldRuleService(t *testing.T) {
+	setupUnionAuthTest(t)
+	db := global.GVA_DB
+	if err := UseGormPlugins(db); err != nil {
+		t.Fatalf("UseGormPlugins() error = %v", err)
+	}
+	if err := db.AutoMigrate(&system.SysFieldRule{}, &fieldRuleRecord{}); err != nil {
+		t.Fatalf("migrate failed: %v", err)
+	}
+	s := FieldRuleServiceApp
+	const resource = "field_rule_records"
+	setRules := func(authorityId uint, rules ...request.FieldRuleInfo) {
+		t.Helper()
+		if err := s.SetFieldRules(0, 0, "", request.SetFieldRules{AuthorityId: authorityId, Rules: rules}); err != nil {
+			t.Fatalf("SetFieldRules() error = %v", err)
+		}
+	}
+	setRules(888,
+		request.FieldRuleInfo{Resource: resource, Field: "phone", Action: system.FieldRuleMasked, Pattern: "3,4"},
+		request.FieldRuleInfo{Resource: resource, Field: "id_card", Action: system.FieldRuleHidden},
+		request.FieldRuleInfo{Resource: resource, Field: "name", Action: system.FieldRuleReadonly},
+	)
+	setRules(9528,
+		request.FieldRuleInfo{Resource: resource, Field: "phone", Action: system.FieldRuleReadonly},
+	)
+
+	rules, err := s.FieldRules(1, 888, resource)
+	if err != nil {
+		t.Fatalf("FieldRules() error = %v", err)
+	}
+	if len(rules) != 3 || rules["phone"].Action != system.FieldRuleMasked {
+		t.Fatalf("FieldRules() = %v", rules)
+	}
+
+	// 开启角色权限并集时 只有都限制的字段受限 取限制最少的
+	global.GVA_CONFIG.System.UseUnionAuth = true
+	defer func() { global.GVA_CONFIG.System.UseUnionAuth = false }()
+	rules, err = s.FieldRules(1, 888, resource)
+	if err != nil {
+		t.Fatalf("FieldRules() error = %v", err)
+	}
+	if len(rules) != 1 || rules["phone"].Action != system.FieldRuleReadonly {
+		t.Fatalf("union FieldRules() = %v", rules)
+	}
+	global.GVA_CONFIG.System.UseUnionAuth = false
+
+	// 带有用户的更新忽略受限字段
+	record := fieldRuleRecord{Name: "a", Phone: "13812341234", IdCard: "110101", Remark: "r"}
+	db.Create(&record)
+	ctx := utils.WithDataScope(context.Background(), 1, 888)
+	update := fieldRuleRecord{ID: record.ID, Name: "b", Phone: "138****1234", Remark: "r2"}
+	if err = db.WithContext(ctx).Select("*").Updates(&update).Error; err != nil {
+		t.Fatalf("Updates() error = %v", err)
+	}
+	var got fieldRuleRecord
+	db.First(&got, record.ID)
+	want := fieldRuleRecord{ID: record.ID, Name: "a", Phone: "13812341234", IdCard: "110101", Remark: "r2"}
+	if got != want {
+		t.Fatalf("after update got %+v, want %+v", got, want)
+	}
+
+	// 业务库只有业务表 字段权限从系统库读取
+	biz := openTestDB(t, &fieldRuleRecord{})
+	if err = biz.Use(FieldRulePlugin{}); err != nil {
+		t.Fatalf("register plugin failed: %v", err)
+	}
+	biz.Create(&fieldRuleRecord{ID: record.ID, Name: "a", Phone: "13812341234", IdCard: "110101", Remark: "r"})
+	if err = biz.WithContext(ctx).Select("*").Updates(&update).Error; err != nil {
+		t.Fatalf("business db Updates() error = %v", err)
+	}
+	got = fieldRuleRecord{}
+	biz.First(&got, record.ID)
+	if got != want {
+		t.Fatalf("business db after update got %+v, want %+v", got, want)
+	}
+
+	// 隐藏和脱敏的字段不能作为查询条件
+	rules, _ = s.FieldRules(1, 888, resource)
+	if !rules.Filterable("name") || !rules.Filterable("remark") || rules.Filterable("phone") || rules.Filterable("idCard") {
+		t.Fatalf("Filterable() mismatch for %v", rules)
+	}
+
+	if err = s.SetFieldRules(0, 0, "", request.SetFieldRules{AuthorityId: 888, Rules: []request.FieldRuleInfo{{Resource: resource, Field: "name", Action: "bad"}}}); err == nil {
+		t.Fatalf("SetFieldRules() invalid action should fail")
+	}
+}
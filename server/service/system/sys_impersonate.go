@@ -0,0 +1,55 @@
+package system
+
+import (
+	"errors"
+	"slices"
+
+	"github.com/flipped-aurora/gin-vue-admin/server/global"
+	"github.com/flipped-aurora/gin-vue-admin/server/model/system"
+	systemReq "github.com/flipped-aurora/gin-vue-admin/server/model/system/request"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrImpersonateNested = errors.New("代登录期间不能再次代登录")
+	ErrImpersonateSelf   = errors.New("不能代登录自己")
+	ErrImpersonateScope  = errors.New("只能代登录本角色及下级角色的用户")
+)
+
+type ImpersonateService struct{}
+
+var ImpersonateServiceApp = new(ImpersonateService)
+
+// Impersonate 校验操作人能否代登录目标用户 返回目标用户
+// 无论是否开启严格角色模式 目标用户的每个角色都必须是操作人当前角色或其下级角色 避免借代登录提升权限
+func (impersonateService *ImpersonateService) Impersonate(actor *systemReq.CustomClaims, userId uint) (user system.SysUser, err error) {
+	if actor.Actor != nil {
+		return user, ErrImpersonateNested
+	}
+	if actor.BaseClaims.ID == userId {
+		return user, ErrImpersonateSelf
+	}
+	err = global.GVA_DB.Preload("Authorities").First(&user, userId).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return user, errors.New("用户不存在")
+		}
+		return
+	}
+	if user.Enable != 1 {
+		return user, errors.New("用户已被冻结")
+	}
+	allowed, err := DataScopeServiceApp.subtree(global.GVA_DB, []uint{actor.AuthorityId})
+	if err != nil {
+		return user, err
+	}
+	if !slices.Contains(allowed, user.AuthorityId) {
+		return user, ErrImpersonateScope
+	}
+	for _, authority := range user.Authorities {
+		if !slices.Contains(allowed, authority.AuthorityId) {
+			return user, ErrImpersonateScope
+		}
+	}
+	return user, nil
+}
@@ -0,0 +1,69 @@
+package system
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/flipped-aurora/gin-vue-admin/server/global"
+	"github.com/flipped-aurora/gin-vue-admin/server/model/system"
+	systemReq "github.com/flipped-aurora/gin-vue-admin/server/model/system/request"
+)
+
+func TestImpersonateService_Impersonate(t *testing.T) {
+	db := setupTestDB(t, &system.SysUser{}, &system.SysAuthority{}, &system.SysUserAuthority{})
+	var err error
+	global.GVA_CONFIG.System.UseStrictAuth = false
+	root, parent := uint(0), uint(888)
+	db.Create(&[]system.SysAuthority{
+		{AuthorityId: 888, AuthorityName: "admin", ParentId: &root},
+		{AuthorityId: 9528, AuthorityName: "child", ParentId: &parent},
+		{AuthorityId: 100, AuthorityName: "other", ParentId: &root},
+	})
+	db.Create(&[]system.SysUser{
+		{GVA_MODEL: global.GVA_MODEL{ID: 1}, Username: "admin", Enable: 1, AuthorityId: 888},
+		{GVA_MODEL: global.GVA_MODEL{ID: 2}, Username: "alice", Enable: 1, AuthorityId: 9528},
+		{GVA_MODEL: global.GVA_MODEL{ID: 3}, Username: "bob", Enable: 2, AuthorityId: 9528},
+		{GVA_MODEL: global.GVA_MODEL{ID: 5}, Username: "carol", Enable: 1, AuthorityId: 9528},
+		{GVA_MODEL: global.GVA_MODEL{ID: 6}, Username: "dave", Enable: 1, AuthorityId: 100},
+	})
+	db.Create(&[]system.SysUserAuthority{
+		{SysUserId: 5, SysAuthorityAuthorityId: 9528},
+		{SysUserId: 5, SysAuthorityAuthorityId: 100},
+	})
+	child := &systemReq.CustomClaims{BaseClaims: systemReq.BaseClaims{ID: 2, Username: "alice", AuthorityId: 9528}}
+
+	admin := &systemReq.CustomClaims{BaseClaims: systemReq.BaseClaims{ID: 1, Username: "admin", AuthorityId: 888}}
+	nested := &systemReq.CustomClaims{BaseClaims: systemReq.BaseClaims{ID: 2, AuthorityId: 9528}, Actor: &systemReq.Actor{ID: 1}}
+	tests := []struct {
+		name    string
+		actor   *systemReq.CustomClaims
+		userId  uint
+		wantErr error
+	}{
+		{name: "代登录普通用户", actor: admin, userId: 2},
+		{name: "代登录自己", actor: admin, userId: 1, wantErr: ErrImpersonateSelf},
+		{name: "代登录期间再次代登录", actor: nested, userId: 1, wantErr: ErrImpersonateNested},
+		// 未开启严格角色模式时同样只能代登录本角色及下级角色的用户
+		{name: "代登录上级角色用户", actor: child, userId: 1, wantErr: ErrImpersonateScope},
+		{name: "代登录其他角色用户", actor: admin, userId: 6, wantErr: ErrImpersonateScope},
+		{name: "目标用户有其他角色", actor: admin, userId: 5, wantErr: ErrImpersonateScope},
+	}
+	s := &ImpersonateService{}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			user, err := s.Impersonate(tt.actor, tt.userId)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Impersonate() error = %v, want %v", err, tt.wantErr)
+			}
+			if tt.wantErr == nil && user.ID != tt.userId {
+				t.Fatalf("Impersonate() user = %d, want %d", user.ID, tt.userId)
+			}
+		})
+	}
+	if _, err = s.Impersonate(admin, 3); err == nil {
+		t.Fatalf("Impersonate() disabled user should fail")
+	}
+	if _, err = s.Impersonate(admin, 4); err == nil {
+		t.Fatalf("Impersonate() missing user should fail")
+	}
+}
@@ -0,0 +1,171 @@
+package system
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/flipped-aurora/gin-vue-admin/server/global"
+	"github.com/flipped-aurora/gin-vue-admin/server/model/system"
+	"github.com/flipped-aurora/gin-vue-admin/server/utils"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+type JwtKeyService struct{}
+
+var JwtKeyServiceApp = new(JwtKeyService)
+
+// jwtKeyMu 保证同一进程内密钥的生成和轮换串行执行
+var jwtKeyMu sync.Mutex
+
+// LoadJwtKeys 从数据库加载未过期的签名密钥 当前算法没有可用签名密钥时自动生成
+func (jwtKeyService *JwtKeyService) LoadJwtKeys() error {
+	jwtKeyMu.Lock()
+	defer jwtKeyMu.Unlock()
+	return jwtKeyService.load()
+}
+
+// RotateJwtKey 生成新的签名密钥 旧密钥停止签名 在宽限期内仍可验签
+func (jwtKeyService *JwtKeyService) RotateJwtKey() error {
+	alg := global.GVA_CONFIG.JWT.SigningMethod
+	if !utils.IsAsymmetricJWTAlg(alg) {
+		return errors.New("对称签名算法不支持密钥轮换")
+	}
+	jwtKeyMu.Lock()
+	defer jwtKeyMu.Unlock()
+	key, err := jwtKeyService.create(alg)
+	if err != nil {
+		return err
+	}
+	if err = jwtKeyService.retire(global.GVA_DB.Where("kid <> ?", key.Kid)); err != nil {
+		return err
+	}
+	return jwtKeyService.load()
+}
+
+// RotateJwtKeyIfDue 定时任务调用 当前签名密钥超过轮换周期时轮换 否则重新加载以同步其他实例的轮换结果
+func (jwtKeyService *JwtKeyService) RotateJwtKeyIfDue() error {
+	alg := global.GVA_CONFIG.JWT.SigningMethod
+	if !utils.IsAsymmetricJWTAlg(alg) || global.GVA_DB == nil {
+		return nil
+	}
+	if global.GVA_CONFIG.JWT.KeyRotation != "" {
+		rotation, err := utils.ParseDuration(global.GVA_CONFIG.JWT.KeyRotation)
+		if err != nil {
+			return err
+		}
+		var active system.SysJwtKey
+		err = global.GVA_DB.Where("alg = ? AND retired_at IS NULL", alg).Order("id desc").First(&active).Error
+		if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			return err
+		}
+		if err == nil && rotation > 0 && time.Since(active.CreatedAt) >= rotation {
+			global.GVA_LOG.Info("jwt签名密钥到期轮换", zap.String("kid", active.Kid))
+			return jwtKeyService.RotateJwtKey()
+		}
+	}
+	return jwtKeyService.LoadJwtKeys()
+}
+
+// GetJwks 获取全部可验签的公钥
+func (jwtKeyService *JwtKeyService) GetJwks() utils.JSONWebKeySet {
+	if utils.IsAsymmetricJWTAlg(global.GVA_CONFIG.JWT.SigningMethod) && len(utils.JWTKeys.Keys()) == 0 {
+		if err := jwtKeyService.LoadJwtKeys(); err != nil {
+			global.GVA_LOG.Error("加载jwt签名密钥失败!", zap.Error(err))
+		}
+	}
+	return utils.JWKS(utils.JWTKeys.Keys())
+}
+
+func (jwtKeyService *JwtKeyService) load() error {
+	alg := global.GVA_CONFIG.JWT.SigningMethod
+	if !utils.IsAsymmetricJWTAlg(alg) {
+		utils.JWTKeys.Store(nil, nil)
+		return nil
+	}
+	if global.GVA_DB == nil {
+		return errors.New("数据库未初始化")
+	}
+	// 切换算法后 其他算法的密钥停止签名 进入宽限期
+	if err := jwtKeyService.retire(global.GVA_DB.Where("alg <> ?", alg)); err != nil {
+		return err
+	}
+	signing, keys, err := jwtKeyService.parse(alg)
+	if err != nil {
+		return err
+	}
+	if signing == nil {
+		if _, err = jwtKeyService.create(alg); err != nil {
+			return err
+		}
+		if signing, keys, err = jwtKeyService.parse(alg); err != nil {
+			return err
+		}
+	}
+	utils.JWTKeys.Store(signing, keys)
+	return nil
+}
+
+// parse 读取未过期的密钥 最新的未停用且私钥可解密的密钥作为签名密钥
+func (jwtKeyService *JwtKeyService) parse(alg string) (signing *utils.JWTKey, keys []*utils.JWTKey, err error) {
+	var records []system.SysJwtKey
+	err = global.GVA_DB.Where("expires_at IS NULL OR expires_at > ?", time.Now()).Order("id desc").Find(&records).Error
+	if err != nil {
+		return
+	}
+	for _, record := range records {
+		public, err := utils.ParseJWTPublicKey(record.PublicKey)
+		if err != nil {
+			global.GVA_LOG.Error("解析jwt公钥失败!", zap.String("kid", record.Kid), zap.Error(err))
+			continue
+		}
+		key := &utils.JWTKey{Kid: record.Kid, Alg: record.Alg, Public: public}
+		if signing == nil && record.RetiredAt == nil && record.Alg == alg {
+			// signing-key 变更后私钥无法解密 此时跳过 由 load 生成新密钥
+			if pemStr, err := utils.AesGcmDecrypt(global.GVA_CONFIG.JWT.SigningKey, record.PrivateKey); err == nil {
+				if key.Private, err = utils.ParseJWTPrivateKey(pemStr); err == nil {
+					signing = key
+				}
+			}
+		}
+		keys = append(keys, key)
+	}
+	return signing, keys, nil
+}
+
+func (jwtKeyService *JwtKeyService) create(alg string) (*utils.JWTKey, error) {
+	key, err := utils.GenerateJWTKey(alg)
+	if err != nil {
+		return nil, err
+	}
+	privatePEM, publicPEM, err := utils.MarshalJWTKey(key)
+	if err != nil {
+		return nil, err
+	}
+	encrypted, err := utils.AesGcmEncrypt(global.GVA_CONFIG.JWT.SigningKey, privatePEM)
+	if err != nil {
+		return nil, err
+	}
+	return key, global.GVA_DB.Create(&system.SysJwtKey{
+		Kid:        key.Kid,
+		Alg:        key.Alg,
+		PrivateKey: encrypted,
+		PublicKey:  publicPEM,
+	}).Error
+}
+
+// retire 停用密钥 宽限期取 key-grace-period 与访问令牌有效期中较大者 保证已签发的令牌在过期前可以验签
+func (jwtKeyService *JwtKeyService) retire(db *gorm.DB) error {
+	grace, _ := utils.ParseDuration(global.GVA_CONFIG.JWT.ExpiresTime)
+	if global.GVA_CONFIG.JWT.KeyGracePeriod != "" {
+		if dr, err := utils.ParseDuration(global.GVA_CONFIG.JWT.KeyGracePeriod); err == nil && dr > grace {
+			grace = dr
+		}
+	}
+	now := time.Now()
+	return db.Model(&system.SysJwtKey{}).Where("retired_at IS NULL").Updates(map[string]interface{}{
+		"retired_at": now,
+		"expires_at": now.Add(grace),
+	}).Error
+}
@@ -0,0 +1,54 @@
+package system
+
+import (
+	"testing"
+
+	"github.com/flipped-aurora/gin-vue-admin/server/global"
+	"github.com/flipped-aurora/gin-vue-admin/server/model/system"
+	"github.com/flipped-aurora/gin-vue-admin/server/model/system/request"
+	"github.com/flipped-aurora/gin-vue-admin/server/utils"
+)
+
+func TestJwtKeyService_RotateJwtKey(t *testing.T) {
+	db := setupTestDB(t, &system.SysJwtKey{})
+	var err error
+	global.GVA_CONFIG.JWT.SigningKey = "test-signing-key"
+	global.GVA_CONFIG.JWT.SigningMethod = utils.JWTAlgES256
+	global.GVA_CONFIG.JWT.ExpiresTime = "1h"
+	global.GVA_CONFIG.JWT.KeyGracePeriod = "1d"
+	defer func() {
+		global.GVA_CONFIG.JWT.SigningMethod = ""
+		utils.JWTKeys.Store(nil, nil)
+	}()
+
+	s := &JwtKeyService{}
+	if err = s.LoadJwtKeys(); err != nil {
+		t.Fatalf("LoadJwtKeys() error = %v", err)
+	}
+	j := utils.NewJWT()
+	oldToken, err := j.CreateToken(j.CreateClaims(request.BaseClaims{Username: "admin"}))
+	if err != nil {
+		t.Fatalf("CreateToken() error = %v", err)
+	}
+	oldKey, _ := utils.JWTKeys.Signing()
+
+	if err = s.RotateJwtKey(); err != nil {
+		t.Fatalf("RotateJwtKey() error = %v", err)
+	}
+	newKey, _ := utils.JWTKeys.Signing()
+	if newKey.Kid == oldKey.Kid {
+		t.Fatalf("RotateJwtKey() signing key not changed")
+	}
+	// 宽限期内旧密钥签发的令牌仍然有效 JWKS同时发布新旧公钥
+	if _, err = j.ParseToken(oldToken); err != nil {
+		t.Fatalf("ParseToken() old token error = %v", err)
+	}
+	if got := len(s.GetJwks().Keys); got != 2 {
+		t.Fatalf("GetJwks() len = %d, want 2", got)
+	}
+	var retired system.SysJwtKey
+	db.Where("kid = ?", oldKey.Kid).First(&retired)
+	if retired.RetiredAt == nil || retired.ExpiresAt == nil {
+		t.Fatalf("old key should be retired with expiry")
+	}
+}
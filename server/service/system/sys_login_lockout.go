@@ -0,0 +1,294 @@
+package system
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/flipped-aurora/gin-vue-admin/server/global"
+	"github.com/flipped-aurora/gin-vue-admin/server/model/system"
+	systemReq "github.com/flipped-aurora/gin-vue-admin/server/model/system/request"
+	emailUtils "github.com/flipped-aurora/gin-vue-admin/server/plugin/email/utils"
+	"github.com/flipped-aurora/gin-vue-admin/server/utils"
+	"github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	lockoutRedisPrefix     = "lockout:"
+	defaultLockoutWindow   = 15 * time.Minute
+	defaultLockoutDuration = 30 * time.Minute
+	defaultLockoutMaxDelay = 30 * time.Second
+)
+
+var (
+	ErrAccountLocked    = errors.New("账号已被锁定")
+	ErrLoginTooFrequent = errors.New("登录失败次数过多")
+)
+
+// lockoutState 用户名当前的连续失败次数和锁定状态
+type lockoutState struct {
+	Failures     int
+	LastFailedAt time.Time
+	LockedUntil  time.Time
+}
+
+// lockoutStore 失败计数的存储 开启redis时多实例共享 否则存数据库
+type lockoutStore interface {
+	get(username string) (lockoutState, error)
+	// fail 失败次数+1 距上次失败超过 window 时重新计数
+	fail(username string, window time.Duration) (lockoutState, error)
+	// lock 锁定到 until 并清零失败次数
+	lock(username string, until time.Time) error
+	reset(username string) error
+}
+
+type LoginLockoutService struct{}
+
+var LoginLockoutServiceApp = new(LoginLockoutService)
+
+// Check 登录前检查 账号被锁定或距上次失败未满等待时间时拒绝本次登录
+func (loginLockoutService *LoginLockoutService) Check(username string) error {
+	cfg := global.GVA_CONFIG.System.Lockout
+	if cfg.MaxAttempts <= 0 && cfg.DelayAfter <= 0 {
+		return nil
+	}
+	state, err := loginLockoutService.store().get(normalizeUsername(username))
+	if err != nil {
+		// 计数存储不可用时不阻止登录
+		global.GVA_LOG.Warn("获取登录失败次数失败!", zap.Error(err))
+		return nil
+	}
+	now := time.Now()
+	if state.LockedUntil.After(now) {
+		return fmt.Errorf("%w, 请%s后再试", ErrAccountLocked, formatWait(state.LockedUntil.Sub(now)))
+	}
+	if wait := loginLockoutService.delay(state.Failures); wait > 0 {
+		if next := state.LastFailedAt.Add(wait); next.After(now) {
+			return fmt.Errorf("%w, 请%s后再试", ErrLoginTooFrequent, formatWait(next.Sub(now)))
+		}
+	}
+	return nil
+}
+
+// Fail 记录一次登录失败 连续失败达到上限时锁定账号
+func (loginLockoutService *LoginLockoutService) Fail(username string, ip string, userAgent string, reason string) {
+	cfg := global.GVA_CONFIG.System.Lockout
+	key := normalizeUsername(username)
+	attempt := system.SysLoginAttempt{Username: username, Ip: ip, UserAgent: userAgent, Reason: reason}
+	if cfg.MaxAttempts > 0 || cfg.DelayAfter > 0 {
+		state, err := loginLockoutService.store().fail(key, parseDurationOr(cfg.Window, defaultLockoutWindow))
+		if err != nil {
+			global.GVA_LOG.Warn("记录登录失败次数失败!", zap.Error(err))
+		} else if cfg.MaxAttempts > 0 && state.Failures >= cfg.MaxAttempts {
+			until := time.Now().Add(parseDurationOr(cfg.Duration, defaultLockoutDuration))
+			if err = loginLockoutService.store().lock(key, until); err != nil {
+				global.GVA_LOG.Warn("锁定账号失败!", zap.Error(err))
+			} else {
+				attempt.Locked = true
+				global.GVA_LOG.Warn("连续登录失败 账号已锁定", zap.String("username", username), zap.String("ip", ip), zap.Time("until", until))
+				if cfg.Notify {
+					go loginLockoutService.notify(username, ip, until)
+				}
+			}
+		}
+	}
+	if err := global.GVA_DB.Create(&attempt).Error; err != nil {
+		global.GVA_LOG.Error("记录登录失败失败!", zap.Error(err))
+	}
+}
+
+// Succeed 登录成功后清零失败次数
+func (loginLockoutService *LoginLockoutService) Succeed(username string) {
+	cfg := global.GVA_CONFIG.System.Lockout
+	if cfg.MaxAttempts <= 0 && cfg.DelayAfter <= 0 {
+		return
+	}
+	if err := loginLockoutService.store().reset(normalizeUsername(username)); err != nil {
+		global.GVA_LOG.Warn("清除登录失败次数失败!", zap.Error(err))
+	}
+}
+
+// Unlock 管理员解除账号锁定
+func (loginLockoutService *LoginLockoutService) Unlock(username string) error {
+	if strings.TrimSpace(username) == "" {
+		return errors.New("用户名不能为空")
+	}
+	return loginLockoutService.store().reset(normalizeUsername(username))
+}
+
+// GetLoginAttemptList 分页获取登录失败记录
+func (loginLockoutService *LoginLockoutService) GetLoginAttemptList(info systemReq.SysLoginAttemptSearch) (list []system.SysLoginAttempt, total int64, err error) {
+	limit := info.PageSize
+	offset := info.PageSize * (info.Page - 1)
+	db := global.GVA_DB.Model(&system.SysLoginAttempt{})
+	if info.Username != "" {
+		db = db.Where("username LIKE ?", "%"+info.Username+"%")
+	}
+	if info.Ip != "" {
+		db = db.Where("ip = ?", info.Ip)
+	}
+	if err = db.Count(&total).Error; err != nil {
+		return
+	}
+	err = db.Order("id desc").Limit(limit).Offset(offset).Find(&list).Error
+	return list, total, err
+}
+
+// delay 连续失败超过 delay-after 次后 每多失败一次等待时间翻倍
+func (loginLockoutService *LoginLockoutService) delay(failures int) time.Duration {
+	cfg := global.GVA_CONFIG.System.Lockout
+	if cfg.DelayAfter <= 0 || failures <= cfg.DelayAfter {
+		return 0
+	}
+	maxDelay := parseDurationOr(cfg.MaxDelay, defaultLockoutMaxDelay)
+	n := failures - cfg.DelayAfter - 1
+	if n >= 30 || time.Second<<n > maxDelay {
+		return maxDelay
+	}
+	return time.Second << n
+}
+
+func (loginLockoutService *LoginLockoutService) notify(username string, ip string, until time.Time) {
+	var user system.SysUser
+	if err := global.GVA_DB.Select("id", "email").Where("username = ?", username).First(&user).Error; err != nil || user.Email == "" {
+		return
+	}
+	body := fmt.Sprintf("您的账号 %s 因连续登录失败已被锁定至 %s, 最近一次尝试来自 %s。如非本人操作请及时修改密码并联系管理员。",
+		username, until.Format(time.DateTime), ip)
+	if err := emailUtils.Email(user.Email, "账号锁定通知", body); err != nil {
+		global.GVA_LOG.Error("发送账号锁定通知失败!", zap.Error(err))
+	}
+}
+
+func (loginLockoutService *LoginLockoutService) store() lockoutStore {
+	if global.GVA_CONFIG.System.UseRedis && global.GVA_REDIS != nil {
+		return redisLockoutStore{client: global.GVA_REDIS}
+	}
+	return dbLockoutStore{}
+}
+
+func normalizeUsername(username string) string {
+	return strings.ToLower(strings.TrimSpace(username))
+}
+
+func parseDurationOr(s string, def time.Duration) time.Duration {
+	d, err := utils.ParseDuration(s)
+	if err != nil || d <= 0 {
+		return def
+	}
+	return d
+}
+
+func formatWait(d time.Duration) string {
+	if d < time.Minute {
+		return strconv.Itoa(int(math.Ceil(d.Seconds()))) + "秒"
+	}
+	return strconv.Itoa(int(math.Ceil(d.Minutes()))) + "分钟"
+}
+
+type redisLockoutStore struct {
+	client redis.UniversalClient
+}
+
+func (s redisLockoutStore) get(username string) (state lockoutState, err error) {
+	v, err := s.client.HGetAll(context.Background(), lockoutRedisPrefix+username).Result()
+	if err != nil {
+		return state, err
+	}
+	state.Failures, _ = strconv.Atoi(v["failures"])
+	if last, e := strconv.ParseInt(v["last"], 10, 64); e == nil {
+		state.LastFailedAt = time.UnixMilli(last)
+	}
+	if locked, e := strconv.ParseInt(v["locked"], 10, 64); e == nil {
+		state.LockedUntil = time.UnixMilli(locked)
+	}
+	return state, nil
+}
+
+func (s redisLockoutStore) fail(username string, window time.Duration) (lockoutState, error) {
+	ctx := context.Background()
+	key := lockoutRedisPrefix + username
+	now := time.Now()
+	var incr *redis.IntCmd
+	_, err := s.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		incr = pipe.HIncrBy(ctx, key, "failures", 1)
+		pipe.HSet(ctx, key, "last", now.UnixMilli())
+		// 超过窗口没有新的失败时整条记录过期 即重新计数
+		pipe.PExpire(ctx, key, window)
+		return nil
+	})
+	if err != nil {
+		return lockoutState{}, err
+	}
+	return lockoutState{Failures: int(incr.Val()), LastFailedAt: now}, nil
+}
+
+func (s redisLockoutStore) lock(username string, until time.Time) error {
+	ctx := context.Background()
+	key := lockoutRedisPrefix + username
+	_, err := s.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.HSet(ctx, key, "failures", 0, "locked", until.UnixMilli())
+		pipe.PExpireAt(ctx, key, until)
+		return nil
+	})
+	return err
+}
+
+func (s redisLockoutStore) reset(username string) error {
+	return s.client.Del(context.Background(), lockoutRedisPrefix+username).Err()
+}
+
+type dbLockoutStore struct{}
+
+func (s dbLockoutStore) get(username string) (state lockoutState, err error) {
+	var lockout system.SysLoginLockout
+	err = global.GVA_DB.Where("username = ?", username).First(&lockout).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return state, nil
+		}
+		return state, err
+	}
+	state.Failures, state.LastFailedAt = lockout.Failures, lockout.LastFailedAt
+	if lockout.LockedUntil != nil {
+		state.LockedUntil = *lockout.LockedUntil
+	}
+	return state, nil
+}
+
+func (s dbLockoutStore) fail(username string, window time.Duration) (lockoutState, error) {
+	now := time.Now()
+	// 窗口内的失败直接累加 避免并发请求读后写丢失计数
+	result := global.GVA_DB.Model(&system.SysLoginLockout{}).
+		Where("username = ? AND last_failed_at >= ?", username, now.Add(-window)).
+		Updates(map[string]interface{}{"failures": gorm.Expr("failures + 1"), "last_failed_at": now})
+	if result.Error != nil {
+		return lockoutState{}, result.Error
+	}
+	if result.RowsAffected == 0 {
+		err := global.GVA_DB.Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "username"}},
+			DoUpdates: clause.Assignments(map[string]interface{}{"failures": 1, "last_failed_at": now}),
+		}).Create(&system.SysLoginLockout{Username: username, Failures: 1, LastFailedAt: now}).Error
+		if err != nil {
+			return lockoutState{}, err
+		}
+	}
+	return s.get(username)
+}
+
+func (s dbLockoutStore) lock(username string, until time.Time) error {
+	return global.GVA_DB.Model(&system.SysLoginLockout{}).Where("username = ?", username).
+		Updates(map[string]interface{}{"failures": 0, "locked_until": until}).Error
+}
+
+func (s dbLockoutStore) reset(username string) error {
+	return global.GVA_DB.Unscoped().Where("username = ?", username).Delete(&system.SysLoginLockout{}).Error
+}
@@ -0,0 +1,85 @@
+package system
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/flipped-aurora/gin-vue-admin/server/config"
+	"github.com/flipped-aurora/gin-vue-admin/server/global"
+	"github.com/flipped-aurora/gin-vue-admin/server/model/common/request"
+	"github.com/flipped-aurora/gin-vue-admin/server/model/system"
+	systemReq "github.com/flipped-aurora/gin-vue-admin/server/model/system/request"
+)
+
+func TestLoginLockoutService_Delay(t *testing.T) {
+	global.GVA_CONFIG.System.Lockout = config.Lockout{DelayAfter: 3, MaxDelay: "10s"}
+	tests := []struct {
+		failures int
+		want     time.Duration
+	}{
+		{failures: 3, want: 0},
+		{failures: 4, want: time.Second},
+		{failures: 5, want: 2 * time.Second},
+		{failures: 7, want: 8 * time.Second},
+		{failures: 8, want: 10 * time.Second},
+		{failures: 100, want: 10 * time.Second},
+	}
+	s := &LoginLockoutService{}
+	for _, tt := range tests {
+		if got := s.delay(tt.failures); got != tt.want {
+			t.Errorf("delay(%d) = %v, want %v", tt.failures, got, tt.want)
+		}
+	}
+}
+
+func TestLoginLockoutService_Lockout(t *testing.T) {
+	db := setupTestDB(t, &system.SysLoginAttempt{}, &system.SysLoginLockout{})
+	var err error
+	global.GVA_CONFIG.System.Lockout = config.Lockout{MaxAttempts: 3, Window: "15m", Duration: "30m"}
+
+	s := &LoginLockoutService{}
+	for i := 0; i < 2; i++ {
+		s.Fail("Admin", "10.0.0.1", "test", "用户名不存在或者密码错误")
+	}
+	if err = s.Check("admin"); err != nil {
+		t.Fatalf("Check() before limit error = %v", err)
+	}
+	// 登录成功后重新计数
+	s.Succeed("admin")
+	for i := 0; i < 2; i++ {
+		s.Fail("admin", "10.0.0.2", "test", "用户名不存在或者密码错误")
+	}
+	if err = s.Check("admin"); err != nil {
+		t.Fatalf("Check() after succeed error = %v", err)
+	}
+	// 用户名不区分大小写 换IP也一样计数
+	s.Fail(" ADMIN", "10.0.0.3", "test", "用户名不存在或者密码错误")
+	if err = s.Check("admin"); !errors.Is(err, ErrAccountLocked) {
+		t.Fatalf("Check() after limit error = %v, want %v", err, ErrAccountLocked)
+	}
+	if err = s.Check("other"); err != nil {
+		t.Fatalf("Check() other user error = %v", err)
+	}
+
+	// 超过窗口的失败不累计
+	s.Fail("other", "10.0.0.1", "test", "用户名不存在或者密码错误")
+	s.Fail("other", "10.0.0.1", "test", "用户名不存在或者密码错误")
+	db.Model(&system.SysLoginLockout{}).Where("username = ?", "other").Update("last_failed_at", time.Now().Add(-time.Hour))
+	s.Fail("other", "10.0.0.1", "test", "用户名不存在或者密码错误")
+	if err = s.Check("other"); err != nil {
+		t.Fatalf("Check() after window error = %v", err)
+	}
+
+	list, total, err := s.GetLoginAttemptList(systemReq.SysLoginAttemptSearch{Username: "admin", PageInfo: request.PageInfo{Page: 1, PageSize: 10}})
+	if err != nil || total != 5 || !list[0].Locked || list[1].Locked {
+		t.Fatalf("GetLoginAttemptList() = %d, %v, err %v", total, list, err)
+	}
+
+	if err = s.Unlock("Admin"); err != nil {
+		t.Fatalf("Unlock() error = %v", err)
+	}
+	if err = s.Check("admin"); err != nil {
+		t.Fatalf("Check() after unlock error = %v", err)
+	}
+}
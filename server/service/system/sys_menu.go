@@ -5,6 +5,7 @@ import (
 	"github.com/flipped-aurora/gin-vue-admin/server/global"
 	"github.com/flipped-aurora/gin-vue-admin/server/model/common/request"
 	"github.com/flipped-aurora/gin-vue-admin/server/model/system"
+	systemReq "github.com/flipped-aurora/gin-vue-admin/server/model/system/request"
 	"gorm.io/gorm"
 	"strconv"
 )
@@ -19,14 +20,17 @@ type MenuService struct{}
 
 var MenuServiceApp = new(MenuService)
 
-func (menuService *MenuService) getMenuTreeMap(authorityId uint) (treeMap map[uint][]system.SysMenu, err error) {
+func (menuService *MenuService) getMenuTreeMap(authorityId uint, authorityIds ...uint) (treeMap map[uint][]system.SysMenu, err error) {
 	var allMenus []system.SysMenu
 	var baseMenu []system.SysBaseMenu
 	var btns []system.SysAuthorityBtn
 	treeMap = make(map[uint][]system.SysMenu)
+	if len(authorityIds) == 0 {
+		authorityIds = []uint{authorityId}
+	}
 
 	var SysAuthorityMenus []system.SysAuthorityMenu
-	err = global.GVA_DB.Where("sys_authority_authority_id = ?", authorityId).Find(&SysAuthorityMenus).Error
+	err = global.GVA_DB.Where("sys_authority_authority_id in ?", authorityIds).Find(&SysAuthorityMenus).Error
 	if err != nil {
 		return
 	}
@@ -51,7 +55,7 @@ func (menuService *MenuService) getMenuTreeMap(authorityId uint) (treeMap map[ui
 		})
 	}
 
-	err = global.GVA_DB.Where("authority_id = ?", authorityId).Preload("SysBaseMenuBtn").Find(&btns).Error
+	err = global.GVA_DB.Where("authority_id in ?", authorityIds).Preload("SysBaseMenuBtn").Find(&btns).Error
 	if err != nil {
 		return
 	}
@@ -72,11 +76,11 @@ func (menuService *MenuService) getMenuTreeMap(authorityId uint) (treeMap map[ui
 //@author: [piexlmax](https://github.com/piexlmax)
 //@function: GetMenuTree
 //@description: 获取动态菜单树
-//@param: authorityId string
+//@param: authorityId uint, authorityIds ...uint 为空时只取当前角色的菜单 否则取这些角色菜单的并集
 //@return: menus []system.SysMenu, err error
 
-func (menuService *MenuService) GetMenuTree(authorityId uint) (menus []system.SysMenu, err error) {
-	menuTree, err := menuService.getMenuTreeMap(authorityId)
+func (menuService *MenuService) GetMenuTree(authorityId uint, authorityIds ...uint) (menus []system.SysMenu, err error) {
+	menuTree, err := menuService.getMenuTreeMap(authorityId, authorityIds...)
 	menus = menuTree[0]
 	for i := 0; i < len(menus); i++ {
 		err = menuService.getChildrenList(&menus[i], menuTree)
@@ -192,11 +196,17 @@ func (menuService *MenuService) GetBaseMenuTree(authorityID uint) (menus []syste
 
 //@author: [piexlmax](https://github.com/piexlmax)
 //@function: AddMenuAuthority
-//@description: 为角色增加menu树
-//@param: menus []model.SysBaseMenu, authorityId string
+//@description: 为角色增加menu树 并保存角色权限的版本
+//@param: menus []model.SysBaseMenu, adminAuthorityID, operatorId uint, operatorName string, authorityId uint
 //@return: err error
 
-func (menuService *MenuService) AddMenuAuthority(menus []system.SysBaseMenu, adminAuthorityID, authorityId uint) (err error) {
+func (menuService *MenuService) AddMenuAuthority(menus []system.SysBaseMenu, adminAuthorityID, operatorId uint, operatorName string, authorityId uint) (err error) {
+	return AuthorityVersionServiceApp.Track(authorityId, operatorId, operatorName, systemReq.AuthorityVersionSetMenu, func() error {
+		return menuService.addMenuAuthority(menus, adminAuthorityID, authorityId)
+	})
+}
+
+func (menuService *MenuService) addMenuAuthority(menus []system.SysBaseMenu, adminAuthorityID, authorityId uint) (err error) {
 	var auth system.SysAuthority
 	auth.AuthorityId = authorityId
 	auth.SysBaseMenus = menus
@@ -277,7 +287,8 @@ func (menuService *MenuService) GetMenuAuthority(info *request.GetAuthorityId) (
 //	Author [SliverHorn](https://github.com/SliverHorn)
 func (menuService *MenuService) UserAuthorityDefaultRouter(user *system.SysUser) {
 	var menuIds []string
-	err := global.GVA_DB.Model(&system.SysAuthorityMenu{}).Where("sys_authority_authority_id = ?", user.AuthorityId).Pluck("sys_base_menu_id", &menuIds).Error
+	authorityIds := CasbinServiceApp.EffectiveAuthorityIds(user.ID, user.AuthorityId)
+	err := global.GVA_DB.Model(&system.SysAuthorityMenu{}).Where("sys_authority_authority_id in ?", authorityIds).Pluck("sys_base_menu_id", &menuIds).Error
 	if err != nil {
 		return
 	}
@@ -0,0 +1,215 @@
+package system
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/coreos/go-oidc/v3/oidc"
+	"github.com/flipped-aurora/gin-vue-admin/server/config"
+	"github.com/flipped-aurora/gin-vue-admin/server/global"
+	systemRes "github.com/flipped-aurora/gin-vue-admin/server/model/system/response"
+	"github.com/flipped-aurora/gin-vue-admin/server/utils"
+	"golang.org/x/oauth2"
+)
+
+var (
+	ErrOidcProviderNotFound = errors.New("身份源不存在")
+	ErrOidcState            = errors.New("登录请求已过期, 请重新登录")
+)
+
+const oidcStatePrefix = "oidc:state:"
+
+// oidcState 授权请求上下文 回调时校验
+type oidcState struct {
+	Provider string `json:"provider"`
+	Verifier string `json:"verifier"` // PKCE code_verifier
+	Nonce    string `json:"nonce"`
+}
+
+type OidcService struct{}
+
+var OidcServiceApp = new(OidcService)
+
+// oidcProviders 缓存发现结果 key为身份源标识
+var oidcProviders sync.Map
+
+// GetProviders 登录页可选择的身份源
+func (oidcService *OidcService) GetProviders() []systemRes.OidcProvider {
+	list := make([]systemRes.OidcProvider, 0, len(global.GVA_CONFIG.Oidc.Providers))
+	for _, p := range global.GVA_CONFIG.Oidc.Providers {
+		name := p.DisplayName
+		if name == "" {
+			name = p.Name
+		}
+		list = append(list, systemRes.OidcProvider{Name: p.Name, DisplayName: name})
+	}
+	return list
+}
+
+// AuthUrl 生成身份源授权地址 state nonce 和 PKCE verifier 保存在服务端
+func (oidcService *OidcService) AuthUrl(ctx context.Context, name string) (string, error) {
+	cfg, ok := oidcService.providerConfig(name)
+	if !ok {
+		return "", ErrOidcProviderNotFound
+	}
+	provider, err := oidcService.provider(ctx, cfg)
+	if err != nil {
+		return "", err
+	}
+	state, err := utils.RandomToken(24)
+	if err != nil {
+		return "", err
+	}
+	nonce, err := utils.RandomToken(24)
+	if err != nil {
+		return "", err
+	}
+	st := oidcState{Provider: cfg.Name, Verifier: oauth2.GenerateVerifier(), Nonce: nonce}
+	b, err := json.Marshal(st)
+	if err != nil {
+		return "", err
+	}
+	dr, err := utils.ParseDuration(global.GVA_CONFIG.Oidc.StateExpires)
+	if err != nil || dr <= 0 {
+		dr = 10 * time.Minute
+	}
+	if err = utils.CacheSet(oidcStatePrefix+state, string(b), dr); err != nil {
+		return "", err
+	}
+	return oidcService.oauth2Config(cfg, provider).AuthCodeURL(state, oidc.Nonce(nonce), oauth2.S256ChallengeOption(st.Verifier)), nil
+}
+
+// Exchange 使用授权码换取令牌并校验id_token 返回外部用户信息和身份源配置
+func (oidcService *OidcService) Exchange(ctx context.Context, state string, code string) (ext ExternalUser, cfg config.OidcProvider, err error) {
+	v, ok := utils.CacheGet(oidcStatePrefix + state)
+	if !ok {
+		return ext, cfg, ErrOidcState
+	}
+	// state 只能使用一次
+	utils.CacheDel(oidcStatePrefix + state)
+	var st oidcState
+	if err = json.Unmarshal([]byte(v), &st); err != nil {
+		return ext, cfg, ErrOidcState
+	}
+	cfg, ok = oidcService.providerConfig(st.Provider)
+	if !ok {
+		return ext, cfg, ErrOidcProviderNotFound
+	}
+	provider, err := oidcService.provider(ctx, cfg)
+	if err != nil {
+		return
+	}
+	token, err := oidcService.oauth2Config(cfg, provider).Exchange(ctx, code, oauth2.VerifierOption(st.Verifier))
+	if err != nil {
+		return ext, cfg, fmt.Errorf("授权码换取令牌失败: %w", err)
+	}
+	rawIdToken, ok := token.Extra("id_token").(string)
+	if !ok {
+		return ext, cfg, errors.New("身份源未返回id_token")
+	}
+	idToken, err := provider.Verifier(&oidc.Config{ClientID: cfg.ClientId}).Verify(ctx, rawIdToken)
+	if err != nil {
+		return ext, cfg, fmt.Errorf("id_token校验失败: %w", err)
+	}
+	if idToken.Nonce != st.Nonce {
+		return ext, cfg, errors.New("id_token nonce不匹配")
+	}
+	claims := map[string]interface{}{}
+	if err = idToken.Claims(&claims); err != nil {
+		return
+	}
+	usernameClaim, groupsClaim := cfg.UsernameClaim, cfg.GroupsClaim
+	if usernameClaim == "" {
+		usernameClaim = "preferred_username"
+	}
+	if groupsClaim == "" {
+		groupsClaim = "groups"
+	}
+	// 部分身份源只在userinfo中返回组信息
+	if _, ok = claims[groupsClaim]; !ok {
+		if info, err := provider.UserInfo(ctx, oauth2.StaticTokenSource(token)); err == nil {
+			extra := map[string]interface{}{}
+			if info.Claims(&extra) == nil {
+				for k, v := range extra {
+					if _, exists := claims[k]; !exists {
+						claims[k] = v
+					}
+				}
+			}
+		}
+	}
+	return ExternalUser{
+		Provider: cfg.Name,
+		Subject:  idToken.Subject,
+		Username: claimString(claims, usernameClaim),
+		NickName: claimString(claims, "name"),
+		Email:    claimString(claims, "email"),
+		Groups:   claimStrings(claims, groupsClaim),
+	}, cfg, nil
+}
+
+func (oidcService *OidcService) providerConfig(name string) (config.OidcProvider, bool) {
+	for _, p := range global.GVA_CONFIG.Oidc.Providers {
+		if p.Name == name {
+			return p, true
+		}
+	}
+	return config.OidcProvider{}, false
+}
+
+// provider 获取身份源元数据 发现成功后缓存 issuer变更时重新发现
+func (oidcService *OidcService) provider(ctx context.Context, cfg config.OidcProvider) (*oidc.Provider, error) {
+	if v, ok := oidcProviders.Load(cfg.Name + "|" + cfg.Issuer); ok {
+		return v.(*oidc.Provider), nil
+	}
+	provider, err := oidc.NewProvider(ctx, cfg.Issuer)
+	if err != nil {
+		return nil, fmt.Errorf("身份源发现失败: %w", err)
+	}
+	oidcProviders.Store(cfg.Name+"|"+cfg.Issuer, provider)
+	return provider, nil
+}
+
+func (oidcService *OidcService) oauth2Config(cfg config.OidcProvider, provider *oidc.Provider) *oauth2.Config {
+	scopes := []string{oidc.ScopeOpenID}
+	for _, s := range cfg.Scopes {
+		if s != oidc.ScopeOpenID {
+			scopes = append(scopes, s)
+		}
+	}
+	return &oauth2.Config{
+		ClientID:     cfg.ClientId,
+		ClientSecret: cfg.ClientSecret,
+		RedirectURL:  cfg.RedirectUrl,
+		Endpoint:     provider.Endpoint(),
+		Scopes:       scopes,
+	}
+}
+
+func claimString(claims map[string]interface{}, key string) string {
+	if v, ok := claims[key].(string); ok {
+		return v
+	}
+	return ""
+}
+
+// claimStrings 组claim可能是字符串数组或单个字符串
+func claimStrings(claims map[string]interface{}, key string) []string {
+	switch v := claims[key].(type) {
+	case string:
+		return []string{v}
+	case []interface{}:
+		values := make([]string, 0, len(v))
+		for _, item := range v {
+			if s, ok := item.(string); ok {
+				values = append(values, s)
+			}
+		}
+		return values
+	}
+	return nil
+}
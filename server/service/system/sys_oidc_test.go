@@ -0,0 +1,162 @@
+package system
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/flipped-aurora/gin-vue-admin/server/config"
+	"github.com/flipped-aurora/gin-vue-admin/server/global"
+	"github.com/flipped-aurora/gin-vue-admin/server/model/system"
+	systemReq "github.com/flipped-aurora/gin-vue-admin/server/model/system/request"
+	"github.com/flipped-aurora/gin-vue-admin/server/utils"
+	jwt "github.com/golang-jwt/jwt/v4"
+)
+
+// mockIdP 本地模拟的OIDC身份源 校验PKCE并签发id_token
+type mockIdP struct {
+	server    *httptest.Server
+	key       *utils.JWTKey
+	nonce     string
+	challenge string
+	subject   string
+	groups    []string
+}
+
+func newMockIdP(t *testing.T) *mockIdP {
+	key, err := utils.GenerateJWTKey(utils.JWTAlgRS256)
+	if err != nil {
+		t.Fatalf("GenerateJWTKey() error = %v", err)
+	}
+	idp := &mockIdP{key: key}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"issuer":                                idp.server.URL,
+			"authorization_endpoint":                idp.server.URL + "/auth",
+			"token_endpoint":                        idp.server.URL + "/token",
+			"jwks_uri":                              idp.server.URL + "/jwks",
+			"id_token_signing_alg_values_supported": []string{"RS256"},
+		})
+	})
+	mux.HandleFunc("/jwks", func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(utils.JWKS([]*utils.JWTKey{idp.key}))
+	})
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		_ = r.ParseForm()
+		sum := sha256.Sum256([]byte(r.PostForm.Get("code_verifier")))
+		if base64.RawURLEncoding.EncodeToString(sum[:]) != idp.challenge || r.PostForm.Get("code") != "test-code" {
+			w.WriteHeader(http.StatusBadRequest)
+			_ = json.NewEncoder(w).Encode(map[string]string{"error": "invalid_grant"})
+			return
+		}
+		token := jwt.NewWithClaims(jwt.SigningMethodRS256, jwt.MapClaims{
+			"iss":                idp.server.URL,
+			"aud":                "gva",
+			"sub":                idp.subject,
+			"iat":                time.Now().Unix(),
+			"exp":                time.Now().Add(time.Minute).Unix(),
+			"nonce":              idp.nonce,
+			"preferred_username": "alice",
+			"email":              "alice@example.com",
+			"groups":             idp.groups,
+		})
+		token.Header["kid"] = idp.key.Kid
+		idToken, _ := token.SignedString(idp.key.Private)
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"access_token": "access",
+			"token_type":   "Bearer",
+			"expires_in":   60,
+			"id_token":     idToken,
+		})
+	})
+	idp.server = httptest.NewServer(mux)
+	t.Cleanup(idp.server.Close)
+	return idp
+}
+
+// authorize 模拟浏览器跳转到身份源 记录nonce和code_challenge
+func (idp *mockIdP) authorize(t *testing.T, authUrl string) (state string) {
+	u, err := url.Parse(authUrl)
+	if err != nil {
+		t.Fatalf("parse auth url failed: %v", err)
+	}
+	q := u.Query()
+	if q.Get("code_challenge_method") != "S256" {
+		t.Fatalf("auth url missing PKCE: %s", authUrl)
+	}
+	idp.nonce = q.Get("nonce")
+	idp.challenge = q.Get("code_challenge")
+	return q.Get("state")
+}
+
+func TestOidcService_Login(t *testing.T) {
+	db := setupTestDB(t, &system.SysUser{}, &system.SysAuthority{}, &system.SysUserIdentity{}, &system.SysAuthorityClaimMap{})
+	db.Create(&system.SysAuthority{AuthorityId: 888, AuthorityName: "admin"})
+	db.Create(&system.SysAuthority{AuthorityId: 9528, AuthorityName: "staff"})
+	db.Create(&system.SysAuthorityClaimMap{Provider: "mock", ClaimValue: "gva-admins", AuthorityId: 888})
+	db.Create(&system.SysAuthorityClaimMap{ClaimValue: "staff", AuthorityId: 9528})
+
+	idp := newMockIdP(t)
+	idp.subject = "user-1"
+	idp.groups = []string{"staff", "gva-admins", "unmapped"}
+	cfg := config.OidcProvider{Name: "mock", Issuer: idp.server.URL, ClientId: "gva", RedirectUrl: "http://localhost:8080/", AutoCreate: true}
+	global.GVA_CONFIG.Oidc.Providers = []config.OidcProvider{cfg}
+
+	s := &OidcService{}
+	ctx := context.Background()
+	authUrl, err := s.AuthUrl(ctx, "mock")
+	if err != nil {
+		t.Fatalf("AuthUrl() error = %v", err)
+	}
+	state := idp.authorize(t, authUrl)
+	ext, _, err := s.Exchange(ctx, state, "test-code")
+	if err != nil {
+		t.Fatalf("Exchange() error = %v", err)
+	}
+	if ext.Subject != "user-1" || ext.Username != "alice" || len(ext.Groups) != 3 {
+		t.Fatalf("Exchange() got %+v", ext)
+	}
+	// state 只能使用一次
+	if _, _, err = s.Exchange(ctx, state, "test-code"); !errors.Is(err, ErrOidcState) {
+		t.Fatalf("Exchange() replay error = %v, want %v", err, ErrOidcState)
+	}
+
+	opts := systemReq.ProvisionOptions{AutoCreate: cfg.AutoCreate}
+	user, err := UserIdentityServiceApp.LoginExternalUser(ext, opts)
+	if err != nil {
+		t.Fatalf("LoginExternalUser() error = %v", err)
+	}
+	if user.Username != "alice" || len(user.Authorities) != 2 || user.AuthorityId != 888 {
+		t.Fatalf("LoginExternalUser() got user %s authority %d authorities %d", user.Username, user.AuthorityId, len(user.Authorities))
+	}
+
+	// 再次登录时按组同步角色 不会重复创建用户
+	ext.Groups = []string{"staff"}
+	again, err := UserIdentityServiceApp.LoginExternalUser(ext, opts)
+	if err != nil {
+		t.Fatalf("LoginExternalUser() second error = %v", err)
+	}
+	if again.ID != user.ID || len(again.Authorities) != 1 || again.AuthorityId != 9528 {
+		t.Fatalf("LoginExternalUser() second got id %d authority %d authorities %d", again.ID, again.AuthorityId, len(again.Authorities))
+	}
+
+	// 其他身份的同名用户不会被自动关联
+	ext.Subject = "user-2"
+	if _, err = UserIdentityServiceApp.LoginExternalUser(ext, opts); err == nil {
+		t.Fatalf("LoginExternalUser() with conflicting username should fail")
+	}
+	// 未匹配到角色且没有默认角色时拒绝开通
+	ext.Subject, ext.Username, ext.Groups = "user-3", "bob", nil
+	if _, err = UserIdentityServiceApp.LoginExternalUser(ext, opts); err == nil {
+		t.Fatalf("LoginExternalUser() without authority should fail")
+	}
+}
@@ -79,10 +79,13 @@ func (operationRecordService *OperationRecordService) GetSysOperationRecordInfoL
 	if info.Status != 0 {
 		db = db.Where("status = ?", info.Status)
 	}
+	if info.ImpersonatorID != 0 {
+		db = db.Where("impersonator_id = ?", info.ImpersonatorID)
+	}
 	err = db.Count(&total).Error
 	if err != nil {
 		return
 	}
-	err = db.Order("id desc").Limit(limit).Offset(offset).Preload("User").Find(&sysOperationRecords).Error
+	err = db.Order("id desc").Limit(limit).Offset(offset).Preload("User").Preload("Impersonator").Find(&sysOperationRecords).Error
 	return sysOperationRecords, total, err
 }
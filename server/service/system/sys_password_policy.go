@@ -0,0 +1,153 @@
+package system
+
+import (
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/flipped-aurora/gin-vue-admin/server/global"
+	"github.com/flipped-aurora/gin-vue-admin/server/model/system"
+	"github.com/flipped-aurora/gin-vue-admin/server/utils"
+	"gorm.io/gorm"
+)
+
+const (
+	passwordChallengePrefix  = "pwd:challenge:"
+	passwordChallengeExpires = 10 * time.Minute
+)
+
+var (
+	ErrPasswordReused    = errors.New("不能使用最近使用过的密码")
+	ErrPasswordChallenge = errors.New("修改密码已超时, 请重新登录")
+)
+
+type PasswordPolicyService struct{}
+
+var PasswordPolicyServiceApp = new(PasswordPolicyService)
+
+// CheckPassword 校验新密码是否满足密码策略 userId 不为0时同时校验历史密码
+func (passwordPolicyService *PasswordPolicyService) CheckPassword(userId uint, username string, password string) error {
+	policy := global.GVA_CONFIG.System.PasswordPolicy
+	if err := utils.CheckPasswordPolicy(policy, username, password); err != nil {
+		return err
+	}
+	if userId == 0 || policy.HistoryCount <= 0 {
+		return nil
+	}
+	var user system.SysUser
+	if err := global.GVA_DB.Select("id", "password").First(&user, userId).Error; err != nil {
+		return err
+	}
+	if utils.BcryptCheck(password, user.Password) {
+		return ErrPasswordReused
+	}
+	var hashes []string
+	err := global.GVA_DB.Model(&system.SysPasswordHistory{}).Where("user_id = ?", userId).
+		Order("id desc").Limit(policy.HistoryCount).Pluck("password_hash", &hashes).Error
+	if err != nil {
+		return err
+	}
+	for _, hash := range hashes {
+		if utils.BcryptCheck(password, hash) {
+			return ErrPasswordReused
+		}
+	}
+	return nil
+}
+
+// SetPassword 设置用户密码并写入历史 mustChange 为true时用户下次登录必须修改密码
+// 调用方需要先通过 CheckPassword 校验
+func (passwordPolicyService *PasswordPolicyService) SetPassword(tx *gorm.DB, userId uint, password string, mustChange bool) error {
+	hash := utils.BcryptHash(password)
+	err := tx.Model(&system.SysUser{}).Where("id = ?", userId).Updates(map[string]interface{}{
+		"password":             hash,
+		"password_changed_at":  time.Now(),
+		"must_change_password": mustChange,
+	}).Error
+	if err != nil {
+		return err
+	}
+	// 管理员重置的临时密码不计入历史
+	if mustChange {
+		return nil
+	}
+	return passwordPolicyService.recordHistory(tx, userId, hash)
+}
+
+// recordHistory 记录密码哈希 只保留策略要求的条数
+func (passwordPolicyService *PasswordPolicyService) recordHistory(tx *gorm.DB, userId uint, hash string) error {
+	keep := global.GVA_CONFIG.System.PasswordPolicy.HistoryCount
+	if keep <= 0 {
+		return nil
+	}
+	if err := tx.Create(&system.SysPasswordHistory{UserId: userId, PasswordHash: hash}).Error; err != nil {
+		return err
+	}
+	var ids []uint
+	err := tx.Model(&system.SysPasswordHistory{}).Where("user_id = ?", userId).
+		Order("id desc").Offset(keep).Pluck("id", &ids).Error
+	if err != nil || len(ids) == 0 {
+		return err
+	}
+	return tx.Unscoped().Delete(&system.SysPasswordHistory{}, ids).Error
+}
+
+// NeedChangePassword 登录时判断用户是否必须先修改密码 返回提示原因
+// 通过外部身份源登录的用户不使用本地密码 不受密码有效期限制
+func (passwordPolicyService *PasswordPolicyService) NeedChangePassword(user system.SysUser) (bool, string) {
+	if user.MustChangePassword {
+		return true, "密码已被管理员重置, 请设置新密码"
+	}
+	maxAge, err := utils.ParseDuration(global.GVA_CONFIG.System.PasswordPolicy.MaxAge)
+	if err != nil || maxAge <= 0 {
+		return false, ""
+	}
+	changedAt := user.CreatedAt
+	if user.PasswordChangedAt != nil {
+		changedAt = *user.PasswordChangedAt
+	}
+	if time.Since(changedAt) < maxAge {
+		return false, ""
+	}
+	var count int64
+	global.GVA_DB.Model(&system.SysUserIdentity{}).Where("user_id = ?", user.ID).Count(&count)
+	if count > 0 {
+		return false, ""
+	}
+	return true, "密码已过期, 请设置新密码"
+}
+
+// CreateChallenge 登录时需要修改密码 签发修改密码的挑战
+func (passwordPolicyService *PasswordPolicyService) CreateChallenge(userId uint) (string, error) {
+	id, err := utils.RandomToken(24)
+	if err != nil {
+		return "", err
+	}
+	return id, utils.CacheSet(passwordChallengePrefix+id, strconv.Itoa(int(userId)), passwordChallengeExpires)
+}
+
+// GetChallenge 获取挑战对应的用户 不消耗挑战 新密码不符合规则时可以重试
+func (passwordPolicyService *PasswordPolicyService) GetChallenge(id string) (uint, error) {
+	return parsePasswordChallenge(utils.CacheGet(passwordChallengePrefix + id))
+}
+
+// TakeChallenge 获取并删除挑战 修改密码前调用 并发请求中只有一个能取到 保证只能使用一次
+func (passwordPolicyService *PasswordPolicyService) TakeChallenge(id string) (uint, error) {
+	return parsePasswordChallenge(utils.CacheTake(passwordChallengePrefix + id))
+}
+
+func parsePasswordChallenge(v string, ok bool) (uint, error) {
+	if !ok {
+		return 0, ErrPasswordChallenge
+	}
+	userId, err := strconv.Atoi(v)
+	if err != nil {
+		return 0, ErrPasswordChallenge
+	}
+	return uint(userId), nil
+}
+
+// FinishChallenge 放弃挑战 用户已被禁用时调用
+func (passwordPolicyService *PasswordPolicyService) FinishChallenge(id string) {
+	utils.CacheDel(passwordChallengePrefix + id)
+}
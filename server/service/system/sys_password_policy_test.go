@@ -0,0 +1,132 @@
+package system
+
+import (
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/flipped-aurora/gin-vue-admin/server/config"
+	"github.com/flipped-aurora/gin-vue-admin/server/global"
+	"github.com/flipped-aurora/gin-vue-admin/server/model/system"
+	"github.com/flipped-aurora/gin-vue-admin/server/utils"
+)
+
+func setupPasswordPolicyTest(t *testing.T) {
+	setupTestDB(t, &system.SysUser{}, &system.SysPasswordHistory{}, &system.SysUserIdentity{}, &system.SysRefreshToken{}, &system.SysUserSession{})
+	global.GVA_CONFIG.System.PasswordPolicy = config.PasswordPolicy{MinLength: 8, RequireDigit: true, DisallowUsername: true, HistoryCount: 2}
+}
+
+func TestPasswordPolicyService_History(t *testing.T) {
+	setupPasswordPolicyTest(t)
+	u := &UserService{}
+	if _, err := u.Register(system.SysUser{Username: "alice", Password: "short1"}); err == nil {
+		t.Fatalf("Register() with weak password should fail")
+	}
+	user, err := u.Register(system.SysUser{Username: "alice", Password: "password1"})
+	if err != nil {
+		t.Fatalf("Register() error = %v", err)
+	}
+
+	change := func(old, new string) error {
+		_, err := u.ChangePassword(&system.SysUser{GVA_MODEL: global.GVA_MODEL{ID: user.ID}, Password: old}, new)
+		return err
+	}
+	if err = change("password1", "password1"); !errors.Is(err, ErrPasswordReused) {
+		t.Fatalf("ChangePassword() to current password error = %v, want %v", err, ErrPasswordReused)
+	}
+	if err = change("password1", "password2"); err != nil {
+		t.Fatalf("ChangePassword() error = %v", err)
+	}
+	if err = change("password2", "password3"); err != nil {
+		t.Fatalf("ChangePassword() error = %v", err)
+	}
+	// 最近两次的密码不能再使用 更早的密码可以
+	if err = change("password3", "password2"); !errors.Is(err, ErrPasswordReused) {
+		t.Fatalf("ChangePassword() to recent password error = %v, want %v", err, ErrPasswordReused)
+	}
+	if err = change("password3", "password1"); err != nil {
+		t.Fatalf("ChangePassword() to old password error = %v", err)
+	}
+	var count int64
+	global.GVA_DB.Model(&system.SysPasswordHistory{}).Where("user_id = ?", user.ID).Count(&count)
+	if count != 2 {
+		t.Fatalf("password history count = %d, want 2", count)
+	}
+}
+
+func TestPasswordPolicyService_NeedChangePassword(t *testing.T) {
+	setupPasswordPolicyTest(t)
+	s := &PasswordPolicyService{}
+	old := time.Now().Add(-100 * 24 * time.Hour)
+	global.GVA_DB.Create(&system.SysUser{GVA_MODEL: global.GVA_MODEL{ID: 1}, Username: "local", Password: utils.BcryptHash("password1"), PasswordChangedAt: &old})
+	global.GVA_DB.Create(&system.SysUser{GVA_MODEL: global.GVA_MODEL{ID: 2}, Username: "sso", PasswordChangedAt: &old})
+	global.GVA_DB.Create(&system.SysUserIdentity{UserId: 2, Provider: "corp", Subject: "sso"})
+
+	load := func(id uint) system.SysUser {
+		var user system.SysUser
+		global.GVA_DB.First(&user, id)
+		return user
+	}
+	if need, _ := s.NeedChangePassword(load(1)); need {
+		t.Fatalf("NeedChangePassword() without max-age should be false")
+	}
+	global.GVA_CONFIG.System.PasswordPolicy.MaxAge = "90d"
+	if need, _ := s.NeedChangePassword(load(1)); !need {
+		t.Fatalf("NeedChangePassword() with expired password should be true")
+	}
+	if need, _ := s.NeedChangePassword(load(2)); need {
+		t.Fatalf("NeedChangePassword() for external user should be false")
+	}
+
+	// 管理员重置后必须修改 修改后清除标记
+	if err := (&UserService{}).ResetPassword(2); err != nil {
+		t.Fatalf("ResetPassword() error = %v", err)
+	}
+	if need, _ := s.NeedChangePassword(load(2)); !need {
+		t.Fatalf("NeedChangePassword() after reset should be true")
+	}
+	if err := s.SetPassword(global.GVA_DB, 2, "password9", false); err != nil {
+		t.Fatalf("SetPassword() error = %v", err)
+	}
+	if need, _ := s.NeedChangePassword(load(2)); need {
+		t.Fatalf("NeedChangePassword() after change should be false")
+	}
+	if need, _ := s.NeedChangePassword(load(1)); !need {
+		t.Fatalf("NeedChangePassword() for other user should be unchanged")
+	}
+
+	id, err := s.CreateChallenge(1)
+	if err != nil {
+		t.Fatalf("CreateChallenge() error = %v", err)
+	}
+	if userId, err := s.GetChallenge(id); err != nil || userId != 1 {
+		t.Fatalf("GetChallenge() = %d, %v", userId, err)
+	}
+	s.FinishChallenge(id)
+	if _, err = s.GetChallenge(id); !errors.Is(err, ErrPasswordChallenge) {
+		t.Fatalf("GetChallenge() after finish error = %v", err)
+	}
+
+	// 并发取同一挑战时只有一个成功
+	id, err = s.CreateChallenge(1)
+	if err != nil {
+		t.Fatalf("CreateChallenge() error = %v", err)
+	}
+	var taken atomic.Int32
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if userId, err := s.TakeChallenge(id); err == nil && userId == 1 {
+				taken.Add(1)
+			}
+		}()
+	}
+	wg.Wait()
+	if taken.Load() != 1 {
+		t.Fatalf("TakeChallenge() succeeded %d times, want 1", taken.Load())
+	}
+}
@@ -0,0 +1,158 @@
+package system
+
+import (
+	"errors"
+	"fmt"
+	"html"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/flipped-aurora/gin-vue-admin/server/global"
+	"github.com/flipped-aurora/gin-vue-admin/server/model/system"
+	emailUtils "github.com/flipped-aurora/gin-vue-admin/server/plugin/email/utils"
+	"github.com/flipped-aurora/gin-vue-admin/server/utils"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+const (
+	defaultPasswordResetExpires = 30 * time.Minute
+	defaultPasswordResetWindow  = time.Hour
+)
+
+var (
+	ErrPasswordResetDisabled = errors.New("未开启找回密码, 请联系管理员")
+	ErrPasswordResetInvalid  = errors.New("重置链接无效或已过期, 请重新申请")
+)
+
+type PasswordResetService struct{}
+
+// IsDisabled 是否因未开启找回密码被拒绝
+func (passwordResetService *PasswordResetService) IsDisabled(err error) bool {
+	return errors.Is(err, ErrPasswordResetDisabled)
+}
+
+var PasswordResetServiceApp = new(PasswordResetService)
+
+// RequestReset 向邮箱对应的用户发送重置链接
+// 邮箱未注册 超过发送频率或用户通过外部身份源登录时静默忽略 避免通过返回结果判断邮箱是否注册
+func (passwordResetService *PasswordResetService) RequestReset(email string, ip string) error {
+	cfg := global.GVA_CONFIG.System.PasswordReset
+	if !cfg.Enable {
+		return ErrPasswordResetDisabled
+	}
+	email = strings.ToLower(strings.TrimSpace(email))
+	if cfg.Limit > 0 {
+		var count int64
+		err := global.GVA_DB.Model(&system.SysPasswordReset{}).
+			Where("email = ? AND created_at > ?", email, time.Now().Add(-parseDurationOr(cfg.Window, defaultPasswordResetWindow))).
+			Count(&count).Error
+		if err != nil {
+			return err
+		}
+		if count >= int64(cfg.Limit) {
+			global.GVA_LOG.Warn("重置密码邮件发送过于频繁", zap.String("email", email), zap.String("ip", ip))
+			return nil
+		}
+	}
+	var users []system.SysUser
+	err := global.GVA_DB.Where("LOWER(email) = ? AND enable = ?", email, 1).
+		Where("id NOT IN (?)", global.GVA_DB.Model(&system.SysUserIdentity{}).Select("user_id")).
+		Find(&users).Error
+	if err != nil {
+		return err
+	}
+	expires := parseDurationOr(cfg.Expires, defaultPasswordResetExpires)
+	for i := range users {
+		token, err := utils.RandomToken(32)
+		if err != nil {
+			return err
+		}
+		err = global.GVA_DB.Create(&system.SysPasswordReset{
+			UserId:    users[i].ID,
+			Email:     email,
+			TokenHash: utils.SHA256V(token),
+			Ip:        ip,
+			ExpiresAt: time.Now().Add(expires),
+		}).Error
+		if err != nil {
+			return err
+		}
+		go passwordResetService.send(users[i], cfg.Url, token, expires)
+	}
+	return nil
+}
+
+// Reset 使用重置令牌设置新密码 令牌只能使用一次
+// 成功后用户的全部令牌和会话失效 登录锁定同时解除
+func (passwordResetService *PasswordResetService) Reset(token string, newPassword string) error {
+	var record system.SysPasswordReset
+	err := global.GVA_DB.Where("token_hash = ?", utils.SHA256V(token)).First(&record).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrPasswordResetInvalid
+		}
+		return err
+	}
+	if record.UsedAt != nil || time.Now().After(record.ExpiresAt) {
+		return ErrPasswordResetInvalid
+	}
+	var user system.SysUser
+	if err = global.GVA_DB.Select("id", "username", "enable").First(&user, record.UserId).Error; err != nil || user.Enable != 1 {
+		return ErrPasswordResetInvalid
+	}
+	if err = PasswordPolicyServiceApp.CheckPassword(user.ID, user.Username, newPassword); err != nil {
+		return err
+	}
+	err = global.GVA_DB.Transaction(func(tx *gorm.DB) error {
+		// 条件更新 保证并发请求中只有一个能够使用该令牌
+		now := time.Now()
+		result := tx.Model(&system.SysPasswordReset{}).Where("id = ? AND used_at IS NULL", record.ID).Update("used_at", now)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrPasswordResetInvalid
+		}
+		// 同一用户之前申请的其他链接一并作废
+		err := tx.Model(&system.SysPasswordReset{}).Where("user_id = ? AND used_at IS NULL", user.ID).Update("used_at", now).Error
+		if err != nil {
+			return err
+		}
+		if err = PasswordPolicyServiceApp.SetPassword(tx, user.ID, newPassword, false); err != nil {
+			return err
+		}
+		// 安全戳与密码一起提交 旧密码签发的令牌随密码修改立即失效
+		return SecurityStampServiceApp.Rotate(tx, user.ID)
+	})
+	if err != nil {
+		return err
+	}
+	// 密码已修改 之后的失败只记录日志 不影响重置结果
+	SecurityStampServiceApp.ClearCache(user.ID)
+	if err = SessionServiceApp.RevokeUserSessions(user.ID); err != nil {
+		global.GVA_LOG.Warn("下线会话失败!", zap.Error(err))
+	}
+	if err = LoginLockoutServiceApp.Unlock(user.Username); err != nil {
+		global.GVA_LOG.Warn("解除账号锁定失败!", zap.Error(err))
+	}
+	return nil
+}
+
+// send 在后台发送邮件 链接地址由调用方读取配置后传入
+func (passwordResetService *PasswordResetService) send(user system.SysUser, base, token string, expires time.Duration) {
+	link, err := url.Parse(base)
+	if err != nil {
+		global.GVA_LOG.Error("重置密码地址配置有误!", zap.Error(err))
+		return
+	}
+	q := link.Query()
+	q.Set("resetToken", token)
+	link.RawQuery = q.Encode()
+	body := fmt.Sprintf(`您好 %s, 您正在找回密码, 请在 %s 内点击以下链接设置新密码, 链接只能使用一次:<br/><a href="%s">%s</a><br/>如非本人操作请忽略本邮件。`,
+		html.EscapeString(user.Username), formatWait(expires), html.EscapeString(link.String()), html.EscapeString(link.String()))
+	if err = emailUtils.Email(user.Email, "找回密码", body); err != nil {
+		global.GVA_LOG.Error("发送重置密码邮件失败!", zap.Error(err))
+	}
+}
@@ -0,0 +1,99 @@
+package system
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/flipped-aurora/gin-vue-admin/server/config"
+	"github.com/flipped-aurora/gin-vue-admin/server/global"
+	"github.com/flipped-aurora/gin-vue-admin/server/model/system"
+	"github.com/flipped-aurora/gin-vue-admin/server/utils"
+)
+
+func setupPasswordResetTest(t *testing.T) {
+	db := setupTestDB(t, &system.SysUser{}, &system.SysUserIdentity{}, &system.SysPasswordReset{}, &system.SysPasswordHistory{},
+		&system.SysRefreshToken{}, &system.SysUserSession{}, &system.SysLoginLockout{})
+	global.GVA_CONFIG.System.PasswordPolicy = config.PasswordPolicy{MinLength: 8}
+	global.GVA_CONFIG.System.Lockout = config.Lockout{MaxAttempts: 3}
+	global.GVA_CONFIG.System.PasswordReset = config.PasswordReset{Enable: true, Url: "http://127.0.0.1:8080/", Limit: 2, Window: "1h"}
+	db.Create(&[]system.SysUser{
+		{GVA_MODEL: global.GVA_MODEL{ID: 1}, Username: "alice", Email: "Alice@example.com", Enable: 1, Password: utils.BcryptHash("password1")},
+		{GVA_MODEL: global.GVA_MODEL{ID: 2}, Username: "sso", Email: "sso@example.com", Enable: 1},
+	})
+	db.Create(&system.SysUserIdentity{UserId: 2, Provider: "corp", Subject: "sso"})
+}
+
+func TestPasswordResetService_RequestReset(t *testing.T) {
+	setupPasswordResetTest(t)
+	s := &PasswordResetService{}
+	count := func(email string) (n int64) {
+		global.GVA_DB.Model(&system.SysPasswordReset{}).Where("email = ?", email).Count(&n)
+		return
+	}
+	// 未注册邮箱和外部身份源用户不报错也不发送
+	for _, email := range []string{"nobody@example.com", "sso@example.com"} {
+		if err := s.RequestReset(email, "10.0.0.1"); err != nil || count(email) != 0 {
+			t.Fatalf("RequestReset(%s) = %v, rows %d", email, err, count(email))
+		}
+	}
+	// 邮箱不区分大小写 超过发送频率后静默忽略
+	for i := 0; i < 3; i++ {
+		if err := s.RequestReset(" alice@EXAMPLE.com", "10.0.0.1"); err != nil {
+			t.Fatalf("RequestReset() error = %v", err)
+		}
+	}
+	if n := count("alice@example.com"); n != 2 {
+		t.Fatalf("reset rows = %d, want 2", n)
+	}
+
+	global.GVA_CONFIG.System.PasswordReset.Enable = false
+	if err := s.RequestReset("alice@example.com", "10.0.0.1"); !errors.Is(err, ErrPasswordResetDisabled) {
+		t.Fatalf("RequestReset() disabled error = %v, want %v", err, ErrPasswordResetDisabled)
+	}
+}
+
+func TestPasswordResetService_Reset(t *testing.T) {
+	setupPasswordResetTest(t)
+	s := &PasswordResetService{}
+	issue := func(token string, expiresAt time.Time) {
+		global.GVA_DB.Create(&system.SysPasswordReset{UserId: 1, Email: "alice@example.com", TokenHash: utils.SHA256V(token), ExpiresAt: expiresAt})
+	}
+	issue("expired", time.Now().Add(-time.Minute))
+	issue("first", time.Now().Add(time.Hour))
+	issue("second", time.Now().Add(time.Hour))
+	stamp, _ := SecurityStampServiceApp.Stamp(1)
+	_ = LoginLockoutServiceApp.store().lock("alice", time.Now().Add(time.Hour))
+
+	tests := []struct {
+		name     string
+		token    string
+		password string
+		wantErr  error
+	}{
+		{name: "令牌不存在", token: "unknown", password: "password2", wantErr: ErrPasswordResetInvalid},
+		{name: "令牌已过期", token: "expired", password: "password2", wantErr: ErrPasswordResetInvalid},
+		{name: "重置成功", token: "first", password: "password2"},
+		{name: "令牌只能使用一次", token: "first", password: "password3", wantErr: ErrPasswordResetInvalid},
+		{name: "之前申请的链接一并失效", token: "second", password: "password3", wantErr: ErrPasswordResetInvalid},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := s.Reset(tt.token, tt.password); !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Reset() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+
+	var user system.SysUser
+	global.GVA_DB.First(&user, 1)
+	if !utils.BcryptCheck("password2", user.Password) {
+		t.Fatalf("password not changed")
+	}
+	if err := SecurityStampServiceApp.Verify(1, stamp); !errors.Is(err, ErrSecurityStampInvalid) {
+		t.Fatalf("Verify() old token error = %v, want %v", err, ErrSecurityStampInvalid)
+	}
+	if err := LoginLockoutServiceApp.Check("alice"); err != nil {
+		t.Fatalf("Check() after reset error = %v", err)
+	}
+}
@@ -0,0 +1,121 @@
+package system
+
+import (
+	"errors"
+	"time"
+
+	"github.com/flipped-aurora/gin-vue-admin/server/global"
+	"github.com/flipped-aurora/gin-vue-admin/server/model/system"
+	"github.com/flipped-aurora/gin-vue-admin/server/utils"
+	"github.com/gofrs/uuid/v5"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrRefreshTokenInvalid = errors.New("刷新令牌无效或已过期")
+	ErrRefreshTokenReused  = errors.New("刷新令牌被重复使用, 该登录已被强制下线")
+)
+
+const (
+	refreshFamilyCachePrefix  = "family:"
+	defaultAccessTokenExpires = 30 * time.Minute
+)
+
+type RefreshTokenService struct{}
+
+var RefreshTokenServiceApp = new(RefreshTokenService)
+
+// NewFamily 生成新的令牌族ID 每次登录对应一个令牌族
+func (refreshTokenService *RefreshTokenService) NewFamily() string {
+	return uuid.Must(uuid.NewV4()).String()
+}
+
+// IssueRefreshToken 为用户签发刷新令牌 数据库只保存令牌摘要
+func (refreshTokenService *RefreshTokenService) IssueRefreshToken(userId uint, familyId string) (token string, expiresAt time.Time, err error) {
+	dr, err := utils.ParseDuration(global.GVA_CONFIG.JWT.RefreshExpiresTime)
+	if err != nil {
+		return
+	}
+	token, err = utils.RandomToken(32)
+	if err != nil {
+		return
+	}
+	expiresAt = time.Now().Add(dr)
+	err = global.GVA_DB.Create(&system.SysRefreshToken{
+		UserId:    userId,
+		FamilyId:  familyId,
+		TokenHash: utils.SHA256V(token),
+		ExpiresAt: expiresAt,
+	}).Error
+	return token, expiresAt, err
+}
+
+// RotateRefreshToken 消费一个刷新令牌 每个刷新令牌只能使用一次
+// 已被使用过的令牌再次出现说明令牌可能泄露 此时作废整个令牌族
+func (refreshTokenService *RefreshTokenService) RotateRefreshToken(token string) (record system.SysRefreshToken, err error) {
+	err = global.GVA_DB.Where("token_hash = ?", utils.SHA256V(token)).First(&record).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return record, ErrRefreshTokenInvalid
+		}
+		return
+	}
+	if record.RevokedAt != nil || time.Now().After(record.ExpiresAt) {
+		return record, ErrRefreshTokenInvalid
+	}
+	if record.UsedAt != nil {
+		_ = refreshTokenService.RevokeFamily(record.FamilyId)
+		return record, ErrRefreshTokenReused
+	}
+	// 条件更新 保证并发请求中只有一个能够成功轮换
+	now := time.Now()
+	result := global.GVA_DB.Model(&system.SysRefreshToken{}).
+		Where("id = ? AND used_at IS NULL", record.ID).
+		Update("used_at", now)
+	if result.Error != nil {
+		return record, result.Error
+	}
+	if result.RowsAffected == 0 {
+		_ = refreshTokenService.RevokeFamily(record.FamilyId)
+		return record, ErrRefreshTokenReused
+	}
+	record.UsedAt = &now
+	return record, nil
+}
+
+// RevokeFamily 作废整个令牌族 该族签发的访问令牌同时失效 对应的会话同时下线
+func (refreshTokenService *RefreshTokenService) RevokeFamily(familyId string) error {
+	if familyId == "" {
+		return nil
+	}
+	now := time.Now()
+	err := global.GVA_DB.Transaction(func(tx *gorm.DB) error {
+		err := tx.Model(&system.SysRefreshToken{}).
+			Where("family_id = ? AND revoked_at IS NULL", familyId).
+			Update("revoked_at", now).Error
+		if err != nil {
+			return err
+		}
+		return tx.Model(&system.SysUserSession{}).
+			Where("family_id = ? AND revoked_at IS NULL", familyId).
+			Update("revoked_at", now).Error
+	})
+	if err != nil {
+		return err
+	}
+	// 该族最后签发的访问令牌最迟在一个访问令牌有效期后过期
+	return JwtServiceApp.addBlacklist(refreshFamilyCachePrefix+familyId, now.Add(accessTokenExpires()))
+}
+
+// IsFamilyRevoked 判断令牌族是否已被作废
+func (refreshTokenService *RefreshTokenService) IsFamilyRevoked(familyId string) bool {
+	if familyId == "" {
+		return false
+	}
+	return JwtServiceApp.IsBlacklist(refreshFamilyCachePrefix + familyId)
+}
+
+// accessTokenExpires 访问令牌有效期 配置有误时按默认值计算
+func accessTokenExpires() time.Duration {
+	return parseDurationOr(global.GVA_CONFIG.JWT.ExpiresTime, defaultAccessTokenExpires)
+}
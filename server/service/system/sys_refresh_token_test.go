@@ -0,0 +1,50 @@
+package system
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/flipped-aurora/gin-vue-admin/server/global"
+	"github.com/flipped-aurora/gin-vue-admin/server/model/system"
+)
+
+func setupRefreshTokenTest(t *testing.T) {
+	setupTestDB(t, &system.SysRefreshToken{}, &system.SysUserSession{})
+	global.GVA_CONFIG.JWT.RefreshExpiresTime = "7d"
+}
+
+func TestRefreshTokenService_RotateRefreshToken(t *testing.T) {
+	setupRefreshTokenTest(t)
+	s := &RefreshTokenService{}
+	family := s.NewFamily()
+
+	first, _, err := s.IssueRefreshToken(1, family)
+	if err != nil {
+		t.Fatalf("IssueRefreshToken() error = %v", err)
+	}
+	record, err := s.RotateRefreshToken(first)
+	if err != nil {
+		t.Fatalf("RotateRefreshToken() error = %v", err)
+	}
+	if record.FamilyId != family || record.UserId != 1 {
+		t.Fatalf("RotateRefreshToken() got family %s user %d", record.FamilyId, record.UserId)
+	}
+	second, _, err := s.IssueRefreshToken(record.UserId, record.FamilyId)
+	if err != nil {
+		t.Fatalf("IssueRefreshToken() error = %v", err)
+	}
+
+	// 重放已使用的刷新令牌 整个令牌族被作废
+	if _, err = s.RotateRefreshToken(first); !errors.Is(err, ErrRefreshTokenReused) {
+		t.Fatalf("RotateRefreshToken() reuse error = %v, want %v", err, ErrRefreshTokenReused)
+	}
+	if !s.IsFamilyRevoked(family) {
+		t.Fatalf("IsFamilyRevoked() = false, want true")
+	}
+	if _, err = s.RotateRefreshToken(second); !errors.Is(err, ErrRefreshTokenInvalid) {
+		t.Fatalf("RotateRefreshToken() after revoke error = %v, want %v", err, ErrRefreshTokenInvalid)
+	}
+	if _, err = s.RotateRefreshToken("not-a-token"); !errors.Is(err, ErrRefreshTokenInvalid) {
+		t.Fatalf("RotateRefreshToken() unknown error = %v, want %v", err, ErrRefreshTokenInvalid)
+	}
+}
@@ -0,0 +1,88 @@
+package system
+
+import (
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/flipped-aurora/gin-vue-admin/server/global"
+	"github.com/flipped-aurora/gin-vue-admin/server/model/system"
+	"github.com/flipped-aurora/gin-vue-admin/server/utils"
+	"gorm.io/gorm"
+)
+
+const (
+	securityStampCachePrefix  = "stamp:"
+	securityStampCacheExpires = 5 * time.Minute
+	// 用户被禁用或删除时缓存的标记 安全戳本身不会出现该字符
+	securityStampInvalid = "!"
+)
+
+var ErrSecurityStampInvalid = errors.New("您的帐户状态已变更, 请重新登录")
+
+type SecurityStampService struct{}
+
+var SecurityStampServiceApp = new(SecurityStampService)
+
+// Stamp 获取用户当前的安全戳 用户被禁用或删除时返回 ErrSecurityStampInvalid
+// 开启redis时结果缓存在redis 更换安全戳时清除 未开启时每次读数据库 其他实例更换安全戳也能立即生效
+func (securityStampService *SecurityStampService) Stamp(userId uint) (string, error) {
+	key := securityStampCachePrefix + strconv.Itoa(int(userId))
+	shared := utils.CacheShared()
+	if v, ok := utils.CacheGet(key); shared && ok {
+		if v == securityStampInvalid {
+			return "", ErrSecurityStampInvalid
+		}
+		return v, nil
+	}
+	var user system.SysUser
+	err := global.GVA_DB.Select("id", "enable", "security_stamp").First(&user, userId).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) || (err == nil && user.Enable != 1) {
+		if shared {
+			_ = utils.CacheSet(key, securityStampInvalid, securityStampCacheExpires)
+		}
+		return "", ErrSecurityStampInvalid
+	}
+	if err != nil {
+		return "", err
+	}
+	if shared {
+		_ = utils.CacheSet(key, user.SecurityStamp, securityStampCacheExpires)
+	}
+	return user.SecurityStamp, nil
+}
+
+// Verify 校验令牌中的安全戳是否仍然有效
+func (securityStampService *SecurityStampService) Verify(userId uint, stamp string) error {
+	current, err := securityStampService.Stamp(userId)
+	if err != nil {
+		return err
+	}
+	if current != stamp {
+		return ErrSecurityStampInvalid
+	}
+	return nil
+}
+
+// Bump 更换用户的安全戳 之前签发的访问令牌全部失效 同时清除缓存的用户角色
+// 在事务中修改用户时需在事务提交后调用
+func (securityStampService *SecurityStampService) Bump(userId uint) error {
+	err := securityStampService.Rotate(global.GVA_DB, userId)
+	securityStampService.ClearCache(userId)
+	return err
+}
+
+// Rotate 在传入的事务中更换安全戳 与其他修改一起提交 提交后需调用 ClearCache
+func (securityStampService *SecurityStampService) Rotate(tx *gorm.DB, userId uint) error {
+	stamp, err := utils.RandomToken(16)
+	if err != nil {
+		return err
+	}
+	return tx.Unscoped().Model(&system.SysUser{}).Where("id = ?", userId).Update("security_stamp", stamp).Error
+}
+
+// ClearCache 清除缓存的安全戳和用户角色
+func (securityStampService *SecurityStampService) ClearCache(userId uint) {
+	utils.CacheDel(securityStampCachePrefix + strconv.Itoa(int(userId)))
+	utils.CacheDel(userAuthorityCachePrefix + strconv.Itoa(int(userId)))
+}
@@ -0,0 +1,107 @@
+package system
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/flipped-aurora/gin-vue-admin/server/global"
+	"github.com/flipped-aurora/gin-vue-admin/server/model/system"
+)
+
+func TestSecurityStampService_Verify(t *testing.T) {
+	db := setupTestDB(t, &system.SysUser{}, &system.SysUserAuthority{}, &system.SysApiKey{}, &system.SysUserPasskey{})
+	var err error
+	db.Create(&system.SysUser{GVA_MODEL: global.GVA_MODEL{ID: 1}, Username: "alice", Enable: 1, AuthorityId: 888})
+	db.Create(&system.SysUserAuthority{SysUserId: 1, SysAuthorityAuthorityId: 888})
+
+	s := &SecurityStampService{}
+	u := &UserService{}
+	// 升级前签发的令牌没有安全戳 用户安全戳也为空 仍然有效
+	if err = s.Verify(1, ""); err != nil {
+		t.Fatalf("Verify() legacy token error = %v", err)
+	}
+	if err = s.Bump(1); err != nil {
+		t.Fatalf("Bump() error = %v", err)
+	}
+	if err = s.Verify(1, ""); !errors.Is(err, ErrSecurityStampInvalid) {
+		t.Fatalf("Verify() after bump error = %v, want %v", err, ErrSecurityStampInvalid)
+	}
+	stamp, err := s.Stamp(1)
+	if err != nil || stamp == "" {
+		t.Fatalf("Stamp() = %q, %v", stamp, err)
+	}
+	if err = s.Verify(1, stamp); err != nil {
+		t.Fatalf("Verify() current stamp error = %v", err)
+	}
+	// 未开启redis时不使用进程内缓存 其他实例更换的安全戳立即生效
+	db.Model(&system.SysUser{}).Where("id = ?", 1).Update("security_stamp", "other-instance")
+	if err = s.Verify(1, stamp); !errors.Is(err, ErrSecurityStampInvalid) {
+		t.Fatalf("Verify() after bump on other instance error = %v, want %v", err, ErrSecurityStampInvalid)
+	}
+	stamp, _ = s.Stamp(1)
+
+	// 修改昵称等信息不影响令牌 冻结后令牌立即失效
+	if err = u.SetUserInfo(system.SysUser{GVA_MODEL: global.GVA_MODEL{ID: 1}, NickName: "Alice", Enable: 1}); err != nil {
+		t.Fatalf("SetUserInfo() error = %v", err)
+	}
+	if err = s.Verify(1, stamp); err != nil {
+		t.Fatalf("Verify() after profile update error = %v", err)
+	}
+	if err = u.SetUserInfo(system.SysUser{GVA_MODEL: global.GVA_MODEL{ID: 1}, NickName: "Alice", Enable: 2}); err != nil {
+		t.Fatalf("SetUserInfo() error = %v", err)
+	}
+	if err = s.Verify(1, stamp); !errors.Is(err, ErrSecurityStampInvalid) {
+		t.Fatalf("Verify() disabled user error = %v, want %v", err, ErrSecurityStampInvalid)
+	}
+	if err = u.SetUserInfo(system.SysUser{GVA_MODEL: global.GVA_MODEL{ID: 1}, NickName: "Alice", Enable: 1}); err != nil {
+		t.Fatalf("SetUserInfo() error = %v", err)
+	}
+	if err = s.Verify(1, stamp); !errors.Is(err, ErrSecurityStampInvalid) {
+		t.Fatalf("Verify() token issued before disable error = %v, want %v", err, ErrSecurityStampInvalid)
+	}
+
+	// 角色变更和删除用户
+	stamp, _ = s.Stamp(1)
+	if err = u.SetUserAuthorities(888, 1, []uint{888, 9528}); err != nil {
+		t.Fatalf("SetUserAuthorities() error = %v", err)
+	}
+	if err = s.Verify(1, stamp); !errors.Is(err, ErrSecurityStampInvalid) {
+		t.Fatalf("Verify() after role change error = %v, want %v", err, ErrSecurityStampInvalid)
+	}
+	stamp, _ = s.Stamp(1)
+	if err = u.DeleteUser(1); err != nil {
+		t.Fatalf("DeleteUser() error = %v", err)
+	}
+	if err = s.Verify(1, stamp); !errors.Is(err, ErrSecurityStampInvalid) {
+		t.Fatalf("Verify() deleted user error = %v, want %v", err, ErrSecurityStampInvalid)
+	}
+}
+
+func TestSyncUserAuthorities(t *testing.T) {
+	db := setupTestDB(t, &system.SysUser{}, &system.SysUserAuthority{})
+	db.Create(&system.SysUser{GVA_MODEL: global.GVA_MODEL{ID: 1}, Username: "alice", AuthorityId: 888})
+	db.Create(&[]system.SysUserAuthority{{SysUserId: 1, SysAuthorityAuthorityId: 888}, {SysUserId: 1, SysAuthorityAuthorityId: 9528}})
+
+	tests := []struct {
+		name        string
+		ids         []uint
+		wantChanged bool
+	}{
+		{name: "角色相同", ids: []uint{9528, 888}, wantChanged: false},
+		{name: "减少角色", ids: []uint{9528}, wantChanged: true},
+		{name: "再次同步", ids: []uint{9528}, wantChanged: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			changed, err := syncUserAuthorities(db, 1, tt.ids)
+			if err != nil || changed != tt.wantChanged {
+				t.Fatalf("syncUserAuthorities() = %v, %v, want %v", changed, err, tt.wantChanged)
+			}
+		})
+	}
+	var user system.SysUser
+	db.First(&user, 1)
+	if user.AuthorityId != 9528 {
+		t.Fatalf("authority id = %d, want 9528", user.AuthorityId)
+	}
+}
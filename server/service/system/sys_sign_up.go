@@ -0,0 +1,330 @@
+package system
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"html"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/flipped-aurora/gin-vue-admin/server/global"
+	"github.com/flipped-aurora/gin-vue-admin/server/model/system"
+	systemReq "github.com/flipped-aurora/gin-vue-admin/server/model/system/request"
+	emailUtils "github.com/flipped-aurora/gin-vue-admin/server/plugin/email/utils"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+const defaultSignUpExpires = 24 * time.Hour
+
+var (
+	ErrSignUpDisabled = errors.New("未开启自助注册, 请联系管理员")
+	ErrSignUpDomain   = errors.New("该邮箱域名不允许注册")
+	ErrSignUpTaken    = errors.New("用户名或邮箱不可用, 请更换后重试")
+	ErrSignUpInvalid  = errors.New("验证链接无效或已过期, 请联系管理员")
+)
+
+type SignUpService struct{}
+
+// IsInvalidToken 是否因验证链接无效或过期被拒绝
+func (signUpService *SignUpService) IsInvalidToken(err error) bool {
+	return errors.Is(err, ErrSignUpInvalid)
+}
+
+var SignUpServiceApp = new(SignUpService)
+
+// SignUp 自助注册 新用户使用配置的默认角色 在验证邮箱和审核通过前保持禁用
+// 返回申请的状态 前端据此提示用户下一步操作
+func (signUpService *SignUpService) SignUp(req systemReq.SignUp, ip string) (status int, err error) {
+	cfg := global.GVA_CONFIG.System.SignUp
+	if !cfg.Enable {
+		return 0, ErrSignUpDisabled
+	}
+	email := strings.ToLower(strings.TrimSpace(req.Email))
+	if !signUpDomainAllowed(email, cfg.AllowDomains) {
+		return 0, ErrSignUpDomain
+	}
+	if err = PasswordPolicyServiceApp.CheckPassword(0, req.Username, req.Password); err != nil {
+		return 0, err
+	}
+
+	status = system.SignUpStatusApproved
+	if cfg.VerifyEmail {
+		status = system.SignUpStatusUnverified
+	} else if cfg.RequireApproval {
+		status = system.SignUpStatusPending
+	}
+	enable := 2
+	if status == system.SignUpStatusApproved {
+		enable = 1
+	}
+	nickName := req.NickName
+	if nickName == "" {
+		nickName = req.Username
+	}
+	user := system.SysUser{
+		Username:    req.Username,
+		NickName:    nickName,
+		Password:    req.Password,
+		Email:       email,
+		Phone:       req.Phone,
+		AuthorityId: cfg.AuthorityId,
+		Authorities: []system.SysAuthority{{AuthorityId: cfg.AuthorityId}},
+		Enable:      enable,
+	}
+	// 用户和注册申请一起提交 用户名或邮箱被占用时返回同样的提示 不暴露是哪一项已注册
+	err = global.GVA_DB.Transaction(func(tx *gorm.DB) error {
+		var count int64
+		if err := tx.Model(&system.SysUser{}).Where("username = ? OR LOWER(email) = ?", req.Username, email).Count(&count).Error; err != nil {
+			return err
+		}
+		if count > 0 {
+			return ErrSignUpTaken
+		}
+		if err := UserServiceApp.createUser(tx, &user); err != nil {
+			return err
+		}
+		return tx.Create(&system.SysSignUp{
+			UserId:   user.ID,
+			Username: user.Username,
+			NickName: user.NickName,
+			Email:    email,
+			Ip:       ip,
+			Status:   status,
+		}).Error
+	})
+	if err != nil {
+		return 0, err
+	}
+	if status == system.SignUpStatusUnverified {
+		expires := parseDurationOr(cfg.Expires, defaultSignUpExpires)
+		go signUpService.sendVerify(user, cfg.Url, signUpToken(user.ID, email, time.Now().Add(expires)), expires)
+	}
+	return status, nil
+}
+
+// Verify 验证邮箱 无需审核时直接启用用户
+// 链接重复打开时返回当前状态
+func (signUpService *SignUpService) Verify(token string) (status int, err error) {
+	userId, ok := parseSignUpToken(token)
+	if !ok {
+		return 0, ErrSignUpInvalid
+	}
+	var record system.SysSignUp
+	err = global.GVA_DB.Where("user_id = ?", userId).Order("id desc").First(&record).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return 0, ErrSignUpInvalid
+		}
+		return 0, err
+	}
+	if !verifySignUpToken(token, record.UserId, record.Email) {
+		return 0, ErrSignUpInvalid
+	}
+	switch record.Status {
+	case system.SignUpStatusUnverified:
+	case system.SignUpStatusRejected:
+		return 0, ErrSignUpInvalid
+	default:
+		return record.Status, nil
+	}
+
+	status = system.SignUpStatusApproved
+	if global.GVA_CONFIG.System.SignUp.RequireApproval {
+		status = system.SignUpStatusPending
+	}
+	now := time.Now()
+	err = global.GVA_DB.Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&system.SysSignUp{}).Where("id = ? AND status = ?", record.ID, system.SignUpStatusUnverified).
+			Updates(map[string]interface{}{"status": status, "verified_at": now})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrSignUpInvalid
+		}
+		if status == system.SignUpStatusApproved {
+			return tx.Model(&system.SysUser{}).Where("id = ?", record.UserId).Update("enable", 1).Error
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	if status == system.SignUpStatusApproved {
+		return status, SecurityStampServiceApp.Bump(record.UserId)
+	}
+	return status, nil
+}
+
+// Review 审核待审核的注册申请 通过后启用用户 拒绝后删除用户并保留申请记录
+func (signUpService *SignUpService) Review(req systemReq.ReviewSignUp, reviewerId uint) error {
+	var record system.SysSignUp
+	if err := global.GVA_DB.First(&record, req.ID).Error; err != nil {
+		return err
+	}
+	if record.Status != system.SignUpStatusPending {
+		return errors.New("该申请不是待审核状态")
+	}
+	status := system.SignUpStatusRejected
+	if req.Approve {
+		status = system.SignUpStatusApproved
+	}
+	now := time.Now()
+	err := global.GVA_DB.Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&system.SysSignUp{}).Where("id = ? AND status = ?", record.ID, system.SignUpStatusPending).
+			Updates(map[string]interface{}{"status": status, "reviewer_id": reviewerId, "reviewed_at": now, "remark": req.Remark})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return errors.New("该申请不是待审核状态")
+		}
+		if req.Approve {
+			return tx.Model(&system.SysUser{}).Where("id = ?", record.UserId).Update("enable", 1).Error
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if req.Approve {
+		err = SecurityStampServiceApp.Bump(record.UserId)
+	} else {
+		err = UserServiceApp.DeleteUser(int(record.UserId))
+	}
+	if err != nil {
+		return err
+	}
+	record.Remark = req.Remark
+	go signUpService.sendResult(record, req.Approve)
+	return nil
+}
+
+// GetSignUpList 分页获取注册申请
+func (signUpService *SignUpService) GetSignUpList(info systemReq.SysSignUpSearch) (list []system.SysSignUp, total int64, err error) {
+	limit := info.PageSize
+	offset := info.PageSize * (info.Page - 1)
+	db := global.GVA_DB.Model(&system.SysSignUp{})
+	if info.Username != "" {
+		db = db.Where("username LIKE ?", "%"+info.Username+"%")
+	}
+	if info.Email != "" {
+		db = db.Where("email LIKE ?", "%"+info.Email+"%")
+	}
+	if info.Status != 0 {
+		db = db.Where("status = ?", info.Status)
+	}
+	if err = db.Count(&total).Error; err != nil {
+		return
+	}
+	err = db.Preload("Reviewer", func(db *gorm.DB) *gorm.DB {
+		return db.Select("id", "username", "nick_name")
+	}).Order("id desc").Limit(limit).Offset(offset).Find(&list).Error
+	return list, total, err
+}
+
+// DisabledReason 自助注册的用户尚未启用时 返回登录失败的具体原因
+func (signUpService *SignUpService) DisabledReason(userId uint) string {
+	var record system.SysSignUp
+	err := global.GVA_DB.Select("status").Where("user_id = ?", userId).Order("id desc").First(&record).Error
+	if err != nil {
+		return ""
+	}
+	switch record.Status {
+	case system.SignUpStatusUnverified:
+		return "邮箱尚未验证, 请点击注册邮件中的链接完成验证"
+	case system.SignUpStatusPending:
+		return "注册申请正在审核中, 审核通过后即可登录"
+	}
+	return ""
+}
+
+func signUpDomainAllowed(email string, domains []string) bool {
+	at := strings.LastIndex(email, "@")
+	if at <= 0 || at == len(email)-1 {
+		return false
+	}
+	if len(domains) == 0 {
+		return true
+	}
+	domain := email[at+1:]
+	for _, d := range domains {
+		if strings.EqualFold(strings.TrimPrefix(strings.TrimSpace(d), "@"), domain) {
+			return true
+		}
+	}
+	return false
+}
+
+// signUpToken 验证链接中的令牌 格式为 用户ID.过期时间.签名
+// 签名覆盖用户ID 邮箱和过期时间 使用jwt签名密钥 无需在数据库保存令牌
+func signUpToken(userId uint, email string, expiresAt time.Time) string {
+	exp := expiresAt.Unix()
+	return fmt.Sprintf("%d.%d.%s", userId, exp, signUpSignature(userId, email, exp))
+}
+
+func signUpSignature(userId uint, email string, exp int64) string {
+	mac := hmac.New(sha256.New, []byte(global.GVA_CONFIG.JWT.SigningKey))
+	_, _ = fmt.Fprintf(mac, "signup|%d|%s|%d", userId, email, exp)
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+func parseSignUpToken(token string) (userId uint, ok bool) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return 0, false
+	}
+	id, err := strconv.ParseUint(parts[0], 10, 64)
+	if err != nil {
+		return 0, false
+	}
+	return uint(id), true
+}
+
+func verifySignUpToken(token string, userId uint, email string) bool {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return false
+	}
+	exp, err := strconv.ParseInt(parts[1], 10, 64)
+	if err != nil || time.Now().Unix() > exp {
+		return false
+	}
+	return hmac.Equal([]byte(parts[2]), []byte(signUpSignature(userId, email, exp)))
+}
+
+// sendVerify 在后台发送邮件 链接地址由调用方读取配置后传入
+func (signUpService *SignUpService) sendVerify(user system.SysUser, base, token string, expires time.Duration) {
+	link, err := url.Parse(base)
+	if err != nil {
+		global.GVA_LOG.Error("注册验证地址配置有误!", zap.Error(err))
+		return
+	}
+	q := link.Query()
+	q.Set("signUpToken", token)
+	link.RawQuery = q.Encode()
+	body := fmt.Sprintf(`您好 %s, 感谢注册, 请在 %s 内点击以下链接验证邮箱:<br/><a href="%s">%s</a><br/>如非本人操作请忽略本邮件。`,
+		html.EscapeString(user.Username), formatWait(expires), html.EscapeString(link.String()), html.EscapeString(link.String()))
+	if err = emailUtils.Email(user.Email, "验证注册邮箱", body); err != nil {
+		global.GVA_LOG.Error("发送注册验证邮件失败!", zap.Error(err))
+	}
+}
+
+func (signUpService *SignUpService) sendResult(record system.SysSignUp, approved bool) {
+	body := fmt.Sprintf(`您好 %s, 您的注册申请已审核通过, 现在可以登录了。`, html.EscapeString(record.Username))
+	if !approved {
+		body = fmt.Sprintf(`您好 %s, 很抱歉, 您的注册申请未通过审核。`, html.EscapeString(record.Username))
+		if record.Remark != "" {
+			body += "<br/>审核意见: " + html.EscapeString(record.Remark)
+		}
+	}
+	if err := emailUtils.Email(record.Email, "注册审核结果", body); err != nil {
+		global.GVA_LOG.Error("发送注册审核结果邮件失败!", zap.Error(err))
+	}
+}
@@ -0,0 +1,136 @@
+package system
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/flipped-aurora/gin-vue-admin/server/config"
+	"github.com/flipped-aurora/gin-vue-admin/server/global"
+	"github.com/flipped-aurora/gin-vue-admin/server/model/common/request"
+	"github.com/flipped-aurora/gin-vue-admin/server/model/system"
+	systemReq "github.com/flipped-aurora/gin-vue-admin/server/model/system/request"
+	"gorm.io/gorm"
+)
+
+func setupSignUpTest(t *testing.T) {
+	db := setupTestDB(t, &system.SysUser{}, &system.SysAuthority{}, &system.SysUserAuthority{}, &system.SysPasswordHistory{}, &system.SysApiKey{}, &system.SysSignUp{}, &system.SysUserPasskey{})
+	db.Create(&system.SysAuthority{AuthorityId: 9528, AuthorityName: "测试角色"})
+	global.GVA_CONFIG.System.PasswordPolicy = config.PasswordPolicy{}
+	global.GVA_CONFIG.JWT.SigningKey = "test"
+	global.GVA_CONFIG.System.SignUp = config.SignUp{Enable: true, AuthorityId: 9528, AllowDomains: []string{"partner.com"}, VerifyEmail: true, RequireApproval: true}
+}
+
+func TestSignUpService_SignUp(t *testing.T) {
+	setupSignUpTest(t)
+	s := &SignUpService{}
+	req := func(name, email string) systemReq.SignUp {
+		return systemReq.SignUp{Username: name, Password: "password1", Email: email}
+	}
+	if _, err := s.SignUp(req("bob", "bob@other.com"), ""); !errors.Is(err, ErrSignUpDomain) {
+		t.Fatalf("SignUp() other domain error = %v, want %v", err, ErrSignUpDomain)
+	}
+	status, err := s.SignUp(req("alice", "Alice@Partner.com"), "127.0.0.1")
+	if err != nil || status != system.SignUpStatusUnverified {
+		t.Fatalf("SignUp() = %d, %v", status, err)
+	}
+	// 邮箱或用户名被占用时返回同样的错误
+	if _, err = s.SignUp(req("alice2", "alice@partner.com"), ""); !errors.Is(err, ErrSignUpTaken) {
+		t.Fatalf("SignUp() same email error = %v, want %v", err, ErrSignUpTaken)
+	}
+	if _, err = s.SignUp(req("alice", "alice2@partner.com"), ""); !errors.Is(err, ErrSignUpTaken) {
+		t.Fatalf("SignUp() same username error = %v, want %v", err, ErrSignUpTaken)
+	}
+	var signUps int64
+	global.GVA_DB.Model(&system.SysSignUp{}).Count(&signUps)
+	if signUps != 1 {
+		t.Fatalf("sign up records = %d, want 1", signUps)
+	}
+	var user system.SysUser
+	global.GVA_DB.Preload("Authorities").Where("username = ?", "alice").First(&user)
+	if user.Enable != 2 || user.AuthorityId != 9528 || len(user.Authorities) != 1 {
+		t.Fatalf("signed up user = enable %d, authority %d, authorities %d", user.Enable, user.AuthorityId, len(user.Authorities))
+	}
+	if msg := s.DisabledReason(user.ID); msg == "" {
+		t.Fatalf("DisabledReason() should not be empty")
+	}
+
+	// 无需验证邮箱和审核时直接启用
+	global.GVA_CONFIG.System.SignUp.VerifyEmail = false
+	global.GVA_CONFIG.System.SignUp.RequireApproval = false
+	if status, err = s.SignUp(req("carol", "carol@partner.com"), ""); err != nil || status != system.SignUpStatusApproved {
+		t.Fatalf("SignUp() without verification = %d, %v", status, err)
+	}
+	var carol system.SysUser
+	global.GVA_DB.Where("username = ?", "carol").First(&carol)
+	if carol.Enable != 1 {
+		t.Fatalf("user enable = %d, want 1", carol.Enable)
+	}
+
+	global.GVA_CONFIG.System.SignUp.Enable = false
+	if _, err = s.SignUp(req("dave", "dave@partner.com"), ""); !errors.Is(err, ErrSignUpDisabled) {
+		t.Fatalf("SignUp() disabled error = %v, want %v", err, ErrSignUpDisabled)
+	}
+}
+
+func TestSignUpService_VerifyAndReview(t *testing.T) {
+	setupSignUpTest(t)
+	s := &SignUpService{}
+	for _, name := range []string{"alice", "bob"} {
+		if _, err := s.SignUp(systemReq.SignUp{Username: name, Password: "password1", Email: name + "@partner.com"}, ""); err != nil {
+			t.Fatalf("SignUp() error = %v", err)
+		}
+	}
+	var records []system.SysSignUp
+	global.GVA_DB.Order("id").Find(&records)
+	alice, bob := records[0], records[1]
+
+	tests := []struct {
+		name  string
+		token string
+	}{
+		{name: "格式错误", token: "abc"},
+		{name: "签名错误", token: signUpToken(alice.UserId, "other@partner.com", time.Now().Add(time.Hour))},
+		{name: "已过期", token: signUpToken(alice.UserId, alice.Email, time.Now().Add(-time.Minute))},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := s.Verify(tt.token); !errors.Is(err, ErrSignUpInvalid) {
+				t.Fatalf("Verify() error = %v, want %v", err, ErrSignUpInvalid)
+			}
+		})
+	}
+
+	for _, r := range records {
+		status, err := s.Verify(signUpToken(r.UserId, r.Email, time.Now().Add(time.Hour)))
+		if err != nil || status != system.SignUpStatusPending {
+			t.Fatalf("Verify() = %d, %v, want %d", status, err, system.SignUpStatusPending)
+		}
+	}
+	// 重复打开链接返回当前状态
+	if status, err := s.Verify(signUpToken(alice.UserId, alice.Email, time.Now().Add(time.Hour))); err != nil || status != system.SignUpStatusPending {
+		t.Fatalf("Verify() again = %d, %v", status, err)
+	}
+
+	if err := s.Review(systemReq.ReviewSignUp{ID: alice.ID, Approve: true}, 1); err != nil {
+		t.Fatalf("Review() approve error = %v", err)
+	}
+	if err := s.Review(systemReq.ReviewSignUp{ID: alice.ID, Approve: false}, 1); err == nil {
+		t.Fatalf("Review() reviewed record should fail")
+	}
+	if err := s.Review(systemReq.ReviewSignUp{ID: bob.ID, Approve: false, Remark: "非合作伙伴"}, 1); err != nil {
+		t.Fatalf("Review() reject error = %v", err)
+	}
+	var user system.SysUser
+	global.GVA_DB.First(&user, alice.UserId)
+	if user.Enable != 1 {
+		t.Fatalf("approved user enable = %d, want 1", user.Enable)
+	}
+	if err := global.GVA_DB.First(&system.SysUser{}, bob.UserId).Error; !errors.Is(err, gorm.ErrRecordNotFound) {
+		t.Fatalf("rejected user should be deleted, error = %v", err)
+	}
+	list, total, err := s.GetSignUpList(systemReq.SysSignUpSearch{Status: system.SignUpStatusRejected, PageInfo: request.PageInfo{Page: 1, PageSize: 10}})
+	if err != nil || total != 1 || list[0].Remark != "非合作伙伴" || list[0].ReviewerId != 1 {
+		t.Fatalf("GetSignUpList() = %+v, %d, %v", list, total, err)
+	}
+}
@@ -29,17 +29,31 @@ func (userService *UserService) Register(u system.SysUser) (userInter system.Sys
 	if !errors.Is(global.GVA_DB.Where("username = ?", u.Username).First(&user).Error, gorm.ErrRecordNotFound) { // 判断用户名是否注册
 		return userInter, errors.New("用户名已注册")
 	}
-	// 否则 附加uuid 密码hash加密 注册
+	if err = PasswordPolicyServiceApp.CheckPassword(0, u.Username, u.Password); err != nil {
+		return userInter, err
+	}
+	err = global.GVA_DB.Transaction(func(tx *gorm.DB) error {
+		return userService.createUser(tx, &u)
+	})
+	return u, err
+}
+
+// createUser 在传入的事务中创建用户 附加uuid 密码hash加密 并记录密码历史
+func (userService *UserService) createUser(tx *gorm.DB, u *system.SysUser) error {
 	u.Password = utils.BcryptHash(u.Password)
 	u.UUID = uuid.Must(uuid.NewV4())
-	err = global.GVA_DB.Create(&u).Error
-	return u, err
+	now := time.Now()
+	u.PasswordChangedAt = &now
+	if err := tx.Create(u).Error; err != nil {
+		return err
+	}
+	return PasswordPolicyServiceApp.recordHistory(tx, u.ID, u.Password)
 }
 
 //@author: [piexlmax](https://github.com/piexlmax)
 //@author: [SliverHorn](https://github.com/SliverHorn)
 //@function: Login
-//@description: 用户登录
+//@description: 本地用户登录 作为认证链中的local认证器 用户不存在或密码错误时返回 ErrInvalidCredentials
 //@param: u *model.SysUser
 //@return: err error, userInter *model.SysUser
 
@@ -50,13 +64,17 @@ func (userService *UserService) Login(u *system.SysUser) (userInter *system.SysU
 
 	var user system.SysUser
 	err = global.GVA_DB.Where("username = ?", u.Username).Preload("Authorities").Preload("Authority").First(&user).Error
-	if err == nil {
-		if ok := utils.BcryptCheck(u.Password, user.Password); !ok {
-			return nil, errors.New("密码错误")
-		}
-		MenuServiceApp.UserAuthorityDefaultRouter(&user)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrInvalidCredentials
 	}
-	return &user, err
+	if err != nil {
+		return nil, err
+	}
+	if ok := utils.BcryptCheck(u.Password, user.Password); !ok {
+		return nil, ErrInvalidCredentials
+	}
+	MenuServiceApp.UserAuthorityDefaultRouter(&user)
+	return &user, nil
 }
 
 //@author: [piexlmax](https://github.com/piexlmax)
@@ -73,9 +91,16 @@ func (userService *UserService) ChangePassword(u *system.SysUser, newPassword st
 	if ok := utils.BcryptCheck(u.Password, user.Password); !ok {
 		return nil, errors.New("原密码错误")
 	}
-	user.Password = utils.BcryptHash(newPassword)
-	err = global.GVA_DB.Save(&user).Error
-	return &user, err
+	if err = PasswordPolicyServiceApp.CheckPassword(user.ID, user.Username, newPassword); err != nil {
+		return nil, err
+	}
+	err = global.GVA_DB.Transaction(func(tx *gorm.DB) error {
+		return PasswordPolicyServiceApp.SetPassword(tx, user.ID, newPassword, false)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &user, SecurityStampServiceApp.Bump(user.ID)
 
 }
 
@@ -158,7 +183,10 @@ func (userService *UserService) SetUserAuthority(id uint, authorityId uint) (err
 	}
 
 	err = global.GVA_DB.Model(&system.SysUser{}).Where("id = ?", id).Update("authority_id", authorityId).Error
-	return err
+	if err != nil {
+		return err
+	}
+	return SecurityStampServiceApp.Bump(id)
 }
 
 //@author: [piexlmax](https://github.com/piexlmax)
@@ -168,7 +196,7 @@ func (userService *UserService) SetUserAuthority(id uint, authorityId uint) (err
 //@return: err error
 
 func (userService *UserService) SetUserAuthorities(adminAuthorityID, id uint, authorityIds []uint) (err error) {
-	return global.GVA_DB.Transaction(func(tx *gorm.DB) error {
+	err = global.GVA_DB.Transaction(func(tx *gorm.DB) error {
 		var user system.SysUser
 		TxErr := tx.Where("id = ?", id).First(&user).Error
 		if TxErr != nil {
@@ -200,6 +228,10 @@ func (userService *UserService) SetUserAuthorities(adminAuthorityID, id uint, au
 		// 返回 nil 提交事务
 		return nil
 	})
+	if err != nil {
+		return err
+	}
+	return SecurityStampServiceApp.Bump(id)
 }
 
 //@author: [piexlmax](https://github.com/piexlmax)
@@ -209,15 +241,22 @@ func (userService *UserService) SetUserAuthorities(adminAuthorityID, id uint, au
 //@return: err error
 
 func (userService *UserService) DeleteUser(id int) (err error) {
-	return global.GVA_DB.Transaction(func(tx *gorm.DB) error {
+	err = global.GVA_DB.Transaction(func(tx *gorm.DB) error {
 		if err := tx.Where("id = ?", id).Delete(&system.SysUser{}).Error; err != nil {
 			return err
 		}
 		if err := tx.Delete(&[]system.SysUserAuthority{}, "sys_user_id = ?", id).Error; err != nil {
 			return err
 		}
-		return nil
+		if err := tx.Where("user_id = ?", id).Delete(&system.SysApiKey{}).Error; err != nil {
+			return err
+		}
+		return tx.Unscoped().Where("user_id = ?", id).Delete(&system.SysUserPasskey{}).Error
 	})
+	if err != nil {
+		return err
+	}
+	return SecurityStampServiceApp.Bump(uint(id))
 }
 
 //@author: [piexlmax](https://github.com/piexlmax)
@@ -227,7 +266,11 @@ func (userService *UserService) DeleteUser(id int) (err error) {
 //@return: err error, user model.SysUser
 
 func (userService *UserService) SetUserInfo(req system.SysUser) error {
-	return global.GVA_DB.Model(&system.SysUser{}).
+	var user system.SysUser
+	if err := global.GVA_DB.Select("id", "enable").First(&user, req.ID).Error; err != nil {
+		return err
+	}
+	err := global.GVA_DB.Model(&system.SysUser{}).
 		Select("updated_at", "nick_name", "header_img", "phone", "email", "enable").
 		Where("id=?", req.ID).
 		Updates(map[string]interface{}{
@@ -238,6 +281,11 @@ func (userService *UserService) SetUserInfo(req system.SysUser) error {
 			"email":      req.Email,
 			"enable":     req.Enable,
 		}).Error
+	if err != nil || user.Enable == req.Enable {
+		return err
+	}
+	// 冻结或解冻时更换安全戳 冻结用户已签发的令牌立即失效
+	return SecurityStampServiceApp.Bump(req.ID)
 }
 
 //@author: [piexlmax](https://github.com/piexlmax)
@@ -312,6 +360,12 @@ func (userService *UserService) FindUserByUuid(uuid string) (user *system.SysUse
 //@return: err error
 
 func (userService *UserService) ResetPassword(ID uint) (err error) {
-	err = global.GVA_DB.Model(&system.SysUser{}).Where("id = ?", ID).Update("password", utils.BcryptHash("123456")).Error
-	return err
+	// 重置后的密码为临时密码 用户下次登录时必须修改
+	if err = PasswordPolicyServiceApp.SetPassword(global.GVA_DB, ID, "123456", true); err != nil {
+		return err
+	}
+	if err = SecurityStampServiceApp.Bump(ID); err != nil {
+		return err
+	}
+	return SessionServiceApp.RevokeUserSessions(ID)
 }
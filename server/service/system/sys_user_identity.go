@@ -0,0 +1,169 @@
+package system
+
+import (
+	"errors"
+	"time"
+
+	"github.com/flipped-aurora/gin-vue-admin/server/global"
+	"github.com/flipped-aurora/gin-vue-admin/server/model/system"
+	systemReq "github.com/flipped-aurora/gin-vue-admin/server/model/system/request"
+	"github.com/flipped-aurora/gin-vue-admin/server/utils"
+	"github.com/gofrs/uuid/v5"
+	"gorm.io/gorm"
+)
+
+// ExternalUser 外部身份源认证通过后的用户信息
+type ExternalUser struct {
+	Provider string
+	Subject  string
+	Username string
+	NickName string
+	Email    string
+	Groups   []string
+}
+
+type UserIdentityService struct{}
+
+var UserIdentityServiceApp = new(UserIdentityService)
+
+// LoginExternalUser 根据外部身份找到本地用户 首次登录按配置自动创建
+// 外部组匹配到角色映射时 每次登录都会将用户角色同步为映射结果
+func (userIdentityService *UserIdentityService) LoginExternalUser(ext ExternalUser, opts systemReq.ProvisionOptions) (*system.SysUser, error) {
+	if ext.Subject == "" {
+		return nil, errors.New("外部身份缺少用户标识")
+	}
+	authorityIds, err := AuthorityClaimMapServiceApp.MapAuthorities(ext.Provider, ext.Groups)
+	if err != nil {
+		return nil, err
+	}
+	var identity system.SysUserIdentity
+	err = global.GVA_DB.Where("provider = ? AND subject = ?", ext.Provider, ext.Subject).First(&identity).Error
+	switch {
+	case err == nil:
+		changed := false
+		err = global.GVA_DB.Transaction(func(tx *gorm.DB) (err error) {
+			if len(authorityIds) > 0 {
+				if changed, err = syncUserAuthorities(tx, identity.UserId, authorityIds); err != nil {
+					return err
+				}
+			}
+			return tx.Model(&identity).Updates(map[string]interface{}{"email": ext.Email, "last_login_at": time.Now()}).Error
+		})
+		// 角色发生变化时 该用户其他会话中的令牌需要失效
+		if err == nil && changed {
+			err = SecurityStampServiceApp.Bump(identity.UserId)
+		}
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		identity, err = userIdentityService.provision(ext, opts, authorityIds)
+	}
+	if err != nil {
+		return nil, err
+	}
+	var user system.SysUser
+	err = global.GVA_DB.Preload("Authorities").Preload("Authority").First(&user, identity.UserId).Error
+	if err != nil {
+		return nil, errors.New("外部身份绑定的用户不存在")
+	}
+	MenuServiceApp.UserAuthorityDefaultRouter(&user)
+	return &user, nil
+}
+
+// provision 为首次登录的外部用户创建本地用户 不会按用户名或邮箱自动关联已有用户 避免账号被冒用
+func (userIdentityService *UserIdentityService) provision(ext ExternalUser, opts systemReq.ProvisionOptions, authorityIds []uint) (identity system.SysUserIdentity, err error) {
+	if !opts.AutoCreate {
+		return identity, errors.New("该账号尚未开通, 请联系管理员")
+	}
+	if len(authorityIds) == 0 {
+		if opts.DefaultAuthorityId == 0 {
+			return identity, errors.New("该账号未分配角色, 请联系管理员")
+		}
+		authorityIds = []uint{opts.DefaultAuthorityId}
+	}
+	username := ext.Username
+	if username == "" {
+		username = ext.Email
+	}
+	if username == "" {
+		username = ext.Provider + "_" + ext.Subject
+	}
+	if !errors.Is(global.GVA_DB.Where("username = ?", username).First(&system.SysUser{}).Error, gorm.ErrRecordNotFound) {
+		return identity, errors.New("用户名 " + username + " 已存在, 请联系管理员处理")
+	}
+	nickName := ext.NickName
+	if nickName == "" {
+		nickName = username
+	}
+	// 外部用户不使用本地密码 写入随机密码使其无法通过密码登录
+	password, err := utils.RandomToken(32)
+	if err != nil {
+		return
+	}
+	now := time.Now()
+	err = global.GVA_DB.Transaction(func(tx *gorm.DB) error {
+		user := system.SysUser{
+			UUID:        uuid.Must(uuid.NewV4()),
+			Username:    username,
+			Password:    utils.BcryptHash(password),
+			NickName:    nickName,
+			Email:       ext.Email,
+			AuthorityId: authorityIds[0],
+			Enable:      1,
+		}
+		if err := tx.Create(&user).Error; err != nil {
+			return err
+		}
+		if _, err := syncUserAuthorities(tx, user.ID, authorityIds); err != nil {
+			return err
+		}
+		identity = system.SysUserIdentity{
+			UserId:      user.ID,
+			Provider:    ext.Provider,
+			Subject:     ext.Subject,
+			Email:       ext.Email,
+			LastLoginAt: &now,
+		}
+		return tx.Create(&identity).Error
+	})
+	return identity, err
+}
+
+// syncUserAuthorities 将用户角色替换为 authorityIds 当前角色不在其中时切换为第一个 返回角色是否有变化
+func syncUserAuthorities(tx *gorm.DB, userId uint, authorityIds []uint) (bool, error) {
+	var current []uint
+	err := tx.Model(&system.SysUserAuthority{}).Where("sys_user_id = ?", userId).
+		Pluck("sys_authority_authority_id", &current).Error
+	if err != nil {
+		return false, err
+	}
+	if sameAuthorities(current, authorityIds) {
+		return false, nil
+	}
+	if err = tx.Delete(&[]system.SysUserAuthority{}, "sys_user_id = ?", userId).Error; err != nil {
+		return false, err
+	}
+	useAuthority := make([]system.SysUserAuthority, 0, len(authorityIds))
+	for _, v := range authorityIds {
+		useAuthority = append(useAuthority, system.SysUserAuthority{SysUserId: userId, SysAuthorityAuthorityId: v})
+	}
+	if err = tx.Create(&useAuthority).Error; err != nil {
+		return false, err
+	}
+	err = tx.Model(&system.SysUser{}).
+		Where("id = ? AND authority_id NOT IN ?", userId, authorityIds).
+		Update("authority_id", authorityIds[0]).Error
+	return true, err
+}
+
+func sameAuthorities(a []uint, b []uint) bool {
+	set := make(map[uint]struct{}, len(a))
+	for _, v := range a {
+		set[v] = struct{}{}
+	}
+	for _, v := range b {
+		if _, ok := set[v]; !ok {
+			return false
+		}
+		delete(set, v)
+	}
+	return len(set) == 0
+}
@@ -0,0 +1,304 @@
+package system
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/flipped-aurora/gin-vue-admin/server/global"
+	"github.com/flipped-aurora/gin-vue-admin/server/model/system"
+	"github.com/flipped-aurora/gin-vue-admin/server/utils"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrMfaNotEnabled     = errors.New("未开启两步验证")
+	ErrMfaAlreadyEnabled = errors.New("已开启两步验证")
+	ErrMfaCodeInvalid    = errors.New("验证码错误")
+	ErrMfaRequired       = errors.New("当前角色要求开启两步验证, 无法关闭")
+	ErrMfaChallenge      = errors.New("登录验证已过期, 请重新登录")
+	ErrMfaEnrollDenied   = errors.New("当前账号无需在登录时绑定两步验证")
+)
+
+const (
+	mfaChallengePrefix   = "mfa:challenge:"
+	mfaAttemptPrefix     = "mfa:attempts:"
+	mfaChallengeAttempts = 5
+	mfaRecoveryCodeCount = 10
+)
+
+// MfaChallenge 登录第二步挑战 第一步校验密码成功后签发 尝试次数单独计数
+// NeedEnroll 签发时角色要求两步验证 且用户既未开启也没有通行密钥 只有此时允许在登录过程中绑定
+type MfaChallenge struct {
+	UserId     uint `json:"userId"`
+	NeedEnroll bool `json:"needEnroll"`
+}
+
+type MfaService struct{}
+
+var MfaServiceApp = new(MfaService)
+
+func (mfaService *MfaService) encryptKey() string {
+	if global.GVA_CONFIG.Mfa.EncryptKey != "" {
+		return global.GVA_CONFIG.Mfa.EncryptKey
+	}
+	return global.GVA_CONFIG.JWT.SigningKey
+}
+
+// IsMfaRequired 用户拥有的任一角色要求两步验证时 该用户必须开启两步验证
+func (mfaService *MfaService) IsMfaRequired(userId uint) bool {
+	var count int64
+	global.GVA_DB.Model(&system.SysAuthority{}).
+		Joins("JOIN sys_user_authority ON sys_user_authority.sys_authority_authority_id = sys_authorities.authority_id").
+		Where("sys_user_authority.sys_user_id = ? AND sys_authorities.require_mfa = ?", userId, true).
+		Count(&count)
+	return count > 0
+}
+
+// IsMfaEnabled 用户是否已开启两步验证
+func (mfaService *MfaService) IsMfaEnabled(userId uint) bool {
+	var count int64
+	global.GVA_DB.Model(&system.SysUserMfa{}).Where("user_id = ? AND enabled = ?", userId, true).Count(&count)
+	return count > 0
+}
+
+// EnrollMfa 生成新的TOTP密钥 需调用 ActivateMfa 验证一次后才会启用
+func (mfaService *MfaService) EnrollMfa(user system.SysUser) (secret string, uri string, err error) {
+	var mfa system.SysUserMfa
+	err = global.GVA_DB.Where("user_id = ?", user.ID).First(&mfa).Error
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return
+	}
+	if mfa.Enabled {
+		return "", "", ErrMfaAlreadyEnabled
+	}
+	secret, err = utils.GenerateTOTPSecret()
+	if err != nil {
+		return
+	}
+	encrypted, err := utils.AesGcmEncrypt(mfaService.encryptKey(), secret)
+	if err != nil {
+		return
+	}
+	mfa.UserId = user.ID
+	mfa.Secret = encrypted
+	mfa.LastUsedStep = 0
+	if err = global.GVA_DB.Save(&mfa).Error; err != nil {
+		return
+	}
+	return secret, utils.TOTPURI(global.GVA_CONFIG.Mfa.Issuer, user.Username, secret), nil
+}
+
+// ActivateMfa 验证绑定的密钥并启用两步验证 返回一次性恢复码明文 仅此一次可见
+func (mfaService *MfaService) ActivateMfa(userId uint, code string) (recoveryCodes []string, err error) {
+	var mfa system.SysUserMfa
+	if err = global.GVA_DB.Where("user_id = ?", userId).First(&mfa).Error; err != nil {
+		return nil, errors.New("请先绑定验证器")
+	}
+	if mfa.Enabled {
+		return nil, ErrMfaAlreadyEnabled
+	}
+	if err = mfaService.checkTotp(&mfa, code); err != nil {
+		return nil, err
+	}
+	err = global.GVA_DB.Transaction(func(tx *gorm.DB) error {
+		// 条件更新 同一验证码并发提交时只有一个成功
+		result := tx.Model(&system.SysUserMfa{}).Where("id = ? AND enabled = ? AND last_used_step < ?", mfa.ID, false, mfa.LastUsedStep).
+			Updates(map[string]interface{}{"enabled": true, "last_used_step": mfa.LastUsedStep})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrMfaCodeInvalid
+		}
+		recoveryCodes, err = mfaService.replaceRecoveryCodes(tx, userId)
+		return err
+	})
+	return recoveryCodes, err
+}
+
+// DisableMfa 用户自行关闭两步验证 需要提供有效的验证码或恢复码
+// 角色要求两步验证时 只有注册了通行密钥才能关闭
+func (mfaService *MfaService) DisableMfa(userId uint, code string) error {
+	if mfaService.IsMfaRequired(userId) && !PasskeyServiceApp.HasPasskey(userId) {
+		return ErrMfaRequired
+	}
+	if err := mfaService.VerifyMfa(userId, code); err != nil {
+		return err
+	}
+	return mfaService.ResetMfa(userId)
+}
+
+// RegenerateRecoveryCodes 重新生成恢复码 旧恢复码全部失效
+func (mfaService *MfaService) RegenerateRecoveryCodes(userId uint, code string) (recoveryCodes []string, err error) {
+	if err = mfaService.VerifyMfa(userId, code); err != nil {
+		return nil, err
+	}
+	err = global.GVA_DB.Transaction(func(tx *gorm.DB) error {
+		recoveryCodes, err = mfaService.replaceRecoveryCodes(tx, userId)
+		return err
+	})
+	return recoveryCodes, err
+}
+
+// ResetMfa 清除用户的两步验证信息 管理员重置或用户关闭时使用
+func (mfaService *MfaService) ResetMfa(userId uint) error {
+	return global.GVA_DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Unscoped().Where("user_id = ?", userId).Delete(&system.SysUserMfa{}).Error; err != nil {
+			return err
+		}
+		return tx.Unscoped().Where("user_id = ?", userId).Delete(&system.SysUserRecoveryCode{}).Error
+	})
+}
+
+// VerifyMfa 校验TOTP验证码或恢复码 恢复码使用后即作废
+func (mfaService *MfaService) VerifyMfa(userId uint, code string) error {
+	var mfa system.SysUserMfa
+	if err := global.GVA_DB.Where("user_id = ? AND enabled = ?", userId, true).First(&mfa).Error; err != nil {
+		return ErrMfaNotEnabled
+	}
+	code = strings.TrimSpace(code)
+	if len(code) == utils.TOTPDigits {
+		if err := mfaService.checkTotp(&mfa, code); err != nil {
+			return err
+		}
+		// 条件更新 同一验证码并发提交时只有一个成功
+		result := global.GVA_DB.Model(&system.SysUserMfa{}).Where("id = ? AND last_used_step < ?", mfa.ID, mfa.LastUsedStep).
+			Update("last_used_step", mfa.LastUsedStep)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrMfaCodeInvalid
+		}
+		return nil
+	}
+	result := global.GVA_DB.Model(&system.SysUserRecoveryCode{}).
+		Where("user_id = ? AND code_hash = ? AND used_at IS NULL", userId, utils.SHA256V(normalizeRecoveryCode(code))).
+		Update("used_at", time.Now())
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrMfaCodeInvalid
+	}
+	return nil
+}
+
+// checkTotp 校验验证码 通过后更新 mfa.LastUsedStep 由调用方按原值条件更新
+func (mfaService *MfaService) checkTotp(mfa *system.SysUserMfa, code string) error {
+	secret, err := utils.AesGcmDecrypt(mfaService.encryptKey(), mfa.Secret)
+	if err != nil {
+		return errors.New("两步验证密钥解密失败, 请联系管理员重置")
+	}
+	step, ok := utils.ValidateTOTP(secret, code, time.Now(), global.GVA_CONFIG.Mfa.Skew)
+	if !ok || step <= mfa.LastUsedStep {
+		return ErrMfaCodeInvalid
+	}
+	mfa.LastUsedStep = step
+	return nil
+}
+
+func (mfaService *MfaService) replaceRecoveryCodes(tx *gorm.DB, userId uint) ([]string, error) {
+	if err := tx.Unscoped().Where("user_id = ?", userId).Delete(&system.SysUserRecoveryCode{}).Error; err != nil {
+		return nil, err
+	}
+	codes := make([]string, 0, mfaRecoveryCodeCount)
+	records := make([]system.SysUserRecoveryCode, 0, mfaRecoveryCodeCount)
+	for i := 0; i < mfaRecoveryCodeCount; i++ {
+		secret, err := utils.GenerateTOTPSecret()
+		if err != nil {
+			return nil, err
+		}
+		code := strings.ToLower(secret[:5] + "-" + secret[5:10])
+		codes = append(codes, code)
+		records = append(records, system.SysUserRecoveryCode{UserId: userId, CodeHash: utils.SHA256V(normalizeRecoveryCode(code))})
+	}
+	return codes, tx.Create(&records).Error
+}
+
+func normalizeRecoveryCode(code string) string {
+	return strings.ToLower(strings.ReplaceAll(strings.TrimSpace(code), "-", ""))
+}
+
+// CreateChallenge 密码校验通过后签发登录挑战
+func (mfaService *MfaService) CreateChallenge(userId uint) (id string, challenge MfaChallenge, err error) {
+	id, err = utils.RandomToken(24)
+	if err != nil {
+		return "", challenge, err
+	}
+	challenge = MfaChallenge{
+		UserId:     userId,
+		NeedEnroll: mfaService.IsMfaRequired(userId) && !mfaService.IsMfaEnabled(userId) && !PasskeyServiceApp.HasPasskey(userId),
+	}
+	return id, challenge, mfaService.saveChallenge(id, challenge)
+}
+
+// EnrollChallenge 登录过程中绑定两步验证 只有签发时需要绑定的挑战可以使用
+// 避免只知道密码的人为已有通行密钥的用户绑定新的验证器 绕过通行密钥
+func (mfaService *MfaService) EnrollChallenge(challenge MfaChallenge, user system.SysUser) (secret string, uri string, err error) {
+	if !challenge.NeedEnroll || challenge.UserId != user.ID {
+		return "", "", ErrMfaEnrollDenied
+	}
+	return mfaService.EnrollMfa(user)
+}
+
+// VerifyChallenge 校验登录第二步的验证码 未开启两步验证时只有需要绑定的挑战可以完成启用
+// 返回启用时生成的恢复码
+func (mfaService *MfaService) VerifyChallenge(challenge MfaChallenge, code string) (recoveryCodes []string, err error) {
+	if mfaService.IsMfaEnabled(challenge.UserId) {
+		return nil, mfaService.VerifyMfa(challenge.UserId, code)
+	}
+	if !challenge.NeedEnroll {
+		return nil, ErrMfaEnrollDenied
+	}
+	return mfaService.ActivateMfa(challenge.UserId, code)
+}
+
+// GetChallenge 获取登录挑战
+func (mfaService *MfaService) GetChallenge(id string) (MfaChallenge, error) {
+	var challenge MfaChallenge
+	v, ok := utils.CacheGet(mfaChallengePrefix + id)
+	if !ok || json.Unmarshal([]byte(v), &challenge) != nil {
+		return challenge, ErrMfaChallenge
+	}
+	return challenge, nil
+}
+
+// AttemptChallenge 校验验证码前计入一次尝试 超过次数后挑战作废 需重新输入密码
+// 计数是原子的 并发提交的请求同样受次数限制
+func (mfaService *MfaService) AttemptChallenge(id string) error {
+	n, err := utils.CacheIncr(mfaAttemptPrefix+id, mfaChallengeExpires())
+	if err != nil {
+		return err
+	}
+	// 计数保留到过期 避免删除后重新从零计数
+	if n > mfaChallengeAttempts {
+		utils.CacheDel(mfaChallengePrefix + id)
+		return ErrMfaChallenge
+	}
+	return nil
+}
+
+// FinishChallenge 登录完成后删除挑战 保证只能使用一次
+func (mfaService *MfaService) FinishChallenge(id string) {
+	utils.CacheDel(mfaChallengePrefix + id)
+	utils.CacheDel(mfaAttemptPrefix + id)
+}
+
+func (mfaService *MfaService) saveChallenge(id string, challenge MfaChallenge) error {
+	b, err := json.Marshal(challenge)
+	if err != nil {
+		return err
+	}
+	return utils.CacheSet(mfaChallengePrefix+id, string(b), mfaChallengeExpires())
+}
+
+func mfaChallengeExpires() time.Duration {
+	dr, err := utils.ParseDuration(global.GVA_CONFIG.Mfa.ChallengeExpires)
+	if err != nil || dr <= 0 {
+		return 5 * time.Minute
+	}
+	return dr
+}
@@ -0,0 +1,116 @@
+package system
+
+import (
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/flipped-aurora/gin-vue-admin/server/global"
+	"github.com/flipped-aurora/gin-vue-admin/server/model/system"
+	"github.com/flipped-aurora/gin-vue-admin/server/utils"
+)
+
+func setupMfaTest(t *testing.T) (secret string) {
+	t.Helper()
+	db := setupTestDB(t, &system.SysUser{}, &system.SysAuthority{}, &system.SysUserAuthority{},
+		&system.SysUserMfa{}, &system.SysUserRecoveryCode{}, &system.SysUserPasskey{})
+	global.GVA_CONFIG.Mfa.EncryptKey = "mfa-test-key"
+	db.Create(&system.SysUser{GVA_MODEL: global.GVA_MODEL{ID: 1}, Username: "alice", Enable: 1, AuthorityId: 888})
+	secret, _, err := MfaServiceApp.EnrollMfa(system.SysUser{GVA_MODEL: global.GVA_MODEL{ID: 1}, Username: "alice"})
+	if err != nil {
+		t.Fatalf("EnrollMfa() error = %v", err)
+	}
+	return secret
+}
+
+// concurrently 并发执行 返回成功的次数
+func concurrently(n int, fn func() error) (ok int32) {
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if fn() == nil {
+				atomic.AddInt32(&ok, 1)
+			}
+		}()
+	}
+	wg.Wait()
+	return ok
+}
+
+func TestMfaService_CodeReplay(t *testing.T) {
+	secret := setupMfaTest(t)
+	s := MfaServiceApp
+	code, err := utils.TOTPCode(secret, utils.TOTPStep(time.Now()))
+	if err != nil {
+		t.Fatalf("TOTPCode() error = %v", err)
+	}
+
+	// 同一验证码并发提交 只有一个能启用
+	if got := concurrently(8, func() error {
+		_, err := s.ActivateMfa(1, code)
+		return err
+	}); got != 1 {
+		t.Fatalf("concurrent ActivateMfa() succeeded %d times, want 1", got)
+	}
+	if err = s.VerifyMfa(1, code); !errors.Is(err, ErrMfaCodeInvalid) {
+		t.Fatalf("VerifyMfa() reused code error = %v, want %v", err, ErrMfaCodeInvalid)
+	}
+
+	// 同一验证码并发校验 只有一个通过
+	global.GVA_DB.Model(&system.SysUserMfa{}).Where("user_id = ?", 1).Update("last_used_step", 0)
+	if got := concurrently(8, func() error { return s.VerifyMfa(1, code) }); got != 1 {
+		t.Fatalf("concurrent VerifyMfa() succeeded %d times, want 1", got)
+	}
+}
+
+func TestMfaService_AttemptChallenge(t *testing.T) {
+	setupMfaTest(t)
+	s := MfaServiceApp
+	id, _, err := s.CreateChallenge(1)
+	if err != nil {
+		t.Fatalf("CreateChallenge() error = %v", err)
+	}
+	// 并发尝试同样受次数限制 超过后挑战作废
+	if got := concurrently(20, func() error { return s.AttemptChallenge(id) }); got != mfaChallengeAttempts {
+		t.Fatalf("concurrent AttemptChallenge() allowed %d, want %d", got, mfaChallengeAttempts)
+	}
+	if _, err = s.GetChallenge(id); !errors.Is(err, ErrMfaChallenge) {
+		t.Fatalf("GetChallenge() after attempts error = %v, want %v", err, ErrMfaChallenge)
+	}
+}
+
+func TestMfaService_PasskeyUserCannotEnrollDuringLogin(t *testing.T) {
+	secret := setupMfaTest(t)
+	db := global.GVA_DB
+	db.Create(&system.SysAuthority{AuthorityId: 888, AuthorityName: "管理员", RequireMfa: true})
+	db.Create(&system.SysUserAuthority{SysUserId: 1, SysAuthorityAuthorityId: 888})
+	s := MfaServiceApp
+	user := system.SysUser{GVA_MODEL: global.GVA_MODEL{ID: 1}, Username: "alice"}
+
+	// 没有通行密钥时登录过程中可以绑定
+	_, challenge, err := s.CreateChallenge(1)
+	if err != nil || !challenge.NeedEnroll {
+		t.Fatalf("CreateChallenge() = %+v, %v", challenge, err)
+	}
+
+	// 已有通行密钥时 只知道密码无法绑定新的验证器代替通行密钥
+	db.Create(&system.SysUserPasskey{UserId: 1, Name: "key", CredentialId: "alice-key"})
+	_, challenge, err = s.CreateChallenge(1)
+	if err != nil || challenge.NeedEnroll {
+		t.Fatalf("CreateChallenge() with passkey = %+v, %v", challenge, err)
+	}
+	if _, _, err = s.EnrollChallenge(challenge, user); !errors.Is(err, ErrMfaEnrollDenied) {
+		t.Fatalf("EnrollChallenge() error = %v, want %v", err, ErrMfaEnrollDenied)
+	}
+	code, _ := utils.TOTPCode(secret, utils.TOTPStep(time.Now()))
+	if _, err = s.VerifyChallenge(challenge, code); !errors.Is(err, ErrMfaEnrollDenied) {
+		t.Fatalf("VerifyChallenge() error = %v, want %v", err, ErrMfaEnrollDenied)
+	}
+	if s.IsMfaEnabled(1) {
+		t.Fatalf("mfa enabled through login challenge")
+	}
+}
@@ -0,0 +1,360 @@
+package system
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/flipped-aurora/gin-vue-admin/server/global"
+	"github.com/flipped-aurora/gin-vue-admin/server/model/system"
+	systemReq "github.com/flipped-aurora/gin-vue-admin/server/model/system/request"
+	systemRes "github.com/flipped-aurora/gin-vue-admin/server/model/system/response"
+	"github.com/flipped-aurora/gin-vue-admin/server/utils"
+	"github.com/gofrs/uuid/v5"
+	"gorm.io/gorm"
+)
+
+const (
+	passkeySessionPrefix  = "passkey:session:"
+	defaultPasskeyTimeout = 5 * time.Minute
+)
+
+var (
+	ErrPasskeyNotFound     = errors.New("通行密钥不存在或已被删除")
+	ErrPasskeySession      = errors.New("通行密钥操作已过期, 请重试")
+	ErrPasskeyPasswordless = errors.New("未开启通行密钥登录")
+	ErrPasskeyLast         = errors.New("当前角色要求两步验证, 请先绑定验证器再删除最后一个通行密钥")
+)
+
+// passkeySession 注册或登录过程中保存的挑战 只能使用一次
+type passkeySession struct {
+	Challenge string `json:"challenge"`
+	RPID      string `json:"rpId"`
+	UserId    uint   `json:"userId"` // 为0时不限定用户 即免密码登录
+	RequireUV bool   `json:"requireUv"`
+}
+
+type PasskeyService struct{}
+
+// IsLastPasskey 是否因删除最后一个通行密钥被拒绝
+func (passkeyService *PasskeyService) IsLastPasskey(err error) bool {
+	return errors.Is(err, ErrPasskeyLast)
+}
+
+var PasskeyServiceApp = new(PasskeyService)
+
+// RelyingParty 依赖方ID优先使用配置 未配置时使用请求来源的主机名
+func (passkeyService *PasskeyService) RelyingParty(origin string) utils.WebauthnRelyingParty {
+	cfg := global.GVA_CONFIG.Webauthn
+	rp := utils.WebauthnRelyingParty{ID: cfg.RPID, Origins: cfg.Origins}
+	if rp.ID == "" {
+		if u, err := url.Parse(origin); err == nil {
+			rp.ID = u.Hostname()
+		}
+	}
+	return rp
+}
+
+// HasPasskey 用户是否注册了通行密钥
+func (passkeyService *PasskeyService) HasPasskey(userId uint) bool {
+	var count int64
+	global.GVA_DB.Model(&system.SysUserPasskey{}).Where("user_id = ?", userId).Count(&count)
+	return count > 0
+}
+
+// BeginRegistration 生成注册参数 已注册的通行密钥不能在同一认证器上重复注册
+func (passkeyService *PasskeyService) BeginRegistration(user system.SysUser, origin string) (options systemRes.PasskeyCreationOptions, err error) {
+	rp := passkeyService.RelyingParty(origin)
+	if rp.ID == "" {
+		return options, errors.New("无法确定通行密钥的域名, 请配置 webauthn.rp-id")
+	}
+	challenge, err := utils.RandomToken(32)
+	if err != nil {
+		return
+	}
+	exclude, err := passkeyService.descriptors(user.ID)
+	if err != nil {
+		return
+	}
+	timeout := passkeyService.timeout()
+	err = passkeyService.saveSession(registrationSessionKey(user.ID), passkeySession{Challenge: challenge, RPID: rp.ID, UserId: user.ID}, timeout)
+	if err != nil {
+		return
+	}
+	params := make([]systemRes.PasskeyCredentialParam, 0, len(utils.WebauthnAlgs))
+	for _, alg := range utils.WebauthnAlgs {
+		params = append(params, systemRes.PasskeyCredentialParam{Type: "public-key", Alg: alg})
+	}
+	displayName := user.NickName
+	if displayName == "" {
+		displayName = user.Username
+	}
+	return systemRes.PasskeyCreationOptions{
+		Rp:                     systemRes.PasskeyRelyingParty{ID: rp.ID, Name: global.GVA_CONFIG.Webauthn.RPName},
+		User:                   systemRes.PasskeyUser{ID: base64.RawURLEncoding.EncodeToString(user.UUID.Bytes()), Name: user.Username, DisplayName: displayName},
+		Challenge:              challenge,
+		PubKeyCredParams:       params,
+		Timeout:                timeout.Milliseconds(),
+		ExcludeCredentials:     exclude,
+		AuthenticatorSelection: systemRes.PasskeyAuthenticatorSelection{ResidentKey: "preferred", UserVerification: "preferred"},
+		Attestation:            "none",
+	}, nil
+}
+
+// FinishRegistration 校验认证器返回的结果并保存通行密钥
+func (passkeyService *PasskeyService) FinishRegistration(userId uint, req systemReq.FinishPasskeyRegistration) (passkey system.SysUserPasskey, err error) {
+	session, err := passkeyService.takeSession(registrationSessionKey(userId))
+	if err != nil {
+		return
+	}
+	clientData, err := utils.DecodeWebauthnBase64(req.Credential.Response.ClientDataJSON)
+	if err != nil {
+		return passkey, utils.ErrWebauthnInvalid
+	}
+	attestation, err := utils.DecodeWebauthnBase64(req.Credential.Response.AttestationObject)
+	if err != nil {
+		return passkey, utils.ErrWebauthnInvalid
+	}
+	rp := utils.WebauthnRelyingParty{ID: session.RPID, Origins: global.GVA_CONFIG.Webauthn.Origins}
+	cred, err := rp.VerifyRegistration(clientData, attestation, session.Challenge, false)
+	if err != nil {
+		return
+	}
+	credentialId := base64.RawURLEncoding.EncodeToString(cred.ID)
+	if len(credentialId) > 255 {
+		return passkey, errors.New("凭证ID过长, 请更换认证器")
+	}
+	err = global.GVA_DB.Where("credential_id = ?", credentialId).First(&system.SysUserPasskey{}).Error
+	if err == nil {
+		return passkey, errors.New("该通行密钥已注册")
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return
+	}
+	name := strings.TrimSpace(req.Name)
+	if name == "" {
+		name = "通行密钥"
+	}
+	aaguid, _ := uuid.FromBytes(cred.AAGUID)
+	passkey = system.SysUserPasskey{
+		UserId:       userId,
+		Name:         name,
+		CredentialId: credentialId,
+		PublicKey:    cred.PublicKey,
+		SignCount:    cred.SignCount,
+		Aaguid:       aaguid.String(),
+		Transports:   strings.Join(req.Credential.Response.Transports, ","),
+	}
+	err = global.GVA_DB.Create(&passkey).Error
+	return passkey, err
+}
+
+// BeginPasswordless 免密码登录 不限定用户 由认证器选择可发现凭证 要求验证PIN或生物识别
+func (passkeyService *PasskeyService) BeginPasswordless(origin string) (sessionId string, options systemRes.PasskeyRequestOptions, err error) {
+	if !global.GVA_CONFIG.Webauthn.Passwordless {
+		return "", options, ErrPasskeyPasswordless
+	}
+	if sessionId, err = utils.RandomToken(24); err != nil {
+		return
+	}
+	options, err = passkeyService.beginLogin(loginSessionKey(sessionId), 0, origin, true)
+	return sessionId, options, err
+}
+
+// FinishPasswordless 完成免密码登录 返回用户ID
+func (passkeyService *PasskeyService) FinishPasswordless(sessionId string, cred systemReq.PasskeyCredential) (uint, error) {
+	return passkeyService.finishLogin(loginSessionKey(sessionId), cred)
+}
+
+// BeginMfa 密码校验通过后 使用通行密钥完成第二步验证
+func (passkeyService *PasskeyService) BeginMfa(challengeId string, userId uint, origin string) (systemRes.PasskeyRequestOptions, error) {
+	return passkeyService.beginLogin(mfaSessionKey(challengeId), userId, origin, false)
+}
+
+// FinishMfa 校验第二步的通行密钥 凭证必须属于该挑战的用户
+func (passkeyService *PasskeyService) FinishMfa(challengeId string, cred systemReq.PasskeyCredential) error {
+	_, err := passkeyService.finishLogin(mfaSessionKey(challengeId), cred)
+	return err
+}
+
+func (passkeyService *PasskeyService) beginLogin(key string, userId uint, origin string, requireUV bool) (options systemRes.PasskeyRequestOptions, err error) {
+	rp := passkeyService.RelyingParty(origin)
+	if rp.ID == "" {
+		return options, errors.New("无法确定通行密钥的域名, 请配置 webauthn.rp-id")
+	}
+	allow := []systemRes.PasskeyDescriptor{}
+	if userId != 0 {
+		if allow, err = passkeyService.descriptors(userId); err != nil {
+			return
+		}
+		if len(allow) == 0 {
+			return options, ErrPasskeyNotFound
+		}
+	}
+	challenge, err := utils.RandomToken(32)
+	if err != nil {
+		return
+	}
+	timeout := passkeyService.timeout()
+	err = passkeyService.saveSession(key, passkeySession{Challenge: challenge, RPID: rp.ID, UserId: userId, RequireUV: requireUV}, timeout)
+	if err != nil {
+		return
+	}
+	userVerification := "preferred"
+	if requireUV {
+		userVerification = "required"
+	}
+	return systemRes.PasskeyRequestOptions{
+		Challenge:        challenge,
+		RpId:             rp.ID,
+		Timeout:          timeout.Milliseconds(),
+		AllowCredentials: allow,
+		UserVerification: userVerification,
+	}, nil
+}
+
+func (passkeyService *PasskeyService) finishLogin(key string, cred systemReq.PasskeyCredential) (uint, error) {
+	session, err := passkeyService.takeSession(key)
+	if err != nil {
+		return 0, err
+	}
+	var passkey system.SysUserPasskey
+	err = global.GVA_DB.Where("credential_id = ?", strings.TrimRight(cred.Id, "=")).First(&passkey).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return 0, ErrPasskeyNotFound
+		}
+		return 0, err
+	}
+	if session.UserId != 0 && passkey.UserId != session.UserId {
+		return 0, ErrPasskeyNotFound
+	}
+	if cred.Response.UserHandle != "" {
+		// 可发现凭证返回的用户标识必须与凭证所属用户一致
+		var user system.SysUser
+		if err = global.GVA_DB.Select("id", "uuid").First(&user, passkey.UserId).Error; err != nil {
+			return 0, ErrPasskeyNotFound
+		}
+		if handle, err := utils.DecodeWebauthnBase64(cred.Response.UserHandle); err != nil || string(handle) != string(user.UUID.Bytes()) {
+			return 0, utils.ErrWebauthnInvalid
+		}
+	}
+	clientData, err1 := utils.DecodeWebauthnBase64(cred.Response.ClientDataJSON)
+	authData, err2 := utils.DecodeWebauthnBase64(cred.Response.AuthenticatorData)
+	signature, err3 := utils.DecodeWebauthnBase64(cred.Response.Signature)
+	if err1 != nil || err2 != nil || err3 != nil {
+		return 0, utils.ErrWebauthnInvalid
+	}
+	rp := utils.WebauthnRelyingParty{ID: session.RPID, Origins: global.GVA_CONFIG.Webauthn.Origins}
+	count, err := rp.VerifyAssertion(passkey.PublicKey, passkey.SignCount, clientData, authData, signature, session.Challenge, session.RequireUV)
+	if err != nil {
+		return 0, err
+	}
+	// 条件更新 并发使用同一计数的请求只有一个成功
+	result := global.GVA_DB.Model(&system.SysUserPasskey{}).Where("id = ? AND sign_count = ?", passkey.ID, passkey.SignCount).
+		Updates(map[string]interface{}{"sign_count": count, "last_used_at": time.Now()})
+	if result.Error != nil {
+		return 0, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return 0, utils.ErrWebauthnSignCount
+	}
+	return passkey.UserId, nil
+}
+
+// GetPasskeyList 获取用户的通行密钥
+func (passkeyService *PasskeyService) GetPasskeyList(userId uint) (list []system.SysUserPasskey, err error) {
+	err = global.GVA_DB.Where("user_id = ?", userId).Order("id").Find(&list).Error
+	return list, err
+}
+
+// RenamePasskey 修改通行密钥名称
+func (passkeyService *PasskeyService) RenamePasskey(userId uint, id uint, name string) error {
+	result := global.GVA_DB.Model(&system.SysUserPasskey{}).Where("id = ? AND user_id = ?", id, userId).Update("name", strings.TrimSpace(name))
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrPasskeyNotFound
+	}
+	return nil
+}
+
+// DeletePasskey 删除通行密钥 角色要求两步验证时不能删除最后一个验证方式
+func (passkeyService *PasskeyService) DeletePasskey(userId uint, id uint) error {
+	var count int64
+	if err := global.GVA_DB.Model(&system.SysUserPasskey{}).Where("user_id = ?", userId).Count(&count).Error; err != nil {
+		return err
+	}
+	if count == 1 && MfaServiceApp.IsMfaRequired(userId) && !MfaServiceApp.IsMfaEnabled(userId) {
+		return ErrPasskeyLast
+	}
+	result := global.GVA_DB.Unscoped().Where("id = ? AND user_id = ?", id, userId).Delete(&system.SysUserPasskey{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrPasskeyNotFound
+	}
+	return nil
+}
+
+// ResetPasskeys 删除用户的全部通行密钥 管理员重置两步验证时使用
+func (passkeyService *PasskeyService) ResetPasskeys(userId uint) error {
+	return global.GVA_DB.Unscoped().Where("user_id = ?", userId).Delete(&system.SysUserPasskey{}).Error
+}
+
+func (passkeyService *PasskeyService) descriptors(userId uint) ([]systemRes.PasskeyDescriptor, error) {
+	var list []system.SysUserPasskey
+	if err := global.GVA_DB.Select("credential_id", "transports").Where("user_id = ?", userId).Find(&list).Error; err != nil {
+		return nil, err
+	}
+	descriptors := make([]systemRes.PasskeyDescriptor, 0, len(list))
+	for i := range list {
+		d := systemRes.PasskeyDescriptor{Type: "public-key", ID: list[i].CredentialId}
+		if list[i].Transports != "" {
+			d.Transports = strings.Split(list[i].Transports, ",")
+		}
+		descriptors = append(descriptors, d)
+	}
+	return descriptors, nil
+}
+
+func (passkeyService *PasskeyService) timeout() time.Duration {
+	return parseDurationOr(global.GVA_CONFIG.Webauthn.Timeout, defaultPasskeyTimeout)
+}
+
+func (passkeyService *PasskeyService) saveSession(key string, session passkeySession, ttl time.Duration) error {
+	b, err := json.Marshal(session)
+	if err != nil {
+		return err
+	}
+	return utils.CacheSet(passkeySessionPrefix+key, string(b), ttl)
+}
+
+// takeSession 取出并删除会话 保证挑战只能使用一次
+func (passkeyService *PasskeyService) takeSession(key string) (passkeySession, error) {
+	var session passkeySession
+	v, ok := utils.CacheGet(passkeySessionPrefix + key)
+	utils.CacheDel(passkeySessionPrefix + key)
+	if !ok || json.Unmarshal([]byte(v), &session) != nil {
+		return session, ErrPasskeySession
+	}
+	return session, nil
+}
+
+func registrationSessionKey(userId uint) string {
+	return "reg:" + strconv.Itoa(int(userId))
+}
+
+func loginSessionKey(sessionId string) string {
+	return "login:" + sessionId
+}
+
+func mfaSessionKey(challengeId string) string {
+	return "mfa:" + challengeId
+}
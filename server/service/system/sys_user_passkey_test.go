@@ -0,0 +1,282 @@
+package system
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/flipped-aurora/gin-vue-admin/server/config"
+	"github.com/flipped-aurora/gin-vue-admin/server/global"
+	"github.com/flipped-aurora/gin-vue-admin/server/model/system"
+	systemReq "github.com/flipped-aurora/gin-vue-admin/server/model/system/request"
+	"github.com/flipped-aurora/gin-vue-admin/server/utils"
+	"github.com/gofrs/uuid/v5"
+)
+
+const passkeyTestOrigin = "https://admin.example.com"
+
+// testPasskey 软件认证器 使用ES256和none证明格式
+type testPasskey struct {
+	key    *ecdsa.PrivateKey
+	credId []byte
+	count  uint32
+	uv     bool
+}
+
+func newTestPasskey(t *testing.T, credId string) *testPasskey {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("generate key failed: %v", err)
+	}
+	return &testPasskey{key: key, credId: []byte(credId), uv: true}
+}
+
+func cborBytes(b []byte) []byte {
+	if len(b) < 24 {
+		return append([]byte{0x40 | byte(len(b))}, b...)
+	}
+	if len(b) < 256 {
+		return append([]byte{0x58, byte(len(b))}, b...)
+	}
+	return append([]byte{0x59, byte(len(b) >> 8), byte(len(b))}, b...)
+}
+
+func (p *testPasskey) authData(attested bool) []byte {
+	h := sha256.Sum256([]byte("admin.example.com"))
+	flags := byte(0x01)
+	if p.uv {
+		flags |= 0x04
+	}
+	b := append(h[:], flags)
+	b = binary.BigEndian.AppendUint32(b, p.count)
+	if attested {
+		b[32] |= 0x40
+		b = append(b, make([]byte, 16)...)
+		b = binary.BigEndian.AppendUint16(b, uint16(len(p.credId)))
+		b = append(b, p.credId...)
+		// COSE EC2 公钥 {1:2, 3:-7, -1:1, -2:x, -3:y}
+		b = append(b, 0xa5, 0x01, 0x02, 0x03, 0x26, 0x20, 0x01, 0x21)
+		b = append(b, cborBytes(p.key.X.FillBytes(make([]byte, 32)))...)
+		b = append(b, 0x22)
+		b = append(b, cborBytes(p.key.Y.FillBytes(make([]byte, 32)))...)
+	}
+	return b
+}
+
+func passkeyClientData(typ, challenge string) []byte {
+	b, _ := json.Marshal(map[string]string{"type": typ, "challenge": challenge, "origin": passkeyTestOrigin})
+	return b
+}
+
+func (p *testPasskey) create(challenge string) systemReq.PasskeyCredential {
+	att := []byte{0xa3, 0x63, 'f', 'm', 't', 0x64, 'n', 'o', 'n', 'e', 0x67, 'a', 't', 't', 'S', 't', 'm', 't', 0xa0, 0x68, 'a', 'u', 't', 'h', 'D', 'a', 't', 'a'}
+	att = append(att, cborBytes(p.authData(true))...)
+	enc := base64.RawURLEncoding.EncodeToString
+	return systemReq.PasskeyCredential{Id: enc(p.credId), Type: "public-key", Response: systemReq.PasskeyResponse{
+		ClientDataJSON:    enc(passkeyClientData("webauthn.create", challenge)),
+		AttestationObject: enc(att),
+		Transports:        []string{"internal"},
+	}}
+}
+
+func (p *testPasskey) get(challenge string, userHandle []byte) systemReq.PasskeyCredential {
+	p.count++
+	cd := passkeyClientData("webauthn.get", challenge)
+	authData := p.authData(false)
+	h := sha256.Sum256(cd)
+	digest := sha256.Sum256(append(append([]byte{}, authData...), h[:]...))
+	sig, err := ecdsa.SignASN1(rand.Reader, p.key, digest[:])
+	if err != nil {
+		panic(err)
+	}
+	enc := base64.RawURLEncoding.EncodeToString
+	return systemReq.PasskeyCredential{Id: enc(p.credId), Type: "public-key", Response: systemReq.PasskeyResponse{
+		ClientDataJSON:    enc(cd),
+		AuthenticatorData: enc(authData),
+		Signature:         enc(sig),
+		UserHandle:        enc(userHandle),
+	}}
+}
+
+func setupPasskeyTest(t *testing.T) (alice, bob system.SysUser) {
+	db := setupTestDB(t, &system.SysUser{}, &system.SysAuthority{}, &system.SysUserAuthority{}, &system.SysUserMfa{}, &system.SysUserPasskey{})
+	global.GVA_CONFIG.Webauthn = config.Webauthn{RPName: "test", Origins: []string{passkeyTestOrigin}, Passwordless: true}
+	alice = system.SysUser{UUID: uuid.Must(uuid.NewV4()), Username: "alice", AuthorityId: 888}
+	bob = system.SysUser{UUID: uuid.Must(uuid.NewV4()), Username: "bob", AuthorityId: 888}
+	db.Create(&alice)
+	db.Create(&bob)
+	return
+}
+
+func registerTestPasskey(t *testing.T, s *PasskeyService, user system.SysUser, p *testPasskey) system.SysUserPasskey {
+	options, err := s.BeginRegistration(user, passkeyTestOrigin)
+	if err != nil {
+		t.Fatalf("BeginRegistration() error = %v", err)
+	}
+	if options.Rp.ID != "admin.example.com" {
+		t.Fatalf("BeginRegistration() rp id = %q", options.Rp.ID)
+	}
+	passkey, err := s.FinishRegistration(user.ID, systemReq.FinishPasskeyRegistration{Name: " 笔记本 ", Credential: p.create(options.Challenge)})
+	if err != nil {
+		t.Fatalf("FinishRegistration() error = %v", err)
+	}
+	return passkey
+}
+
+func TestPasskeyService_Registration(t *testing.T) {
+	alice, _ := setupPasskeyTest(t)
+	s := &PasskeyService{}
+	p := newTestPasskey(t, "alice-laptop")
+	passkey := registerTestPasskey(t, s, alice, p)
+	if passkey.Name != "笔记本" || passkey.Transports != "internal" || !s.HasPasskey(alice.ID) {
+		t.Fatalf("registered passkey = %+v", passkey)
+	}
+
+	// 注册会话只能使用一次
+	if _, err := s.FinishRegistration(alice.ID, systemReq.FinishPasskeyRegistration{Credential: p.create("")}); !errors.Is(err, ErrPasskeySession) {
+		t.Fatalf("FinishRegistration() reused session error = %v, want %v", err, ErrPasskeySession)
+	}
+	// 已注册的认证器会出现在排除列表中 重复提交被拒绝
+	options, _ := s.BeginRegistration(alice, passkeyTestOrigin)
+	if len(options.ExcludeCredentials) != 1 {
+		t.Fatalf("ExcludeCredentials = %v", options.ExcludeCredentials)
+	}
+	if _, err := s.FinishRegistration(alice.ID, systemReq.FinishPasskeyRegistration{Credential: p.create(options.Challenge)}); err == nil {
+		t.Fatalf("FinishRegistration() duplicate credential should fail")
+	}
+	// 挑战不匹配
+	options, _ = s.BeginRegistration(alice, passkeyTestOrigin)
+	other := newTestPasskey(t, "alice-phone")
+	if _, err := s.FinishRegistration(alice.ID, systemReq.FinishPasskeyRegistration{Credential: other.create(options.Challenge + "x")}); !errors.Is(err, utils.ErrWebauthnInvalid) {
+		t.Fatalf("FinishRegistration() wrong challenge error = %v, want %v", err, utils.ErrWebauthnInvalid)
+	}
+}
+
+func TestPasskeyService_Mfa(t *testing.T) {
+	alice, bob := setupPasskeyTest(t)
+	s := &PasskeyService{}
+	p := newTestPasskey(t, "alice-key")
+	registerTestPasskey(t, s, alice, p)
+	registerTestPasskey(t, s, bob, newTestPasskey(t, "bob-key"))
+
+	options, err := s.BeginMfa("c1", alice.ID, passkeyTestOrigin)
+	if err != nil || len(options.AllowCredentials) != 1 {
+		t.Fatalf("BeginMfa() = %+v, %v", options, err)
+	}
+	cred := p.get(options.Challenge, nil)
+	if err = s.FinishMfa("c1", cred); err != nil {
+		t.Fatalf("FinishMfa() error = %v", err)
+	}
+	var stored system.SysUserPasskey
+	global.GVA_DB.Where("user_id = ?", alice.ID).First(&stored)
+	if stored.SignCount != 1 || stored.LastUsedAt == nil {
+		t.Fatalf("stored passkey = count %d, last used %v", stored.SignCount, stored.LastUsedAt)
+	}
+	if err = s.FinishMfa("c1", cred); !errors.Is(err, ErrPasskeySession) {
+		t.Fatalf("FinishMfa() reused session error = %v, want %v", err, ErrPasskeySession)
+	}
+
+	// 克隆的认证器 计数没有增加
+	options, _ = s.BeginMfa("c2", alice.ID, passkeyTestOrigin)
+	p.count--
+	if err = s.FinishMfa("c2", p.get(options.Challenge, nil)); !errors.Is(err, utils.ErrWebauthnSignCount) {
+		t.Fatalf("FinishMfa() cloned authenticator error = %v, want %v", err, utils.ErrWebauthnSignCount)
+	}
+
+	// 他人的登录挑战不能使用自己的通行密钥
+	options, _ = s.BeginMfa("c3", bob.ID, passkeyTestOrigin)
+	if err = s.FinishMfa("c3", p.get(options.Challenge, nil)); !errors.Is(err, ErrPasskeyNotFound) {
+		t.Fatalf("FinishMfa() other user error = %v, want %v", err, ErrPasskeyNotFound)
+	}
+}
+
+func TestPasskeyService_Passwordless(t *testing.T) {
+	alice, bob := setupPasskeyTest(t)
+	s := &PasskeyService{}
+	p := newTestPasskey(t, "alice-key")
+	registerTestPasskey(t, s, alice, p)
+
+	sessionId, options, err := s.BeginPasswordless(passkeyTestOrigin)
+	if err != nil || options.UserVerification != "required" || len(options.AllowCredentials) != 0 {
+		t.Fatalf("BeginPasswordless() = %+v, %v", options, err)
+	}
+	userId, err := s.FinishPasswordless(sessionId, p.get(options.Challenge, alice.UUID.Bytes()))
+	if err != nil || userId != alice.ID {
+		t.Fatalf("FinishPasswordless() = %d, %v", userId, err)
+	}
+
+	tests := []struct {
+		name string
+		cred func(challenge string) systemReq.PasskeyCredential
+		want error
+	}{
+		{name: "未验证用户", want: utils.ErrWebauthnInvalid, cred: func(challenge string) systemReq.PasskeyCredential {
+			p.uv = false
+			defer func() { p.uv = true }()
+			return p.get(challenge, alice.UUID.Bytes())
+		}},
+		{name: "用户标识不匹配", want: utils.ErrWebauthnInvalid, cred: func(challenge string) systemReq.PasskeyCredential {
+			return p.get(challenge, bob.UUID.Bytes())
+		}},
+		{name: "未注册的凭证", want: ErrPasskeyNotFound, cred: func(challenge string) systemReq.PasskeyCredential {
+			return newTestPasskey(t, "unknown").get(challenge, alice.UUID.Bytes())
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sessionId, options, err := s.BeginPasswordless(passkeyTestOrigin)
+			if err != nil {
+				t.Fatalf("BeginPasswordless() error = %v", err)
+			}
+			if _, err = s.FinishPasswordless(sessionId, tt.cred(options.Challenge)); !errors.Is(err, tt.want) {
+				t.Fatalf("FinishPasswordless() error = %v, want %v", err, tt.want)
+			}
+		})
+	}
+
+	global.GVA_CONFIG.Webauthn.Passwordless = false
+	if _, _, err = s.BeginPasswordless(passkeyTestOrigin); !errors.Is(err, ErrPasskeyPasswordless) {
+		t.Fatalf("BeginPasswordless() disabled error = %v, want %v", err, ErrPasskeyPasswordless)
+	}
+}
+
+func TestPasskeyService_Manage(t *testing.T) {
+	alice, bob := setupPasskeyTest(t)
+	s := &PasskeyService{}
+	first := registerTestPasskey(t, s, alice, newTestPasskey(t, "alice-1"))
+	second := registerTestPasskey(t, s, alice, newTestPasskey(t, "alice-2"))
+
+	if err := s.RenamePasskey(bob.ID, first.ID, "抢走"); !errors.Is(err, ErrPasskeyNotFound) {
+		t.Fatalf("RenamePasskey() other user error = %v, want %v", err, ErrPasskeyNotFound)
+	}
+	if err := s.RenamePasskey(alice.ID, first.ID, "手机"); err != nil {
+		t.Fatalf("RenamePasskey() error = %v", err)
+	}
+	if err := s.DeletePasskey(bob.ID, first.ID); !errors.Is(err, ErrPasskeyNotFound) {
+		t.Fatalf("DeletePasskey() other user error = %v, want %v", err, ErrPasskeyNotFound)
+	}
+
+	// 角色要求两步验证且未绑定验证器时 不能删除最后一个通行密钥
+	global.GVA_DB.Create(&system.SysAuthority{AuthorityId: 888, AuthorityName: "管理员", RequireMfa: true})
+	global.GVA_DB.Create(&system.SysUserAuthority{SysUserId: alice.ID, SysAuthorityAuthorityId: 888})
+	if err := s.DeletePasskey(alice.ID, first.ID); err != nil {
+		t.Fatalf("DeletePasskey() error = %v", err)
+	}
+	if err := s.DeletePasskey(alice.ID, second.ID); !errors.Is(err, ErrPasskeyLast) {
+		t.Fatalf("DeletePasskey() last error = %v, want %v", err, ErrPasskeyLast)
+	}
+	list, _ := s.GetPasskeyList(alice.ID)
+	if len(list) != 1 || list[0].ID != second.ID {
+		t.Fatalf("GetPasskeyList() = %+v", list)
+	}
+
+	if err := s.ResetPasskeys(alice.ID); err != nil || s.HasPasskey(alice.ID) {
+		t.Fatalf("ResetPasskeys() error = %v", err)
+	}
+}
@@ -0,0 +1,144 @@
+package system
+
+import (
+	"errors"
+	"time"
+
+	"github.com/flipped-aurora/gin-vue-admin/server/global"
+	"github.com/flipped-aurora/gin-vue-admin/server/model/system"
+	"gorm.io/gorm"
+)
+
+const (
+	sessionSeenCachePrefix = "session:seen:"
+	sessionTouchInterval   = time.Minute
+)
+
+type SessionService struct{}
+
+var SessionServiceApp = new(SessionService)
+
+// RecordSession 登录或刷新令牌时记录会话 新会话超出角色限制时下线最早的会话
+func (sessionService *SessionService) RecordSession(user system.SysUser, familyId string, jti string, ip string, userAgent string, expiresAt time.Time) error {
+	now := time.Now()
+	var session system.SysUserSession
+	err := global.GVA_DB.Where("family_id = ?", familyId).First(&session).Error
+	if err == nil {
+		return global.GVA_DB.Model(&session).Updates(map[string]interface{}{
+			"jti":          jti,
+			"ip":           ip,
+			"user_agent":   userAgent,
+			"last_seen_at": now,
+			"expires_at":   expiresAt,
+		}).Error
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return err
+	}
+	session = system.SysUserSession{
+		UserId:     user.ID,
+		FamilyId:   familyId,
+		Jti:        jti,
+		UserAgent:  userAgent,
+		Ip:         ip,
+		LastSeenAt: now,
+		ExpiresAt:  expiresAt,
+	}
+	if err = global.GVA_DB.Create(&session).Error; err != nil {
+		return err
+	}
+	return sessionService.enforceMaxSessions(user)
+}
+
+// MaxSessions 角色允许的最大同时在线会话数 0为不限制
+// 角色未配置时 开启多点登录拦截(use-multipoint)等同于只允许一个会话
+func (sessionService *SessionService) MaxSessions(authorityId uint) int {
+	var authority system.SysAuthority
+	global.GVA_DB.Select("max_sessions").Where("authority_id = ?", authorityId).First(&authority)
+	if authority.MaxSessions > 0 {
+		return authority.MaxSessions
+	}
+	if global.GVA_CONFIG.System.UseMultipoint {
+		return 1
+	}
+	return 0
+}
+
+func (sessionService *SessionService) enforceMaxSessions(user system.SysUser) error {
+	max := sessionService.MaxSessions(user.AuthorityId)
+	if max <= 0 {
+		return nil
+	}
+	var families []string
+	err := global.GVA_DB.Model(&system.SysUserSession{}).
+		Where("user_id = ? AND revoked_at IS NULL AND expires_at > ?", user.ID, time.Now()).
+		Order("created_at desc, id desc").
+		Pluck("family_id", &families).Error
+	if err != nil || len(families) <= max {
+		return err
+	}
+	for _, familyId := range families[max:] {
+		if err = RefreshTokenServiceApp.RevokeFamily(familyId); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// GetSessionList 获取用户当前在线的会话 currentFamily 用于标记发起请求的会话
+func (sessionService *SessionService) GetSessionList(userId uint, currentFamily string) (list []system.SysUserSession, err error) {
+	err = global.GVA_DB.Where("user_id = ? AND revoked_at IS NULL AND expires_at > ?", userId, time.Now()).
+		Order("last_seen_at desc").Find(&list).Error
+	for i := range list {
+		list[i].Current = list[i].FamilyId == currentFamily
+	}
+	return list, err
+}
+
+// RevokeSession 用户下线自己的某个会话
+func (sessionService *SessionService) RevokeSession(userId uint, id uint) error {
+	var session system.SysUserSession
+	err := global.GVA_DB.Where("id = ? AND user_id = ?", id, userId).First(&session).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return errors.New("会话不存在")
+		}
+		return err
+	}
+	return RefreshTokenServiceApp.RevokeFamily(session.FamilyId)
+}
+
+// RevokeUserSessions 强制下线用户的全部会话
+func (sessionService *SessionService) RevokeUserSessions(userId uint) error {
+	return sessionService.RevokeOtherSessions(userId, "")
+}
+
+// RevokeOtherSessions 下线用户除 currentFamily 以外的全部会话 用于修改密码后踢出其他设备
+func (sessionService *SessionService) RevokeOtherSessions(userId uint, currentFamily string) error {
+	var families []string
+	err := global.GVA_DB.Model(&system.SysRefreshToken{}).
+		Where("user_id = ? AND revoked_at IS NULL AND family_id <> ?", userId, currentFamily).
+		Distinct().Pluck("family_id", &families).Error
+	if err != nil {
+		return err
+	}
+	for _, familyId := range families {
+		if err = RefreshTokenServiceApp.RevokeFamily(familyId); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// TouchSession 更新会话最近活跃时间 同一会话每分钟最多写一次数据库
+func (sessionService *SessionService) TouchSession(familyId string, ip string) {
+	if familyId == "" {
+		return
+	}
+	if _, ok := global.BlackCache.Get(sessionSeenCachePrefix + familyId); ok {
+		return
+	}
+	global.BlackCache.Set(sessionSeenCachePrefix+familyId, struct{}{}, sessionTouchInterval)
+	global.GVA_DB.Model(&system.SysUserSession{}).Where("family_id = ?", familyId).
+		Updates(map[string]interface{}{"last_seen_at": time.Now(), "ip": ip})
+}
@@ -0,0 +1,71 @@
+package system
+
+import (
+	"testing"
+	"time"
+
+	"github.com/flipped-aurora/gin-vue-admin/server/global"
+	"github.com/flipped-aurora/gin-vue-admin/server/model/system"
+)
+
+func setupSessionTest(t *testing.T) {
+	setupTestDB(t, &system.SysRefreshToken{}, &system.SysUserSession{}, &system.SysAuthority{})
+	global.GVA_CONFIG.JWT.RefreshExpiresTime = "7d"
+	global.GVA_CONFIG.System.UseMultipoint = false
+}
+
+func TestSessionService_MaxSessions(t *testing.T) {
+	setupSessionTest(t)
+	global.GVA_DB.Create(&system.SysAuthority{AuthorityId: 888, AuthorityName: "admin", MaxSessions: 2})
+	user := system.SysUser{GVA_MODEL: global.GVA_MODEL{ID: 1}, AuthorityId: 888}
+
+	s := &SessionService{}
+	families := make([]string, 0, 3)
+	for i := 0; i < 3; i++ {
+		family := RefreshTokenServiceApp.NewFamily()
+		_, expiresAt, err := RefreshTokenServiceApp.IssueRefreshToken(user.ID, family)
+		if err != nil {
+			t.Fatalf("IssueRefreshToken() error = %v", err)
+		}
+		if err = s.RecordSession(user, family, "jti", "127.0.0.1", "test", expiresAt); err != nil {
+			t.Fatalf("RecordSession() error = %v", err)
+		}
+		families = append(families, family)
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	// 超出角色最大会话数 最早的会话被下线
+	if !RefreshTokenServiceApp.IsFamilyRevoked(families[0]) {
+		t.Fatalf("oldest session should be revoked")
+	}
+	list, err := s.GetSessionList(user.ID, families[2])
+	if err != nil {
+		t.Fatalf("GetSessionList() error = %v", err)
+	}
+	if len(list) != 2 {
+		t.Fatalf("GetSessionList() len = %d, want 2", len(list))
+	}
+	if !list[0].Current && !list[1].Current {
+		t.Fatalf("GetSessionList() current session not marked")
+	}
+
+	if err = s.RevokeUserSessions(user.ID); err != nil {
+		t.Fatalf("RevokeUserSessions() error = %v", err)
+	}
+	if list, _ = s.GetSessionList(user.ID, ""); len(list) != 0 {
+		t.Fatalf("GetSessionList() after force logout len = %d, want 0", len(list))
+	}
+}
+
+func TestSessionService_MaxSessionsMultipoint(t *testing.T) {
+	setupSessionTest(t)
+	s := &SessionService{}
+	if got := s.MaxSessions(999); got != 0 {
+		t.Fatalf("MaxSessions() = %d, want 0", got)
+	}
+	global.GVA_CONFIG.System.UseMultipoint = true
+	defer func() { global.GVA_CONFIG.System.UseMultipoint = false }()
+	if got := s.MaxSessions(999); got != 1 {
+		t.Fatalf("MaxSessions() with multipoint = %d, want 1", got)
+	}
+}
@@ -56,6 +56,31 @@ func (i *initApi) InitializeData(ctx context.Context) (context.Context, error) {
 		{ApiGroup: "系统用户", Method: "POST", Path: "/user/setUserAuthority", Description: "修改用户角色(必选)"},
 		{ApiGroup: "系统用户", Method: "POST", Path: "/user/resetPassword", Description: "重置用户密码"},
 		{ApiGroup: "系统用户", Method: "PUT", Path: "/user/setSelfSetting", Description: "用户界面配置"},
+		{ApiGroup: "两步验证", Method: "GET", Path: "/user/getMfaStatus", Description: "获取两步验证状态"},
+		{ApiGroup: "两步验证", Method: "POST", Path: "/user/enrollMfa", Description: "绑定两步验证"},
+		{ApiGroup: "两步验证", Method: "POST", Path: "/user/activateMfa", Description: "启用两步验证"},
+		{ApiGroup: "两步验证", Method: "POST", Path: "/user/disableMfa", Description: "关闭两步验证"},
+		{ApiGroup: "两步验证", Method: "POST", Path: "/user/regenerateRecoveryCodes", Description: "重新生成恢复码"},
+		{ApiGroup: "两步验证", Method: "POST", Path: "/user/resetMfa", Description: "重置用户两步验证"},
+		{ApiGroup: "通行密钥", Method: "GET", Path: "/user/getPasskeyList", Description: "获取自身通行密钥"},
+		{ApiGroup: "通行密钥", Method: "POST", Path: "/user/beginPasskeyRegistration", Description: "开始注册通行密钥"},
+		{ApiGroup: "通行密钥", Method: "POST", Path: "/user/finishPasskeyRegistration", Description: "完成注册通行密钥"},
+		{ApiGroup: "通行密钥", Method: "PUT", Path: "/user/renamePasskey", Description: "修改通行密钥名称"},
+		{ApiGroup: "通行密钥", Method: "DELETE", Path: "/user/deletePasskey", Description: "删除通行密钥"},
+		{ApiGroup: "会话管理", Method: "GET", Path: "/user/getSessionList", Description: "获取自身在线会话"},
+		{ApiGroup: "会话管理", Method: "POST", Path: "/user/revokeSession", Description: "下线自身会话"},
+		{ApiGroup: "会话管理", Method: "POST", Path: "/user/forceLogout", Description: "强制下线用户"},
+		{ApiGroup: "系统用户", Method: "POST", Path: "/user/impersonate", Description: "代登录用户"},
+		{ApiGroup: "外部组角色映射", Method: "POST", Path: "/authorityClaimMap/createClaimMap", Description: "新增外部组映射"},
+		{ApiGroup: "外部组角色映射", Method: "DELETE", Path: "/authorityClaimMap/deleteClaimMap", Description: "删除外部组映射"},
+		{ApiGroup: "外部组角色映射", Method: "GET", Path: "/authorityClaimMap/getClaimMapList", Description: "获取外部组映射"},
+		{ApiGroup: "API密钥", Method: "POST", Path: "/apiKey/createApiKey", Description: "创建API密钥"},
+		{ApiGroup: "API密钥", Method: "DELETE", Path: "/apiKey/deleteApiKey", Description: "吊销API密钥"},
+		{ApiGroup: "API密钥", Method: "GET", Path: "/apiKey/getApiKeyList", Description: "获取API密钥列表"},
+		{ApiGroup: "登录失败记录", Method: "GET", Path: "/loginAttempt/getLoginAttemptList", Description: "获取登录失败记录"},
+		{ApiGroup: "登录失败记录", Method: "POST", Path: "/loginAttempt/unlockUser", Description: "解除账号锁定"},
+		{ApiGroup: "注册审核", Method: "GET", Path: "/signUp/getSignUpList", Description: "获取注册申请列表"},
+		{ApiGroup: "注册审核", Method: "POST", Path: "/signUp/reviewSignUp", Description: "审核注册申请"},
 
 		{ApiGroup: "api", Method: "POST", Path: "/api/createApi", Description: "创建api"},
 		{ApiGroup: "api", Method: "POST", Path: "/api/deleteApi", Description: "删除Api"},
@@ -75,9 +100,20 @@ func (i *initApi) InitializeData(ctx context.Context) (context.Context, error) {
 		{ApiGroup: "角色", Method: "PUT", Path: "/authority/updateAuthority", Description: "更新角色信息"},
 		{ApiGroup: "角色", Method: "POST", Path: "/authority/getAuthorityList", Description: "获取角色列表"},
 		{ApiGroup: "角色", Method: "POST", Path: "/authority/setDataAuthority", Description: "设置角色资源权限"},
+		{ApiGroup: "角色", Method: "GET", Path: "/authority/exportAuthorityBundle", Description: "导出角色"},
+		{ApiGroup: "角色", Method: "POST", Path: "/authority/importAuthorityBundle", Description: "导入角色"},
+		{ApiGroup: "角色", Method: "GET", Path: "/authority/getDataScopes", Description: "获取角色数据范围"},
+		{ApiGroup: "角色", Method: "POST", Path: "/authority/setDataScopes", Description: "设置角色数据范围"},
+		{ApiGroup: "角色", Method: "GET", Path: "/authority/getFieldRules", Description: "获取角色字段权限"},
+		{ApiGroup: "角色", Method: "POST", Path: "/authority/setFieldRules", Description: "设置角色字段权限"},
+		{ApiGroup: "角色", Method: "GET", Path: "/authority/getAuthorityVersionList", Description: "获取角色权限历史版本"},
+		{ApiGroup: "角色", Method: "GET", Path: "/authority/getAuthorityVersion", Description: "获取角色权限历史版本详情"},
+		{ApiGroup: "角色", Method: "POST", Path: "/authority/rollbackAuthorityVersion", Description: "恢复角色权限到历史版本"},
 
 		{ApiGroup: "casbin", Method: "POST", Path: "/casbin/updateCasbin", Description: "更改角色api权限"},
 		{ApiGroup: "casbin", Method: "POST", Path: "/casbin/getPolicyPathByAuthorityId", Description: "获取权限列表"},
+		{ApiGroup: "casbin", Method: "POST", Path: "/casbin/explain", Description: "模拟访问接口"},
+		{ApiGroup: "casbin", Method: "POST", Path: "/casbin/simulate", Description: "模拟访问全部路由"},
 
 		{ApiGroup: "菜单", Method: "POST", Path: "/menu/addBaseMenu", Description: "新增菜单"},
 		{ApiGroup: "菜单", Method: "POST", Path: "/menu/getMenu", Description: "获取菜单树(必选)"},
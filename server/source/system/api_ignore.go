@@ -47,11 +47,27 @@ func (i *initApiIgnore) InitializeData(ctx context.Context) (context.Context, er
 		{Method: "GET", Path: "/api/freshCasbin"},
 		{Method: "GET", Path: "/uploads/file/*filepath"},
 		{Method: "GET", Path: "/health"},
+		{Method: "GET", Path: "/.well-known/jwks.json"},
 		{Method: "HEAD", Path: "/uploads/file/*filepath"},
 		{Method: "POST", Path: "/autoCode/llmAuto"},
 		{Method: "POST", Path: "/system/reloadSystem"},
 		{Method: "POST", Path: "/base/login"},
 		{Method: "POST", Path: "/base/captcha"},
+		{Method: "POST", Path: "/base/refresh"},
+		{Method: "POST", Path: "/base/mfaEnroll"},
+		{Method: "POST", Path: "/base/mfaLogin"},
+		{Method: "POST", Path: "/base/mfaPasskeyBegin"},
+		{Method: "POST", Path: "/base/mfaPasskeyLogin"},
+		{Method: "POST", Path: "/base/passkeyLoginBegin"},
+		{Method: "POST", Path: "/base/passkeyLogin"},
+		{Method: "GET", Path: "/base/oidcProviders"},
+		{Method: "GET", Path: "/base/oidcAuthUrl"},
+		{Method: "POST", Path: "/base/oidcLogin"},
+		{Method: "POST", Path: "/base/changeExpiredPassword"},
+		{Method: "POST", Path: "/base/forgotPassword"},
+		{Method: "POST", Path: "/base/resetPasswordByToken"},
+		{Method: "POST", Path: "/base/signUp"},
+		{Method: "POST", Path: "/base/verifySignUp"},
 		{Method: "POST", Path: "/init/initdb"},
 		{Method: "POST", Path: "/init/checkdb"},
 		{Method: "GET", Path: "/info/getInfoDataSource"},
@@ -4,6 +4,7 @@ import (
 	"context"
 
 	adapter "github.com/casbin/gorm-adapter/v3"
+	"github.com/flipped-aurora/gin-vue-admin/server/model/system/request"
 	"github.com/flipped-aurora/gin-vue-admin/server/service/system"
 	"github.com/pkg/errors"
 	"gorm.io/gorm"
@@ -65,6 +66,15 @@ func (i *initCasbin) InitializeData(ctx context.Context) (context.Context, error
 		{Ptype: "p", V0: "888", V1: "/authority/deleteAuthority", V2: "POST"},
 		{Ptype: "p", V0: "888", V1: "/authority/getAuthorityList", V2: "POST"},
 		{Ptype: "p", V0: "888", V1: "/authority/setDataAuthority", V2: "POST"},
+		{Ptype: "p", V0: "888", V1: "/authority/exportAuthorityBundle", V2: "GET"},
+		{Ptype: "p", V0: "888", V1: "/authority/importAuthorityBundle", V2: "POST"},
+		{Ptype: "p", V0: "888", V1: "/authority/getDataScopes", V2: "GET"},
+		{Ptype: "p", V0: "888", V1: "/authority/setDataScopes", V2: "POST"},
+		{Ptype: "p", V0: "888", V1: "/authority/getFieldRules", V2: "GET"},
+		{Ptype: "p", V0: "888", V1: "/authority/setFieldRules", V2: "POST"},
+		{Ptype: "p", V0: "888", V1: "/authority/getAuthorityVersionList", V2: "GET"},
+		{Ptype: "p", V0: "888", V1: "/authority/getAuthorityVersion", V2: "GET"},
+		{Ptype: "p", V0: "888", V1: "/authority/rollbackAuthorityVersion", V2: "POST"},
 
 		{Ptype: "p", V0: "888", V1: "/menu/getMenu", V2: "POST"},
 		{Ptype: "p", V0: "888", V1: "/menu/getMenuList", V2: "POST"},
@@ -86,6 +96,31 @@ func (i *initCasbin) InitializeData(ctx context.Context) (context.Context, error
 		{Ptype: "p", V0: "888", V1: "/user/setUserAuthorities", V2: "POST"},
 		{Ptype: "p", V0: "888", V1: "/user/resetPassword", V2: "POST"},
 		{Ptype: "p", V0: "888", V1: "/user/setSelfSetting", V2: "PUT"},
+		{Ptype: "p", V0: "888", V1: "/user/getMfaStatus", V2: "GET"},
+		{Ptype: "p", V0: "888", V1: "/user/enrollMfa", V2: "POST"},
+		{Ptype: "p", V0: "888", V1: "/user/activateMfa", V2: "POST"},
+		{Ptype: "p", V0: "888", V1: "/user/disableMfa", V2: "POST"},
+		{Ptype: "p", V0: "888", V1: "/user/regenerateRecoveryCodes", V2: "POST"},
+		{Ptype: "p", V0: "888", V1: "/user/resetMfa", V2: "POST"},
+		{Ptype: "p", V0: "888", V1: "/user/getPasskeyList", V2: "GET"},
+		{Ptype: "p", V0: "888", V1: "/user/beginPasskeyRegistration", V2: "POST"},
+		{Ptype: "p", V0: "888", V1: "/user/finishPasskeyRegistration", V2: "POST"},
+		{Ptype: "p", V0: "888", V1: "/user/renamePasskey", V2: "PUT"},
+		{Ptype: "p", V0: "888", V1: "/user/deletePasskey", V2: "DELETE"},
+		{Ptype: "p", V0: "888", V1: "/user/getSessionList", V2: "GET"},
+		{Ptype: "p", V0: "888", V1: "/user/revokeSession", V2: "POST"},
+		{Ptype: "p", V0: "888", V1: "/user/forceLogout", V2: "POST"},
+		{Ptype: "p", V0: "888", V1: "/user/impersonate", V2: "POST"},
+		{Ptype: "p", V0: "888", V1: "/authorityClaimMap/createClaimMap", V2: "POST"},
+		{Ptype: "p", V0: "888", V1: "/authorityClaimMap/deleteClaimMap", V2: "DELETE"},
+		{Ptype: "p", V0: "888", V1: "/authorityClaimMap/getClaimMapList", V2: "GET"},
+		{Ptype: "p", V0: "888", V1: "/apiKey/createApiKey", V2: "POST"},
+		{Ptype: "p", V0: "888", V1: "/apiKey/deleteApiKey", V2: "DELETE"},
+		{Ptype: "p", V0: "888", V1: "/apiKey/getApiKeyList", V2: "GET"},
+		{Ptype: "p", V0: "888", V1: "/loginAttempt/getLoginAttemptList", V2: "GET"},
+		{Ptype: "p", V0: "888", V1: "/loginAttempt/unlockUser", V2: "POST"},
+		{Ptype: "p", V0: "888", V1: "/signUp/getSignUpList", V2: "GET"},
+		{Ptype: "p", V0: "888", V1: "/signUp/reviewSignUp", V2: "POST"},
 
 		{Ptype: "p", V0: "888", V1: "/fileUploadAndDownload/findFile", V2: "GET"},
 		{Ptype: "p", V0: "888", V1: "/fileUploadAndDownload/breakpointContinueFinish", V2: "POST"},
@@ -100,6 +135,8 @@ func (i *initCasbin) InitializeData(ctx context.Context) (context.Context, error
 
 		{Ptype: "p", V0: "888", V1: "/casbin/updateCasbin", V2: "POST"},
 		{Ptype: "p", V0: "888", V1: "/casbin/getPolicyPathByAuthorityId", V2: "POST"},
+		{Ptype: "p", V0: "888", V1: "/casbin/explain", V2: "POST"},
+		{Ptype: "p", V0: "888", V1: "/casbin/simulate", V2: "POST"},
 
 		{Ptype: "p", V0: "888", V1: "/jwt/jsonInBlacklist", V2: "POST"},
 
@@ -269,6 +306,10 @@ func (i *initCasbin) InitializeData(ctx context.Context) (context.Context, error
 		{Ptype: "p", V0: "9528", V1: "/autoCode/createTemp", V2: "POST"},
 		{Ptype: "p", V0: "9528", V1: "/user/getUserInfo", V2: "GET"},
 	}
+	for k := range entities {
+		entities[k].V3 = request.CasbinEffectAllow
+		entities[k].V4 = request.CasbinCondNone
+	}
 	if err := db.Create(&entities).Error; err != nil {
 		return ctx, errors.Wrap(err, "Casbin 表 ("+i.InitializerName()+") 数据初始化失败!")
 	}
@@ -82,6 +82,8 @@ func (i *initMenu) InitializeData(ctx context.Context) (next context.Context, er
 		{MenuLevel: 0, Hidden: false, ParentId: 15, Path: "exportTemplate", Name: "exportTemplate", Component: "view/systemTools/exportTemplate/exportTemplate.vue", Sort: 5, Meta: Meta{Title: "导出模板", Icon: "reading"}},
 		{MenuLevel: 0, Hidden: false, ParentId: 24, Path: "anInfo", Name: "anInfo", Component: "plugin/announcement/view/info.vue", Sort: 5, Meta: Meta{Title: "公告管理[示例]", Icon: "scaleToOriginal"}},
 		{MenuLevel: 0, Hidden: false, ParentId: 3, Path: "sysParams", Name: "sysParams", Component: "view/superAdmin/params/sysParams.vue", Sort: 7, Meta: Meta{Title: "参数管理", Icon: "compass"}},
+		{MenuLevel: 0, Hidden: false, ParentId: 3, Path: "loginAttempt", Name: "loginAttempt", Component: "view/superAdmin/loginAttempt/loginAttempt.vue", Sort: 8, Meta: Meta{Title: "登录失败记录", Icon: "lock"}},
+		{MenuLevel: 0, Hidden: false, ParentId: 3, Path: "signUp", Name: "signUp", Component: "view/superAdmin/signUp/signUp.vue", Sort: 9, Meta: Meta{Title: "注册审核", Icon: "stamp"}},
 	}
 	if err = db.Create(&entities).Error; err != nil {
 		return ctx, errors.Wrap(err, SysBaseMenu{}.TableName()+"表数据初始化失败!")
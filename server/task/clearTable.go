@@ -26,10 +26,40 @@ func ClearTable(db *gorm.DB) error {
 
 	ClearTableDetail = append(ClearTableDetail, common.ClearDB{
 		TableName:    "jwt_blacklists",
+		CompareField: "expires_at",
+		Interval:     "1h",
+	})
+
+	ClearTableDetail = append(ClearTableDetail, common.ClearDB{
+		TableName:    "sys_refresh_tokens",
+		CompareField: "expires_at",
+		Interval:     "24h",
+	})
+
+	ClearTableDetail = append(ClearTableDetail, common.ClearDB{
+		TableName:    "sys_user_sessions",
+		CompareField: "expires_at",
+		Interval:     "24h",
+	})
+
+	ClearTableDetail = append(ClearTableDetail, common.ClearDB{
+		TableName:    "sys_login_attempts",
 		CompareField: "created_at",
+		Interval:     "2160h",
+	})
+
+	ClearTableDetail = append(ClearTableDetail, common.ClearDB{
+		TableName:    "sys_login_lockouts",
+		CompareField: "updated_at",
 		Interval:     "168h",
 	})
 
+	ClearTableDetail = append(ClearTableDetail, common.ClearDB{
+		TableName:    "sys_password_resets",
+		CompareField: "expires_at",
+		Interval:     "24h",
+	})
+
 	if db == nil {
 		return errors.New("db Cannot be empty")
 	}
@@ -0,0 +1,17 @@
+package utils
+
+import "testing"
+
+func TestAesGcm(t *testing.T) {
+	encrypted, err := AesGcmEncrypt("secret-key", "JBSWY3DPEHPK3PXP")
+	if err != nil {
+		t.Fatalf("AesGcmEncrypt() error = %v", err)
+	}
+	plain, err := AesGcmDecrypt("secret-key", encrypted)
+	if err != nil || plain != "JBSWY3DPEHPK3PXP" {
+		t.Fatalf("AesGcmDecrypt() = %v, %v", plain, err)
+	}
+	if _, err = AesGcmDecrypt("other-key", encrypted); err == nil {
+		t.Fatalf("AesGcmDecrypt() with wrong key should fail")
+	}
+}
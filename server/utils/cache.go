@@ -0,0 +1,102 @@
+package utils
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/flipped-aurora/gin-vue-admin/server/global"
+)
+
+// 短期缓存 开启redis时使用redis以便多实例共享 否则退化为进程内的 BlackCache
+// 适用于登录挑战、一次性状态等生命周期很短的数据
+
+// cacheTakeMu 进程内缓存读取后修改的操作需要加锁
+var cacheTakeMu sync.Mutex
+
+func useRedisCache() bool {
+	return global.GVA_CONFIG.System.UseRedis && global.GVA_REDIS != nil
+}
+
+// CacheShared 缓存是否在多实例间共享 进程内缓存无法被其他实例清除 需要立即生效的数据不应使用
+func CacheShared() bool {
+	return useRedisCache()
+}
+
+// CacheSet 写入缓存
+func CacheSet(key string, value string, d time.Duration) error {
+	if useRedisCache() {
+		return global.GVA_REDIS.Set(context.Background(), key, value, d).Err()
+	}
+	global.BlackCache.Set(key, value, d)
+	return nil
+}
+
+// CacheGet 读取缓存
+func CacheGet(key string) (string, bool) {
+	if useRedisCache() {
+		v, err := global.GVA_REDIS.Get(context.Background(), key).Result()
+		if err != nil {
+			return "", false
+		}
+		return v, true
+	}
+	v, ok := global.BlackCache.Get(key)
+	if !ok {
+		return "", false
+	}
+	s, ok := v.(string)
+	return s, ok
+}
+
+// CacheDel 删除缓存
+func CacheDel(key string) {
+	if useRedisCache() {
+		_ = global.GVA_REDIS.Del(context.Background(), key).Err()
+		return
+	}
+	global.BlackCache.Delete(key)
+}
+
+// CacheTake 读取并删除缓存 并发调用时只有一个能取到 用于一次性的挑战
+func CacheTake(key string) (string, bool) {
+	if useRedisCache() {
+		v, err := global.GVA_REDIS.GetDel(context.Background(), key).Result()
+		if err != nil {
+			return "", false
+		}
+		return v, true
+	}
+	cacheTakeMu.Lock()
+	defer cacheTakeMu.Unlock()
+	v, ok := global.BlackCache.Get(key)
+	if !ok {
+		return "", false
+	}
+	global.BlackCache.Delete(key)
+	s, ok := v.(string)
+	return s, ok
+}
+
+// CacheIncr 计数加一并返回加一后的值 并发调用时不会丢失计数 首次计数时设置过期时间
+func CacheIncr(key string, d time.Duration) (int64, error) {
+	if useRedisCache() {
+		n, err := global.GVA_REDIS.Incr(context.Background(), key).Result()
+		if err != nil {
+			return 0, err
+		}
+		if n == 1 {
+			err = global.GVA_REDIS.Expire(context.Background(), key, d).Err()
+		}
+		return n, err
+	}
+	cacheTakeMu.Lock()
+	defer cacheTakeMu.Unlock()
+	var n int64
+	if v, ok := global.BlackCache.Get(key); ok {
+		n, _ = v.(int64)
+	}
+	n++
+	global.BlackCache.Set(key, n, d)
+	return n, nil
+}
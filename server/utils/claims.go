@@ -6,10 +6,15 @@ import (
 	systemReq "github.com/flipped-aurora/gin-vue-admin/server/model/system/request"
 	"github.com/gin-gonic/gin"
 	"github.com/gofrs/uuid/v5"
+	jwt "github.com/golang-jwt/jwt/v4"
 	"net"
+	"strings"
 	"time"
 )
 
+// ApiKeyPrefix API密钥的固定前缀 通过 Authorization: Bearer gva_xxx 请求头传递
+const ApiKeyPrefix = "gva_"
+
 func ClearToken(c *gin.Context) {
 	// 增加cookie x-token 向来源的web添加
 	host, _, err := net.SplitHostPort(c.Request.Host)
@@ -53,6 +58,19 @@ func GetToken(c *gin.Context) string {
 	return token
 }
 
+// GetApiKey 从 Authorization 请求头中获取API密钥 不是API密钥时返回空
+func GetApiKey(c *gin.Context) string {
+	auth := c.Request.Header.Get("Authorization")
+	if len(auth) < 7 || !strings.EqualFold(auth[:7], "Bearer ") {
+		return ""
+	}
+	key := strings.TrimSpace(auth[7:])
+	if !strings.HasPrefix(key, ApiKeyPrefix) {
+		return ""
+	}
+	return key
+}
+
 func GetClaims(c *gin.Context) (*systemReq.CustomClaims, error) {
 	token := GetToken(c)
 	j := NewJWT()
@@ -133,7 +151,8 @@ func GetUserName(c *gin.Context) string {
 	}
 }
 
-func LoginToken(user system.Login) (token string, claims systemReq.CustomClaims, err error) {
+// LoginToken 签发访问令牌 familyId 为本次登录对应的刷新令牌族 securityStamp 为用户当前的安全戳
+func LoginToken(user system.Login, familyId string, securityStamp string) (token string, claims systemReq.CustomClaims, err error) {
 	j := &JWT{SigningKey: []byte(global.GVA_CONFIG.JWT.SigningKey)} // 唯一签名
 	claims = j.CreateClaims(systemReq.BaseClaims{
 		UUID:        user.GetUUID(),
@@ -142,9 +161,30 @@ func LoginToken(user system.Login) (token string, claims systemReq.CustomClaims,
 		Username:    user.GetUsername(),
 		AuthorityId: user.GetAuthorityId(),
 	})
+	claims.FamilyId = familyId
+	claims.SecurityStamp = securityStamp
 	token, err = j.CreateToken(claims)
 	if err != nil {
 		return
 	}
 	return
 }
+
+// ImpersonateToken 签发代登录令牌 令牌身份为被代登录的用户 实际操作人记录在 Actor 中
+// 代登录令牌沿用操作人的令牌族 操作人登出或会话被下线时一并失效 有效期为 d 且不签发刷新令牌
+func ImpersonateToken(user system.Login, actor *systemReq.CustomClaims, securityStamp string, d time.Duration) (token string, claims systemReq.CustomClaims, err error) {
+	j := &JWT{SigningKey: []byte(global.GVA_CONFIG.JWT.SigningKey)}
+	claims = j.CreateClaims(systemReq.BaseClaims{
+		UUID:        user.GetUUID(),
+		ID:          user.GetUserId(),
+		NickName:    user.GetNickname(),
+		Username:    user.GetUsername(),
+		AuthorityId: user.GetAuthorityId(),
+	})
+	claims.FamilyId = actor.FamilyId
+	claims.SecurityStamp = securityStamp
+	claims.Actor = &systemReq.Actor{ID: actor.BaseClaims.ID, Username: actor.Username, SecurityStamp: actor.SecurityStamp}
+	claims.ExpiresAt = jwt.NewNumericDate(time.Now().Add(d))
+	token, err = j.CreateToken(claims)
+	return
+}
@@ -0,0 +1,34 @@
+package utils
+
+import (
+	"context"
+
+	"github.com/gin-gonic/gin"
+)
+
+type dataScopeKey struct{}
+
+// DataScopeUser 按数据范围过滤时使用的当前用户
+type DataScopeUser struct {
+	UserId      uint
+	AuthorityId uint
+}
+
+// WithDataScope 在ctx中记录当前用户 使用该ctx的查询会按数据范围过滤实现了 system.DataScoped 的模型
+func WithDataScope(ctx context.Context, userId, authorityId uint) context.Context {
+	return context.WithValue(ctx, dataScopeKey{}, DataScopeUser{UserId: userId, AuthorityId: authorityId})
+}
+
+// DataScopeFromContext 取出ctx中的当前用户 没有时不过滤
+func DataScopeFromContext(ctx context.Context) (DataScopeUser, bool) {
+	if ctx == nil {
+		return DataScopeUser{}, false
+	}
+	user, ok := ctx.Value(dataScopeKey{}).(DataScopeUser)
+	return user, ok
+}
+
+// DataScopeContext 使用当前请求的用户和角色 用法 global.GVA_DB.WithContext(utils.DataScopeContext(c))
+func DataScopeContext(c *gin.Context) context.Context {
+	return WithDataScope(c.Request.Context(), GetUserID(c), GetUserAuthorityId(c))
+}
@@ -8,6 +8,7 @@ import (
 
 	"github.com/flipped-aurora/gin-vue-admin/server/global"
 	"github.com/flipped-aurora/gin-vue-admin/server/model/system/request"
+	"github.com/gofrs/uuid/v5"
 )
 
 type JWT struct {
@@ -28,38 +29,50 @@ func NewJWT() *JWT {
 }
 
 func (j *JWT) CreateClaims(baseClaims request.BaseClaims) request.CustomClaims {
-	bf, _ := ParseDuration(global.GVA_CONFIG.JWT.BufferTime)
 	ep, _ := ParseDuration(global.GVA_CONFIG.JWT.ExpiresTime)
 	claims := request.CustomClaims{
 		BaseClaims: baseClaims,
-		BufferTime: int64(bf / time.Second), // 缓冲时间1天 缓冲时间内会获得新的token刷新令牌 此时一个用户会存在两个有效令牌 但是前端只留一个 另一个会丢失
 		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        uuid.Must(uuid.NewV4()).String(),          // 令牌唯一标识 jti
 			Audience:  jwt.ClaimStrings{"GVA"},                   // 受众
 			NotBefore: jwt.NewNumericDate(time.Now().Add(-1000)), // 签名生效时间
-			ExpiresAt: jwt.NewNumericDate(time.Now().Add(ep)),    // 过期时间 7天  配置文件
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(ep)),    // 过期时间 访问令牌应尽量短 过期后使用刷新令牌换取
 			Issuer:    global.GVA_CONFIG.JWT.Issuer,              // 签名的发行者
 		},
 	}
 	return claims
 }
 
-// CreateToken 创建一个token
+// CreateToken 创建一个token 非对称算法时在header中写入kid
 func (j *JWT) CreateToken(claims request.CustomClaims) (string, error) {
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString(j.SigningKey)
-}
-
-// CreateTokenByOldToken 旧token 换新token 使用归并回源避免并发问题
-func (j *JWT) CreateTokenByOldToken(oldToken string, claims request.CustomClaims) (string, error) {
-	v, err, _ := global.GVA_Concurrency_Control.Do("JWT:"+oldToken, func() (interface{}, error) {
-		return j.CreateToken(claims)
-	})
-	return v.(string), err
+	alg := global.GVA_CONFIG.JWT.SigningMethod
+	if !IsAsymmetricJWTAlg(alg) {
+		token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+		return token.SignedString(j.SigningKey)
+	}
+	key, err := JWTKeys.Signing()
+	if err != nil {
+		return "", err
+	}
+	token := jwt.NewWithClaims(jwt.GetSigningMethod(key.Alg), claims)
+	token.Header["kid"] = key.Kid
+	return token.SignedString(key.Private)
 }
 
-// ParseToken 解析 token
+// ParseToken 解析 token 带kid的令牌使用对应公钥验签 否则使用对称密钥
+// 配置为非对称算法时 只在迁移截止时间前接受对称签名的令牌
 func (j *JWT) ParseToken(tokenString string) (*request.CustomClaims, error) {
 	token, err := jwt.ParseWithClaims(tokenString, &request.CustomClaims{}, func(token *jwt.Token) (i interface{}, e error) {
+		if kid, ok := token.Header["kid"].(string); ok && kid != "" {
+			key, ok := JWTKeys.Lookup(kid)
+			if !ok || token.Method.Alg() != key.Alg {
+				return nil, TokenInvalid
+			}
+			return key.Public, nil
+		}
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok || len(j.SigningKey) == 0 || !acceptHMAC() {
+			return nil, TokenInvalid
+		}
 		return j.SigningKey, nil
 	})
 	if err != nil {
@@ -86,3 +99,20 @@ func (j *JWT) ParseToken(tokenString string) (*request.CustomClaims, error) {
 		return nil, TokenInvalid
 	}
 }
+
+func acceptHMAC() bool {
+	if !IsAsymmetricJWTAlg(global.GVA_CONFIG.JWT.SigningMethod) {
+		return true
+	}
+	until, err := time.ParseInLocation(time.DateTime, global.GVA_CONFIG.JWT.HmacAcceptUntil, time.Local)
+	return err == nil && time.Now().Before(until)
+}
+
+// ParseTokenUnverified 不验签解析 token 仅用于读取已入库令牌的jti和过期时间
+func (j *JWT) ParseTokenUnverified(tokenString string) (*request.CustomClaims, error) {
+	claims := &request.CustomClaims{}
+	if _, _, err := jwt.NewParser().ParseUnverified(tokenString, claims); err != nil {
+		return nil, TokenMalformed
+	}
+	return claims, nil
+}
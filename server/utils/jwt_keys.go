@@ -0,0 +1,247 @@
+package utils
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"math/big"
+	"sync"
+	"time"
+)
+
+const (
+	JWTAlgHS256 = "HS256"
+	JWTAlgRS256 = "RS256"
+	JWTAlgES256 = "ES256"
+	JWTAlgEdDSA = "EdDSA"
+)
+
+// jwtKeyReloadInterval 遇到未知kid时重新加载密钥的最小间隔 避免伪造kid打爆数据库
+const jwtKeyReloadInterval = 10 * time.Second
+
+var ErrJWTKeyNotFound = errors.New("jwt签名密钥不存在")
+
+// JWTKey 非对称签名密钥 Private 仅当前签名密钥持有
+type JWTKey struct {
+	Kid     string
+	Alg     string
+	Private crypto.Signer
+	Public  crypto.PublicKey
+}
+
+// JSONWebKey RFC 7517 公钥格式
+type JSONWebKey struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	N   string `json:"n,omitempty"`
+	E   string `json:"e,omitempty"`
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+	Y   string `json:"y,omitempty"`
+}
+
+type JSONWebKeySet struct {
+	Keys []JSONWebKey `json:"keys"`
+}
+
+// JWTKeyring 进程内的签名密钥集合 由 service 层从数据库加载后写入
+type JWTKeyring struct {
+	mu       sync.RWMutex
+	signing  *JWTKey
+	keys     map[string]*JWTKey
+	loader   func() error
+	loadedAt time.Time
+}
+
+var JWTKeys = &JWTKeyring{keys: map[string]*JWTKey{}}
+
+// SetLoader 设置密钥加载函数 签名密钥缺失或遇到未知kid时调用
+func (k *JWTKeyring) SetLoader(loader func() error) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	k.loader = loader
+}
+
+// Store 替换全部密钥 signing 为当前用于签名的密钥 keys 为所有可用于验签的密钥
+func (k *JWTKeyring) Store(signing *JWTKey, keys []*JWTKey) {
+	m := make(map[string]*JWTKey, len(keys))
+	for _, key := range keys {
+		m[key.Kid] = key
+	}
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	k.signing = signing
+	k.keys = m
+	k.loadedAt = time.Now()
+}
+
+// Signing 获取当前签名密钥 尚未加载时触发加载
+func (k *JWTKeyring) Signing() (*JWTKey, error) {
+	k.mu.RLock()
+	signing := k.signing
+	k.mu.RUnlock()
+	if signing != nil {
+		return signing, nil
+	}
+	if err := k.reload(true); err != nil {
+		return nil, err
+	}
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+	if k.signing == nil {
+		return nil, ErrJWTKeyNotFound
+	}
+	return k.signing, nil
+}
+
+// Lookup 按kid查找验签密钥 未找到时尝试重新加载 兼容其他实例刚轮换出的新密钥
+func (k *JWTKeyring) Lookup(kid string) (*JWTKey, bool) {
+	k.mu.RLock()
+	key, ok := k.keys[kid]
+	k.mu.RUnlock()
+	if ok {
+		return key, true
+	}
+	if k.reload(false) != nil {
+		return nil, false
+	}
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+	key, ok = k.keys[kid]
+	return key, ok
+}
+
+// Keys 返回全部验签密钥
+func (k *JWTKeyring) Keys() []*JWTKey {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+	keys := make([]*JWTKey, 0, len(k.keys))
+	for _, key := range k.keys {
+		keys = append(keys, key)
+	}
+	return keys
+}
+
+func (k *JWTKeyring) reload(force bool) error {
+	k.mu.RLock()
+	loader, loadedAt := k.loader, k.loadedAt
+	k.mu.RUnlock()
+	if loader == nil {
+		return ErrJWTKeyNotFound
+	}
+	if !force && time.Since(loadedAt) < jwtKeyReloadInterval {
+		return nil
+	}
+	return loader()
+}
+
+// IsAsymmetricJWTAlg 是否为非对称签名算法
+func IsAsymmetricJWTAlg(alg string) bool {
+	switch alg {
+	case JWTAlgRS256, JWTAlgES256, JWTAlgEdDSA:
+		return true
+	}
+	return false
+}
+
+// GenerateJWTKey 生成新的非对称签名密钥
+func GenerateJWTKey(alg string) (*JWTKey, error) {
+	var private crypto.Signer
+	var err error
+	switch alg {
+	case JWTAlgRS256:
+		private, err = rsa.GenerateKey(rand.Reader, 2048)
+	case JWTAlgES256:
+		private, err = ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	case JWTAlgEdDSA:
+		_, private, err = ed25519.GenerateKey(rand.Reader)
+	default:
+		return nil, errors.New("不支持的jwt签名算法: " + alg)
+	}
+	if err != nil {
+		return nil, err
+	}
+	kid, err := RandomToken(16)
+	if err != nil {
+		return nil, err
+	}
+	return &JWTKey{Kid: kid, Alg: alg, Private: private, Public: private.Public()}, nil
+}
+
+// MarshalJWTKey 将密钥编码为PEM 私钥为PKCS8 公钥为PKIX
+func MarshalJWTKey(key *JWTKey) (privatePEM string, publicPEM string, err error) {
+	der, err := x509.MarshalPKCS8PrivateKey(key.Private)
+	if err != nil {
+		return
+	}
+	privatePEM = string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}))
+	der, err = x509.MarshalPKIXPublicKey(key.Public)
+	if err != nil {
+		return
+	}
+	publicPEM = string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}))
+	return
+}
+
+// ParseJWTPublicKey 解析PEM公钥
+func ParseJWTPublicKey(publicPEM string) (crypto.PublicKey, error) {
+	block, _ := pem.Decode([]byte(publicPEM))
+	if block == nil {
+		return nil, errors.New("公钥格式错误")
+	}
+	return x509.ParsePKIXPublicKey(block.Bytes)
+}
+
+// ParseJWTPrivateKey 解析PEM私钥
+func ParseJWTPrivateKey(privatePEM string) (crypto.Signer, error) {
+	block, _ := pem.Decode([]byte(privatePEM))
+	if block == nil {
+		return nil, errors.New("私钥格式错误")
+	}
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+	signer, ok := key.(crypto.Signer)
+	if !ok {
+		return nil, errors.New("私钥格式错误")
+	}
+	return signer, nil
+}
+
+// JWKS 将公钥转换为 JSON Web Key Set
+func JWKS(keys []*JWTKey) JSONWebKeySet {
+	set := JSONWebKeySet{Keys: make([]JSONWebKey, 0, len(keys))}
+	for _, key := range keys {
+		jwk := JSONWebKey{Kid: key.Kid, Use: "sig", Alg: key.Alg}
+		switch pub := key.Public.(type) {
+		case *rsa.PublicKey:
+			jwk.Kty = "RSA"
+			jwk.N = base64.RawURLEncoding.EncodeToString(pub.N.Bytes())
+			jwk.E = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(pub.E)).Bytes())
+		case *ecdsa.PublicKey:
+			size := (pub.Curve.Params().BitSize + 7) / 8
+			jwk.Kty = "EC"
+			jwk.Crv = pub.Curve.Params().Name
+			jwk.X = base64.RawURLEncoding.EncodeToString(pub.X.FillBytes(make([]byte, size)))
+			jwk.Y = base64.RawURLEncoding.EncodeToString(pub.Y.FillBytes(make([]byte, size)))
+		case ed25519.PublicKey:
+			jwk.Kty = "OKP"
+			jwk.Crv = "Ed25519"
+			jwk.X = base64.RawURLEncoding.EncodeToString(pub)
+		default:
+			continue
+		}
+		set.Keys = append(set.Keys, jwk)
+	}
+	return set
+}
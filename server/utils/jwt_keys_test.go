@@ -0,0 +1,111 @@
+package utils
+
+import (
+	"testing"
+	"time"
+
+	"github.com/flipped-aurora/gin-vue-admin/server/global"
+	"github.com/flipped-aurora/gin-vue-admin/server/model/system/request"
+)
+
+func TestJWT_AsymmetricSignAndParse(t *testing.T) {
+	global.GVA_CONFIG.JWT.ExpiresTime = "1h"
+	global.GVA_CONFIG.JWT.SigningKey = "test-signing-key"
+	defer func() {
+		global.GVA_CONFIG.JWT.SigningMethod = ""
+		JWTKeys.Store(nil, nil)
+	}()
+
+	tests := []struct {
+		alg string
+		kty string
+	}{
+		{alg: JWTAlgRS256, kty: "RSA"},
+		{alg: JWTAlgES256, kty: "EC"},
+		{alg: JWTAlgEdDSA, kty: "OKP"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.alg, func(t *testing.T) {
+			key, err := GenerateJWTKey(tt.alg)
+			if err != nil {
+				t.Fatalf("GenerateJWTKey() error = %v", err)
+			}
+			// PEM 编解码后仍可使用
+			privatePEM, publicPEM, err := MarshalJWTKey(key)
+			if err != nil {
+				t.Fatalf("MarshalJWTKey() error = %v", err)
+			}
+			private, err := ParseJWTPrivateKey(privatePEM)
+			if err != nil {
+				t.Fatalf("ParseJWTPrivateKey() error = %v", err)
+			}
+			public, err := ParseJWTPublicKey(publicPEM)
+			if err != nil {
+				t.Fatalf("ParseJWTPublicKey() error = %v", err)
+			}
+			loaded := &JWTKey{Kid: key.Kid, Alg: key.Alg, Private: private, Public: public}
+			JWTKeys.Store(loaded, []*JWTKey{loaded})
+			global.GVA_CONFIG.JWT.SigningMethod = tt.alg
+
+			j := NewJWT()
+			token, err := j.CreateToken(j.CreateClaims(request.BaseClaims{Username: "admin"}))
+			if err != nil {
+				t.Fatalf("CreateToken() error = %v", err)
+			}
+			claims, err := j.ParseToken(token)
+			if err != nil {
+				t.Fatalf("ParseToken() error = %v", err)
+			}
+			if claims.Username != "admin" {
+				t.Fatalf("ParseToken() username = %s, want admin", claims.Username)
+			}
+
+			jwks := JWKS(JWTKeys.Keys())
+			if len(jwks.Keys) != 1 || jwks.Keys[0].Kty != tt.kty || jwks.Keys[0].Kid != key.Kid {
+				t.Fatalf("JWKS() = %+v", jwks)
+			}
+
+			// 密钥移除后无法验签
+			JWTKeys.Store(nil, nil)
+			if _, err = j.ParseToken(token); err == nil {
+				t.Fatalf("ParseToken() with unknown kid should fail")
+			}
+		})
+	}
+}
+
+func TestJWT_SymmetricSignAndParse(t *testing.T) {
+	global.GVA_CONFIG.JWT.ExpiresTime = "1h"
+	global.GVA_CONFIG.JWT.SigningKey = "test-signing-key"
+	global.GVA_CONFIG.JWT.SigningMethod = JWTAlgHS256
+	defer func() { global.GVA_CONFIG.JWT.SigningMethod = "" }()
+
+	j := NewJWT()
+	token, err := j.CreateToken(j.CreateClaims(request.BaseClaims{Username: "admin"}))
+	if err != nil {
+		t.Fatalf("CreateToken() error = %v", err)
+	}
+	if _, err = j.ParseToken(token); err != nil {
+		t.Fatalf("ParseToken() error = %v", err)
+	}
+	// 使用其他对称密钥签发的令牌无法通过校验
+	other := &JWT{SigningKey: []byte("another-key")}
+	if _, err = other.ParseToken(token); err == nil {
+		t.Fatalf("ParseToken() with wrong key should fail")
+	}
+
+	// 切换为非对称算法后 只在迁移截止时间前接受对称签名的令牌
+	global.GVA_CONFIG.JWT.SigningMethod = JWTAlgRS256
+	defer func() { global.GVA_CONFIG.JWT.HmacAcceptUntil = "" }()
+	if _, err = j.ParseToken(token); err == nil {
+		t.Fatalf("ParseToken() hmac token with asymmetric method should fail")
+	}
+	global.GVA_CONFIG.JWT.HmacAcceptUntil = time.Now().Add(time.Hour).Format(time.DateTime)
+	if _, err = j.ParseToken(token); err != nil {
+		t.Fatalf("ParseToken() hmac token before cutoff error = %v", err)
+	}
+	global.GVA_CONFIG.JWT.HmacAcceptUntil = time.Now().Add(-time.Hour).Format(time.DateTime)
+	if _, err = j.ParseToken(token); err == nil {
+		t.Fatalf("ParseToken() hmac token after cutoff should fail")
+	}
+}
@@ -0,0 +1,73 @@
+package utils
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/flipped-aurora/gin-vue-admin/server/config"
+)
+
+// commonPasswords 内置的常见弱密码 开启 ban-common-passwords 后禁止使用
+var commonPasswords = []string{
+	"123456", "12345678", "123456789", "1234567890", "111111", "000000", "123123", "654321",
+	"666666", "888888", "121212", "112233", "147258", "159753", "1q2w3e4r", "1qaz2wsx",
+	"qwerty", "qwerty123", "qwertyuiop", "asdfgh", "zxcvbnm", "abc123", "abc12345", "a123456",
+	"password", "password1", "password123", "passw0rd", "p@ssw0rd", "admin", "admin123", "admin@123",
+	"root", "root123", "letmein", "welcome", "iloveyou", "monkey", "dragon", "football",
+	"baseball", "sunshine", "princess", "superman", "master", "shadow", "woaini", "woaini1314",
+}
+
+// CheckPasswordPolicy 按密码策略校验明文密码 历史密码由 service 层校验
+func CheckPasswordPolicy(policy config.PasswordPolicy, username string, password string) error {
+	if n := utf8.RuneCountInString(password); n < policy.MinLength {
+		return fmt.Errorf("密码长度不能少于%d位", policy.MinLength)
+	}
+	var upper, lower, digit, symbol bool
+	for _, r := range password {
+		switch {
+		case unicode.IsUpper(r):
+			upper = true
+		case unicode.IsLower(r):
+			lower = true
+		case unicode.IsDigit(r):
+			digit = true
+		case unicode.IsPunct(r) || unicode.IsSymbol(r):
+			symbol = true
+		}
+	}
+	var missing []string
+	if policy.RequireUpper && !upper {
+		missing = append(missing, "大写字母")
+	}
+	if policy.RequireLower && !lower {
+		missing = append(missing, "小写字母")
+	}
+	if policy.RequireDigit && !digit {
+		missing = append(missing, "数字")
+	}
+	if policy.RequireSymbol && !symbol {
+		missing = append(missing, "特殊字符")
+	}
+	if len(missing) > 0 {
+		return errors.New("密码必须包含" + strings.Join(missing, "、"))
+	}
+	if policy.DisallowUsername && username != "" && strings.EqualFold(password, username) {
+		return errors.New("密码不能与用户名相同")
+	}
+	if policy.BanCommonPasswords && containsFold(commonPasswords, password) || containsFold(policy.BannedPasswords, password) {
+		return errors.New("密码过于简单, 请更换")
+	}
+	return nil
+}
+
+func containsFold(list []string, s string) bool {
+	for _, v := range list {
+		if strings.EqualFold(v, s) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,45 @@
+package utils
+
+import (
+	"testing"
+
+	"github.com/flipped-aurora/gin-vue-admin/server/config"
+)
+
+func TestCheckPasswordPolicy(t *testing.T) {
+	strict := config.PasswordPolicy{
+		MinLength:          8,
+		RequireUpper:       true,
+		RequireLower:       true,
+		RequireDigit:       true,
+		RequireSymbol:      true,
+		BanCommonPasswords: true,
+		DisallowUsername:   true,
+	}
+	tests := []struct {
+		name     string
+		policy   config.PasswordPolicy
+		username string
+		password string
+		wantErr  bool
+	}{
+		{name: "默认策略", policy: config.PasswordPolicy{MinLength: 6}, username: "admin", password: "123456"},
+		{name: "长度不足", policy: config.PasswordPolicy{MinLength: 6}, username: "admin", password: "12345", wantErr: true},
+		{name: "中文按字符计算长度", policy: config.PasswordPolicy{MinLength: 4}, username: "admin", password: "密码密码"},
+		{name: "满足全部要求", policy: strict, username: "admin", password: "Gva@2024pass"},
+		{name: "缺少大写字母", policy: strict, username: "admin", password: "gva@2024pass", wantErr: true},
+		{name: "缺少特殊字符", policy: strict, username: "admin", password: "Gva2024pass", wantErr: true},
+		{name: "常见弱密码", policy: config.PasswordPolicy{BanCommonPasswords: true}, username: "admin", password: "Password123", wantErr: true},
+		{name: "配置的禁用密码不区分大小写", policy: config.PasswordPolicy{BannedPasswords: []string{"Company@2024"}}, username: "admin", password: "company@2024", wantErr: true},
+		{name: "与用户名相同", policy: config.PasswordPolicy{DisallowUsername: true}, username: "Admin123", password: "admin123", wantErr: true},
+		{name: "未开启用户名校验", policy: config.PasswordPolicy{}, username: "admin123", password: "admin123"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := CheckPasswordPolicy(tt.policy, tt.username, tt.password)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("CheckPasswordPolicy() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
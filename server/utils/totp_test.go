@@ -0,0 +1,51 @@
+package utils
+
+import (
+	"encoding/base32"
+	"testing"
+	"time"
+)
+
+// RFC 6238 附录B 测试向量 (SHA1, 8位)
+func TestHotpRFC6238(t *testing.T) {
+	key := []byte("12345678901234567890")
+	tests := []struct {
+		name string
+		unix int64
+		want string
+	}{
+		{name: "59", unix: 59, want: "94287082"},
+		{name: "1111111109", unix: 1111111109, want: "07081804"},
+		{name: "1111111111", unix: 1111111111, want: "14050471"},
+		{name: "1234567890", unix: 1234567890, want: "89005924"},
+		{name: "2000000000", unix: 2000000000, want: "69279037"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := hotp(key, uint64(tt.unix/TOTPPeriod), 8); got != tt.want {
+				t.Errorf("hotp() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidateTOTP(t *testing.T) {
+	secret := base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString([]byte("12345678901234567890"))
+	now := time.Unix(1111111109, 0)
+	code, err := TOTPCode(secret, TOTPStep(now))
+	if err != nil {
+		t.Fatalf("TOTPCode() error = %v", err)
+	}
+	if code != "081804" {
+		t.Fatalf("TOTPCode() = %v, want 081804", code)
+	}
+	if step, ok := ValidateTOTP(secret, code, now.Add(TOTPPeriod*time.Second), 1); !ok || step != TOTPStep(now) {
+		t.Errorf("ValidateTOTP() within skew = %v %v", step, ok)
+	}
+	if _, ok := ValidateTOTP(secret, code, now.Add(3*TOTPPeriod*time.Second), 1); ok {
+		t.Errorf("ValidateTOTP() outside skew should fail")
+	}
+	if _, ok := ValidateTOTP(secret, "000000", now, 1); ok {
+		t.Errorf("ValidateTOTP() wrong code should fail")
+	}
+}
@@ -16,4 +16,23 @@ var (
 	OldAuthorityVerify     = Rules{"OldAuthorityId": {NotEmpty()}}
 	ChangePasswordVerify   = Rules{"Password": {NotEmpty()}, "NewPassword": {NotEmpty()}}
 	SetUserAuthorityVerify = Rules{"AuthorityId": {NotEmpty()}}
+	RefreshTokenVerify     = Rules{"RefreshToken": {NotEmpty()}}
+	MfaCodeVerify          = Rules{"Code": {NotEmpty()}}
+	MfaChallengeVerify     = Rules{"ChallengeId": {NotEmpty()}}
+	MfaLoginVerify         = Rules{"ChallengeId": {NotEmpty()}, "Code": {NotEmpty()}}
+	OidcAuthUrlVerify      = Rules{"Provider": {NotEmpty()}}
+	OidcLoginVerify        = Rules{"State": {NotEmpty()}, "Code": {NotEmpty()}}
+	ClaimMapVerify         = Rules{"ClaimValue": {NotEmpty()}, "AuthorityId": {NotEmpty()}}
+	ApiKeyVerify           = Rules{"Name": {NotEmpty()}}
+	ExpiredPasswordVerify  = Rules{"ChallengeId": {NotEmpty()}, "NewPassword": {NotEmpty()}}
+	UnlockUserVerify       = Rules{"Username": {NotEmpty()}}
+	ForgotPasswordVerify   = Rules{"Email": {NotEmpty()}}
+	ResetByTokenVerify     = Rules{"Token": {NotEmpty()}, "NewPassword": {NotEmpty()}}
+	SignUpVerify           = Rules{"Username": {NotEmpty()}, "Password": {NotEmpty()}, "Email": {NotEmpty()}, "CaptchaId": {NotEmpty()}, "Captcha": {NotEmpty()}}
+	VerifySignUpVerify     = Rules{"Token": {NotEmpty()}}
+	PasskeyLoginVerify     = Rules{"SessionId": {NotEmpty()}}
+	RenamePasskeyVerify    = Rules{"ID": {NotEmpty()}, "Name": {NotEmpty()}}
+	CasbinExplainVerify    = Rules{"Method": {NotEmpty()}, "Path": {NotEmpty()}}
+	AuthorityBundleVerify  = Rules{"Content": {NotEmpty()}}
+	AuthorityVersionVerify = Rules{"AuthorityId": {NotEmpty()}, "Version": {NotEmpty()}}
 )
@@ -0,0 +1,391 @@
+package utils
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdh"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/subtle"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/url"
+	"strings"
+)
+
+// WebAuthn(通行密钥)签名算法 COSE 算法编号
+const (
+	WebauthnAlgES256 = -7
+	WebauthnAlgEdDSA = -8
+	WebauthnAlgRS256 = -257
+)
+
+// 认证器数据标志位
+const (
+	webauthnFlagUP = 0x01 // 用户在场
+	webauthnFlagUV = 0x04 // 用户已验证(PIN 或生物识别)
+	webauthnFlagAT = 0x40 // 包含凭证数据
+)
+
+var (
+	ErrWebauthnInvalid   = errors.New("通行密钥验证失败")
+	ErrWebauthnSignCount = errors.New("通行密钥签名计数异常, 可能已被复制")
+)
+
+// WebauthnAlgs 支持的签名算法 按优先顺序
+var WebauthnAlgs = []int{WebauthnAlgES256, WebauthnAlgEdDSA, WebauthnAlgRS256}
+
+// WebauthnCredential 注册成功后需要保存的凭证信息
+type WebauthnCredential struct {
+	ID           []byte // 凭证ID
+	PublicKey    []byte // COSE 编码的公钥
+	SignCount    uint32 // 签名计数
+	AAGUID       []byte // 认证器型号
+	UserVerified bool   // 注册时是否完成用户验证
+}
+
+// WebauthnRelyingParty 依赖方 ID 为域名 Origins 为允许的前端来源
+// Origins 为空时允许主机名与 ID 相同的来源
+type WebauthnRelyingParty struct {
+	ID      string
+	Origins []string
+}
+
+type webauthnClientData struct {
+	Type      string `json:"type"`
+	Challenge string `json:"challenge"`
+	Origin    string `json:"origin"`
+}
+
+// DecodeWebauthnBase64 解码前端传来的 base64url 数据 兼容带填充的写法
+func DecodeWebauthnBase64(s string) ([]byte, error) {
+	return base64.RawURLEncoding.DecodeString(strings.TrimRight(s, "="))
+}
+
+// VerifyRegistration 校验注册(navigator.credentials.create)的结果 支持 none 和 packed 证明格式
+func (rp WebauthnRelyingParty) VerifyRegistration(clientDataJSON, attestationObject []byte, challenge string, requireUV bool) (*WebauthnCredential, error) {
+	if err := rp.verifyClientData(clientDataJSON, "webauthn.create", challenge); err != nil {
+		return nil, err
+	}
+	obj, _, err := cborDecode(attestationObject, 0)
+	if err != nil {
+		return nil, webauthnError("证明数据格式错误")
+	}
+	att, ok := obj.(map[interface{}]interface{})
+	if !ok {
+		return nil, webauthnError("证明数据格式错误")
+	}
+	format, _ := att["fmt"].(string)
+	authData, _ := att["authData"].([]byte)
+	stmt, _ := att["attStmt"].(map[interface{}]interface{})
+	flags, signCount, err := rp.verifyAuthData(authData, requireUV)
+	if err != nil {
+		return nil, err
+	}
+	if flags&webauthnFlagAT == 0 || len(authData) < 55 {
+		return nil, webauthnError("缺少凭证数据")
+	}
+	aaguid := authData[37:53]
+	idLen := int(binary.BigEndian.Uint16(authData[53:55]))
+	if len(authData) < 55+idLen {
+		return nil, webauthnError("凭证数据格式错误")
+	}
+	credId := authData[55 : 55+idLen]
+	_, rest, err := cborDecode(authData[55+idLen:], 0)
+	if err != nil {
+		return nil, webauthnError("公钥格式错误")
+	}
+	publicKey := authData[55+idLen : len(authData)-len(rest)]
+	alg, pub, err := parseCOSEKey(publicKey)
+	if err != nil {
+		return nil, err
+	}
+
+	clientDataHash := sha256.Sum256(clientDataJSON)
+	signed := append(append([]byte{}, authData...), clientDataHash[:]...)
+	switch format {
+	case "none":
+		if len(stmt) != 0 {
+			return nil, webauthnError("证明数据格式错误")
+		}
+	case "packed":
+		stmtAlg, _ := stmt["alg"].(int64)
+		sig, _ := stmt["sig"].([]byte)
+		if x5c, ok := stmt["x5c"].([]interface{}); ok && len(x5c) > 0 {
+			// 只校验证书签名 不校验证书链 需要限制认证器型号时可按 AAGUID 判断
+			der, _ := x5c[0].([]byte)
+			cert, err := x509.ParseCertificate(der)
+			if err != nil {
+				return nil, webauthnError("证明证书格式错误")
+			}
+			if err = webauthnVerify(int(stmtAlg), cert.PublicKey, signed, sig); err != nil {
+				return nil, err
+			}
+		} else {
+			if int(stmtAlg) != alg {
+				return nil, webauthnError("证明算法与公钥不一致")
+			}
+			if err = webauthnVerify(alg, pub, signed, sig); err != nil {
+				return nil, err
+			}
+		}
+	default:
+		return nil, webauthnError("不支持的证明格式 " + format)
+	}
+	return &WebauthnCredential{
+		ID:           append([]byte{}, credId...),
+		PublicKey:    append([]byte{}, publicKey...),
+		SignCount:    signCount,
+		AAGUID:       append([]byte{}, aaguid...),
+		UserVerified: flags&webauthnFlagUV != 0,
+	}, nil
+}
+
+// VerifyAssertion 校验登录(navigator.credentials.get)的结果 返回新的签名计数
+// 计数不为0时必须大于已保存的计数 否则说明凭证可能被复制
+func (rp WebauthnRelyingParty) VerifyAssertion(publicKey []byte, signCount uint32, clientDataJSON, authData, signature []byte, challenge string, requireUV bool) (uint32, error) {
+	if err := rp.verifyClientData(clientDataJSON, "webauthn.get", challenge); err != nil {
+		return 0, err
+	}
+	_, newCount, err := rp.verifyAuthData(authData, requireUV)
+	if err != nil {
+		return 0, err
+	}
+	alg, pub, err := parseCOSEKey(publicKey)
+	if err != nil {
+		return 0, err
+	}
+	clientDataHash := sha256.Sum256(clientDataJSON)
+	signed := append(append([]byte{}, authData...), clientDataHash[:]...)
+	if err = webauthnVerify(alg, pub, signed, signature); err != nil {
+		return 0, err
+	}
+	if (newCount != 0 || signCount != 0) && newCount <= signCount {
+		return 0, ErrWebauthnSignCount
+	}
+	return newCount, nil
+}
+
+func (rp WebauthnRelyingParty) verifyClientData(clientDataJSON []byte, typ string, challenge string) error {
+	var cd webauthnClientData
+	if err := json.Unmarshal(clientDataJSON, &cd); err != nil {
+		return webauthnError("客户端数据格式错误")
+	}
+	if cd.Type != typ {
+		return webauthnError("客户端数据类型错误")
+	}
+	if challenge == "" || subtle.ConstantTimeCompare([]byte(strings.TrimRight(cd.Challenge, "=")), []byte(challenge)) != 1 {
+		return webauthnError("挑战不匹配")
+	}
+	if !rp.originAllowed(cd.Origin) {
+		return webauthnError("来源不被允许 " + cd.Origin)
+	}
+	return nil
+}
+
+func (rp WebauthnRelyingParty) originAllowed(origin string) bool {
+	if len(rp.Origins) > 0 {
+		for _, o := range rp.Origins {
+			if strings.TrimRight(o, "/") == origin {
+				return true
+			}
+		}
+		return false
+	}
+	u, err := url.Parse(origin)
+	return err == nil && u.Hostname() == rp.ID
+}
+
+// verifyAuthData 校验依赖方ID摘要和用户在场标志 返回标志位和签名计数
+func (rp WebauthnRelyingParty) verifyAuthData(authData []byte, requireUV bool) (flags byte, signCount uint32, err error) {
+	if len(authData) < 37 {
+		return 0, 0, webauthnError("认证器数据格式错误")
+	}
+	rpIdHash := sha256.Sum256([]byte(rp.ID))
+	if !bytes.Equal(authData[:32], rpIdHash[:]) {
+		return 0, 0, webauthnError("依赖方ID不匹配")
+	}
+	flags = authData[32]
+	if flags&webauthnFlagUP == 0 {
+		return 0, 0, webauthnError("未检测到用户在场")
+	}
+	if requireUV && flags&webauthnFlagUV == 0 {
+		return 0, 0, webauthnError("需要验证 PIN 或生物识别")
+	}
+	return flags, binary.BigEndian.Uint32(authData[33:37]), nil
+}
+
+// parseCOSEKey 解析 COSE 编码的公钥 仅支持 ES256 EdDSA 和 RS256
+func parseCOSEKey(b []byte) (alg int, pub crypto.PublicKey, err error) {
+	v, _, err := cborDecode(b, 0)
+	if err != nil {
+		return 0, nil, webauthnError("公钥格式错误")
+	}
+	m, ok := v.(map[interface{}]interface{})
+	if !ok {
+		return 0, nil, webauthnError("公钥格式错误")
+	}
+	kty, _ := m[int64(1)].(int64)
+	a, _ := m[int64(3)].(int64)
+	switch {
+	case kty == 2 && a == WebauthnAlgES256:
+		crv, _ := m[int64(-1)].(int64)
+		x, _ := m[int64(-2)].([]byte)
+		y, _ := m[int64(-3)].([]byte)
+		if crv != 1 || len(x) != 32 || len(y) != 32 {
+			return 0, nil, webauthnError("公钥格式错误")
+		}
+		// 借助 ecdh 校验点在曲线上
+		if _, err = ecdh.P256().NewPublicKey(append(append([]byte{4}, x...), y...)); err != nil {
+			return 0, nil, webauthnError("公钥格式错误")
+		}
+		return WebauthnAlgES256, &ecdsa.PublicKey{Curve: elliptic.P256(), X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}, nil
+	case kty == 1 && a == WebauthnAlgEdDSA:
+		crv, _ := m[int64(-1)].(int64)
+		x, _ := m[int64(-2)].([]byte)
+		if crv != 6 || len(x) != ed25519.PublicKeySize {
+			return 0, nil, webauthnError("公钥格式错误")
+		}
+		return WebauthnAlgEdDSA, ed25519.PublicKey(x), nil
+	case kty == 3 && a == WebauthnAlgRS256:
+		n, _ := m[int64(-1)].([]byte)
+		e, _ := m[int64(-2)].([]byte)
+		if len(n) < 256 || len(e) == 0 || len(e) > 4 {
+			return 0, nil, webauthnError("公钥格式错误")
+		}
+		return WebauthnAlgRS256, &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}, nil
+	}
+	return 0, nil, webauthnError(fmt.Sprintf("不支持的公钥算法 %d", a))
+}
+
+func webauthnVerify(alg int, pub crypto.PublicKey, msg, sig []byte) error {
+	ok := false
+	switch alg {
+	case WebauthnAlgES256:
+		if k, is := pub.(*ecdsa.PublicKey); is {
+			h := sha256.Sum256(msg)
+			ok = ecdsa.VerifyASN1(k, h[:], sig)
+		}
+	case WebauthnAlgRS256:
+		if k, is := pub.(*rsa.PublicKey); is {
+			h := sha256.Sum256(msg)
+			ok = rsa.VerifyPKCS1v15(k, crypto.SHA256, h[:], sig) == nil
+		}
+	case WebauthnAlgEdDSA:
+		if k, is := pub.(ed25519.PublicKey); is {
+			ok = ed25519.Verify(k, msg, sig)
+		}
+	}
+	if !ok {
+		return webauthnError("签名错误")
+	}
+	return nil
+}
+
+func webauthnError(msg string) error {
+	return fmt.Errorf("%w: %s", ErrWebauthnInvalid, msg)
+}
+
+const cborMaxDepth = 16
+
+// cborDecode 解码 WebAuthn 用到的 CBOR 子集 不支持不定长编码和浮点数
+// 整数统一返回 int64 map 返回 map[interface{}]interface{}
+func cborDecode(b []byte, depth int) (v interface{}, rest []byte, err error) {
+	if depth > cborMaxDepth {
+		return nil, nil, errors.New("cbor: nesting too deep")
+	}
+	if len(b) == 0 {
+		return nil, nil, errors.New("cbor: unexpected end")
+	}
+	major, info := b[0]>>5, b[0]&0x1f
+	b = b[1:]
+	var arg uint64
+	switch {
+	case info < 24:
+		arg = uint64(info)
+	case info <= 27:
+		n := 1 << (info - 24)
+		if len(b) < n {
+			return nil, nil, errors.New("cbor: unexpected end")
+		}
+		for _, c := range b[:n] {
+			arg = arg<<8 | uint64(c)
+		}
+		b = b[n:]
+	default:
+		return nil, nil, errors.New("cbor: unsupported length")
+	}
+	switch major {
+	case 0:
+		if arg > 1<<63-1 {
+			return nil, nil, errors.New("cbor: integer overflow")
+		}
+		return int64(arg), b, nil
+	case 1:
+		if arg > 1<<63-1 {
+			return nil, nil, errors.New("cbor: integer overflow")
+		}
+		return -1 - int64(arg), b, nil
+	case 2, 3:
+		if uint64(len(b)) < arg {
+			return nil, nil, errors.New("cbor: unexpected end")
+		}
+		if major == 2 {
+			return b[:arg], b[arg:], nil
+		}
+		return string(b[:arg]), b[arg:], nil
+	case 4:
+		if uint64(len(b)) < arg {
+			return nil, nil, errors.New("cbor: unexpected end")
+		}
+		list := make([]interface{}, 0, arg)
+		for i := uint64(0); i < arg; i++ {
+			if v, b, err = cborDecode(b, depth+1); err != nil {
+				return nil, nil, err
+			}
+			list = append(list, v)
+		}
+		return list, b, nil
+	case 5:
+		if uint64(len(b)) < arg*2 {
+			return nil, nil, errors.New("cbor: unexpected end")
+		}
+		m := make(map[interface{}]interface{}, arg)
+		for i := uint64(0); i < arg; i++ {
+			var key interface{}
+			if key, b, err = cborDecode(b, depth+1); err != nil {
+				return nil, nil, err
+			}
+			switch key.(type) {
+			case int64, string:
+			default:
+				return nil, nil, errors.New("cbor: unsupported map key")
+			}
+			if v, b, err = cborDecode(b, depth+1); err != nil {
+				return nil, nil, err
+			}
+			m[key] = v
+		}
+		return m, b, nil
+	case 6:
+		return cborDecode(b, depth+1)
+	default:
+		switch info {
+		case 20:
+			return false, b, nil
+		case 21:
+			return true, b, nil
+		case 22, 23:
+			return nil, b, nil
+		}
+		return nil, nil, errors.New("cbor: unsupported simple value")
+	}
+}
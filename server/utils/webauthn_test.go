@@ -0,0 +1,252 @@
+package utils
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"testing"
+)
+
+// cborPair 测试用 CBOR 编码 map 按顺序输出
+type cborPair struct {
+	k, v interface{}
+}
+
+func cborHead(major byte, n uint64) []byte {
+	switch {
+	case n < 24:
+		return []byte{major<<5 | byte(n)}
+	case n < 1<<8:
+		return []byte{major<<5 | 24, byte(n)}
+	case n < 1<<16:
+		return []byte{major<<5 | 25, byte(n >> 8), byte(n)}
+	}
+	b := []byte{major<<5 | 26, 0, 0, 0, 0}
+	binary.BigEndian.PutUint32(b[1:], uint32(n))
+	return b
+}
+
+func cborEncode(v interface{}) []byte {
+	switch x := v.(type) {
+	case int:
+		if x < 0 {
+			return cborHead(1, uint64(-1-x))
+		}
+		return cborHead(0, uint64(x))
+	case []byte:
+		return append(cborHead(2, uint64(len(x))), x...)
+	case string:
+		return append(cborHead(3, uint64(len(x))), x...)
+	case []interface{}:
+		b := cborHead(4, uint64(len(x)))
+		for _, e := range x {
+			b = append(b, cborEncode(e)...)
+		}
+		return b
+	case []cborPair:
+		b := cborHead(5, uint64(len(x)))
+		for _, p := range x {
+			b = append(b, cborEncode(p.k)...)
+			b = append(b, cborEncode(p.v)...)
+		}
+		return b
+	}
+	panic("unsupported type")
+}
+
+// softAuthenticator 软件认证器 模拟浏览器和安全密钥完成注册和登录
+type softAuthenticator struct {
+	alg    int
+	key    crypto.Signer
+	credId []byte
+	count  uint32
+	flags  byte
+}
+
+func newSoftAuthenticator(t *testing.T, alg int) *softAuthenticator {
+	a := &softAuthenticator{alg: alg, credId: []byte("credential-" + t.Name()), flags: webauthnFlagUP | webauthnFlagUV}
+	var err error
+	switch alg {
+	case WebauthnAlgES256:
+		a.key, err = ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	case WebauthnAlgEdDSA:
+		_, a.key, err = ed25519.GenerateKey(rand.Reader)
+	case WebauthnAlgRS256:
+		a.key, err = rsa.GenerateKey(rand.Reader, 2048)
+	}
+	if err != nil {
+		t.Fatalf("generate key failed: %v", err)
+	}
+	return a
+}
+
+func (a *softAuthenticator) coseKey() []byte {
+	switch k := a.key.Public().(type) {
+	case *ecdsa.PublicKey:
+		return cborEncode([]cborPair{{1, 2}, {3, a.alg}, {-1, 1}, {-2, k.X.FillBytes(make([]byte, 32))}, {-3, k.Y.FillBytes(make([]byte, 32))}})
+	case ed25519.PublicKey:
+		return cborEncode([]cborPair{{1, 1}, {3, a.alg}, {-1, 6}, {-2, []byte(k)}})
+	case *rsa.PublicKey:
+		return cborEncode([]cborPair{{1, 3}, {3, a.alg}, {-1, k.N.Bytes()}, {-2, big.NewInt(int64(k.E)).Bytes()}})
+	}
+	return nil
+}
+
+func (a *softAuthenticator) sign(msg []byte) []byte {
+	var sig []byte
+	var err error
+	if a.alg == WebauthnAlgEdDSA {
+		sig, err = a.key.Sign(rand.Reader, msg, crypto.Hash(0))
+	} else {
+		h := sha256.Sum256(msg)
+		sig, err = a.key.Sign(rand.Reader, h[:], crypto.SHA256)
+	}
+	if err != nil {
+		panic(err)
+	}
+	return sig
+}
+
+func (a *softAuthenticator) authData(rpId string, attested bool) []byte {
+	h := sha256.Sum256([]byte(rpId))
+	b := append(h[:], a.flags, 0, 0, 0, 0)
+	binary.BigEndian.PutUint32(b[33:], a.count)
+	if attested {
+		b[32] |= webauthnFlagAT
+		b = append(b, make([]byte, 16)...)
+		b = binary.BigEndian.AppendUint16(b, uint16(len(a.credId)))
+		b = append(b, a.credId...)
+		b = append(b, a.coseKey()...)
+	}
+	return b
+}
+
+func clientData(typ, challenge, origin string) []byte {
+	b, _ := json.Marshal(map[string]string{"type": typ, "challenge": challenge, "origin": origin})
+	return b
+}
+
+func (a *softAuthenticator) create(rpId, origin, challenge, format string) (cd, att []byte) {
+	cd = clientData("webauthn.create", challenge, origin)
+	authData := a.authData(rpId, true)
+	stmt := []cborPair{}
+	if format == "packed" {
+		h := sha256.Sum256(cd)
+		stmt = []cborPair{{"alg", a.alg}, {"sig", a.sign(append(append([]byte{}, authData...), h[:]...))}}
+	}
+	return cd, cborEncode([]cborPair{{"fmt", format}, {"attStmt", stmt}, {"authData", authData}})
+}
+
+func (a *softAuthenticator) get(rpId, origin, challenge string) (cd, authData, sig []byte) {
+	a.count++
+	cd = clientData("webauthn.get", challenge, origin)
+	authData = a.authData(rpId, false)
+	h := sha256.Sum256(cd)
+	return cd, authData, a.sign(append(append([]byte{}, authData...), h[:]...))
+}
+
+func TestWebauthn_Ceremonies(t *testing.T) {
+	rp := WebauthnRelyingParty{ID: "admin.example.com", Origins: []string{"https://admin.example.com"}}
+	const origin = "https://admin.example.com"
+	tests := []struct {
+		name   string
+		alg    int
+		format string
+	}{
+		{name: "ES256", alg: WebauthnAlgES256, format: "none"},
+		{name: "EdDSA", alg: WebauthnAlgEdDSA, format: "packed"},
+		{name: "RS256", alg: WebauthnAlgRS256, format: "packed"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := newSoftAuthenticator(t, tt.alg)
+			cd, att := a.create(rp.ID, origin, "reg-challenge", tt.format)
+			cred, err := rp.VerifyRegistration(cd, att, "reg-challenge", true)
+			if err != nil {
+				t.Fatalf("VerifyRegistration() error = %v", err)
+			}
+			if string(cred.ID) != string(a.credId) || !cred.UserVerified {
+				t.Fatalf("VerifyRegistration() credential = %+v", cred)
+			}
+
+			cd, authData, sig := a.get(rp.ID, origin, "login-challenge")
+			count, err := rp.VerifyAssertion(cred.PublicKey, cred.SignCount, cd, authData, sig, "login-challenge", true)
+			if err != nil || count != 1 {
+				t.Fatalf("VerifyAssertion() = %d, %v", count, err)
+			}
+			// 重放同一次登录结果 计数没有增加
+			if _, err = rp.VerifyAssertion(cred.PublicKey, count, cd, authData, sig, "login-challenge", true); !errors.Is(err, ErrWebauthnSignCount) {
+				t.Fatalf("VerifyAssertion() replay error = %v, want %v", err, ErrWebauthnSignCount)
+			}
+		})
+	}
+}
+
+func TestWebauthn_Rejects(t *testing.T) {
+	rp := WebauthnRelyingParty{ID: "admin.example.com"}
+	const origin = "https://admin.example.com:8080"
+	a := newSoftAuthenticator(t, WebauthnAlgES256)
+	cd, att := a.create(rp.ID, origin, "c1", "none")
+	cred, err := rp.VerifyRegistration(cd, att, "c1", false)
+	if err != nil {
+		t.Fatalf("VerifyRegistration() without origins error = %v", err)
+	}
+
+	other := newSoftAuthenticator(t, WebauthnAlgES256)
+	tests := []struct {
+		name   string
+		assert func() ([]byte, []byte, []byte)
+		uv     bool
+	}{
+		{name: "挑战不匹配", assert: func() ([]byte, []byte, []byte) { return a.get(rp.ID, origin, "other") }},
+		{name: "钓鱼网站来源", assert: func() ([]byte, []byte, []byte) { return a.get(rp.ID, "https://admin-example.com", "c2") }},
+		{name: "依赖方ID不匹配", assert: func() ([]byte, []byte, []byte) { return a.get("example.com", origin, "c2") }},
+		{name: "其他密钥签名", assert: func() ([]byte, []byte, []byte) { return other.get(rp.ID, origin, "c2") }},
+		{name: "类型错误", assert: func() ([]byte, []byte, []byte) {
+			_, authData, sig := a.get(rp.ID, origin, "c2")
+			return clientData("webauthn.create", "c2", origin), authData, sig
+		}},
+		{name: "未验证用户", uv: true, assert: func() ([]byte, []byte, []byte) {
+			a.flags = webauthnFlagUP
+			defer func() { a.flags = webauthnFlagUP | webauthnFlagUV }()
+			return a.get(rp.ID, origin, "c2")
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cd, authData, sig := tt.assert()
+			if _, err := rp.VerifyAssertion(cred.PublicKey, 0, cd, authData, sig, "c2", tt.uv); !errors.Is(err, ErrWebauthnInvalid) {
+				t.Fatalf("VerifyAssertion() error = %v, want %v", err, ErrWebauthnInvalid)
+			}
+		})
+	}
+
+	// 不支持的证明格式和被篡改的自证明
+	cd, att = a.create(rp.ID, origin, "c3", "fido-u2f")
+	if _, err = rp.VerifyRegistration(cd, att, "c3", false); !errors.Is(err, ErrWebauthnInvalid) {
+		t.Fatalf("VerifyRegistration() unsupported format error = %v", err)
+	}
+	cd, att = a.create(rp.ID, origin, "c3", "packed")
+	att[len(att)-1] ^= 0xff
+	if _, err = rp.VerifyRegistration(cd, att, "c3", false); !errors.Is(err, ErrWebauthnInvalid) {
+		t.Fatalf("VerifyRegistration() tampered error = %v", err)
+	}
+}
+
+func TestDecodeWebauthnBase64(t *testing.T) {
+	raw := []byte{0xfb, 0xff, 0x01}
+	for _, s := range []string{base64.RawURLEncoding.EncodeToString(raw), base64.URLEncoding.EncodeToString(raw)} {
+		if b, err := DecodeWebauthnBase64(s); err != nil || string(b) != string(raw) {
+			t.Fatalf("DecodeWebauthnBase64(%q) = %v, %v", s, b, err)
+		}
+	}
+}
@@ -0,0 +1,38 @@
+import service from '@/utils/request'
+
+// @Tags ApiKey
+// @Summary 获取自身的API密钥列表
+// @Security ApiKeyAuth
+// @Router /apiKey/getApiKeyList [get]
+export const getApiKeyList = () => {
+  return service({
+    url: '/apiKey/getApiKeyList',
+    method: 'get'
+  })
+}
+
+// @Tags ApiKey
+// @Summary 创建API密钥 明文密钥只在创建时返回
+// @Security ApiKeyAuth
+// @Param data body {name:"string",expiresAt:"string",scopes:"array",allowedIps:"array"}
+// @Router /apiKey/createApiKey [post]
+export const createApiKey = (data) => {
+  return service({
+    url: '/apiKey/createApiKey',
+    method: 'post',
+    data
+  })
+}
+
+// @Tags ApiKey
+// @Summary 吊销API密钥
+// @Security ApiKeyAuth
+// @Param data body {id:"number"}
+// @Router /apiKey/deleteApiKey [delete]
+export const deleteApiKey = (data) => {
+  return service({
+    url: '/apiKey/deleteApiKey',
+    method: 'delete',
+    data
+  })
+}
@@ -83,3 +83,133 @@ export const updateAuthority = (data) => {
     data
   })
 }
+
+// @Summary 导出角色
+// @Security ApiKeyAuth
+// @Produce application/json
+// @Param data query systemReq.ExportAuthorityBundle true "角色ID, 格式json|yaml"
+// @Success 200 {string} string "{"success":true,"data":{"filename":"","content":""},"msg":"导出成功"}"
+// @Router /authority/exportAuthorityBundle [get]
+export const exportAuthorityBundle = (params) => {
+  return service({
+    url: '/authority/exportAuthorityBundle',
+    method: 'get',
+    params
+  })
+}
+
+// @Summary 导入角色
+// @Security ApiKeyAuth
+// @accept application/json
+// @Produce application/json
+// @Param data body systemReq.ImportAuthorityBundle true "导出的内容, 导入方式merge|replace, 是否只预览"
+// @Success 200 {string} string "{"success":true,"data":{},"msg":"导入成功"}"
+// @Router /authority/importAuthorityBundle [post]
+export const importAuthorityBundle = (data) => {
+  return service({
+    url: '/authority/importAuthorityBundle',
+    method: 'post',
+    data
+  })
+}
+
+// @Summary 获取角色数据范围
+// @Security ApiKeyAuth
+// @Produce application/json
+// @Param data query request.GetAuthorityId true "角色ID"
+// @Success 200 {string} string "{"success":true,"data":[],"msg":"获取成功"}"
+// @Router /authority/getDataScopes [get]
+export const getDataScopes = (params) => {
+  return service({
+    url: '/authority/getDataScopes',
+    method: 'get',
+    params
+  })
+}
+
+// @Summary 设置角色数据范围
+// @Security ApiKeyAuth
+// @accept application/json
+// @Produce application/json
+// @Param data body systemReq.SetDataScopes true "角色ID, 各资源的数据范围"
+// @Success 200 {string} string "{"success":true,"data":{},"msg":"设置成功"}"
+// @Router /authority/setDataScopes [post]
+export const setDataScopes = (data) => {
+  return service({
+    url: '/authority/setDataScopes',
+    method: 'post',
+    data
+  })
+}
+
+// @Summary 获取角色字段权限
+// @Security ApiKeyAuth
+// @Produce application/json
+// @Param data query request.GetAuthorityId true "角色ID"
+// @Success 200 {string} string "{"success":true,"data":[],"msg":"获取成功"}"
+// @Router /authority/getFieldRules [get]
+export const getFieldRules = (params) => {
+  return service({
+    url: '/authority/getFieldRules',
+    method: 'get',
+    params
+  })
+}
+
+// @Summary 设置角色字段权限
+// @Security ApiKeyAuth
+// @accept application/json
+// @Produce application/json
+// @Param data body systemReq.SetFieldRules true "角色ID, 各资源字段的权限"
+// @Success 200 {string} string "{"success":true,"data":{},"msg":"设置成功"}"
+// @Router /authority/setFieldRules [post]
+export const setFieldRules = (data) => {
+  return service({
+    url: '/authority/setFieldRules',
+    method: 'post',
+    data
+  })
+}
+
+// @Summary 分页获取角色权限的历史版本
+// @Security ApiKeyAuth
+// @Produce application/json
+// @Param data query systemReq.AuthorityVersionSearch true "角色ID, 页码, 每页大小"
+// @Success 200 {string} string "{"success":true,"data":{},"msg":"获取成功"}"
+// @Router /authority/getAuthorityVersionList [get]
+export const getAuthorityVersionList = (params) => {
+  return service({
+    url: '/authority/getAuthorityVersionList',
+    method: 'get',
+    params
+  })
+}
+
+// @Summary 获取角色权限历史版本的快照及与上一版本的差异
+// @Security ApiKeyAuth
+// @Produce application/json
+// @Param data query systemReq.AuthorityVersionReq true "角色ID, 版本号"
+// @Success 200 {string} string "{"success":true,"data":{},"msg":"获取成功"}"
+// @Router /authority/getAuthorityVersion [get]
+export const getAuthorityVersion = (params) => {
+  return service({
+    url: '/authority/getAuthorityVersion',
+    method: 'get',
+    params
+  })
+}
+
+// @Summary 将角色权限恢复到历史版本
+// @Security ApiKeyAuth
+// @accept application/json
+// @Produce application/json
+// @Param data body systemReq.RollbackAuthorityVersion true "角色ID, 版本号, 是否只预览"
+// @Success 200 {string} string "{"success":true,"data":{},"msg":"恢复成功"}"
+// @Router /authority/rollbackAuthorityVersion [post]
+export const rollbackAuthorityVersion = (data) => {
+  return service({
+    url: '/authority/rollbackAuthorityVersion',
+    method: 'post',
+    data
+  })
+}
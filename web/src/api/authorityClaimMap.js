@@ -0,0 +1,40 @@
+import service from '@/utils/request'
+
+// @Tags AuthorityClaimMap
+// @Summary 获取外部组到角色的映射
+// @Security ApiKeyAuth
+// @Param authorityId query number true "角色ID"
+// @Router /authorityClaimMap/getClaimMapList [get]
+export const getClaimMapList = (params) => {
+  return service({
+    url: '/authorityClaimMap/getClaimMapList',
+    method: 'get',
+    params
+  })
+}
+
+// @Tags AuthorityClaimMap
+// @Summary 新增外部组到角色的映射
+// @Security ApiKeyAuth
+// @Param data body {provider:"string",claimValue:"string",authorityId:"number"}
+// @Router /authorityClaimMap/createClaimMap [post]
+export const createClaimMap = (data) => {
+  return service({
+    url: '/authorityClaimMap/createClaimMap',
+    method: 'post',
+    data
+  })
+}
+
+// @Tags AuthorityClaimMap
+// @Summary 删除外部组到角色的映射
+// @Security ApiKeyAuth
+// @Param data body {id:"number"}
+// @Router /authorityClaimMap/deleteClaimMap [delete]
+export const deleteClaimMap = (data) => {
+  return service({
+    url: '/authorityClaimMap/deleteClaimMap',
+    method: 'delete',
+    data
+  })
+}
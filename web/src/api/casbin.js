@@ -30,3 +30,35 @@ export const getPolicyPathByAuthorityId = (data) => {
     data
   })
 }
+
+// @Tags casbin
+// @Summary 模拟用户或角色访问接口
+// @Security ApiKeyAuth
+// @accept application/json
+// @Produce application/json
+// @Param data body request.CasbinExplain true "用户ID或角色ID, 方法, 路径, IP"
+// @Success 200 {string} json "{"success":true,"data":{},"msg":"获取成功"}"
+// @Router /casbin/explain [post]
+export const explainCasbin = (data) => {
+  return service({
+    url: '/casbin/explain',
+    method: 'post',
+    data
+  })
+}
+
+// @Tags casbin
+// @Summary 模拟用户或角色访问全部路由
+// @Security ApiKeyAuth
+// @accept application/json
+// @Produce application/json
+// @Param data body request.CasbinSimulate true "用户ID或角色ID, IP"
+// @Success 200 {string} json "{"success":true,"data":{},"msg":"获取成功"}"
+// @Router /casbin/simulate [post]
+export const simulateCasbin = (data) => {
+  return service({
+    url: '/casbin/simulate',
+    method: 'post',
+    data
+  })
+}
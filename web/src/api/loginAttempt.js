@@ -0,0 +1,27 @@
+import service from '@/utils/request'
+
+// @Tags LoginAttempt
+// @Summary 分页获取登录失败记录
+// @Security ApiKeyAuth
+// @Param data query {page:"number",pageSize:"number",username:"string",ip:"string"}
+// @Router /loginAttempt/getLoginAttemptList [get]
+export const getLoginAttemptList = (params) => {
+  return service({
+    url: '/loginAttempt/getLoginAttemptList',
+    method: 'get',
+    params
+  })
+}
+
+// @Tags LoginAttempt
+// @Summary 解除账号锁定
+// @Security ApiKeyAuth
+// @Param data body {username:"string"}
+// @Router /loginAttempt/unlockUser [post]
+export const unlockUser = (data) => {
+  return service({
+    url: '/loginAttempt/unlockUser',
+    method: 'post',
+    data
+  })
+}
@@ -0,0 +1,108 @@
+import service from '@/utils/request'
+
+// @Tags Base
+// @Summary 开始通行密钥免密码登录
+// @Router /base/passkeyLoginBegin [post]
+export const passkeyLoginBegin = () => {
+  return service({
+    url: '/base/passkeyLoginBegin',
+    method: 'post'
+  })
+}
+
+// @Tags Base
+// @Summary 使用通行密钥免密码登录
+// @Param data body {sessionId:"string",credential:"object"}
+// @Router /base/passkeyLogin [post]
+export const passkeyLogin = (data) => {
+  return service({
+    url: '/base/passkeyLogin',
+    method: 'post',
+    data
+  })
+}
+
+// @Tags Base
+// @Summary 登录第二步 开始使用通行密钥验证
+// @Param data body {challengeId:"string"}
+// @Router /base/mfaPasskeyBegin [post]
+export const mfaPasskeyBegin = (data) => {
+  return service({
+    url: '/base/mfaPasskeyBegin',
+    method: 'post',
+    data
+  })
+}
+
+// @Tags Base
+// @Summary 登录第二步 使用通行密钥代替验证码
+// @Param data body {challengeId:"string",credential:"object"}
+// @Router /base/mfaPasskeyLogin [post]
+export const mfaPasskeyLogin = (data) => {
+  return service({
+    url: '/base/mfaPasskeyLogin',
+    method: 'post',
+    data
+  })
+}
+
+// @Tags SysUser
+// @Summary 获取自身的通行密钥
+// @Security ApiKeyAuth
+// @Router /user/getPasskeyList [get]
+export const getPasskeyList = () => {
+  return service({
+    url: '/user/getPasskeyList',
+    method: 'get'
+  })
+}
+
+// @Tags SysUser
+// @Summary 开始注册通行密钥
+// @Security ApiKeyAuth
+// @Router /user/beginPasskeyRegistration [post]
+export const beginPasskeyRegistration = () => {
+  return service({
+    url: '/user/beginPasskeyRegistration',
+    method: 'post'
+  })
+}
+
+// @Tags SysUser
+// @Summary 完成注册通行密钥
+// @Security ApiKeyAuth
+// @Param data body {name:"string",credential:"object"}
+// @Router /user/finishPasskeyRegistration [post]
+export const finishPasskeyRegistration = (data) => {
+  return service({
+    url: '/user/finishPasskeyRegistration',
+    method: 'post',
+    data
+  })
+}
+
+// @Tags SysUser
+// @Summary 修改通行密钥名称
+// @Security ApiKeyAuth
+// @Param data body {id:"number",name:"string"}
+// @Router /user/renamePasskey [put]
+export const renamePasskey = (data) => {
+  return service({
+    url: '/user/renamePasskey',
+    method: 'put',
+    data
+  })
+}
+
+// @Tags SysUser
+// @Summary 删除通行密钥
+// @Security ApiKeyAuth
+// @Param data body {id:"number"}
+// @Router /user/deletePasskey [delete]
+export const deletePasskey = (data) => {
+  return service({
+    url: '/user/deletePasskey',
+    method: 'delete',
+    data
+  })
+}
@@ -0,0 +1,27 @@
+import service from '@/utils/request'
+
+// @Tags SignUp
+// @Summary 分页获取注册申请
+// @Security ApiKeyAuth
+// @Param data query {page:"number",pageSize:"number",username:"string",email:"string",status:"number"}
+// @Router /signUp/getSignUpList [get]
+export const getSignUpList = (params) => {
+  return service({
+    url: '/signUp/getSignUpList',
+    method: 'get',
+    params
+  })
+}
+
+// @Tags SignUp
+// @Summary 审核注册申请
+// @Security ApiKeyAuth
+// @Param data body {id:"number",approve:"boolean",remark:"string"}
+// @Router /signUp/reviewSignUp [post]
+export const reviewSignUp = (data) => {
+  return service({
+    url: '/signUp/reviewSignUp',
+    method: 'post',
+    data
+  })
+}
@@ -11,6 +11,76 @@ export const login = (data) => {
   })
 }
 
+// @Summary 登录第二步 校验两步验证码
+// @Produce  application/json
+// @Param data body {challengeId:"string",code:"string"}
+// @Router /base/mfaLogin [post]
+export const mfaLogin = (data) => {
+  return service({
+    url: '/base/mfaLogin',
+    method: 'post',
+    data: data
+  })
+}
+
+// @Summary 登录时绑定两步验证
+// @Produce  application/json
+// @Param data body {challengeId:"string"}
+// @Router /base/mfaEnroll [post]
+export const mfaEnroll = (data) => {
+  return service({
+    url: '/base/mfaEnroll',
+    method: 'post',
+    data: data
+  })
+}
+
+// @Summary 登录时修改过期或被重置的密码
+// @Produce  application/json
+// @Param data body {challengeId:"string",newPassword:"string"}
+// @Router /base/changeExpiredPassword [post]
+export const changeExpiredPassword = (data) => {
+  return service({
+    url: '/base/changeExpiredPassword',
+    method: 'post',
+    data: data
+  })
+}
+
+// @Summary 获取单点登录身份源
+// @Produce  application/json
+// @Router /base/oidcProviders [get]
+export const getOidcProviders = () => {
+  return service({
+    url: '/base/oidcProviders',
+    method: 'get'
+  })
+}
+
+// @Summary 获取单点登录授权地址
+// @Produce  application/json
+// @Param provider query string true "身份源标识"
+// @Router /base/oidcAuthUrl [get]
+export const getOidcAuthUrl = (params) => {
+  return service({
+    url: '/base/oidcAuthUrl',
+    method: 'get',
+    params
+  })
+}
+
+// @Summary 单点登录回调
+// @Produce  application/json
+// @Param data body {state:"string",code:"string"}
+// @Router /base/oidcLogin [post]
+export const oidcLogin = (data) => {
+  return service({
+    url: '/base/oidcLogin',
+    method: 'post',
+    data: data
+  })
+}
+
 // @Summary 获取验证码
 // @Produce  application/json
 // @Param data body {username:"string",password:"string"}
@@ -179,3 +249,101 @@ export const resetPassword = (data) => {
     data: data
   })
 }
+
+// @Summary 获取自身在线会话
+// @Security ApiKeyAuth
+// @Produce  application/json
+// @Router /user/getSessionList [get]
+export const getSessionList = () => {
+  return service({
+    url: '/user/getSessionList',
+    method: 'get'
+  })
+}
+
+// @Summary 下线自身会话
+// @Security ApiKeyAuth
+// @Produce  application/json
+// @Param data body {id:"number"}
+// @Router /user/revokeSession [post]
+export const revokeSession = (data) => {
+  return service({
+    url: '/user/revokeSession',
+    method: 'post',
+    data: data
+  })
+}
+
+// @Summary 强制下线用户
+// @Security ApiKeyAuth
+// @Produce  application/json
+// @Param data body {id:"number"}
+// @Router /user/forceLogout [post]
+export const forceLogout = (data) => {
+  return service({
+    url: '/user/forceLogout',
+    method: 'post',
+    data: data
+  })
+}
+
+// @Summary 管理员代登录用户
+// @Security ApiKeyAuth
+// @Produce  application/json
+// @Param data body {id:"number"}
+// @Router /user/impersonate [post]
+export const impersonate = (data) => {
+  return service({
+    url: '/user/impersonate',
+    method: 'post',
+    data: data
+  })
+}
+
+// @Summary 忘记密码 向邮箱发送重置链接
+// @Produce  application/json
+// @Param data body {email:"string"}
+// @Router /base/forgotPassword [post]
+export const forgotPassword = (data) => {
+  return service({
+    url: '/base/forgotPassword',
+    method: 'post',
+    data: data
+  })
+}
+
+// @Summary 通过重置链接设置新密码
+// @Produce  application/json
+// @Param data body {token:"string",newPassword:"string"}
+// @Router /base/resetPasswordByToken [post]
+export const resetPasswordByToken = (data) => {
+  return service({
+    url: '/base/resetPasswordByToken',
+    method: 'post',
+    data: data
+  })
+}
+
+// @Summary 自助注册
+// @Produce  application/json
+// @Param data body {userName:"string",passWord:"string",nickName:"string",email:"string",phone:"string",captcha:"string",captchaId:"string"}
+// @Router /base/signUp [post]
+export const signUp = (data) => {
+  return service({
+    url: '/base/signUp',
+    method: 'post',
+    data: data
+  })
+}
+
+// @Summary 通过邮件中的链接验证注册邮箱
+// @Produce  application/json
+// @Param data body {token:"string"}
+// @Router /base/verifySignUp [post]
+export const verifySignUp = (data) => {
+  return service({
+    url: '/base/verifySignUp',
+    method: 'post',
+    data: data
+  })
+}
@@ -1,7 +1,22 @@
-import { login, getUserInfo } from '@/api/user'
+import {
+  login,
+  mfaLogin,
+  mfaEnroll,
+  oidcLogin,
+  changeExpiredPassword,
+  impersonate,
+  getUserInfo
+} from '@/api/user'
 import { jsonInBlacklist } from '@/api/jwt'
+import {
+  passkeyLoginBegin,
+  passkeyLogin,
+  mfaPasskeyBegin,
+  mfaPasskeyLogin
+} from '@/api/passkey'
+import { getPasskey, isPasskeySupported } from '@/utils/webauthn'
 import router from '@/router/index'
-import { ElLoading, ElMessage } from 'element-plus'
+import { ElLoading, ElMessage, ElMessageBox } from 'element-plus'
 import { defineStore } from 'pinia'
 import { ref, computed } from 'vue'
 import { useRouterStore } from './router'
@@ -23,6 +38,13 @@ export const useUserStore = defineStore('user', () => {
   const token = useStorage('token', '')
   const xToken = useCookies('x-token')
   const currentToken = computed(() => token.value || xToken.value || '')
+  const refreshToken = useStorage('refreshToken', '')
+  // 代登录时的实际操作人 以及代登录前操作人自己的令牌 退出代登录时恢复
+  const impersonator = ref(null)
+  const impersonateOrigin = useStorage('impersonateOrigin', {
+    token: '',
+    refreshToken: ''
+  })
 
   const setUserInfo = (val) => {
     userInfo.value = val
@@ -38,6 +60,10 @@ export const useUserStore = defineStore('user', () => {
     xToken.value = val
   }
 
+  const setRefreshToken = (val) => {
+    refreshToken.value = val
+  }
+
   const NeedInit = async () => {
     await ClearStorage()
     await router.push({ name: 'Init', replace: true })
@@ -54,26 +80,97 @@ export const useUserStore = defineStore('user', () => {
     const res = await getUserInfo()
     if (res.code === 0) {
       setUserInfo(res.data.userInfo)
+      impersonator.value = res.data.impersonator || null
     }
     return res
   }
+  /* 代登录 以指定用户的身份重新进入系统*/
+  const Impersonate = async (id) => {
+    const res = await impersonate({ id })
+    if (res.code !== 0) {
+      return
+    }
+    impersonateOrigin.value = {
+      token: currentToken.value,
+      refreshToken: refreshToken.value
+    }
+    setToken(res.data.token)
+    // 代登录令牌不能刷新 到期后需退出代登录
+    setRefreshToken('')
+    sessionStorage.clear()
+    window.location.replace(window.location.pathname)
+  }
+  /* 退出代登录 恢复操作人自己的登录 代登录令牌已过期时无需作废*/
+  const StopImpersonate = async (revoke = true) => {
+    const origin = impersonateOrigin.value
+    impersonateOrigin.value = { token: '', refreshToken: '' }
+    if (revoke) {
+      await jsonInBlacklist()
+    }
+    setToken(origin.token)
+    setRefreshToken(origin.refreshToken)
+    sessionStorage.clear()
+    window.location.replace(window.location.pathname)
+  }
   /* 登录*/
   const LoginIn = async (loginInfo) => {
+    return handleLogin(() => login(loginInfo))
+  }
+  /* 通行密钥免密码登录 */
+  const PasskeyLoginIn = async () => {
+    return handleLogin(async () => {
+      const begin = await passkeyLoginBegin()
+      if (begin.code !== 0) {
+        return begin
+      }
+      const credential = await getPasskey(begin.data.options)
+      return passkeyLogin({ sessionId: begin.data.sessionId, credential })
+    })
+  }
+  /* 单点登录回调 */
+  const OidcLoginIn = async (data) => {
+    return handleLogin(() => oidcLogin(data))
+  }
+  const handleLogin = async (request) => {
     try {
       loadingInstance.value = ElLoading.service({
         fullscreen: true,
         text: '登录中，请稍候...'
       })
 
-      const res = await login(loginInfo)
+      let res = await request()
       
       if (res.code !== 0) {
         ElMessage.error(res.message || '登录失败')
         return false
       }
+      // 需要两步验证
+      if (res.data.challengeId && !res.data.needChangePassword) {
+        loadingInstance.value?.close()
+        res = await mfaStep(res.data)
+        if (!res || res.code !== 0) {
+          return false
+        }
+        if (res.data.recoveryCodes?.length) {
+          await ElMessageBox.alert(
+            res.data.recoveryCodes.join('<br/>'),
+            '请妥善保存恢复码, 仅显示一次',
+            { dangerouslyUseHTMLString: true }
+          )
+        }
+      }
+      // 密码过期或被管理员重置 需要先修改密码
+      if (res.data.needChangePassword) {
+        loadingInstance.value?.close()
+        res = await passwordStep(res.data)
+        if (!res || res.code !== 0) {
+          return false
+        }
+      }
       // 登陆成功，设置用户信息和权限相关信息
       setUserInfo(res.data.user)
       setToken(res.data.token)
+      setRefreshToken(res.data.refreshToken)
 
       // 初始化路由信息
       const routerStore = useRouterStore()
@@ -103,8 +200,74 @@ export const useUserStore = defineStore('user', () => {
       loadingInstance.value?.close()
     }
   }
+  /* 两步验证 */
+  const mfaStep = async ({ challengeId, needEnroll, passkey }) => {
+    // 已注册通行密钥时优先使用通行密钥 取消后改为输入验证码
+    if (passkey && isPasskeySupported()) {
+      try {
+        await ElMessageBox.confirm('请使用已注册的通行密钥完成验证', '两步验证', {
+          confirmButtonText: '使用通行密钥',
+          cancelButtonText: '输入验证码',
+          distinguishCancelAndClose: true
+        })
+        const begin = await mfaPasskeyBegin({ challengeId })
+        if (begin.code !== 0) {
+          return null
+        }
+        const credential = await getPasskey(begin.data)
+        return await mfaPasskeyLogin({ challengeId, credential })
+      } catch (e) {
+        if (e !== 'cancel') {
+          return null
+        }
+      }
+    }
+    let message = '请输入验证器中的6位验证码或恢复码'
+    if (needEnroll) {
+      const enrollRes = await mfaEnroll({ challengeId })
+      if (enrollRes.code !== 0) {
+        return null
+      }
+      message = `当前角色要求开启两步验证, 请在验证器中添加密钥 ${enrollRes.data.secret} 后输入6位验证码`
+    }
+    try {
+      const { value } = await ElMessageBox.prompt(message, '两步验证', {
+        confirmButtonText: '验证',
+        cancelButtonText: '取消',
+        inputPattern: /\S+/,
+        inputErrorMessage: '请输入验证码'
+      })
+      return await mfaLogin({ challengeId, code: value })
+    } catch (e) {
+      return null
+    }
+  }
+  /* 登录时修改密码 新密码不满足密码策略时提示错误并重新输入 */
+  const passwordStep = async ({ challengeId, reason }) => {
+    for (;;) {
+      try {
+        const { value } = await ElMessageBox.prompt(reason, '修改密码', {
+          confirmButtonText: '修改并登录',
+          cancelButtonText: '取消',
+          inputType: 'password',
+          inputPlaceholder: '请输入新密码',
+          inputPattern: /\S+/,
+          inputErrorMessage: '请输入新密码'
+        })
+        const res = await changeExpiredPassword({ challengeId, newPassword: value })
+        if (res.code === 0) {
+          return res
+        }
+      } catch (e) {
+        return null
+      }
+    }
+  }
   /* 登出*/
   const LoginOut = async () => {
+    if (impersonateOrigin.value.token) {
+      return StopImpersonate()
+    }
     const res = await jsonInBlacklist()
 
     // 登出失败
@@ -122,6 +285,8 @@ export const useUserStore = defineStore('user', () => {
   const ClearStorage = async () => {
     token.value = ''
     xToken.value = ''
+    refreshToken.value = ''
+    impersonateOrigin.value = { token: '', refreshToken: '' }
     sessionStorage.clear()
     localStorage.removeItem('originSetting')
   }
@@ -129,12 +294,20 @@ export const useUserStore = defineStore('user', () => {
   return {
     userInfo,
     token: currentToken,
+    refreshToken,
+    impersonator,
+    impersonateOrigin,
     NeedInit,
     ResetUserInfo,
     GetUserInfo,
     LoginIn,
+    OidcLoginIn,
+    PasskeyLoginIn,
     LoginOut,
+    Impersonate,
+    StopImpersonate,
     setToken,
+    setRefreshToken,
     loadingInstance,
     ClearStorage
   }
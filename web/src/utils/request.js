@@ -36,6 +36,39 @@ const closeLoading = () => {
     loadingInstance && loadingInstance.close()
   }
 }
+
+// 访问令牌过期后使用刷新令牌换取新令牌 并发请求共用同一次刷新
+let refreshPromise = null
+const refreshAccessToken = () => {
+  const userStore = useUserStore()
+  // 代登录令牌到期 恢复操作人自己的登录
+  if (userStore.impersonateOrigin.token) {
+    userStore.StopImpersonate(false)
+    return Promise.resolve(false)
+  }
+  if (!userStore.refreshToken) {
+    return Promise.resolve(false)
+  }
+  if (!refreshPromise) {
+    refreshPromise = axios
+      .post(import.meta.env.VITE_BASE_API + '/base/refresh', {
+        refreshToken: userStore.refreshToken
+      })
+      .then((res) => {
+        if (res.data.code !== 0) {
+          return false
+        }
+        userStore.setToken(res.data.data.token)
+        userStore.setRefreshToken(res.data.data.refreshToken)
+        return true
+      })
+      .catch(() => false)
+      .finally(() => {
+        refreshPromise = null
+      })
+  }
+  return refreshPromise
+}
 // http request 拦截器
 service.interceptors.request.use(
   (config) => {
@@ -88,11 +121,21 @@ service.interceptors.response.use(
       return response.data.msg ? response.data : response
     }
   },
-  (error) => {
+  async (error) => {
     if (!error.config.donNotShowLoading) {
       closeLoading()
     }
 
+    if (error.response?.status === 401 && !error.config.isRetryRequest) {
+      const refreshed = await refreshAccessToken()
+      if (refreshed) {
+        const userStore = useUserStore()
+        error.config.isRetryRequest = true
+        error.config.headers['x-token'] = userStore.token
+        return service(error.config)
+      }
+    }
+
     if (!error.response) {
       ElMessageBox.confirm(
         `
@@ -0,0 +1,65 @@
+// 通行密钥 服务端使用base64url传输二进制字段 浏览器接口使用ArrayBuffer
+
+const toBuffer = (value) => {
+  const base64 = value.replace(/-/g, '+').replace(/_/g, '/')
+  const binary = atob(base64.padEnd(base64.length + ((4 - (base64.length % 4)) % 4), '='))
+  return Uint8Array.from(binary, (c) => c.charCodeAt(0)).buffer
+}
+
+const toBase64url = (buffer) => {
+  if (!buffer) {
+    return ''
+  }
+  const binary = String.fromCharCode(...new Uint8Array(buffer))
+  return btoa(binary).replace(/\+/g, '-').replace(/\//g, '_').replace(/=+$/, '')
+}
+
+const toDescriptors = (list) =>
+  (list || []).map((item) => ({ ...item, id: toBuffer(item.id) }))
+
+export const isPasskeySupported = () =>
+  !!(window.PublicKeyCredential && navigator.credentials)
+
+// 注册 options 为 beginPasskeyRegistration 返回值
+export const createPasskey = async (options) => {
+  const credential = await navigator.credentials.create({
+    publicKey: {
+      ...options,
+      challenge: toBuffer(options.challenge),
+      user: { ...options.user, id: toBuffer(options.user.id) },
+      excludeCredentials: toDescriptors(options.excludeCredentials)
+    }
+  })
+  return {
+    id: credential.id,
+    type: credential.type,
+    response: {
+      clientDataJSON: toBase64url(credential.response.clientDataJSON),
+      attestationObject: toBase64url(credential.response.attestationObject),
+      transports: credential.response.getTransports
+        ? credential.response.getTransports()
+        : []
+    }
+  }
+}
+
+// 登录 options 为 passkeyLoginBegin 或 mfaPasskeyBegin 返回值
+export const getPasskey = async (options) => {
+  const credential = await navigator.credentials.get({
+    publicKey: {
+      ...options,
+      challenge: toBuffer(options.challenge),
+      allowCredentials: toDescriptors(options.allowCredentials)
+    }
+  })
+  return {
+    id: credential.id,
+    type: credential.type,
+    response: {
+      clientDataJSON: toBase64url(credential.response.clientDataJSON),
+      authenticatorData: toBase64url(credential.response.authenticatorData),
+      signature: toBase64url(credential.response.signature),
+      userHandle: toBase64url(credential.response.userHandle)
+    }
+  }
+}